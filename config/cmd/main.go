@@ -10,24 +10,24 @@ import (
 )
 
 func main() {
-	//os.Create("./config/conf/config.yaml")
-	//生成的 example.yaml 为配置文件示例, 使用时重命名为 config.yaml
+	//生成的 generated.yaml 包含所有配置项, 带注释的示例见 example.yaml
 	generaetEmptyConfig()
 }
 
 func generaetEmptyConfig() {
-	fileName := "example.yaml"
+	fileName := "generated.yaml"
 	targetPath := "./config/conf"
 
 	conf := config.Config{}
-	conf.Mysql.Host = "127.0.0.1"
-	conf.Mysql.Port = 3306
-	conf.Mysql.Username = "root"
-	conf.Mysql.Password = "123456"
-	conf.Mysql.Dbname = "douyin2"
-	conf.Mysql.Timeout = "10s"
-	conf.Mysql.MaxIdleConns = 10
-	conf.Mysql.MaxOpenConns = 11
+	conf.Database.Driver = "postgres"
+	conf.Database.Host = "127.0.0.1"
+	conf.Database.Port = 5432
+	conf.Database.Username = "bookcommunity"
+	conf.Database.Password = "123456"
+	conf.Database.Dbname = "bookcommunity"
+	conf.Database.Timeout = "10s"
+	conf.Database.MaxIdleConns = 10
+	conf.Database.MaxOpenConns = 100
 	conf.Log.Level = "trace"
 	conf.Log.Path = "./log_file"
 	conf.Log.PanicLogName = "gin_panic.log"
@@ -41,8 +41,15 @@ func generaetEmptyConfig() {
 	if err != nil {
 		panic(err)
 	}
-	readConfig(filepath.Join(targetPath, fileName))
-	fmt.Println(ReadTest)
+	// 读回生成的文件并检查
+	readTest, err := config.Load(filepath.Join(targetPath, fileName))
+	if err != nil {
+		panic(err)
+	}
+	if err := readTest.Validate(); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(readTest)
 }
 
 // saveNewConfig 保存配置文件,需要传入结构体对象,配置文件名,配置文件类型,配置文件路径。
@@ -71,20 +78,3 @@ func saveNewConfig(obj interface{}, configName, configType, configPath string) e
 	}
 	return nil
 }
-
-var ReadTest config.Config
-
-func readConfig(file string) {
-	viper.SetConfigFile(file)
-	viper.SetConfigType("yaml")
-
-	err := viper.ReadInConfig()
-	if err != nil {
-		panic("读取配置文件失败, error:" + err.Error())
-	}
-
-	err = viper.Unmarshal(&ReadTest)
-	if err != nil {
-		panic("解析配置文件失败, error:" + err.Error())
-	}
-}
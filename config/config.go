@@ -1,42 +1,43 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
+	"sync/atomic"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/log"
-	"github.com/sylvia-ymlin/Coconut-book-community/utils"
-	"github.com/spf13/viper"
 )
 
-var allConfig Config
+// DefaultFile 默认的配置文件路径
+const DefaultFile = "./config/conf/config.yaml"
 
-func init() {
-	file := "config.yaml"
-	basePath := "./config/conf"
-	if !utils.FileOrDirIsExist(filepath.Join(basePath, file)) {
-		os.Create(filepath.Join(basePath, file))
-		fmt.Println("配置文件不存在,已创建配置文件,请修改配置文件后重启程序")
-	}
-	readConfig(filepath.Join(basePath, file))
-	if allConfig.Vedio.BasePath == "" {
-		panic("请配置视频存储路径")
-	}
-	if allConfig.Vedio.UrlPrefix == "" {
-		panic("请配置视频访问路径")
+// current 当前使用的配置，由 Set 设置，热更新时整体替换。导入本包不会读取任何文件
+var current atomic.Pointer[Config]
+
+// Set 设置当前使用的配置，启动时由 initiate 调用，测试中可以直接设置需要的配置
+func Set(conf Config) {
+	current.Store(&conf)
+}
+
+// Get 获取当前使用的配置
+func Get() Config {
+	return *get()
+}
+
+func get() *Config {
+	if conf := current.Load(); conf != nil {
+		return conf
 	}
+	return &Config{}
 }
 
 // GetDatabaseConfig 获取数据库配置
 func GetDatabaseConfig() DatabaseConfig {
-	return allConfig.Database
+	return get().Database
 }
 
 // GetMysqlConfig 兼容旧方法（已弃用）
 // Deprecated: Use GetDatabaseConfig instead
 func GetMysqlConfig() MysqlConfig {
-	return allConfig.Database
+	return get().Database
 }
 
 func GetGlobalLoggerConfig() log.LogConfig {
@@ -44,59 +45,102 @@ func GetGlobalLoggerConfig() log.LogConfig {
 }
 
 func GetLogConfig() LogConfig {
-	return allConfig.Log
+	return get().Log
+}
+
+func GetMailConfig() MailConfig {
+	return get().Mail
+}
+
+func GetLoginLimitConfig() LoginLimitConfig {
+	return get().LoginLimit
+}
+
+func GetOIDCProviderConfigs() []OIDCProviderConfig {
+	return get().OIDCProviders
+}
+
+func GetBootstrapAdmins() []string {
+	return get().BootstrapAdmins
+}
+
+func GetModerationConfig() ModerationConfig {
+	return get().Moderation
+}
+
+func GetTextFilterConfig() TextFilterConfig {
+	return get().TextFilter
+}
+
+func GetReviewContentConfig() ReviewContentConfig {
+	return get().ReviewContent
+}
+
+func GetMessageQueueConfig() MessageQueueConfig {
+	return get().MessageQueue
+}
+
+func GetRabbitMQConfig() RabbitMQConfig {
+	return get().RabbitMQ
+}
+
+func GetOutboxConfig() OutboxConfig {
+	return get().Outbox
 }
 
 func GetJwtConfig() JwtConfig {
-	return JwtConfig{allConfig.JwtSignKeyHex, allConfig.JwtSecretHex}
+	conf := get()
+	return JwtConfig{
+		SignKeyHex:      conf.JwtSignKeyHex,
+		SecretHex:       conf.JwtSecretHex,
+		AccessTokenTTL:  conf.JwtAccessTokenTTL,
+		RefreshTokenTTL: conf.JwtRefreshTokenTTL,
+		SignKeys:        conf.JwtSignKeys,
+		SecretKeys:      conf.JwtSecretKeys,
+	}
 }
 
 func GetServerPort() string {
-	return allConfig.ServerPort
+	return get().ServerPort
 }
 
 func GetVedioConfig() VedioConfig {
-	return allConfig.Vedio
+	return get().Vedio
 }
 
 func GetRecommendConfig() RecommendConfig {
-	return allConfig.Recommendation
+	return get().Recommendation
+}
+
+func GetCORSConfig() CORSConfig {
+	return get().CORS
+}
+
+func GetFeatureFlagsConfig() FeatureFlagsConfig {
+	return get().FeatureFlags
 }
 
 // GetRedisConfig 获取Redis配置
 func GetRedisConfig() RedisConfig {
-	return allConfig.Redis
+	return get().Redis
 }
 
 func IsDebug() bool {
-	if log.PraseLevel(GetLogConfig().Level) < log.PraseLevel("debug") {
-		return false
-	} else {
-		return true
-	}
+	return get().IsDebug()
+}
+
+// IsDebug 日志级别为debug或更详细时为true
+func (c Config) IsDebug() bool {
+	return log.PraseLevel(c.Log.Level) >= log.PraseLevel("debug")
 }
 
 func defaultLogConfig() log.LogConfig {
+	conf := get()
 	var Config log.LogConfig
 	Config.DateSplit = true
 	Config.ErrSeparate = true
-	Config.LogPath = allConfig.Log.Path
-	Config.LogLevel = allConfig.Log.Level
+	Config.LogPath = conf.Log.Path
+	Config.LogLevel = conf.Log.Level
 	Config.ShowShortFileInConsole = true
 	return Config
 }
-
-func readConfig(file string) {
-	viper.SetConfigFile(file)
-	viper.SetConfigType("yaml")
-
-	err := viper.ReadInConfig()
-	if err != nil {
-		panic("读取配置文件失败, error:" + err.Error())
-	}
-
-	err = viper.Unmarshal(&allConfig)
-	if err != nil {
-		panic("解析配置文件失败, error:" + err.Error())
-	}
-}
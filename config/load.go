@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 环境变量前缀。每个配置项都可以用环境变量覆盖，
+// 名字为前缀加上大写的配置路径，例如 database.password 对应 BOOKCOMMUNITY_DATABASE_PASSWORD
+const EnvPrefix = "BOOKCOMMUNITY"
+
+// fileEnvSuffix 环境变量名加上该后缀时，值为文件路径，从文件读取配置值，
+// 例如 BOOKCOMMUNITY_DATABASE_PASSWORD_FILE=/run/secrets/db-password，用于挂载为文件的secret
+const fileEnvSuffix = "_FILE"
+
+// Load 读取配置，优先级从高到低为: 环境变量、配置文件、默认值。
+// file为空时只使用环境变量和默认值。不检查配置是否有效，也不影响当前使用的配置
+func Load(file string) (Config, error) {
+	v := viper.New()
+	setDefaults(v)
+	var conf Config
+	if file != "" {
+		v.SetConfigFile(file)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return conf, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+	}
+	if err := bindEnv(v); err != nil {
+		return conf, err
+	}
+	if err := applyRenamedKeys(v); err != nil {
+		return conf, err
+	}
+	if err := v.Unmarshal(&conf); err != nil {
+		return conf, fmt.Errorf("解析配置失败: %w", err)
+	}
+	return conf, nil
+}
+
+// EnvName 配置路径对应的环境变量名
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// setDefaults 配置文件和环境变量都没有设置时使用的值
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server_port", "8080")
+	v.SetDefault("http_server.read_header_timeout", "10s")
+	v.SetDefault("http_server.read_timeout", "30s")
+	v.SetDefault("http_server.write_timeout", "60s")
+	v.SetDefault("http_server.idle_timeout", "120s")
+	v.SetDefault("shutdown_timeout", "20s")
+	v.SetDefault("database.driver", "postgres")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.timezone", "Asia/Shanghai")
+	v.SetDefault("database.max_idle_conns", 10)
+	v.SetDefault("database.max_open_conns", 100)
+	v.SetDefault("database.conn_max_lifetime", "1h")
+	v.SetDefault("log.path", "./log_file")
+	v.SetDefault("log.panic_log_name", "gin_panic.log")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("jwt_access_token_ttl", "15m")
+	v.SetDefault("jwt_refresh_token_ttl", "720h")
+	v.SetDefault("redis.port", 6379)
+	v.SetDefault("message_queue.backend", "memory")
+	v.SetDefault("cors.allow_origins", []string{"http://localhost:3000", "http://localhost:5173", "http://127.0.0.1:3000", "http://127.0.0.1:5173"})
+}
+
+// bindEnv 为每个配置项绑定环境变量。列表中的结构体(如 oidc_providers)和按名字配置的项(如 feature_flags.flags)只能在配置文件中设置，
+// 字符串列表用逗号分隔，例如 BOOKCOMMUNITY_BOOTSTRAP_ADMINS=alice,bob
+func bindEnv(v *viper.Viper) error {
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		name := EnvName(key)
+		if err := v.BindEnv(key, name); err != nil {
+			return err
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		path := os.Getenv(name + fileEnvSuffix)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name+fileEnvSuffix, err)
+		}
+		v.Set(key, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// applyRenamedKeys 兼容改名前的配置项。
+// message_queue.durable 已改为 message_queue.backend: 只设置旧名字时按旧名字转换并提示改名，
+// 同时设置且不一致时返回错误
+func applyRenamedKeys(v *viper.Viper) error {
+	const oldKey, newKey = "message_queue.durable", "message_queue.backend"
+	if err := v.BindEnv(oldKey, EnvName(oldKey)); err != nil {
+		return err
+	}
+	if !explicitlySet(v, oldKey) {
+		return nil
+	}
+	backend := "memory"
+	if v.GetBool(oldKey) {
+		backend = "durable"
+	}
+	if explicitlySet(v, newKey) {
+		if !strings.EqualFold(v.GetString(newKey), backend) {
+			return fmt.Errorf("%s 已改名为 %s，两者不一致: %s=%t, %s=%s",
+				oldKey, newKey, oldKey, v.GetBool(oldKey), newKey, v.GetString(newKey))
+		}
+		return nil
+	}
+	logrus.Warnf("%s 已改名为 %s，请改为 %s: %s", oldKey, newKey, newKey, backend)
+	v.Set(newKey, backend)
+	return nil
+}
+
+// explicitlySet 配置文件或环境变量中设置了key，不包括默认值
+func explicitlySet(v *viper.Viper, key string) bool {
+	if v.InConfig(key) {
+		return true
+	}
+	_, ok := os.LookupEnv(EnvName(key))
+	return ok
+}
+
+// configKeys 按mapstructure标签列出t中所有可以用环境变量设置的配置路径
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			keys = append(keys, configKeys(field.Type, key)...)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+		case field.Type.Kind() == reflect.Map:
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
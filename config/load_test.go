@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadExample(t *testing.T) {
+	conf, err := Load("conf/example.yaml")
+	require.NoError(t, err)
+	assert.NoError(t, conf.Validate())
+
+	flag, ok := conf.FeatureFlags.Flags["chat_with_book"]
+	require.True(t, ok)
+	require.NotNil(t, flag.Percentage)
+	assert.False(t, flag.Enabled)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	conf, err := Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "8080", conf.ServerPort)
+	assert.Equal(t, "postgres", conf.Database.Driver)
+	assert.Equal(t, 5432, conf.Database.Port)
+	assert.Equal(t, "info", conf.Log.Level)
+}
+
+func TestLoadEnvOverride(t *testing.T) {
+	t.Setenv("BOOKCOMMUNITY_DATABASE_PASSWORD", "from-env")
+	t.Setenv("BOOKCOMMUNITY_DATABASE_PORT", "6543")
+	t.Setenv("BOOKCOMMUNITY_SERVER_PORT", "9090")
+	t.Setenv("BOOKCOMMUNITY_BOOTSTRAP_ADMINS", "alice,bob")
+	conf, err := Load("conf/example.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", conf.Database.Password)
+	assert.Equal(t, 6543, conf.Database.Port)
+	assert.Equal(t, "9090", conf.ServerPort)
+	assert.Equal(t, []string{"alice", "bob"}, conf.BootstrapAdmins)
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db-password")
+	require.NoError(t, os.WriteFile(secret, []byte("from-file\n"), 0o600))
+	t.Setenv("BOOKCOMMUNITY_DATABASE_PASSWORD_FILE", secret)
+	conf, err := Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", conf.Database.Password)
+
+	// 同时设置时环境变量优先
+	t.Setenv("BOOKCOMMUNITY_DATABASE_PASSWORD", "from-env")
+	conf, err = Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", conf.Database.Password)
+
+	t.Setenv("BOOKCOMMUNITY_REDIS_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load("")
+	assert.ErrorContains(t, err, "BOOKCOMMUNITY_REDIS_PASSWORD_FILE")
+}
+
+func TestLoadRenamedQueueBackend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "conf.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("message_queue:\n  durable: true\n"), 0o600))
+	conf, err := Load(file)
+	require.NoError(t, err)
+	assert.Equal(t, "durable", conf.MessageQueue.Backend)
+
+	t.Setenv("BOOKCOMMUNITY_MESSAGE_QUEUE_DURABLE", "false")
+	conf, err = Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "memory", conf.MessageQueue.Backend)
+
+	// 同时设置新旧名字且不一致时报错
+	t.Setenv("BOOKCOMMUNITY_MESSAGE_QUEUE_BACKEND", "rabbitmq")
+	_, err = Load("")
+	assert.ErrorContains(t, err, "message_queue.durable")
+	t.Setenv("BOOKCOMMUNITY_MESSAGE_QUEUE_BACKEND", "memory")
+	conf, err = Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "memory", conf.MessageQueue.Backend)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "BOOKCOMMUNITY_DATABASE_PASSWORD", EnvName("database.password"))
+	assert.Equal(t, "BOOKCOMMUNITY_JWT_SIGN_KEY_HEX", EnvName("jwt_sign_key_hex"))
+}
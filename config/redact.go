@@ -0,0 +1,74 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+)
+
+// redactedValue 替换secret后的值，secret为空时保持为空，便于看出是否配置
+const redactedValue = "******"
+
+// Redacted 按配置文件的结构返回配置，带 secret 标签的配置项被替换，用于展示当前生效的配置
+func (c Config) Redacted() map[string]interface{} {
+	return redactStruct(reflect.ValueOf(c))
+}
+
+func redactStruct(v reflect.Value) map[string]interface{} {
+	t := v.Type()
+	result := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		result[key] = redactValue(v.Field(i), field.Tag.Get("secret"))
+	}
+	return result
+}
+
+func redactValue(v reflect.Value, secret string) interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i), secret)
+		}
+		return items
+	case reflect.Map:
+		items := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[iter.Key().String()] = redactValue(iter.Value(), secret)
+		}
+		return items
+	case reflect.String:
+		return redactString(v.String(), secret)
+	default:
+		return v.Interface()
+	}
+}
+
+func redactString(s string, secret string) string {
+	if s == "" {
+		return s
+	}
+	switch secret {
+	case "true":
+		return redactedValue
+	case "url":
+		// 只隐藏地址中的密码
+		u, err := url.Parse(s)
+		if err != nil {
+			return redactedValue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+		return u.String()
+	default:
+		return s
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Subscriber 配置热更新后调用。old和new只有可以热更新的配置项不同，见 applyReloadable
+type Subscriber func(old, new Config)
+
+type subscriber struct {
+	name string
+	fn   Subscriber
+}
+
+var (
+	// reloadMu 保证同一时间只有一次热更新，订阅者按注册顺序看到每次修改
+	reloadMu    sync.Mutex
+	subscribers []subscriber
+	reloadedAt  time.Time
+)
+
+// OnChange 注册热更新的订阅者，name用于日志
+func OnChange(name string, fn Subscriber) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, subscriber{name: name, fn: fn})
+}
+
+// ReloadedAt 最后一次热更新生效的时间，没有热更新过时为零值
+func ReloadedAt() time.Time {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return reloadedAt
+}
+
+// applyReloadable 把next中可以热更新的配置项复制到conf。
+// 这些配置项在每次使用时读取或由订阅者应用，其他配置项在启动时使用，修改后需要重启
+func applyReloadable(conf *Config, next Config) {
+	conf.Log.Level = next.Log.Level
+	conf.CORS = next.CORS
+	conf.LoginLimit = next.LoginLimit
+	conf.Recommendation = next.Recommendation
+	conf.FeatureFlags.Flags = next.FeatureFlags.Flags
+}
+
+// Reload 重新读取配置，检查通过后替换可以热更新的配置项并通知订阅者。
+// 配置有问题时返回错误，继续使用当前配置。返回值表示是否有配置项被修改
+func Reload(file string) (bool, error) {
+	next, err := Load(file)
+	if err != nil {
+		return false, err
+	}
+	if err := next.Validate(); err != nil {
+		return false, err
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	old := Get()
+	merged := old
+	applyReloadable(&merged, next)
+	if !reflect.DeepEqual(merged, next) {
+		logrus.Warn("config reload: some changed settings are not reloadable and need a restart")
+	}
+	if reflect.DeepEqual(merged, old) {
+		return false, nil
+	}
+	current.Store(&merged)
+	reloadedAt = time.Now()
+	for _, s := range subscribers {
+		notify(s, old, merged)
+	}
+	logrus.Info("config reloaded")
+	return true, nil
+}
+
+func notify(s subscriber, old, new Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("config subscriber %s panic: %v", s.name, r)
+		}
+	}()
+	s.fn(old, new)
+}
+
+// Watch 监听file的修改并热更新，ctx结束时停止。
+// 监听所在目录，文件被替换(编辑器保存、Kubernetes更新ConfigMap的符号链接)时同样生效
+func Watch(ctx context.Context, file string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file = filepath.Clean(file)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch %s: %w", file, err)
+	}
+	go func() {
+		defer watcher.Close()
+		realFile, _ := filepath.EvalSymlinks(file)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				currentFile, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				relinked := currentFile != "" && currentFile != realFile
+				if !written && !relinked {
+					continue
+				}
+				realFile = currentFile
+				if _, err := Reload(file); err != nil {
+					logrus.Errorf("config reload failed, keep current config: %v", err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logrus.Errorf("config watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
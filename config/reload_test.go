@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTestConfig = `
+server_port: "%s"
+database:
+  username: bookcommunity
+  dbname: bookcommunity
+jwt_sign_key_hex: "0123456789abcdef0123456789abcdef"
+jwt_secret_hex: "fedcba9876543210fedcba9876543210"
+log:
+  level: %s
+`
+
+func writeReloadConfig(t *testing.T, file string, level string, port string) {
+	if port == "" {
+		port = "8080"
+	}
+	require.NoError(t, os.WriteFile(file, []byte(fmt.Sprintf(reloadTestConfig, port, level)), 0o600))
+}
+
+// resetReload 恢复热更新相关的全局状态
+func resetReload(t *testing.T) {
+	old := current.Load()
+	oldSubscribers := subscribers
+	t.Cleanup(func() {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		current.Store(old)
+		subscribers = oldSubscribers
+		reloadedAt = time.Time{}
+	})
+}
+
+func TestReload(t *testing.T) {
+	resetReload(t)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, file, "info", "")
+	conf, err := Load(file)
+	require.NoError(t, err)
+	Set(conf)
+
+	var notified []string
+	OnChange("test", func(old, new Config) {
+		notified = append(notified, old.Log.Level+"->"+new.Log.Level)
+	})
+
+	// 没有修改时不通知
+	changed, err := Reload(file)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, notified)
+	assert.True(t, ReloadedAt().IsZero())
+
+	writeReloadConfig(t, file, "debug", "9090")
+	changed, err = Reload(file)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"info->debug"}, notified)
+	assert.Equal(t, "debug", GetLogConfig().Level)
+	// 端口不能热更新
+	assert.Equal(t, "8080", GetServerPort())
+	assert.False(t, ReloadedAt().IsZero())
+}
+
+func TestReloadInvalidKeepsCurrent(t *testing.T) {
+	resetReload(t)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, file, "info", "")
+	conf, err := Load(file)
+	require.NoError(t, err)
+	Set(conf)
+
+	writeReloadConfig(t, file, "loud", "")
+	_, err = Reload(file)
+	assert.ErrorContains(t, err, "log.level")
+	assert.Equal(t, "info", GetLogConfig().Level)
+}
+
+func TestReloadSubscriberPanic(t *testing.T) {
+	resetReload(t)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, file, "info", "")
+	conf, err := Load(file)
+	require.NoError(t, err)
+	Set(conf)
+
+	called := false
+	OnChange("panic", func(_, _ Config) { panic("boom") })
+	OnChange("after", func(_, _ Config) { called = true })
+	writeReloadConfig(t, file, "warn", "")
+	_, err = Reload(file)
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestWatch(t *testing.T) {
+	resetReload(t)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, file, "info", "")
+	conf, err := Load(file)
+	require.NoError(t, err)
+	Set(conf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, Watch(ctx, file))
+
+	writeReloadConfig(t, file, "error", "")
+	assert.Eventually(t, func() bool {
+		return GetLogConfig().Level == "error"
+	}, 5*time.Second, 20*time.Millisecond)
+}
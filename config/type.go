@@ -5,22 +5,100 @@ type Config struct {
 	Redis    RedisConfig    `mapstructure:"redis" yaml:"redis"`
 	Log      LogConfig      `mapstructure:"log" yaml:"log"`
 	//jwt签名密钥
-	JwtSignKeyHex string `mapstructure:"jwt_sign_key_hex" yaml:"jwt_sign_key_hex"`
+	JwtSignKeyHex string `mapstructure:"jwt_sign_key_hex" yaml:"jwt_sign_key_hex" secret:"true"`
 	//jwt加密密钥
-	JwtSecretHex string `mapstructure:"jwt_secret_hex" yaml:"jwt_secret_hex"`
+	JwtSecretHex string `mapstructure:"jwt_secret_hex" yaml:"jwt_secret_hex" secret:"true"`
+	//access token有效期,e.g. "15m"
+	JwtAccessTokenTTL string `mapstructure:"jwt_access_token_ttl" yaml:"jwt_access_token_ttl"`
+	//refresh token有效期,e.g. "720h"
+	JwtRefreshTokenTTL string `mapstructure:"jwt_refresh_token_ttl" yaml:"jwt_refresh_token_ttl"`
+	//带kid的jwt签名密钥环,用于密钥轮换
+	JwtSignKeys []JwtSignKeyConfig `mapstructure:"jwt_sign_keys" yaml:"jwt_sign_keys"`
+	//带版本号的jwt加密密钥(AES-GCM),用于密钥轮换
+	JwtSecretKeys []JwtSecretKeyConfig `mapstructure:"jwt_secret_keys" yaml:"jwt_secret_keys"`
 	//服务端口号
 	ServerPort string `mapstructure:"server_port" yaml:"server_port"`
+	//HTTP服务器超时
+	HTTPServer HTTPServerConfig `mapstructure:"http_server" yaml:"http_server"`
+	//收到SIGTERM后等待请求和消息处理完的最长时间, e.g. "20s"，超过后直接退出
+	ShutdownTimeout string `mapstructure:"shutdown_timeout" yaml:"shutdown_timeout"`
 	//视频配置
 	Vedio VedioConfig `mapstructure:"vedio" yaml:"vedio"`
 	//推荐系统配置（预留）
 	Recommendation RecommendConfig `mapstructure:"recommendation" yaml:"recommendation"`
+	//邮件配置
+	Mail MailConfig `mapstructure:"mail" yaml:"mail"`
+	//登录防爆破配置
+	LoginLimit LoginLimitConfig `mapstructure:"login_limit" yaml:"login_limit"`
+	//OIDC第三方登录提供方
+	OIDCProviders []OIDCProviderConfig `mapstructure:"oidc_providers" yaml:"oidc_providers"`
+	//启动时提升为管理员的用户名
+	BootstrapAdmins []string `mapstructure:"bootstrap_admins" yaml:"bootstrap_admins"`
+	//内容审核配置
+	Moderation ModerationConfig `mapstructure:"moderation" yaml:"moderation"`
+	//用户发布文本的过滤配置
+	TextFilter TextFilterConfig `mapstructure:"text_filter" yaml:"text_filter"`
+	//书评正文Markdown渲染配置
+	ReviewContent ReviewContentConfig `mapstructure:"review_content" yaml:"review_content"`
+	//点赞、评论、关注消息队列配置
+	MessageQueue MessageQueueConfig `mapstructure:"message_queue" yaml:"message_queue"`
+
+	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq" yaml:"rabbitmq"`
+
+	Outbox OutboxConfig `mapstructure:"outbox" yaml:"outbox"`
+	//跨域配置
+	CORS CORSConfig `mapstructure:"cors" yaml:"cors"`
+	//功能开关
+	FeatureFlags FeatureFlagsConfig `mapstructure:"feature_flags" yaml:"feature_flags"`
+}
+
+// FeatureFlagsConfig 功能开关配置。管理员在后台修改的开关保存在数据库中，同名时优先于配置文件
+type FeatureFlagsConfig struct {
+	// 从数据库刷新开关的间隔, e.g. "30s"，多个实例时其他实例上的修改在刷新后生效
+	RefreshInterval string `mapstructure:"refresh_interval" yaml:"refresh_interval"`
+	// 按开关名配置，开关名只能包含小写字母、数字和下划线，修改后不需要重启
+	Flags map[string]FeatureFlagConfig `mapstructure:"flags" yaml:"flags"`
+}
+
+// FeatureFlagConfig 一个功能开关
+type FeatureFlagConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	// 按用户ID灰度的比例，0到100，不配置时对所有用户开启
+	Percentage *int `mapstructure:"percentage" yaml:"percentage"`
+	// 不受灰度比例限制的用户ID，用于内部试用
+	Users       []uint `mapstructure:"users" yaml:"users"`
+	Description string `mapstructure:"description" yaml:"description"`
+}
+
+// HTTPServerConfig HTTP服务器的超时，防止慢客户端长时间占用连接
+type HTTPServerConfig struct {
+	// 读取请求头的超时, e.g. "10s"
+	ReadHeaderTimeout string `mapstructure:"read_header_timeout" yaml:"read_header_timeout"`
+	// 读取整个请求的超时, e.g. "30s"
+	ReadTimeout string `mapstructure:"read_timeout" yaml:"read_timeout"`
+	// 从读完请求头到写完响应的超时, e.g. "60s"，需要大于对话等慢接口的处理时间
+	WriteTimeout string `mapstructure:"write_timeout" yaml:"write_timeout"`
+	// keep-alive连接的空闲超时, e.g. "120s"
+	IdleTimeout string `mapstructure:"idle_timeout" yaml:"idle_timeout"`
+	// 可信的反向代理地址或网段, e.g. 10.0.0.0/8。只有来自这些地址的请求才使用X-Forwarded-For作为客户端IP，
+	// 不配置时不信任任何代理，直接使用连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies" yaml:"trusted_proxies"`
+	// Prometheus指标单独监听的地址, e.g. 127.0.0.1:9100，不对外暴露。
+	// 为空时只能由管理员通过 /api/admin/metrics 访问
+	MetricsAddr string `mapstructure:"metrics_addr" yaml:"metrics_addr"`
+}
+
+// CORSConfig 跨域请求配置，修改后不需要重启
+type CORSConfig struct {
+	// 允许的前端地址, e.g. http://localhost:5173
+	AllowOrigins []string `mapstructure:"allow_origins" yaml:"allow_origins"`
 }
 
 // DatabaseConfig 数据库配置 (支持 PostgreSQL)
 type DatabaseConfig struct {
 	Driver   string `mapstructure:"driver" yaml:"driver"` // postgres, mysql
 	Username string `mapstructure:"username" yaml:"username"`
-	Password string `mapstructure:"password" yaml:"password"`
+	Password string `mapstructure:"password" yaml:"password" secret:"true"`
 	Host     string `mapstructure:"host" yaml:"host"`
 	Port     int    `mapstructure:"port" yaml:"port"`
 	Dbname   string `mapstructure:"dbname" yaml:"dbname"`
@@ -50,6 +128,171 @@ type LogConfig struct {
 type JwtConfig struct {
 	SignKeyHex string `mapstructure:"sign_key_hex" yaml:"sign_key_hex"`
 	SecretHex  string `mapstructure:"secret_hex" yaml:"secret_hex"`
+	// access token有效期,为空时使用默认值
+	AccessTokenTTL string `mapstructure:"access_token_ttl" yaml:"access_token_ttl"`
+	// refresh token有效期,为空时使用默认值
+	RefreshTokenTTL string `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+	// 签名密钥环,SignKeyHex作为kid为空的旧密钥继续用于验签
+	SignKeys []JwtSignKeyConfig `mapstructure:"sign_keys" yaml:"sign_keys"`
+	// GCM加密密钥,为空时使用SecretHex的CBC模式
+	SecretKeys []JwtSecretKeyConfig `mapstructure:"secret_keys" yaml:"secret_keys"`
+}
+
+// JwtSignKeyConfig 一把jwt签名密钥
+type JwtSignKeyConfig struct {
+	// 写入token header的key id
+	Kid        string `mapstructure:"kid" yaml:"kid"`
+	SignKeyHex string `mapstructure:"sign_key_hex" yaml:"sign_key_hex" secret:"true"`
+	// 开始用于签名的时间(RFC3339),为空表示立即生效;生效前只用于验签
+	ActivateAt string `mapstructure:"activate_at" yaml:"activate_at"`
+}
+
+// JwtSecretKeyConfig 一把jwt加密密钥
+type JwtSecretKeyConfig struct {
+	// 写入密文的密钥版本号
+	Version   uint32 `mapstructure:"version" yaml:"version"`
+	SecretHex string `mapstructure:"secret_hex" yaml:"secret_hex" secret:"true"`
+	// 开始用于加密的时间(RFC3339),为空表示立即生效;生效前只用于解密
+	ActivateAt string `mapstructure:"activate_at" yaml:"activate_at"`
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	// smtp, file, log
+	Driver   string `mapstructure:"driver" yaml:"driver"`
+	Host     string `mapstructure:"host" yaml:"host"`
+	Port     int    `mapstructure:"port" yaml:"port"`
+	Username string `mapstructure:"username" yaml:"username"`
+	Password string `mapstructure:"password" yaml:"password" secret:"true"`
+	From     string `mapstructure:"from" yaml:"from"`
+	// driver为file时邮件写入的文件
+	FilePath string `mapstructure:"file_path" yaml:"file_path"`
+	// 邮件中链接的前端地址, e.g. http://localhost:5173
+	LinkBaseURL string `mapstructure:"link_base_url" yaml:"link_base_url"`
+	// 邮箱验证token有效期, e.g. "24h"
+	VerifyTokenTTL string `mapstructure:"verify_token_ttl" yaml:"verify_token_ttl"`
+	// 重置密码token有效期, e.g. "30m"
+	ResetTokenTTL string `mapstructure:"reset_token_ttl" yaml:"reset_token_ttl"`
+}
+
+// LoginLimitConfig 登录防爆破配置，为0或为空时使用默认值
+type LoginLimitConfig struct {
+	// 同一账号连续失败多少次后开始锁定
+	AccountMaxFailures int `mapstructure:"account_max_failures" yaml:"account_max_failures"`
+	// 同一IP失败多少次后开始锁定
+	IPMaxFailures int `mapstructure:"ip_max_failures" yaml:"ip_max_failures"`
+	// 第一次锁定的时长，之后每失败一次翻倍, e.g. "1m"
+	BaseLockout string `mapstructure:"base_lockout" yaml:"base_lockout"`
+	// 最长锁定时长, e.g. "1h"
+	MaxLockout string `mapstructure:"max_lockout" yaml:"max_lockout"`
+	// 从第一次失败开始计算的计数窗口，超过后重新计数, e.g. "24h"
+	FailureWindow string `mapstructure:"failure_window" yaml:"failure_window"`
+}
+
+// ModerationConfig 内容审核配置
+type ModerationConfig struct {
+	// 书评或评论被多少个不同用户举报后自动隐藏，为0时使用默认值5，小于0时不自动隐藏
+	AutoHideReports int `mapstructure:"auto_hide_reports" yaml:"auto_hide_reports"`
+}
+
+// TextFilterConfig 书评、评论发布前的文本过滤，动作可选 reject, mask, hold
+type TextFilterConfig struct {
+	Dictionaries []TextFilterDictConfig `mapstructure:"dictionaries" yaml:"dictionaries"`
+	// 检查词库文件是否修改的间隔, e.g. "30s"，为空时不自动重新加载
+	ReloadInterval string `mapstructure:"reload_interval" yaml:"reload_interval"`
+	// 链接数超过该值时按 link_action 处理，为0时不检查
+	MaxLinks   int    `mapstructure:"max_links" yaml:"max_links"`
+	LinkAction string `mapstructure:"link_action" yaml:"link_action"`
+	// 同一字符连续出现超过该次数时按 repeat_action 处理，为0时不检查
+	MaxRepeat    int    `mapstructure:"max_repeat" yaml:"max_repeat"`
+	RepeatAction string `mapstructure:"repeat_action" yaml:"repeat_action"`
+	// 与该时间内同一用户发布的内容比较, e.g. "24h"，为空时不检查重复
+	DuplicateWindow string `mapstructure:"duplicate_window" yaml:"duplicate_window"`
+	// 最多比较最近多少条，为0时使用默认值10
+	DuplicateLookback int `mapstructure:"duplicate_lookback" yaml:"duplicate_lookback"`
+	// 相似度达到该值视为重复，为0时使用默认值0.9
+	DuplicateSimilarity float64 `mapstructure:"duplicate_similarity" yaml:"duplicate_similarity"`
+	DuplicateAction     string  `mapstructure:"duplicate_action" yaml:"duplicate_action"`
+}
+
+// ReviewContentConfig 书评正文的Markdown渲染
+type ReviewContentConfig struct {
+	// 允许在正文中显示的图片地址前缀，为空时只允许 vedio.url_prefix 下的上传文件
+	ImageURLPrefixes []string `mapstructure:"image_url_prefixes" yaml:"image_url_prefixes"`
+	// 信息流卡片摘要的字数，为0时使用默认值140，最大 MaxExcerptLength
+	ExcerptLength int `mapstructure:"excerpt_length" yaml:"excerpt_length"`
+}
+
+// MaxExcerptLength 摘要最多的字数。excerpt列长度为1000，截断时还会加上一个…
+const MaxExcerptLength = 999
+
+// MessageQueueConfig 点赞、评论、关注的消息队列
+type MessageQueueConfig struct {
+	// 队列的实现: memory(只保存在内存中), durable(先写入磁盘日志，重启后继续处理),
+	// rabbitmq(使用 rabbitmq 中配置的broker)，默认memory。durable和rabbitmq会重试失败的消息
+	Backend string `mapstructure:"backend" yaml:"backend"`
+	// durable的日志文件目录
+	Dir string `mapstructure:"dir" yaml:"dir"`
+	// 最多处理的次数，超过后放入死信，为0时使用默认值5
+	MaxAttempts int `mapstructure:"max_attempts" yaml:"max_attempts"`
+	// 第一次重试前的等待时间, e.g. "1s"，之后每次翻倍
+	RetryBackoff string `mapstructure:"retry_backoff" yaml:"retry_backoff"`
+	// 重试等待时间的上限, e.g. "5m"
+	MaxRetryBackoff string `mapstructure:"max_retry_backoff" yaml:"max_retry_backoff"`
+	// 内存队列中最多等待的消息数，为0时不限制
+	Capacity int `mapstructure:"capacity" yaml:"capacity"`
+	// 内存队列满时的处理: block(等待), drop(丢弃), error(返回服务繁忙)，默认block
+	OverflowPolicy string `mapstructure:"overflow_policy" yaml:"overflow_policy"`
+	// 已处理消息的Key保留多久, e.g. "168h"，超过后清理
+	ProcessedKeyRetention string `mapstructure:"processed_key_retention" yaml:"processed_key_retention"`
+}
+
+// RabbitMQConfig message_queue.backend 为 rabbitmq 时使用的broker
+type RabbitMQConfig struct {
+	// 地址中的密码是secret
+	URL string `mapstructure:"url" yaml:"url" secret:"url"`
+	// 业务消息的交换机，默认bookcommunity
+	Exchange string `mapstructure:"exchange" yaml:"exchange"`
+	// 队列名前缀，评论队列为 前缀.comment，默认bookcommunity
+	QueuePrefix string `mapstructure:"queue_prefix" yaml:"queue_prefix"`
+	// 每个队列同时处理的消息数，为0时使用默认值10
+	Prefetch int `mapstructure:"prefetch" yaml:"prefetch"`
+	// 连接断开后重连的间隔, e.g. "5s"
+	ReconnectInterval string `mapstructure:"reconnect_interval" yaml:"reconnect_interval"`
+	// 等待broker确认发布的时间, e.g. "5s"
+	PublishTimeout string `mapstructure:"publish_timeout" yaml:"publish_timeout"`
+}
+
+// OutboxConfig 领域事件outbox的发布
+type OutboxConfig struct {
+	// 没有新事件通知时检查未发布事件的间隔, e.g. "5s"
+	RelayInterval string `mapstructure:"relay_interval" yaml:"relay_interval"`
+	// 每次最多发布的事件数，为0时使用默认值100
+	BatchSize int `mapstructure:"batch_size" yaml:"batch_size"`
+	// 已发布事件的保留时间, e.g. "72h"，超过后删除
+	Retention string `mapstructure:"retention" yaml:"retention"`
+}
+
+// TextFilterDictConfig 一个词库文件及命中后的动作
+type TextFilterDictConfig struct {
+	Path   string `mapstructure:"path" yaml:"path"`
+	Action string `mapstructure:"action" yaml:"action"`
+}
+
+// OIDCProviderConfig 一个OIDC身份提供方
+type OIDCProviderConfig struct {
+	// 路由中使用的名字, e.g. google
+	Name         string `mapstructure:"name" yaml:"name"`
+	Issuer       string `mapstructure:"issuer" yaml:"issuer"`
+	ClientID     string `mapstructure:"client_id" yaml:"client_id"`
+	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret" secret:"true"`
+	// 授权后跳转的地址，一般是前端页面，由前端把code和state提交给回调接口
+	RedirectURL string   `mapstructure:"redirect_url" yaml:"redirect_url"`
+	Scopes      []string `mapstructure:"scopes" yaml:"scopes"`
+	// 以下为空时通过issuer的discovery文档获取
+	AuthURL  string `mapstructure:"auth_url" yaml:"auth_url"`
+	TokenURL string `mapstructure:"token_url" yaml:"token_url"`
+	JWKSURL  string `mapstructure:"jwks_url" yaml:"jwks_url"`
 }
 
 type VedioConfig struct {
@@ -80,11 +323,11 @@ type MockConfig struct {
 
 // RedisConfig Redis配置
 type RedisConfig struct {
-	Enabled  bool   `mapstructure:"enabled" yaml:"enabled"`   // 是否启用Redis
-	Host     string `mapstructure:"host" yaml:"host"`         // Redis地址
-	Port     int    `mapstructure:"port" yaml:"port"`         // Redis端口
-	Password string `mapstructure:"password" yaml:"password"` // Redis密码（可选）
-	DB       int    `mapstructure:"db" yaml:"db"`             // Redis数据库索引（0-15）
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled"`                 // 是否启用Redis
+	Host     string `mapstructure:"host" yaml:"host"`                       // Redis地址
+	Port     int    `mapstructure:"port" yaml:"port"`                       // Redis端口
+	Password string `mapstructure:"password" yaml:"password" secret:"true"` // Redis密码（可选）
+	DB       int    `mapstructure:"db" yaml:"db"`                           // Redis数据库索引（0-15）
 	// 连接池配置
 	PoolSize     int    `mapstructure:"pool_size" yaml:"pool_size"`         // 最大连接数
 	MinIdleConns int    `mapstructure:"min_idle_conns" yaml:"min_idle_conns"` // 最小空闲连接数
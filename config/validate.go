@@ -0,0 +1,319 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/featureflag"
+)
+
+// FieldError 一个配置项的问题，Field为配置路径，例如 database.port、oidc_providers[0].issuer
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError Validate发现的所有问题
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("配置有%d个问题:", len(e)))
+	for _, fe := range e {
+		lines = append(lines, "  "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate 检查配置，一次返回所有问题。没有问题时返回nil，否则返回 ValidationError
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.port("server_port", c.ServerPort)
+	v.duration("http_server.read_header_timeout", c.HTTPServer.ReadHeaderTimeout)
+	v.duration("http_server.read_timeout", c.HTTPServer.ReadTimeout)
+	v.duration("http_server.write_timeout", c.HTTPServer.WriteTimeout)
+	v.duration("http_server.idle_timeout", c.HTTPServer.IdleTimeout)
+	for i, proxy := range c.HTTPServer.TrustedProxies {
+		v.ipOrCIDR(fmt.Sprintf("http_server.trusted_proxies[%d]", i), proxy)
+	}
+	if c.HTTPServer.MetricsAddr != "" {
+		v.hostPort("http_server.metrics_addr", c.HTTPServer.MetricsAddr)
+	}
+	v.duration("shutdown_timeout", c.ShutdownTimeout)
+	v.validateDatabase(c.Database)
+
+	v.required("log.path", c.Log.Path)
+	v.oneOf("log.level", strings.ToLower(c.Log.Level), "trace", "debug", "info", "warn", "error", "fatal", "panic")
+
+	v.validateJwt(c)
+
+	if c.Redis.Enabled {
+		v.required("redis.host", c.Redis.Host)
+		v.portNumber("redis.port", c.Redis.Port)
+	}
+	v.duration("redis.default_expiration", c.Redis.DefaultExpiration)
+
+	v.oneOf("mail.driver", c.Mail.Driver, "smtp", "file", "log")
+	if c.Mail.Driver == "smtp" {
+		v.required("mail.host", c.Mail.Host)
+		v.portNumber("mail.port", c.Mail.Port)
+		v.required("mail.from", c.Mail.From)
+	}
+	v.duration("mail.verify_token_ttl", c.Mail.VerifyTokenTTL)
+	v.duration("mail.reset_token_ttl", c.Mail.ResetTokenTTL)
+
+	v.duration("login_limit.base_lockout", c.LoginLimit.BaseLockout)
+	v.duration("login_limit.max_lockout", c.LoginLimit.MaxLockout)
+	v.duration("login_limit.failure_window", c.LoginLimit.FailureWindow)
+
+	for i, p := range c.OIDCProviders {
+		field := fmt.Sprintf("oidc_providers[%d]", i)
+		v.required(field+".name", p.Name)
+		v.required(field+".issuer", p.Issuer)
+		v.required(field+".client_id", p.ClientID)
+		for j := 0; j < i; j++ {
+			if p.Name != "" && c.OIDCProviders[j].Name == p.Name {
+				v.add(field+".name", "与 oidc_providers[%d] 重复", j)
+			}
+		}
+	}
+
+	v.validateTextFilter(c.TextFilter)
+	if c.ReviewContent.ExcerptLength < 0 || c.ReviewContent.ExcerptLength > MaxExcerptLength {
+		v.add("review_content.excerpt_length", "需要在0到%d之间", MaxExcerptLength)
+	}
+
+	v.oneOf("message_queue.backend", c.MessageQueue.Backend, "memory", "durable", "rabbitmq")
+	v.oneOf("message_queue.overflow_policy", c.MessageQueue.OverflowPolicy, "block", "drop", "error")
+	v.duration("message_queue.retry_backoff", c.MessageQueue.RetryBackoff)
+	v.duration("message_queue.max_retry_backoff", c.MessageQueue.MaxRetryBackoff)
+	v.duration("message_queue.processed_key_retention", c.MessageQueue.ProcessedKeyRetention)
+	if c.MessageQueue.Backend == "rabbitmq" {
+		v.required("rabbitmq.url", c.RabbitMQ.URL)
+	}
+	v.duration("rabbitmq.reconnect_interval", c.RabbitMQ.ReconnectInterval)
+	v.duration("rabbitmq.publish_timeout", c.RabbitMQ.PublishTimeout)
+
+	v.duration("outbox.relay_interval", c.Outbox.RelayInterval)
+	v.duration("outbox.retention", c.Outbox.Retention)
+
+	if c.Recommendation.Enabled && !c.Recommendation.Mock.Enabled {
+		v.required("recommendation.api_url", c.Recommendation.APIURL)
+	}
+	v.duration("recommendation.timeout", c.Recommendation.Timeout)
+
+	v.validateFeatureFlags(c.FeatureFlags)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *validator) validateDatabase(db DatabaseConfig) {
+	v.oneOf("database.driver", db.Driver, "postgres", "postgresql")
+	v.required("database.driver", db.Driver)
+	v.required("database.host", db.Host)
+	v.portNumber("database.port", db.Port)
+	v.required("database.username", db.Username)
+	v.required("database.dbname", db.Dbname)
+	v.oneOf("database.sslmode", db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	if db.Timezone != "" {
+		if _, err := time.LoadLocation(db.Timezone); err != nil {
+			v.add("database.timezone", "未知的时区 %q", db.Timezone)
+		}
+	}
+	v.duration("database.timeout", db.Timeout)
+	v.duration("database.conn_max_lifetime", db.ConnMaxLifetime)
+	if db.MaxIdleConns < 0 {
+		v.add("database.max_idle_conns", "不能小于0")
+	}
+	if db.MaxOpenConns < 0 {
+		v.add("database.max_open_conns", "不能小于0")
+	}
+}
+
+func (v *validator) validateJwt(c Config) {
+	if c.JwtSignKeyHex == "" && len(c.JwtSignKeys) == 0 {
+		v.add("jwt_sign_key_hex", "jwt_sign_key_hex 和 jwt_sign_keys 至少需要配置一个")
+	}
+	v.hexKey("jwt_sign_key_hex", c.JwtSignKeyHex)
+	for i, k := range c.JwtSignKeys {
+		field := fmt.Sprintf("jwt_sign_keys[%d]", i)
+		v.required(field+".kid", k.Kid)
+		v.required(field+".sign_key_hex", k.SignKeyHex)
+		v.hexKey(field+".sign_key_hex", k.SignKeyHex)
+		v.rfc3339(field+".activate_at", k.ActivateAt)
+	}
+
+	if c.JwtSecretHex == "" && len(c.JwtSecretKeys) == 0 {
+		v.add("jwt_secret_hex", "jwt_secret_hex 和 jwt_secret_keys 至少需要配置一个")
+	}
+	v.aesKey("jwt_secret_hex", c.JwtSecretHex)
+	for i, k := range c.JwtSecretKeys {
+		field := fmt.Sprintf("jwt_secret_keys[%d]", i)
+		v.required(field+".secret_hex", k.SecretHex)
+		v.aesKey(field+".secret_hex", k.SecretHex)
+		v.rfc3339(field+".activate_at", k.ActivateAt)
+	}
+	v.duration("jwt_access_token_ttl", c.JwtAccessTokenTTL)
+	v.duration("jwt_refresh_token_ttl", c.JwtRefreshTokenTTL)
+}
+
+func (v *validator) validateTextFilter(tf TextFilterConfig) {
+	actions := []string{"reject", "mask", "hold"}
+	for i, dict := range tf.Dictionaries {
+		field := fmt.Sprintf("text_filter.dictionaries[%d]", i)
+		v.required(field+".path", dict.Path)
+		v.oneOf(field+".action", strings.ToLower(dict.Action), actions...)
+	}
+	v.oneOf("text_filter.link_action", strings.ToLower(tf.LinkAction), actions...)
+	v.oneOf("text_filter.repeat_action", strings.ToLower(tf.RepeatAction), actions...)
+	v.oneOf("text_filter.duplicate_action", strings.ToLower(tf.DuplicateAction), actions...)
+	v.duration("text_filter.reload_interval", tf.ReloadInterval)
+	v.duration("text_filter.duplicate_window", tf.DuplicateWindow)
+	if tf.DuplicateSimilarity < 0 || tf.DuplicateSimilarity > 1 {
+		v.add("text_filter.duplicate_similarity", "需要在0到1之间")
+	}
+}
+
+func (v *validator) validateFeatureFlags(ff FeatureFlagsConfig) {
+	v.duration("feature_flags.refresh_interval", ff.RefreshInterval)
+	names := make([]string, 0, len(ff.Flags))
+	for name := range ff.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "feature_flags.flags." + name
+		if !featureflag.ValidName(name) {
+			v.add(field, "开关名只能包含小写字母、数字和下划线，最长64个字符")
+		}
+		if p := ff.Flags[name].Percentage; p != nil && (*p < 0 || *p > featureflag.FullRollout) {
+			v.add(field+".percentage", "需要在0到100之间")
+		}
+	}
+}
+
+// validator 收集检查中发现的问题。除 required 外，值为空时都视为使用默认值，不报告问题
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "不能为空")
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(field, "%q 无效，可选 %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) duration(field, value string) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.add(field, "%q 不是有效的时长，例如 30s、5m、1h", value)
+		return
+	}
+	if d < 0 {
+		v.add(field, "不能为负数")
+	}
+}
+
+func (v *validator) port(field, value string) {
+	if value == "" {
+		v.add(field, "不能为空")
+		return
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		v.add(field, "%q 不是有效的端口", value)
+		return
+	}
+	v.portNumber(field, port)
+}
+
+// hostPort 监听地址，e.g. 127.0.0.1:9100 或 :9100
+func (v *validator) hostPort(field, value string) {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		v.add(field, "%q 不是有效的监听地址", value)
+		return
+	}
+	v.port(field, port)
+}
+
+func (v *validator) portNumber(field string, port int) {
+	if port <= 0 || port > 65535 {
+		v.add(field, "端口 %d 需要在1到65535之间", port)
+	}
+}
+
+// ipOrCIDR 单个IP或CIDR网段
+func (v *validator) ipOrCIDR(field, value string) {
+	if net.ParseIP(value) != nil {
+		return
+	}
+	if _, _, err := net.ParseCIDR(value); err != nil {
+		v.add(field, "%q 不是有效的IP地址或网段，例如 10.0.0.0/8", value)
+	}
+}
+
+func (v *validator) rfc3339(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		v.add(field, "%q 不是RFC3339格式的时间，例如 2024-01-01T00:00:00Z", value)
+	}
+}
+
+func (v *validator) hexKey(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		v.add(field, "不是有效的16进制字符串")
+	}
+}
+
+// aesKey AES密钥为16、24或32字节，即32、48或64个16进制字符
+func (v *validator) aesKey(field, value string) {
+	if value == "" {
+		return
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		v.add(field, "不是有效的16进制字符串")
+		return
+	}
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		v.add(field, "AES密钥长度为%d字节，需要16、24或32字节", n)
+	}
+}
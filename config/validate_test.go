@@ -0,0 +1,103 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig(t *testing.T) Config {
+	conf, err := Load("")
+	require.NoError(t, err)
+	conf.Database.Username = "bookcommunity"
+	conf.Database.Dbname = "bookcommunity"
+	conf.JwtSignKeyHex = "0123456789abcdef0123456789abcdef"
+	conf.JwtSecretHex = "fedcba9876543210fedcba9876543210"
+	return conf
+}
+
+func fields(err error) []string {
+	var result []string
+	for _, fe := range err.(ValidationError) {
+		result = append(result, fe.Field)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validConfig(t).Validate())
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	conf := validConfig(t)
+	conf.ServerPort = "http"
+	conf.Database.Port = 0
+	conf.Database.Dbname = ""
+	conf.JwtSecretHex = "abcd"
+	conf.MessageQueue.Backend = "rabbitmq"
+	conf.MessageQueue.RetryBackoff = "soon"
+	conf.OIDCProviders = []OIDCProviderConfig{{Name: "google", Issuer: "https://accounts.google.com"}}
+
+	err := conf.Validate()
+	require.Error(t, err)
+	assert.ElementsMatch(t, []string{
+		"server_port",
+		"database.port",
+		"database.dbname",
+		"jwt_secret_hex",
+		"message_queue.retry_backoff",
+		"rabbitmq.url",
+		"oidc_providers[0].client_id",
+	}, fields(err))
+	assert.Contains(t, err.Error(), "配置有7个问题")
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	conf := validConfig(t)
+	conf.HTTPServer.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12", "::1", "proxy.local"}
+	assert.Equal(t, []string{"http_server.trusted_proxies[3]"}, fields(conf.Validate()))
+}
+
+func TestValidateMetricsAddr(t *testing.T) {
+	conf := validConfig(t)
+	conf.HTTPServer.MetricsAddr = "127.0.0.1:9100"
+	assert.NoError(t, conf.Validate())
+	conf.HTTPServer.MetricsAddr = "9100"
+	assert.Equal(t, []string{"http_server.metrics_addr"}, fields(conf.Validate()))
+}
+
+func TestValidateExcerptLength(t *testing.T) {
+	conf := validConfig(t)
+	conf.ReviewContent.ExcerptLength = MaxExcerptLength
+	assert.NoError(t, conf.Validate())
+	conf.ReviewContent.ExcerptLength = 1000
+	assert.Equal(t, []string{"review_content.excerpt_length"}, fields(conf.Validate()))
+}
+
+func TestValidateJwtKeys(t *testing.T) {
+	conf := validConfig(t)
+	conf.JwtSignKeyHex = ""
+	conf.JwtSecretHex = ""
+	assert.ElementsMatch(t, []string{"jwt_sign_key_hex", "jwt_secret_hex"}, fields(conf.Validate()))
+
+	conf.JwtSignKeys = []JwtSignKeyConfig{{Kid: "k1", SignKeyHex: "zz", ActivateAt: "tomorrow"}}
+	conf.JwtSecretKeys = []JwtSecretKeyConfig{{Version: 1, SecretHex: "fedcba9876543210fedcba9876543210"}}
+	assert.ElementsMatch(t, []string{"jwt_sign_keys[0].sign_key_hex", "jwt_sign_keys[0].activate_at"}, fields(conf.Validate()))
+}
+
+func TestValidateFeatureFlags(t *testing.T) {
+	conf := validConfig(t)
+	percentage := 120
+	conf.FeatureFlags.RefreshInterval = "often"
+	conf.FeatureFlags.Flags = map[string]FeatureFlagConfig{
+		"chat_with_book": {Enabled: true},
+		"chat-with-book": {Enabled: true},
+		"rollout":        {Enabled: true, Percentage: &percentage},
+	}
+	assert.ElementsMatch(t, []string{
+		"feature_flags.refresh_interval",
+		"feature_flags.flags.chat-with-book",
+		"feature_flags.flags.rollout.percentage",
+	}, fields(conf.Validate()))
+}
@@ -0,0 +1,44 @@
+package initiate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+)
+
+// resolveConfigFile 默认的配置文件不存在时只使用环境变量和默认值，例如在容器中运行时，返回空；
+// 指定的配置文件必须存在
+func resolveConfigFile(file string) string {
+	if file == config.DefaultFile {
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			return ""
+		}
+	}
+	return file
+}
+
+// loadConfig 读取并检查配置，有问题时返回所有问题
+func loadConfig(file string) (config.Config, error) {
+	conf, err := config.Load(resolveConfigFile(file))
+	if err != nil {
+		return conf, err
+	}
+	return conf, conf.Validate()
+}
+
+// Config 执行 config 子命令，返回进程的退出码
+func Config(configFile string, args []string) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "usage: bookcommunity [--config file] config check")
+		return 2
+	}
+	if _, err := loadConfig(configFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("config ok")
+	return 0
+}
@@ -0,0 +1,104 @@
+package initiate
+
+import (
+	"context"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/collect"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/comment"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/follow"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/like"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/review"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/storage"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/server"
+	"gorm.io/gorm"
+)
+
+// 进程内缓存的容量
+const cacheSize = 1000
+
+// Container 组装服务运行需要的依赖: 配置、数据库、缓存和消息队列。
+// 书评、点赞、收藏、评论和关注的处理器由 NewServer 创建，通过结构体字段使用这里的数据库、缓存和消息队列；
+// 服务、中间件和其余处理器仍然通过 config、database、msgQueue 包的访问函数使用依赖，
+// Install把依赖设置到这些包中，之后才能使用。测试中可以用任意的实现构造Container，不需要配置文件和数据库
+type Container struct {
+	Config config.Config
+	DB     *gorm.DB
+	Caches database.Caches
+	// 没有配置 vedio.base_path 时为nil
+	VideoSaver storage.VideoStorageService[storage.SimpleObject]
+	// 由 StartQueues 创建
+	CommentMQ messageQueue.MQ[msgQueue.CommentMsg]
+	FollowMQ  messageQueue.MQ[msgQueue.FollowMsg]
+}
+
+// NewContainer 按配置连接数据库并创建缓存
+func NewContainer(conf config.Config) (*Container, error) {
+	db, err := database.Open(conf.Database, conf.IsDebug())
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{
+		Config: conf,
+		DB:     db,
+		Caches: database.NewCaches(cacheSize),
+	}
+	if conf.Vedio.BasePath != "" {
+		c.VideoSaver = storage.InitLocalOSS(db, conf.Vedio.BasePath)
+	}
+	return c, nil
+}
+
+// Install 把依赖设置到各个包中
+func (c *Container) Install() {
+	config.Set(c.Config)
+	database.SetDB(c.DB)
+	database.SetCaches(c.Caches)
+	database.SetVideoSaver(c.VideoSaver)
+	audit.SetStore(services.AuditLogStore{})
+}
+
+// StartQueues 按 message_queue.backend 创建消息队列，并在事件队列开始消费之前注册领域事件的订阅者。
+// 需要先Install，配置的durable或rabbitmq不可用时返回错误
+func (c *Container) StartQueues() error {
+	for _, initQueue := range []func() error{msgQueue.InitCommentMQ, msgQueue.InitFollowMQ} {
+		if err := initQueue(); err != nil {
+			return err
+		}
+	}
+	c.CommentMQ = msgQueue.GetCommentMQ()
+	c.FollowMQ = msgQueue.GetFollowMQ()
+	services.RegisterEventSubscribers()
+	return msgQueue.InitEventMQ()
+}
+
+// CloseQueues 停止接收新消息并等待队列中的消息处理完或ctx结束
+func (c *Container) CloseQueues(ctx context.Context) error {
+	return msgQueue.Close(ctx)
+}
+
+// Close 关闭数据库连接，在使用数据库的请求、队列和后台任务都停止之后调用
+func (c *Container) Close() error {
+	sqlDB, err := c.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// NewServer 创建HTTP服务器，并把数据库、缓存和消息队列注入处理器。
+// 调用前需要先Install，需要处理关注和旧评论接口时还要先StartQueues
+func (c *Container) NewServer() *server.DouyinServer {
+	return server.NewDouyinServer(c.Config, server.Handlers{
+		Review:  review.NewHandler(c.DB),
+		Like:    like.NewHandler(c.DB),
+		Collect: collect.NewHandler(c.DB),
+		Comment: comment.NewHandler(c.DB, c.Caches.VideoComment, c.CommentMQ),
+		Follow:  follow.NewHandler(c.FollowMQ),
+	})
+}
@@ -0,0 +1,101 @@
+package initiate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newTestContainer 不读取配置文件，数据库只在执行查询时才会连接
+func newTestContainer(t *testing.T) *Container {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 user=test dbname=test sslmode=disable"}),
+		&gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	var conf config.Config
+	conf.Log.Path = t.TempDir()
+	conf.Log.PanicLogName = "panic.log"
+	conf.JwtSignKeyHex = "0123456789abcdef0123456789abcdef"
+	conf.JwtSecretHex = "fedcba9876543210fedcba9876543210"
+	return &Container{
+		Config: conf,
+		DB:     db,
+		Caches: database.NewCaches(16),
+	}
+}
+
+func TestContainerInstall(t *testing.T) {
+	c := newTestContainer(t)
+	c.Install()
+
+	assert.Same(t, c.DB, database.GetDB())
+	assert.NotNil(t, database.GetUserInfoCacher())
+	assert.Nil(t, database.GetVideoSaver())
+}
+
+func TestContainerServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := newTestContainer(t)
+	c.Install()
+	router := c.NewServer().Router
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 没有token时在鉴权中间件返回，不会访问数据库
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/logout", nil))
+	assert.NotEqual(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestContainerServer_InjectsDB(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	other := dbtest.Open(t)
+	// database包中的全局数据库是db，处理器应该使用各自Container的数据库
+	db := dbtest.Open(t)
+	author := models.UserModel{Username: "author"}
+	require.NoError(t, db.Create(&author).Error)
+	review := models.BookReviewModel{Title: "title", Content: "content", AuthorID: author.ID,
+		Status: models.ReviewStatusPublished, Visibility: models.ReviewVisibilityPublic}
+	require.NoError(t, db.Create(&review).Error)
+
+	get := func(c *Container) int {
+		w := httptest.NewRecorder()
+		c.NewServer().Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/reviews/%d", review.ID), nil))
+		return w.Code
+	}
+	c := newTestContainer(t)
+	c.DB = db
+	assert.Equal(t, http.StatusOK, get(c))
+	c = newTestContainer(t)
+	c.DB = other
+	assert.Equal(t, http.StatusNotFound, get(c))
+}
+
+func TestContainerQueues(t *testing.T) {
+	c := newTestContainer(t)
+	c.Install()
+
+	// 没有配置backend时使用内存队列，不需要外部服务
+	require.NoError(t, c.StartQueues())
+	assert.NotNil(t, msgQueue.GetFollowMQ())
+	assert.Equal(t, msgQueue.GetFollowMQ(), c.FollowMQ)
+	assert.Equal(t, msgQueue.GetCommentMQ(), c.CommentMQ)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, c.CloseQueues(ctx))
+}
@@ -1,35 +1,95 @@
 package initiate
 
 import (
+	"context"
+	"time"
+
 	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/server"
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/log"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// 检查定时发布书评的间隔
+	reviewSchedulerInterval = 30 * time.Second
+	// 清理过期消息Key的间隔
+	processedMessageCleanupInterval = time.Hour
+	// 消息Key默认保留7天，重放超过这个时间的死信可能会重复生效
+	defaultProcessedKeyRetention = 7 * 24 * time.Hour
 )
 
-func Run() {
+// Run 按configFile启动服务，configFile为空时只使用环境变量和默认值
+func Run(configFile string) {
+	conf, err := loadConfig(configFile)
+	if err != nil {
+		panic(err)
+	}
+	// 日志在连接数据库之前初始化
+	config.Set(conf)
 	initGlobalLogger()
 	logrus.Info("hello world")
-	initMysql()
-	initVideoStorageServer()
 
-	// init cache
-	var cacheSize = 1000
-	database.InitVideoInfoCacher(cacheSize)
-	database.InitVideoCommentCacher(cacheSize)
-	database.InitUserCacher(cacheSize)
-	database.InitUserFavoriteCacher(cacheSize)
+	container, err := NewContainer(conf)
+	if err != nil {
+		panic(err)
+	}
+	container.Install()
+	initMysql(container.DB)
+
+	// 后台任务在关闭服务时停止
+	bg := newBackground()
+
+	// 配置热更新
+	registerConfigSubscribers()
+	watchConfig(bg.ctx, resolveConfigFile(configFile))
+	services.ApplyRecommendConfig(conf.Recommendation)
+
+	// 功能开关: 合并配置文件和数据库中的开关，定期刷新其他实例上的修改
+	if err := services.LoadFeatureFlags(); err != nil {
+		panic(err)
+	}
+	bg.Go(services.RunFeatureFlagRefresh)
+
+	services.BootstrapAdmins(config.GetBootstrapAdmins())
+	// 启动时加载词库，避免第一次发布时才读取文件
+	services.GetTextFilter()
+	bg.Go(services.RunTextFilterReload)
 
-	// init message queue
-	msgQueue.InitFavoriteMQ()
-	msgQueue.InitCommentMQ()
-	msgQueue.InitFollowMQ()
+	// 给旧书评生成HTML和摘要
+	bg.Go(services.BackfillReviewContent)
+
+	// 定时发布书评
+	bg.Go(func(ctx context.Context) {
+		services.RunReviewScheduler(ctx, reviewSchedulerInterval)
+	})
+
+	// 消息队列和领域事件的订阅者，配置的durable或rabbitmq不可用时不能启动
+	if err := container.StartQueues(); err != nil {
+		panic("初始化消息队列失败, error:" + err.Error())
+	}
+	bg.Go(func(ctx context.Context) {
+		services.RunProcessedMessageCleanup(ctx, processedMessageCleanupInterval, processedKeyRetention())
+	})
+	// 发布outbox中的领域事件
+	bg.Go(func(ctx context.Context) {
+		services.RunOutboxRelay(ctx, msgQueue.PublishEvent)
+	})
 
 	// main logic
-	runDouyinServer()
+	serve(container.NewServer(), container, bg)
+}
+
+func processedKeyRetention() time.Duration {
+	retention, err := time.ParseDuration(config.GetMessageQueueConfig().ProcessedKeyRetention)
+	if err != nil || retention <= 0 {
+		return defaultProcessedKeyRetention
+	}
+	return retention
 }
 
 func initGlobalLogger() {
@@ -45,18 +105,9 @@ func initGlobalLogger() {
 	}
 }
 
-func initMysql() {
-	database.GetMysqlDB()
-}
-
-func initVideoStorageServer() {
-	database.GetVideoSaver()
-}
-
-func runDouyinServer() {
-	douyinServer := server.NewDouyinServer()
-	err := douyinServer.Run(":" + config.GetServerPort())
-	if err != nil {
-		panic("启动服务失败, error:" + err.Error())
+func initMysql(db *gorm.DB) {
+	// 多个实例同时启动时只有一个执行迁移
+	if err := database.RunMigrations(db); err != nil {
+		panic("数据库迁移失败, error:" + err.Error())
 	}
 }
@@ -0,0 +1,133 @@
+package initiate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/cache"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/server"
+)
+
+// defaultShutdownTimeout 没有配置 shutdown_timeout 时关闭服务的最长时间
+const defaultShutdownTimeout = 20 * time.Second
+
+// background 服务运行期间的后台任务，关闭服务时取消ctx并等待退出
+type background struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newBackground() *background {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &background{ctx: ctx, cancel: cancel}
+}
+
+// Go 在后台执行fn，fn需要在ctx结束后尽快返回
+func (b *background) Go(fn func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn(b.ctx)
+	}()
+}
+
+// Stop 取消后台任务并等待退出，ctx结束时不再等待，返回ctx的错误
+func (b *background) Stop(ctx context.Context) error {
+	b.cancel()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdownStep 关闭服务的一步，fn在ctx结束后需要尽快返回
+type shutdownStep struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// shutdown 依次执行每一步，某一步失败或超时后继续执行后面的步骤，保证连接都被关闭
+func shutdown(ctx context.Context, steps []shutdownStep) error {
+	var errs []error
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.fn(ctx); err != nil {
+			logrus.Errorf("shutdown: %s failed after %v, err: %v", step.name, time.Since(start), err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		logrus.Infof("shutdown: %s done in %v", step.name, time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownSteps 按依赖关系的顺序关闭: 先停止接收请求，请求和后台任务会写入队列，
+// 队列的消费者和后台写入使用Redis和数据库，最后关闭连接。
+// 浏览次数等计数没有在内存中缓冲，每次都由 services.Async 直接写入数据库，
+// 所以等待后台写入完成就是计数的flush
+func shutdownSteps(douyinServer *server.DouyinServer, container *Container, bg *background) []shutdownStep {
+	return []shutdownStep{
+		{name: "http server", fn: douyinServer.Shutdown},
+		{name: "background tasks", fn: bg.Stop},
+		{name: "message queues", fn: container.CloseQueues},
+		{name: "async writes and counters", fn: services.WaitAsync},
+		{name: "redis", fn: func(context.Context) error { return cache.Close() }},
+		{name: "database", fn: func(context.Context) error { return container.Close() }},
+	}
+}
+
+// serve 处理请求直到收到SIGINT或SIGTERM，然后在 shutdown_timeout 内关闭服务。
+// 关闭期间再次收到信号时直接退出
+func serve(douyinServer *server.DouyinServer, container *Container, bg *background) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- douyinServer.Run()
+	}()
+	logrus.Infof("server listening on :%s", container.Config.ServerPort)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			panic("启动服务失败, error:" + err.Error())
+		}
+	case <-ctx.Done():
+	}
+	stop()
+
+	timeout := shutdownTimeout(container.Config)
+	logrus.Infof("shutting down, timeout %v", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx, shutdownSteps(douyinServer, container, bg)); err != nil {
+		logrus.Errorf("shutdown finished with errors: %v", err)
+		os.Exit(1)
+	}
+	logrus.Info("shutdown complete")
+}
+
+func shutdownTimeout(conf config.Config) time.Duration {
+	timeout, err := time.ParseDuration(conf.ShutdownTimeout)
+	if err != nil || timeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return timeout
+}
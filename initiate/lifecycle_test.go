@@ -0,0 +1,109 @@
+package initiate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownContinuesAfterFailure(t *testing.T) {
+	var order []string
+	step := func(name string, err error) shutdownStep {
+		return shutdownStep{name: name, fn: func(context.Context) error {
+			order = append(order, name)
+			return err
+		}}
+	}
+	errQueue := errors.New("queue not drained")
+
+	err := shutdown(context.Background(), []shutdownStep{
+		step("http", nil),
+		step("queue", errQueue),
+		step("database", nil),
+	})
+	assert.Equal(t, []string{"http", "queue", "database"}, order)
+	assert.ErrorIs(t, err, errQueue)
+	assert.Contains(t, err.Error(), "queue")
+}
+
+func TestBackgroundStop(t *testing.T) {
+	bg := newBackground()
+	stopped := make(chan struct{})
+	bg.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	require.NoError(t, bg.Stop(context.Background()))
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Stop returned before the task exited")
+	}
+}
+
+func TestBackgroundStopTimeout(t *testing.T) {
+	bg := newBackground()
+	release := make(chan struct{})
+	defer close(release)
+	bg.Go(func(context.Context) {
+		<-release
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bg.Stop(ctx), context.DeadlineExceeded)
+}
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := newTestContainer(t)
+	c.Install()
+	s := c.NewServer()
+	started := make(chan struct{})
+	s.Router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		resCh <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	res := <-resCh
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.NoError(t, <-served)
+
+	// 关闭后不再接收新连接
+	_, err = http.Get("http://" + l.Addr().String() + "/health")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package initiate
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/migrate"
+)
+
+const migrateUsage = `usage: bookcommunity [--config file] migrate <command>
+
+commands:
+  up            执行所有未执行的迁移
+  down          回滚最后执行的一个迁移
+  status        查看各版本的迁移状态
+  to <version>  升级或回滚到指定版本，0表示回滚全部
+`
+
+// Migrate 执行 migrate 子命令，返回进程的退出码
+func Migrate(configFile string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	conf, err := loadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	db, err := database.Open(conf.Database, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var count int
+	switch args[0] {
+	case "up":
+		count, err = migrator.Up()
+	case "down":
+		count, err = migrator.Down()
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprint(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		count, err = migrator.To(version)
+	case "status":
+		return printMigrationStatus(migrator)
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s failed after %d migrations: %v\n", args[0], count, err)
+		return 1
+	}
+	fmt.Printf("migrate %s: %d migrations executed\n", args[0], count)
+	return 0
+}
+
+func printMigrationStatus(migrator *migrate.Migrator) int {
+	statuses, err := migrator.Status()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.ChecksumMismatch {
+			state = "modified"
+		}
+		if s.Missing {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+	return 0
+}
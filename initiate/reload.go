@@ -0,0 +1,64 @@
+package initiate
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/middleware"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/pkg/log"
+)
+
+// registerConfigSubscribers 注册配置热更新的订阅者，CORS由路由自己订阅
+func registerConfigSubscribers() {
+	config.OnChange("log", func(old, new config.Config) {
+		if old.Log.Level != new.Log.Level {
+			log.SetGlobalLevel(new.Log.Level)
+			logrus.Infof("log level changed to %s", new.Log.Level)
+		}
+	})
+	config.OnChange("login_limit", func(_, new config.Config) {
+		middleware.GetLoginLimiter().Apply(new.LoginLimit)
+	})
+	config.OnChange("recommendation", func(_, new config.Config) {
+		services.ApplyRecommendConfig(new.Recommendation)
+	})
+	config.OnChange("feature_flags", func(old, new config.Config) {
+		if reflect.DeepEqual(old.FeatureFlags.Flags, new.FeatureFlags.Flags) {
+			return
+		}
+		// 读取数据库失败时配置文件的修改仍然生效，数据库中的开关使用上一次读取的结果
+		if err := services.LoadFeatureFlags(); err != nil {
+			logrus.Errorf("reload feature flags from database failed, database overrides may be stale: %v", err)
+		}
+	})
+}
+
+// watchConfig 配置文件修改或收到SIGHUP时热更新，ctx结束时停止。configFile为空时只响应SIGHUP
+func watchConfig(ctx context.Context, configFile string) {
+	if configFile != "" {
+		if err := config.Watch(ctx, configFile); err != nil {
+			logrus.Errorf("watch config file failed, reload with SIGHUP instead: %v", err)
+		}
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if _, err := config.Reload(configFile); err != nil {
+					logrus.Errorf("config reload failed, keep current config: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -15,6 +15,12 @@ type User struct {
 
 const UserKeyName = "user"
 
+// UserIDKeyName 鉴权中间件写入gin.Context的用户ID(uint)
+const UserIDKeyName = "user_id"
+
+// UserRoleKeyName RBAC中间件写入gin.Context的用户角色(string)
+const UserRoleKeyName = "user_role"
+
 func ZeroCheck[T comparable](v ...T) bool {
 	if !config.IsDebug() {
 		return false
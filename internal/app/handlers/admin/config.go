@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+)
+
+// ConfigHandler 查看当前生效的配置
+// @Summary 查看当前生效的配置
+// @Description 包括环境变量覆盖和热更新后的值，密码和密钥已隐藏
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.ConfigResponse
+// @Router /api/admin/config [get]
+func ConfigHandler(c *gin.Context) {
+	var res response.ConfigResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Config = config.Get().Redacted()
+	if reloadedAt := config.ReloadedAt(); !reloadedAt.IsZero() {
+		res.ReloadedAt = &reloadedAt
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// DeleteReviewHandler 删除书评
+// @Summary 删除书评
+// @Description 软删除任意书评，可以通过恢复接口撤销
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "书评ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/reviews/{id} [delete]
+func DeleteReviewHandler(c *gin.Context) {
+	changeContent(c, audit.TargetReview, audit.ActionReviewDelete, services.AdminDeleteReview, response.ContentDeletedMsg)
+}
+
+// RestoreReviewHandler 恢复书评
+// @Summary 恢复书评
+// @Description 恢复被软删除的书评
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "书评ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/reviews/{id}/restore [post]
+func RestoreReviewHandler(c *gin.Context) {
+	changeContent(c, audit.TargetReview, audit.ActionReviewRestore, services.AdminRestoreReview, response.ContentRestoredMsg)
+}
+
+// DeleteCommentHandler 删除评论
+// @Summary 删除评论
+// @Description 软删除任意评论，可以通过恢复接口撤销
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/comments/{id} [delete]
+func DeleteCommentHandler(c *gin.Context) {
+	changeContent(c, audit.TargetComment, audit.ActionCommentDelete, services.AdminDeleteComment, response.ContentDeletedMsg)
+}
+
+// RestoreCommentHandler 恢复评论
+// @Summary 恢复评论
+// @Description 恢复被软删除的评论
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/comments/{id}/restore [post]
+func RestoreCommentHandler(c *gin.Context) {
+	changeContent(c, audit.TargetComment, audit.ActionCommentRestore, services.AdminRestoreComment, response.ContentRestoredMsg)
+}
+
+func changeContent(c *gin.Context, targetType string, action string, change func(id uint) error, msg string) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req response.ModerationRequest
+	c.ShouldBind(&req)
+	if err := change(id); err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() != response.ErrServerInternal {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  err.Error(),
+		})
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     action,
+		ActorID:    user.ID,
+		TargetType: targetType,
+		TargetID:   id,
+		IP:         c.ClientIP(),
+		Detail:     req.Reason,
+	})
+	response.ResponseSuccess(c, msg)
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+)
+
+// DeadLetterListHandler 查询死信
+// @Summary 查询死信
+// @Description 查看点赞、评论、关注队列中多次处理失败后放弃的消息
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param queue query string false "队列: comment, follow, events"
+// @Param include_replayed query bool false "是否包括已经重放的消息"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.DeadLetterListResponse
+// @Router /api/admin/queues/dead-letters [get]
+func DeadLetterListHandler(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	letters, total, err := services.QueryDeadLetters(c.Query("queue"), c.Query("include_replayed") == "true", page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.DeadLetterListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.DeadLetters = make([]response.DeadLetterInfo, 0, len(letters))
+	for _, d := range letters {
+		res.DeadLetters = append(res.DeadLetters, response.DeadLetterInfo{
+			ID:         d.ID,
+			Queue:      d.Queue,
+			Key:        d.MessageKey,
+			Payload:    d.Payload,
+			Attempts:   d.Attempts,
+			LastError:  d.LastError,
+			CreatedAt:  d.CreatedAt,
+			ReplayedAt: d.ReplayedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ReplayDeadLetterHandler 重放死信
+// @Summary 重放死信
+// @Description 修复问题后把消息重新放入原来的队列，消息Key不变，已经生效的部分不会重复生效
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "死信ID"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/queues/dead-letters/{id}/replay [post]
+func ReplayDeadLetterHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	dead, err := services.GetDeadLetter(id)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if dead.ReplayedAt != nil {
+		response.ResponseError(c, response.ErrDeadLetterReplayed)
+		return
+	}
+	if err := msgQueue.Replay(dead.Queue, dead.MessageKey, []byte(dead.Payload)); err != nil {
+		logrus.Errorf("replay dead letter %d failed, err: %v", id, err)
+		response.ResponseError(c, response.ErrDeadLetterReplay)
+		return
+	}
+	if err := services.MarkDeadLetterReplayed(id); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionDeadLetterReplay,
+		ActorID:    user.ID,
+		TargetType: audit.TargetDeadLetter,
+		TargetID:   id,
+		IP:         c.ClientIP(),
+		Detail:     dead.Queue + " " + dead.MessageKey,
+	})
+	response.ResponseSuccess(c, response.DeadLetterReplayedMsg)
+}
+
+// DeleteDeadLetterHandler 删除死信
+// @Summary 删除死信
+// @Description 确认不需要处理的消息可以删除
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "死信ID"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/queues/dead-letters/{id} [delete]
+func DeleteDeadLetterHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteDeadLetter(id); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionDeadLetterDelete,
+		ActorID:    user.ID,
+		TargetType: audit.TargetDeadLetter,
+		TargetID:   id,
+		IP:         c.ClientIP(),
+	})
+	response.ResponseSuccess(c, response.DeadLetterDeletedMsg)
+}
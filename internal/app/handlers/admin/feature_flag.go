@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/featureflag"
+)
+
+// FeatureFlagListHandler 查询功能开关
+// @Summary 查询功能开关
+// @Description 当前生效的功能开关，包括配置文件中的和后台修改的，同名时后台修改的优先
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.FeatureFlagListResponse
+// @Router /api/admin/feature-flags [get]
+func FeatureFlagListHandler(c *gin.Context) {
+	flags := services.ListFeatureFlags()
+	var res response.FeatureFlagListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.FeatureFlags = make([]response.FeatureFlagInfo, 0, len(flags))
+	for _, f := range flags {
+		users := f.Users
+		if users == nil {
+			users = []uint{}
+		}
+		res.FeatureFlags = append(res.FeatureFlags, response.FeatureFlagInfo{
+			Name:        f.Name,
+			Enabled:     f.Enabled,
+			Percentage:  f.Percentage,
+			Users:       users,
+			Description: f.Description,
+			Source:      f.Source,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// SetFeatureFlagHandler 设置功能开关
+// @Summary 设置功能开关
+// @Description 开关保存在数据库中并立即生效，覆盖配置文件中的同名开关。percentage为按用户ID灰度的比例，不传时对所有用户开启
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "开关名"
+// @Param body body response.SetFeatureFlagRequest true "开关设置"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/feature-flags/{name} [put]
+func SetFeatureFlagHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	var req response.SetFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	flag := featureflag.Flag{
+		Name:        c.Param("name"),
+		Enabled:     req.Enabled,
+		Percentage:  featureflag.FullRollout,
+		Users:       req.Users,
+		Description: req.Description,
+	}
+	if req.Percentage != nil {
+		flag.Percentage = *req.Percentage
+	}
+	if err := services.SetFeatureFlag(flag, user.ID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionFeatureFlagSet,
+		ActorID:    user.ID,
+		Target:     flag.Name,
+		TargetType: audit.TargetFeatureFlag,
+		IP:         c.ClientIP(),
+		Detail:     fmt.Sprintf("name=%s enabled=%t percentage=%d users=%v", flag.Name, flag.Enabled, flag.Percentage, flag.Users),
+	})
+	response.ResponseSuccess(c, response.FeatureFlagUpdatedMsg)
+}
+
+// DeleteFeatureFlagHandler 删除后台设置的功能开关
+// @Summary 删除后台设置的功能开关
+// @Description 恢复为配置文件中的设置，配置文件中没有该开关时功能关闭
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "开关名"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/feature-flags/{name} [delete]
+func DeleteFeatureFlagHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	name := c.Param("name")
+	if err := services.DeleteFeatureFlag(name); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionFeatureFlagDelete,
+		ActorID:    user.ID,
+		Target:     name,
+		TargetType: audit.TargetFeatureFlag,
+		IP:         c.ClientIP(),
+		Detail:     "name=" + name,
+	})
+	response.ResponseSuccess(c, response.FeatureFlagDeletedMsg)
+}
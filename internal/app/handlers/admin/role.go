@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// SetUserRoleHandler 修改用户角色
+// @Summary 修改用户角色
+// @Description 管理员修改其他用户的角色(user, moderator, admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param body body response.SetRoleRequest true "角色"
+// @Success 200 {object} response.CommonResponse
+// @Failure 403 {object} response.CommonResponse
+// @Router /api/admin/users/{id}/role [put]
+func SetUserRoleHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req response.SetRoleRequest
+	if err != nil || c.ShouldBind(&req) != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if err := services.SetUserRole(user.ID, uint(targetID), req.Role); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionUserRole,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   uint(targetID),
+		IP:         c.ClientIP(),
+		Detail:     "role=" + req.Role,
+	})
+	response.ResponseSuccess(c, response.RoleUpdatedMsg)
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+)
+
+// AuditLogListHandler 查询审计日志
+// @Summary 查询审计日志
+// @Description 按时间倒序查看版主和管理员的操作记录
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "操作者用户ID"
+// @Param action query string false "操作类型，例如 review_hide、user_ban"
+// @Param target_type query string false "操作对象类型: review, comment, user"
+// @Param target_id query int false "操作对象ID"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.AuditLogListResponse
+// @Router /api/admin/audit-logs [get]
+func AuditLogListHandler(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	actorID, _ := strconv.ParseUint(c.Query("actor_id"), 10, 32)
+	targetID, _ := strconv.ParseUint(c.Query("target_id"), 10, 32)
+	filter := services.AuditLogFilter{
+		ActorID:    uint(actorID),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   uint(targetID),
+	}
+	logs, total, err := services.QueryAuditLogs(filter, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.AuditLogListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Logs = make([]response.AuditLogInfo, 0, len(logs))
+	for _, l := range logs {
+		res.Logs = append(res.Logs, response.AuditLogInfo{
+			ID:         l.ID,
+			ActorID:    l.ActorID,
+			Action:     l.Action,
+			TargetType: l.TargetType,
+			TargetID:   l.TargetID,
+			IP:         l.IP,
+			Detail:     l.Detail,
+			CreatedAt:  l.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// StatsHandler 系统统计
+// @Summary 系统统计
+// @Description 用户、书评、评论数量，缓存统计和消息队列积压情况
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.AdminStatsResponse
+// @Router /api/admin/stats [get]
+func StatsHandler(c *gin.Context) {
+	counts, err := services.GetSiteCounts()
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.AdminStatsResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Counts = counts
+	res.Cache = services.GetUserCacheService().GetCacheStats()
+	res.Queues = msgQueue.QueueDepths()
+	c.JSON(http.StatusOK, res)
+}
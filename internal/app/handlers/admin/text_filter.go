@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// ReloadTextFilterHandler 重新加载敏感词库
+// @Summary 重新加载敏感词库
+// @Description 修改词库文件后立即生效，不必等待定时检查
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.TextFilterReloadResponse
+// @Router /api/admin/text-filter/reload [post]
+func ReloadTextFilterHandler(c *gin.Context) {
+	count, err := services.ReloadTextFilter()
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.TextFilterReloadResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.TextFilterReloadedMsg
+	res.WordCount = count
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,222 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/middleware"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// SearchUsersHandler 搜索用户
+// @Summary 搜索用户
+// @Description 按用户名或邮箱模糊搜索用户，可按角色和封禁状态筛选
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param keyword query string false "用户名或邮箱关键字"
+// @Param role query string false "角色"
+// @Param banned query bool false "只返回封禁中的用户"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.AdminUserListResponse
+// @Router /api/admin/users [get]
+func SearchUsersHandler(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	banned, _ := strconv.ParseBool(c.Query("banned"))
+	filter := services.AdminUserFilter{
+		Keyword:    c.Query("keyword"),
+		Role:       c.Query("role"),
+		BannedOnly: banned,
+	}
+	users, total, err := services.SearchUsers(filter, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	now := time.Now()
+	var res response.AdminUserListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Users = make([]response.AdminUserInfo, 0, len(users))
+	for i := range users {
+		res.Users = append(res.Users, convertAdminUser(&users[i], now))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func convertAdminUser(user *models.UserModel, now time.Time) response.AdminUserInfo {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	return response.AdminUserInfo{
+		ID:                    user.ID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		EmailVerified:         user.EmailVerifiedAt != nil,
+		Role:                  role,
+		Banned:                user.IsBanned(now),
+		BannedAt:              user.BannedAt,
+		BannedUntil:           user.BannedUntil,
+		BanReason:             user.BanReason,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt,
+	}
+}
+
+// BanUserHandler 封禁用户
+// @Summary 封禁用户
+// @Description 封禁用户并吊销其所有登录会话，封禁期间不能登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param body body response.BanUserRequest false "封禁时长和原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/users/{id}/ban [post]
+func BanUserHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	targetID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req response.BanUserRequest
+	c.ShouldBind(&req)
+	var until *time.Time
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			response.ResponseError(c, response.ErrBanDuration)
+			return
+		}
+		t := time.Now().Add(d)
+		until = &t
+	}
+	if err := services.BanUser(user.ID, targetID, until, req.Reason); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if err := middleware.LogoutAllSessions(targetID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	detail := req.Reason
+	if req.Duration != "" {
+		detail = "duration=" + req.Duration + " " + req.Reason
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionUserBan,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		Detail:     detail,
+	})
+	response.ResponseSuccess(c, response.UserBannedMsg)
+}
+
+// UnbanUserHandler 解封用户
+// @Summary 解封用户
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/users/{id}/unban [post]
+func UnbanUserHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	targetID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req response.ModerationRequest
+	c.ShouldBind(&req)
+	if err := services.UnbanUser(targetID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionUserUnban,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		Detail:     req.Reason,
+	})
+	response.ResponseSuccess(c, response.UserUnbannedMsg)
+}
+
+// ForcePasswordResetHandler 强制重置密码
+// @Summary 强制重置密码
+// @Description 吊销用户所有登录会话并向其已验证邮箱发送重置邮件，重置前不能使用密码登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/admin/users/{id}/force-password-reset [post]
+func ForcePasswordResetHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	targetID, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req response.ModerationRequest
+	c.ShouldBind(&req)
+	if err := services.ForcePasswordReset(targetID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if err := middleware.LogoutAllSessions(targetID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionUserForceReset,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		Detail:     req.Reason,
+	})
+	response.ResponseSuccess(c, response.ForceResetMsg)
+}
+
+// parseID 解析路径中的id，不合法时直接返回400
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parsePage 解析分页参数，默认第1页每页20条
+func parsePage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
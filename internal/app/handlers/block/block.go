@@ -0,0 +1,127 @@
+package block
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// ListBlocksHandler 我屏蔽的用户
+// @Summary 屏蔽列表
+// @Description 按时间倒序返回当前用户拉黑和静音的用户
+// @Tags Block
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "block 或 mute，不传返回全部"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.BlockListResponse
+// @Router /api/users/me/blocks [get]
+func ListBlocksHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	kind := c.Query("kind")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	if kind != "" && !models.IsValidBlockKind(kind) {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrBlockKind,
+		})
+		return
+	}
+	blocks, total, err := services.ListBlocks(user.ID, kind, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.BlockListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Blocks = make([]response.BlockInfo, 0, len(blocks))
+	for _, b := range blocks {
+		res.Blocks = append(res.Blocks, response.BlockInfo{
+			User: &response.UserInfo{
+				ID:            b.TargetID,
+				Username:      b.Target.Username,
+				FollowerCount: b.Target.FollowerCount,
+			},
+			Kind:      b.Kind,
+			CreatedAt: b.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// BlockUserHandler 拉黑或静音用户
+// @Summary 拉黑或静音用户
+// @Description 拉黑后双方不能互相关注、评论、点赞，也看不到对方的内容，已有的关注关系会被解除；
+// @Description 静音只是不在自己的信息流和评论列表中显示对方的内容。重复提交会改为新的类型
+// @Tags Block
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body response.BlockRequest true "用户和类型"
+// @Success 200 {object} response.CommonResponse
+// @Failure 400 {object} response.CommonResponse
+// @Router /api/users/me/blocks [post]
+func BlockUserHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	var req response.BlockRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.BlockKindBlock
+	}
+	if err := services.BlockUser(user.ID, req.UserID, req.Kind); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if req.Kind == models.BlockKindMute {
+		response.ResponseSuccess(c, response.MuteSuccessMsg)
+		return
+	}
+	response.ResponseSuccess(c, response.BlockSuccessMsg)
+}
+
+// UnblockUserHandler 解除拉黑或静音
+// @Summary 解除拉黑或静音
+// @Tags Block
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "被屏蔽的用户ID"
+// @Success 200 {object} response.CommonResponse
+// @Failure 400 {object} response.CommonResponse
+// @Router /api/users/me/blocks/{id} [delete]
+func UnblockUserHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || targetID == 0 {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if err := services.UnblockUser(user.ID, uint(targetID)); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.UnblockSuccessMsg)
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/utils"
 	"gorm.io/gorm"
 )
@@ -26,7 +26,7 @@ var logger = utils.NewLogger("collect_handler")
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews/{id}/collect [post]
-func CollectReviewHandler(c *gin.Context) {
+func (h *Handler) CollectReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -47,11 +47,11 @@ func CollectReviewHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
-	// 检查书评是否存在
+	// 检查书评是否存在，被隐藏的书评不能收藏
 	var review models.BookReviewModel
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := db.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, response.CommonResponse{
 				StatusCode: response.Failed,
@@ -66,6 +66,14 @@ func CollectReviewHandler(c *gin.Context) {
 		return
 	}
 
+	if !services.CanViewReview(userID.(uint), &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
 	// 检查是否已经收藏
 	var existingCollection models.UserCollectionModel
 	result := db.Where("user_id = ? AND review_id = ?", userID, reviewID).First(&existingCollection)
@@ -122,7 +130,7 @@ func CollectReviewHandler(c *gin.Context) {
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews/{id}/collect [delete]
-func UncollectReviewHandler(c *gin.Context) {
+func (h *Handler) UncollectReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -143,7 +151,16 @@ func UncollectReviewHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
+
+	// 已删除的书评不能取消收藏，书评恢复时重新计算计数
+	if err := db.Select("id").First(&models.BookReviewModel{}, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
 
 	// 删除收藏记录
 	result := db.Where("user_id = ? AND review_id = ?", userID, reviewID).
@@ -190,15 +207,16 @@ func UncollectReviewHandler(c *gin.Context) {
 // @Tags Collect
 // @Accept json
 // @Produce json
-// @Param user_id path int true "用户ID"
+// @Param Authorization header string false "Bearer {token}，查看私密账号的收藏需要登录"
+// @Param id path int true "用户ID"
 // @Param page query int false "页码（默认1）"
 // @Param page_size query int false "每页数量（默认20）"
 // @Success 200 {object} response.ReviewListResponse
 // @Failure 400 {object} response.CommonResponse
-// @Router /api/users/{user_id}/collections [get]
-func GetUserCollectionsHandler(c *gin.Context) {
+// @Router /api/users/{id}/collections [get]
+func (h *Handler) GetUserCollectionsHandler(c *gin.Context) {
 	// 获取用户ID
-	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	targetUserID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -224,7 +242,16 @@ func GetUserCollectionsHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	db := database.GetMysqlDB()
+	// 私密账号的收藏只对粉丝可见
+	if !services.CanViewUserContent(userID, uint(targetUserID)) {
+		c.JSON(http.StatusForbidden, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrPrivateAccount,
+		})
+		return
+	}
+
+	db := h.DB
 
 	// 查询收藏记录
 	var collections []models.UserCollectionModel
@@ -250,13 +277,14 @@ func GetUserCollectionsHandler(c *gin.Context) {
 	// 查询书评信息
 	var reviews []models.BookReviewModel
 	if len(reviewIDs) > 0 {
-		db.Where("id IN ?", reviewIDs).Preload("Author").Find(&reviews)
+		db.Scopes(models.NotHidden, services.VisibleReviews(userID)).Where("id IN ?", reviewIDs).Preload("Author").Find(&reviews)
 	}
 
-	// 转换为响应格式
+	// 转换为响应格式，按用户设置隐藏剧透
+	reveal := services.RevealSpoilers(userID)
 	reviewInfos := make([]*response.ReviewInfo, 0, len(reviews))
 	for i := range reviews {
-		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID))
+		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID, reveal))
 	}
 
 	// 查询总数
@@ -0,0 +1,13 @@
+package collect
+
+import "gorm.io/gorm"
+
+// Handler 收藏和收藏列表的接口
+type Handler struct {
+	DB *gorm.DB
+}
+
+// NewHandler 使用db查询书评和收藏
+func NewHandler(db *gorm.DB) *Handler {
+	return &Handler{DB: db}
+}
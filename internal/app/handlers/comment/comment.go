@@ -9,7 +9,6 @@ import (
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
 	"github.com/sirupsen/logrus"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
@@ -57,13 +56,17 @@ func (dto *PostCommentDTO) newMsg(commenterID uint) msgQueue.CommentMsg {
 	return Msg
 }
 
-func PostCommentHandler(c *gin.Context) {
+func (h *Handler) PostCommentHandler(c *gin.Context) {
 	var dto PostCommentDTO
 	dto.getAndCheckPostCommentDTO(c)
-	mq := msgQueue.GetCommentMQ()
+	mq := h.CommentMQ
 	user := c.MustGet(app.UserKeyName).(app.User)
 	var Msg msgQueue.CommentMsg = dto.newMsg(user.ID)
-	mq.Push(Msg)
+	if err := mq.PushCtx(c.Request.Context(), Msg); err != nil {
+		logrus.Error("push comment message failed, err: ", err)
+		response.ResponseError(c, response.ErrServerBusy)
+		return
+	}
 	if dto.ActionType != PostCommentDTO_ActionType_Add {
 		response.ResponseSuccess(c, SuccComment)
 		return
@@ -125,12 +128,12 @@ const (
 
 const CommentResFormat = "2006-01-02 15:04:05"
 
-func QueryCommentListHandler(c *gin.Context) {
+func (h *Handler) QueryCommentListHandler(c *gin.Context) {
 	var dto QueryCommentListDTO
 	dto.getAndCheckQueryCommentListDTO(c)
 
 	// get comments from cache
-	commentCache := database.GetVideoCommentCacher()
+	commentCache := h.Comments
 	commentsCache, exist := commentCache.Get(dto.VideoID)
 	if exist {
 		queryCommentListHandler_CacheHit(c, commentsCache)
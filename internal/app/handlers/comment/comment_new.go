@@ -3,11 +3,14 @@ package comment
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/utils"
 	"gorm.io/gorm"
 )
@@ -53,7 +56,7 @@ type CommentInfo struct {
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews/{id}/comments [post]
-func CreateCommentHandler(c *gin.Context) {
+func (h *Handler) CreateCommentHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -84,11 +87,11 @@ func CreateCommentHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
 	// 检查书评是否存在
 	var review models.BookReviewModel
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := db.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, response.CommonResponse{
 				StatusCode: response.Failed,
@@ -103,11 +106,44 @@ func CreateCommentHandler(c *gin.Context) {
 		return
 	}
 
+	if !services.CanViewReview(userID.(uint), &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
+	// 与书评作者互相拉黑时不能评论
+	if services.IsBlocked(userID.(uint), review.AuthorID) {
+		c.JSON(http.StatusForbidden, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrBlocked,
+		})
+		return
+	}
+
+	// 文本过滤
+	content, check := services.ModerateComment(userID.(uint), req.Content)
+	if check.Action == textfilter.ActionReject {
+		logger.Printf("User %d comment rejected by text filter: %v", userID, check.Reasons)
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrContentRejected,
+		})
+		return
+	}
+
 	// 创建评论
 	comment := models.CommentModel{
 		ReviewID: uint(reviewID),
 		UserID:   userID.(uint),
-		Content:  req.Content,
+		Content:  content,
+	}
+	// 需要人工审核的评论先隐藏
+	if check.Action == textfilter.ActionHold {
+		now := time.Now()
+		comment.HiddenAt = &now
 	}
 
 	if err := db.Create(&comment).Error; err != nil {
@@ -119,15 +155,21 @@ func CreateCommentHandler(c *gin.Context) {
 		return
 	}
 
-	// 更新书评的评论数
-	db.Model(&models.BookReviewModel{}).
-		Where("id = ?", reviewID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count + 1"))
+	msg := "评论成功"
+	if check.Action == textfilter.ActionHold {
+		services.HoldForReview(models.ReportTargetComment, comment.ID, comment.UserID, check)
+		msg = response.ContentHeldMsg
+	}
 
-	// 更新用户的评论数统计
-	db.Model(&models.UserModel{}).
-		Where("id = ?", userID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count + 1"))
+	// 更新书评的评论数和用户的评论数统计，待审核的评论通过后再计入
+	if check.Action != textfilter.ActionHold {
+		db.Model(&models.BookReviewModel{}).
+			Where("id = ?", reviewID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1"))
+		db.Model(&models.UserModel{}).
+			Where("id = ?", userID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1"))
+	}
 
 	// 加载评论者信息
 	db.Preload("Commenter").First(&comment, comment.ID)
@@ -150,7 +192,7 @@ func CreateCommentHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, CommentResponse{
 		CommonResponse: response.CommonResponse{
 			StatusCode: response.Success,
-			StatusMsg:  "评论成功",
+			StatusMsg:  msg,
 		},
 		Comment: commentInfo,
 	})
@@ -164,13 +206,14 @@ func CreateCommentHandler(c *gin.Context) {
 // @Tags Comment
 // @Accept json
 // @Produce json
+// @Param Authorization header string false "Bearer {token}，登录后过滤被屏蔽用户的评论"
 // @Param id path int true "书评ID"
 // @Param page query int false "页码（默认1）"
 // @Param page_size query int false "每页数量（默认20）"
 // @Success 200 {object} CommentListResponse
 // @Failure 400 {object} response.CommonResponse
 // @Router /api/reviews/{id}/comments [get]
-func GetCommentListHandler(c *gin.Context) {
+func (h *Handler) GetCommentListHandler(c *gin.Context) {
 	// 获取书评ID
 	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -191,11 +234,11 @@ func GetCommentListHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
 	// 检查书评是否存在
 	var review models.BookReviewModel
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := db.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
 		c.JSON(http.StatusNotFound, response.CommonResponse{
 			StatusCode: response.Failed,
 			StatusMsg:  "书评不存在",
@@ -203,10 +246,25 @@ func GetCommentListHandler(c *gin.Context) {
 		return
 	}
 
+	var viewerID uint
+	if id, exists := c.Get("user_id"); exists {
+		viewerID = id.(uint)
+	}
+	if !services.CanViewReview(viewerID, &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
+	// 登录用户看不到自己屏蔽的人和拉黑了自己的人的评论
+	hiddenUsers := models.ExcludeUsers("user_id", services.HiddenUserIDs(viewerID))
+
 	// 查询评论
 	var comments []models.CommentModel
 	offset := (page - 1) * pageSize
-	if err := db.Where("review_id = ?", reviewID).
+	if err := db.Scopes(models.NotHidden, hiddenUsers).Where("review_id = ?", reviewID).
 		Preload("Commenter").
 		Order("created_at DESC").
 		Offset(offset).
@@ -241,7 +299,7 @@ func GetCommentListHandler(c *gin.Context) {
 
 	// 查询总数
 	var total int64
-	db.Model(&models.CommentModel{}).Where("review_id = ?", reviewID).Count(&total)
+	db.Model(&models.CommentModel{}).Scopes(models.NotHidden, hiddenUsers).Where("review_id = ?", reviewID).Count(&total)
 
 	c.JSON(http.StatusOK, CommentListResponse{
 		CommonResponse: response.CommonResponse{
@@ -265,7 +323,7 @@ func GetCommentListHandler(c *gin.Context) {
 // @Failure 403 {object} response.CommonResponse
 // @Failure 404 {object} response.CommonResponse
 // @Router /api/comments/{id} [delete]
-func DeleteCommentHandler(c *gin.Context) {
+func (h *Handler) DeleteCommentHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -286,7 +344,7 @@ func DeleteCommentHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
 	// 查询评论
 	var comment models.CommentModel
@@ -298,8 +356,9 @@ func DeleteCommentHandler(c *gin.Context) {
 		return
 	}
 
-	// 检查权限（只能删除自己的评论）
-	if comment.UserID != userID.(uint) {
+	// 检查权限（评论者本人或版主）
+	isOwner := comment.UserID == userID.(uint)
+	if !isOwner && !services.UserHasPermission(userID.(uint), models.PermModerateComment) {
 		c.JSON(http.StatusForbidden, response.CommonResponse{
 			StatusCode: response.Failed,
 			StatusMsg:  "无权限删除此评论",
@@ -307,8 +366,8 @@ func DeleteCommentHandler(c *gin.Context) {
 		return
 	}
 
-	// 删除评论
-	if err := db.Delete(&comment).Error; err != nil {
+	// 删除评论，同时更新书评和评论者的评论数
+	if err := services.AdminDeleteComment(comment.ID); err != nil {
 		logger.Printf("Failed to delete comment: %v", err)
 		c.JSON(http.StatusInternalServerError, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -317,20 +376,22 @@ func DeleteCommentHandler(c *gin.Context) {
 		return
 	}
 
-	// 更新书评的评论数
-	db.Model(&models.BookReviewModel{}).
-		Where("id = ?", comment.ReviewID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count - 1"))
-
-	// 更新用户的评论数统计
-	db.Model(&models.UserModel{}).
-		Where("id = ?", userID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count - 1"))
-
 	c.JSON(http.StatusOK, response.CommonResponse{
 		StatusCode: response.Success,
 		StatusMsg:  "删除成功",
 	})
 
 	logger.Printf("User %d deleted comment %d", userID, commentID)
+	if !isOwner {
+		var req response.ModerationRequest
+		c.ShouldBind(&req)
+		audit.Record(audit.Event{
+			Action:     audit.ActionCommentDelete,
+			ActorID:    userID.(uint),
+			TargetType: audit.TargetComment,
+			TargetID:   comment.ID,
+			IP:         c.ClientIP(),
+			Detail:     req.Reason,
+		})
+	}
 }
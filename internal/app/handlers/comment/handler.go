@@ -0,0 +1,22 @@
+package comment
+
+import (
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/cache"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+	"gorm.io/gorm"
+)
+
+// Handler 评论的接口
+type Handler struct {
+	DB *gorm.DB
+	// 旧接口使用的评论缓存和评论队列，key为书评ID
+	Comments  cache.Cacher[uint, models.CommentCacheModel]
+	CommentMQ messageQueue.MQ[msgQueue.CommentMsg]
+}
+
+// NewHandler 创建评论接口，comments和mq只有旧接口使用
+func NewHandler(db *gorm.DB, comments cache.Cacher[uint, models.CommentCacheModel], mq messageQueue.MQ[msgQueue.CommentMsg]) *Handler {
+	return &Handler{DB: db, Comments: comments, CommentMQ: mq}
+}
@@ -59,12 +59,42 @@ func (p *PostFollowActionDTO) getAndCheckDTO(c *gin.Context) {
 	p.ToUserID = uint(uintID)
 }
 
-func PostFollowActionHandler(c *gin.Context) {
+func (h *Handler) PostFollowActionHandler(c *gin.Context) {
 	var p PostFollowActionDTO
 	p.getAndCheckDTO(c)
 	msg := p.newMsg(c)
-	que := msgQueue.GetFollowMQ()
-	que.Push(msg)
+	// 关注是异步处理的，先在这里检查拉黑，让用户能看到原因
+	if msg.ActionType == ActionType_Follow && services.IsBlocked(msg.UserID, msg.ToUserID) {
+		response.ResponseError(c, response.ErrBlocked)
+		return
+	}
+	// 私密账号先发送关注请求，对方同意后才真正关注
+	if msg.ActionType == ActionType_Follow {
+		requested, err := services.RequestFollow(msg.UserID, msg.ToUserID)
+		if err != nil {
+			response.ResponseError(c, err.Error())
+			return
+		}
+		if requested {
+			response.ResponseSuccess(c, response.FollowRequestedMsg)
+			return
+		}
+	}
+	// 还没被同意时取消关注就是撤回请求
+	if msg.ActionType == ActionType_Unfollow && !services.IsFollowing(msg.UserID, msg.ToUserID) {
+		if err := services.CancelFollowRequest(msg.UserID, msg.ToUserID); err != nil {
+			response.ResponseError(c, err.Error())
+			return
+		}
+		response.ResponseSuccess(c, SuccessUnfollowed)
+		return
+	}
+	que := h.FollowMQ
+	if err := que.PushCtx(c.Request.Context(), msg); err != nil {
+		logrus.Error("push follow message failed, err: ", err)
+		response.ResponseError(c, response.ErrServerBusy)
+		return
+	}
 	response.ResponseSuccess(c, SuccessFollowed)
 }
 
@@ -101,9 +131,21 @@ func (p *QueryFollowListDTO) getAndCheckDTO(c *gin.Context) {
 	p.UserID = uint(uintID)
 }
 
-func QueryFollowListHandler(c *gin.Context) {
+// currentUser 关注和粉丝列表允许未登录访问，未登录时返回零值
+func currentUser(c *gin.Context) app.User {
+	value, _ := c.Get(app.UserKeyName)
+	user, _ := value.(app.User)
+	return user
+}
+
+func (h *Handler) QueryFollowListHandler(c *gin.Context) {
 	var p QueryFollowListDTO
 	p.getAndCheckDTO(c)
+	user := currentUser(c)
+	if !services.CanViewUserContent(user.ID, p.UserID) {
+		response.ResponseError(c, response.ErrPrivateAccount)
+		return
+	}
 	followList, err := services.QueryFollowUserListByUserID(p.UserID)
 	if err != nil {
 		logrus.Error("QueryFollowListHandler err:", err)
@@ -112,7 +154,6 @@ func QueryFollowListHandler(c *gin.Context) {
 	}
 	var res response.QueryFollowListResponse
 	//查询查询者是否关注了被查询者的关注列表中的用户
-	user := c.MustGet(app.UserKeyName).(app.User)
 	var UserList = make([]response.UserList, len(followList))
 
 	if user.ID == p.UserID {
@@ -171,9 +212,14 @@ func (p *QueryFanListDTO) getAndCheckDTO(c *gin.Context) {
 	p.UserID = uint(uintID)
 }
 
-func QueryFanListHandler(c *gin.Context) {
+func (h *Handler) QueryFanListHandler(c *gin.Context) {
 	var p QueryFanListDTO
 	p.getAndCheckDTO(c)
+	user := currentUser(c)
+	if !services.CanViewUserContent(user.ID, p.UserID) {
+		response.ResponseError(c, response.ErrPrivateAccount)
+		return
+	}
 	tagetUser, err := services.QueryUserWithFanListByUserID(p.UserID)
 	if err != nil {
 		logrus.Error("QueryFanListHandler err:", err)
@@ -184,7 +230,6 @@ func QueryFanListHandler(c *gin.Context) {
 	var res response.QueryFanListResponse
 
 	//查询查询者是否关注了被查询者的粉丝列表中的用户
-	user := c.MustGet(app.UserKeyName).(app.User)
 	var UserList = make([]response.UserList, len(fanList))
 
 	queryIDs := make([]uint, len(fanList))
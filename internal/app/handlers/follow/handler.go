@@ -0,0 +1,17 @@
+package follow
+
+import (
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/msgQueue"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+)
+
+// Handler 关注、关注列表和关注请求的接口
+type Handler struct {
+	// 关注和取消关注通过队列异步处理
+	FollowMQ messageQueue.MQ[msgQueue.FollowMsg]
+}
+
+// NewHandler 关注和取消关注的消息放入mq
+func NewHandler(mq messageQueue.MQ[msgQueue.FollowMsg]) *Handler {
+	return &Handler{FollowMQ: mq}
+}
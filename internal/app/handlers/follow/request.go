@@ -0,0 +1,122 @@
+package follow
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// UpdatePrivacyHandler 设置私密账号
+// @Summary 设置私密账号
+// @Description 私密账号的关注需要本人同意，书评、收藏和关注列表只对粉丝可见。改为公开时自动同意所有待处理的关注请求
+// @Tags Follow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body response.PrivacyRequest true "隐私设置"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/users/me/privacy [put]
+func (h *Handler) UpdatePrivacyHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	var req response.PrivacyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if err := services.SetAccountPrivate(user.ID, *req.IsPrivate); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.PrivacyUpdatedMsg)
+}
+
+// ListFollowRequestsHandler 待处理的关注请求
+// @Summary 待处理的关注请求
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.FollowRequestListResponse
+// @Router /api/users/me/follow-requests [get]
+func (h *Handler) ListFollowRequestsHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	requests, total, err := services.ListFollowRequests(user.ID, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.FollowRequestListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Requests = make([]response.FollowRequestInfo, 0, len(requests))
+	for _, r := range requests {
+		res.Requests = append(res.Requests, response.FollowRequestInfo{
+			ID: r.ID,
+			User: &response.UserInfo{
+				ID:            r.RequesterID,
+				Username:      r.Requester.Username,
+				FollowerCount: r.Requester.FollowerCount,
+			},
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ApproveFollowRequestHandler 同意关注请求
+// @Summary 同意关注请求
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "关注请求ID"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/users/me/follow-requests/{id}/approve [post]
+func (h *Handler) ApproveFollowRequestHandler(c *gin.Context) {
+	handleFollowRequest(c, services.ApproveFollowRequest, response.FollowRequestApprovedMsg)
+}
+
+// RejectFollowRequestHandler 拒绝关注请求
+// @Summary 拒绝关注请求
+// @Tags Follow
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "关注请求ID"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/users/me/follow-requests/{id}/reject [post]
+func (h *Handler) RejectFollowRequestHandler(c *gin.Context) {
+	handleFollowRequest(c, services.RejectFollowRequest, response.FollowRequestRejectedMsg)
+}
+
+func handleFollowRequest(c *gin.Context, handle func(userID uint, requestID uint) error, successMsg string) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || requestID == 0 {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if err := handle(user.ID, uint(requestID)); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, successMsg)
+}
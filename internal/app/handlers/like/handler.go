@@ -0,0 +1,13 @@
+package like
+
+import "gorm.io/gorm"
+
+// Handler 点赞和点赞列表的接口
+type Handler struct {
+	DB *gorm.DB
+}
+
+// NewHandler 使用db查询书评和点赞
+func NewHandler(db *gorm.DB) *Handler {
+	return &Handler{DB: db}
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/utils"
 	"gorm.io/gorm"
 )
@@ -26,7 +26,7 @@ var logger = utils.NewLogger("like_handler")
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews/{id}/like [post]
-func LikeReviewHandler(c *gin.Context) {
+func (h *Handler) LikeReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -47,11 +47,11 @@ func LikeReviewHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
-	// 检查书评是否存在
+	// 检查书评是否存在，被隐藏的书评不能点赞
 	var review models.BookReviewModel
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := db.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, response.CommonResponse{
 				StatusCode: response.Failed,
@@ -66,25 +66,32 @@ func LikeReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// 检查是否已经点赞
-	var existingLike models.UserLikeModel
-	result := db.Where("user_id = ? AND review_id = ?", userID, reviewID).First(&existingLike)
-	if result.Error == nil {
-		// 已经点赞过
-		c.JSON(http.StatusOK, response.CommonResponse{
-			StatusCode: response.Success,
-			StatusMsg:  "已经点赞过了",
+	if !services.CanViewReview(userID.(uint), &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
 		})
 		return
 	}
 
-	// 创建点赞记录
-	like := models.UserLikeModel{
-		UserID:   userID.(uint),
-		ReviewID: uint(reviewID),
+	// 与书评作者互相拉黑时不能点赞
+	if services.IsBlocked(userID.(uint), review.AuthorID) {
+		c.JSON(http.StatusForbidden, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrBlocked,
+		})
+		return
 	}
 
-	if err := db.Create(&like).Error; err != nil {
+	// 点赞记录、点赞数和 ReviewLiked 事件在同一个事务中写入
+	if err := services.LikeReview(userID.(uint), uint(reviewID)); err != nil {
+		if err.Error() == services.ErrDuplicate {
+			c.JSON(http.StatusOK, response.CommonResponse{
+				StatusCode: response.Success,
+				StatusMsg:  "已经点赞过了",
+			})
+			return
+		}
 		logger.Printf("Failed to create like: %v", err)
 		c.JSON(http.StatusInternalServerError, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -93,11 +100,6 @@ func LikeReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// 更新书评的点赞数
-	db.Model(&models.BookReviewModel{}).
-		Where("id = ?", reviewID).
-		UpdateColumn("like_count", gorm.Expr("like_count + 1"))
-
 	c.JSON(http.StatusOK, response.CommonResponse{
 		StatusCode: response.Success,
 		StatusMsg:  "点赞成功",
@@ -118,7 +120,7 @@ func LikeReviewHandler(c *gin.Context) {
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews/{id}/like [delete]
-func UnlikeReviewHandler(c *gin.Context) {
+func (h *Handler) UnlikeReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -139,34 +141,34 @@ func UnlikeReviewHandler(c *gin.Context) {
 		return
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
-	// 删除点赞记录
-	result := db.Where("user_id = ? AND review_id = ?", userID, reviewID).
-		Delete(&models.UserLikeModel{})
-
-	if result.Error != nil {
-		logger.Printf("Failed to delete like: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, response.CommonResponse{
+	// 已删除的书评不能取消点赞，书评恢复时重新计算计数
+	if err := db.Select("id").First(&models.BookReviewModel{}, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
 			StatusCode: response.Failed,
-			StatusMsg:  "取消点赞失败",
+			StatusMsg:  "书评不存在",
 		})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusOK, response.CommonResponse{
-			StatusCode: response.Success,
-			StatusMsg:  "未点赞过此书评",
+	// 删除点赞记录，点赞数和 ReviewUnliked 事件在同一个事务中更新
+	if err := services.UnlikeReview(userID.(uint), uint(reviewID)); err != nil {
+		if err.Error() == services.ErrDeleteNotExists {
+			c.JSON(http.StatusOK, response.CommonResponse{
+				StatusCode: response.Success,
+				StatusMsg:  "未点赞过此书评",
+			})
+			return
+		}
+		logger.Printf("Failed to delete like: %v", err)
+		c.JSON(http.StatusInternalServerError, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "取消点赞失败",
 		})
 		return
 	}
 
-	// 更新书评的点赞数
-	db.Model(&models.BookReviewModel{}).
-		Where("id = ?", reviewID).
-		UpdateColumn("like_count", gorm.Expr("like_count - 1"))
-
 	c.JSON(http.StatusOK, response.CommonResponse{
 		StatusCode: response.Success,
 		StatusMsg:  "取消点赞成功",
@@ -177,17 +179,18 @@ func UnlikeReviewHandler(c *gin.Context) {
 
 // GetReviewLikesHandler 获取书评的点赞列表
 // @Summary 获取书评点赞列表
-// @Description 获取点赞某条书评的用户列表
+// @Description 获取点赞某条书评的用户列表，登录可选，只能查看自己能看到的书评
 // @Tags Like
 // @Accept json
 // @Produce json
+// @Param Authorization header string false "Bearer {token}"
 // @Param id path int true "书评ID"
 // @Param page query int false "页码（默认1）"
 // @Param page_size query int false "每页数量（默认20）"
 // @Success 200 {object} response.UserListResponse
 // @Failure 400 {object} response.CommonResponse
 // @Router /api/reviews/{id}/likes [get]
-func GetReviewLikesHandler(c *gin.Context) {
+func (h *Handler) GetReviewLikesHandler(c *gin.Context) {
 	// 获取书评ID
 	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -208,11 +211,24 @@ func GetReviewLikesHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
-	// 检查书评是否存在
+	// 检查书评是否存在，被隐藏的书评不显示点赞列表
 	var review models.BookReviewModel
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := db.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
+	// 私密账号和仅粉丝可见的书评，看不到书评的人也看不到点赞列表
+	var viewerID uint
+	if id, exists := c.Get("user_id"); exists {
+		viewerID = id.(uint)
+	}
+	if !services.CanViewReview(viewerID, &review) {
 		c.JSON(http.StatusNotFound, response.CommonResponse{
 			StatusCode: response.Failed,
 			StatusMsg:  "书评不存在",
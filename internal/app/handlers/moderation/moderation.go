@@ -0,0 +1,127 @@
+package moderation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// HideReviewHandler 隐藏书评
+// @Summary 隐藏书评
+// @Description 版主隐藏任意书评，隐藏后不出现在列表、详情和Feed中
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "书评ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Failure 403 {object} response.CommonResponse
+// @Router /api/moderation/reviews/{id}/hide [post]
+func HideReviewHandler(c *gin.Context) {
+	setHidden(c, audit.TargetReview, true)
+}
+
+// UnhideReviewHandler 恢复显示书评
+// @Summary 恢复显示书评
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "书评ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Failure 403 {object} response.CommonResponse
+// @Router /api/moderation/reviews/{id}/unhide [post]
+func UnhideReviewHandler(c *gin.Context) {
+	setHidden(c, audit.TargetReview, false)
+}
+
+// HideCommentHandler 隐藏评论
+// @Summary 隐藏评论
+// @Description 版主隐藏任意评论，隐藏后不出现在评论列表中
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Failure 403 {object} response.CommonResponse
+// @Router /api/moderation/comments/{id}/hide [post]
+func HideCommentHandler(c *gin.Context) {
+	setHidden(c, audit.TargetComment, true)
+}
+
+// UnhideCommentHandler 恢复显示评论
+// @Summary 恢复显示评论
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "评论ID"
+// @Param body body response.ModerationRequest false "原因"
+// @Success 200 {object} response.CommonResponse
+// @Failure 403 {object} response.CommonResponse
+// @Router /api/moderation/comments/{id}/unhide [post]
+func UnhideCommentHandler(c *gin.Context) {
+	setHidden(c, audit.TargetComment, false)
+}
+
+func setHidden(c *gin.Context, targetType string, hidden bool) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	var req response.ModerationRequest
+	c.ShouldBind(&req)
+
+	var action string
+	if targetType == audit.TargetReview {
+		err = services.SetReviewHidden(user.ID, uint(id), hidden)
+		action = audit.ActionReviewUnhide
+		if hidden {
+			action = audit.ActionReviewHide
+		}
+	} else {
+		err = services.SetCommentHidden(user.ID, uint(id), hidden)
+		action = audit.ActionCommentUnhide
+		if hidden {
+			action = audit.ActionCommentHide
+		}
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() != response.ErrServerInternal {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  err.Error(),
+		})
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     action,
+		ActorID:    user.ID,
+		TargetType: targetType,
+		TargetID:   uint(id),
+		IP:         c.ClientIP(),
+		Detail:     req.Reason,
+	})
+	msg := response.UnhideSuccessMsg
+	if hidden {
+		msg = response.HideSuccessMsg
+	}
+	response.ResponseSuccess(c, msg)
+}
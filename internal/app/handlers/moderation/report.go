@@ -0,0 +1,106 @@
+package moderation
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// ReportQueueHandler 审核队列
+// @Summary 审核队列
+// @Description 按举报时间正序列出举报，默认只返回未处理的举报
+// @Tags Moderation
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "open(默认), actioned, dismissed, all"
+// @Param target_type query string false "review, comment, user"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.ReportListResponse
+// @Router /api/moderation/reports [get]
+func ReportQueueHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	status := c.DefaultQuery("status", models.ReportStatusOpen)
+	if status == "all" {
+		status = ""
+	}
+	reports, total, err := services.QueryReports(status, c.Query("target_type"), page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.ReportListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Reports = make([]response.ReportInfo, 0, len(reports))
+	for _, r := range reports {
+		res.Reports = append(res.Reports, response.ReportInfo{
+			ID:           r.ID,
+			ReporterID:   r.ReporterID,
+			TargetType:   r.TargetType,
+			TargetID:     r.TargetID,
+			TargetUserID: r.TargetUserID,
+			Reason:       r.Reason,
+			Detail:       r.Detail,
+			Status:       r.Status,
+			ResolvedBy:   r.ResolvedBy,
+			ResolvedAt:   r.ResolvedAt,
+			Resolution:   r.Resolution,
+			CreatedAt:    r.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ResolveReportHandler 处理举报
+// @Summary 处理举报
+// @Description 同一对象上所有未处理的举报一并处理并通知举报人。actioned隐藏被举报的书评或评论，dismissed恢复被自动隐藏的内容
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "举报ID"
+// @Param body body response.ResolveReportRequest true "处理结果"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/moderation/reports/{id}/resolve [post]
+func ResolveReportHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req response.ResolveReportRequest
+	if err != nil || c.ShouldBind(&req) != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	report, resolved, err := services.ResolveReport(user.ID, uint(id), req.Status, req.Note)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	audit.Record(audit.Event{
+		Action:     audit.ActionReportResolve,
+		ActorID:    user.ID,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		IP:         c.ClientIP(),
+		Detail:     fmt.Sprintf("status=%s reports=%d %s", req.Status, resolved, req.Note),
+	})
+	response.ResponseSuccess(c, response.ReportResolvedMsg)
+}
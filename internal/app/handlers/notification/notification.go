@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// ListNotificationsHandler 通知列表
+// @Summary 通知列表
+// @Description 按时间倒序返回当前用户的站内通知和未读数
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "只返回未读通知"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.NotificationListResponse
+// @Router /api/notifications [get]
+func ListNotificationsHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+	notifications, total, unread, err := services.ListNotifications(user.ID, unreadOnly, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.NotificationListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Unread = unread
+	res.Notifications = make([]response.NotificationInfo, 0, len(notifications))
+	for _, n := range notifications {
+		res.Notifications = append(res.Notifications, response.NotificationInfo{
+			ID:        n.ID,
+			Type:      n.Type,
+			Content:   n.Content,
+			RefType:   n.RefType,
+			RefID:     n.RefID,
+			ReadAt:    n.ReadAt,
+			CreatedAt: n.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// MarkNotificationsReadHandler 全部标记为已读
+// @Summary 全部标记为已读
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.CommonResponse
+// @Router /api/notifications/read [post]
+func MarkNotificationsReadHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	if err := services.MarkNotificationsRead(user.ID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.NotificationsReadMsg)
+}
@@ -0,0 +1,117 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/middleware"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// oidcBindingCookie 保存发起登录的浏览器绑定值，回调时校验，防止登录CSRF
+const (
+	oidcBindingCookie     = "oidc_binding"
+	oidcBindingCookiePath = "/api/auth/oidc"
+)
+
+// OIDCCallbackDTO 提供方跳转回前端后，前端提交的参数
+type OIDCCallbackDTO struct {
+	Code  string `json:"code" form:"code" binding:"required"`
+	State string `json:"state" form:"state" binding:"required"`
+}
+
+// OIDCLoginHandler 发起第三方登录
+// @Summary 发起第三方登录
+// @Description 返回OIDC提供方的授权地址(authorization code + PKCE)，前端跳转到该地址
+// @Tags User
+// @Produce json
+// @Param provider path string true "提供方名字"
+// @Success 200 {object} response.OIDCAuthURLResponse
+// @Router /api/auth/oidc/{provider}/login [get]
+func OIDCLoginHandler(c *gin.Context) {
+	startOIDC(c, 0)
+}
+
+// OIDCLinkHandler 绑定第三方账号
+// @Summary 绑定第三方账号
+// @Description 已登录用户发起授权，回调成功后第三方账号绑定到当前用户
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "提供方名字"
+// @Success 200 {object} response.OIDCAuthURLResponse
+// @Router /api/auth/oidc/{provider}/link [post]
+func OIDCLinkHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	startOIDC(c, user.ID)
+}
+
+func startOIDC(c *gin.Context, linkUserID uint) {
+	authURL, binding, err := services.StartOIDCLogin(c.Request.Context(), c.Param("provider"), linkUserID)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	// 提供方跳转回前端后由前端提交回调，SameSite=Lax时同站的请求会带上cookie
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(services.OIDCStateTTL.Seconds()), oidcBindingCookiePath, "", c.Request.TLS != nil, true)
+	var res response.OIDCAuthURLResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.AuthURL = authURL
+	c.JSON(http.StatusOK, res)
+}
+
+// OIDCCallbackHandler 第三方登录回调
+// @Summary 第三方登录回调
+// @Description 校验state和发起登录时设置的cookie，用code换取id_token并登录，首次登录时绑定已验证邮箱相同的用户或创建新用户。
+// @Description 绑定第三方账号时需要带上发起绑定的用户的token
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer {token}，绑定第三方账号时必需"
+// @Param provider path string true "提供方名字"
+// @Param body body OIDCCallbackDTO true "code和state"
+// @Success 200 {object} response.LoginResponse
+// @Router /api/auth/oidc/{provider}/callback [post]
+func OIDCCallbackHandler(c *gin.Context) {
+	var req OIDCCallbackDTO
+	if err := c.ShouldBind(&req); err != nil {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	provider := c.Param("provider")
+	binding, _ := c.Cookie(oidcBindingCookie)
+	// state只能使用一次，无论成功与否都删除cookie
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, "", -1, oidcBindingCookiePath, "", c.Request.TLS != nil, true)
+	var currentUserID uint
+	if v, ok := c.Get(app.UserKeyName); ok {
+		currentUserID = v.(app.User).ID
+	}
+	user, err := services.FinishOIDCLogin(c.Request.Context(), provider, req.Code, req.State, binding, currentUserID)
+	if err != nil {
+		audit.Log(audit.Event{Action: audit.ActionLoginFailed, Target: provider, IP: c.ClientIP(), Detail: "oidc login failed: " + err.Error()})
+		response.ResponseError(c, err.Error())
+		return
+	}
+	pair, err := middleware.IssueTokenPair(user.ID, user.Username)
+	if err != nil {
+		logrus.Error("IssueTokenPair error: ", err)
+		response.ResponseError(c, response.ErrServerInternal)
+		return
+	}
+	audit.Log(audit.Event{Action: audit.ActionLoginSuccess, ActorID: user.ID, Target: user.Username, IP: c.ClientIP(), Detail: "oidc " + provider})
+	var res response.LoginResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.UserID = int(user.ID)
+	res.Token = pair.AccessToken
+	res.RefreshToken = pair.RefreshToken
+	res.ExpiresIn = pair.ExpiresIn
+	c.JSON(http.StatusOK, res)
+}
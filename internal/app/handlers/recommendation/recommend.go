@@ -1,8 +1,11 @@
 package recommendation
 
 import (
+	"net/http"
 	"strconv"
 
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -145,3 +148,36 @@ func GetBookDetailHandler(c *gin.Context) {
 		},
 	})
 }
+
+// ChatWithBookHandler 与图书对话
+// @Summary Chat with a book
+// @Description 就一本书向LLM提问，需要启用推荐服务，功能开关 chat_with_book 对当前用户开启
+// @Tags Recommendation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param isbn path string true "ISBN"
+// @Param body body response.ChatWithBookRequest true "用户消息"
+// @Success 200 {object} response.ChatWithBookResponse
+// @Failure 404 {object} response.CommonResponse
+// @Router /api/books/{isbn}/chat [post]
+func ChatWithBookHandler(c *gin.Context) {
+	var req response.ChatWithBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	reply, err := recommendService.ChatWithBook(c.Param("isbn"), req.Message, c.GetUint(app.UserIDKeyName))
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.ChatWithBookResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Response = reply
+	c.JSON(http.StatusOK, res)
+}
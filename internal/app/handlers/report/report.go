@@ -0,0 +1,37 @@
+package report
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// CreateReportHandler 举报
+// @Summary 举报书评、评论或用户
+// @Description 同一用户对同一对象只能举报一次，处理后会收到站内通知
+// @Tags Report
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body response.ReportRequest true "举报对象和原因"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/reports [post]
+func CreateReportHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	var req response.ReportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+	if _, err := services.CreateReport(user.ID, req.TargetType, req.TargetID, req.Reason, req.Detail); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.ReportSuccessMsg)
+}
@@ -0,0 +1,19 @@
+package response
+
+// errors
+const (
+	ErrInvalidEmail         = "邮箱不合法"
+	ErrEmailExists          = "邮箱已被使用"
+	ErrEmailNotSet          = "未设置邮箱"
+	ErrEmailAlreadyVerified = "邮箱已验证"
+	ErrVerifyTokenInvalid   = "验证链接无效或已过期"
+	ErrResetTokenInvalid    = "重置链接无效或已过期"
+)
+
+// success response
+const (
+	VerifyMailSentMsg = "验证邮件已发送"
+	EmailVerifiedMsg  = "邮箱验证成功"
+	ResetMailSentMsg  = "如果该邮箱已验证，重置邮件已发送"
+	PasswordResetMsg  = "密码已重置，请重新登录"
+)
@@ -0,0 +1,78 @@
+package response
+
+import "time"
+
+// errors
+const (
+	ErrBanSelf           = "不能封禁自己"
+	ErrBanAdmin          = "不能封禁管理员，请先修改其角色"
+	ErrBanDuration       = "封禁时长不合法"
+	ErrResetNoEmail      = "用户没有已验证的邮箱，无法发送重置邮件"
+	ErrReviewNotDeleted  = "书评未被删除"
+	ErrCommentNotDeleted = "评论未被删除"
+)
+
+// success response
+const (
+	UserBannedMsg      = "用户已封禁"
+	UserUnbannedMsg    = "用户已解封"
+	ForceResetMsg      = "已要求用户重置密码，重置邮件已发送"
+	ContentDeletedMsg  = "已删除"
+	ContentRestoredMsg = "已恢复"
+)
+
+// BanUserRequest 封禁用户请求
+type BanUserRequest struct {
+	// 封禁时长，例如 72h，为空表示永久封禁
+	Duration string `json:"duration" form:"duration"`
+	Reason   string `json:"reason" form:"reason"`
+}
+
+// AdminUserInfo 管理后台中的用户信息
+type AdminUserInfo struct {
+	ID                    uint       `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	EmailVerified         bool       `json:"email_verified"`
+	Role                  string     `json:"role"`
+	Banned                bool       `json:"banned"`
+	BannedAt              *time.Time `json:"banned_at,omitempty"`
+	BannedUntil           *time.Time `json:"banned_until,omitempty"`
+	BanReason             string     `json:"ban_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+type AdminUserListResponse struct {
+	CommonResponse
+	Users []AdminUserInfo `json:"users"`
+	Total int64           `json:"total"`
+}
+
+// AuditLogInfo 一条审计日志
+type AuditLogInfo struct {
+	ID         uint      `json:"id"`
+	ActorID    uint      `json:"actor_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   uint      `json:"target_id"`
+	IP         string    `json:"ip"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AuditLogListResponse struct {
+	CommonResponse
+	Logs  []AuditLogInfo `json:"logs"`
+	Total int64          `json:"total"`
+}
+
+type AdminStatsResponse struct {
+	CommonResponse
+	// 用户、书评、评论等数量
+	Counts map[string]int64 `json:"counts"`
+	// HybridCache 统计信息
+	Cache map[string]interface{} `json:"cache"`
+	// 各消息队列中积压的消息数
+	Queues map[string]int `json:"queues"`
+}
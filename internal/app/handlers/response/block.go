@@ -0,0 +1,37 @@
+package response
+
+import "time"
+
+// errors
+const (
+	ErrBlockSelf = "不能屏蔽自己"
+	ErrBlockKind = "屏蔽类型不合法"
+	ErrBlocked   = "由于对方的隐私设置，无法进行此操作"
+)
+
+// success response
+const (
+	BlockSuccessMsg   = "已拉黑"
+	MuteSuccessMsg    = "已静音"
+	UnblockSuccessMsg = "已解除屏蔽"
+)
+
+// BlockRequest 拉黑或静音请求
+type BlockRequest struct {
+	UserID uint `json:"user_id" form:"user_id" binding:"required"`
+	// block: 拉黑(默认), mute: 静音
+	Kind string `json:"kind" form:"kind"`
+}
+
+// BlockInfo 屏蔽列表中的一项
+type BlockInfo struct {
+	User      *UserInfo `json:"user"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BlockListResponse struct {
+	CommonResponse
+	Blocks []BlockInfo `json:"blocks"`
+	Total  int64       `json:"total"`
+}
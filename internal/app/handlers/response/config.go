@@ -0,0 +1,12 @@
+package response
+
+import "time"
+
+// ConfigResponse 当前生效的配置
+type ConfigResponse struct {
+	CommonResponse
+	// 按配置文件的结构，密码和密钥已隐藏
+	Config map[string]interface{} `json:"config"`
+	// 最后一次热更新的时间，没有热更新过时为空
+	ReloadedAt *time.Time `json:"reloaded_at,omitempty"`
+}
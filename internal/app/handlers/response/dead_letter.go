@@ -0,0 +1,36 @@
+package response
+
+import "time"
+
+// errors
+const (
+	ErrDeadLetterNotFound = "死信不存在"
+	ErrDeadLetterReplayed = "该消息已经重放过"
+	ErrDeadLetterReplay   = "重放失败，消息队列不存在或消息格式不正确"
+)
+
+// success response
+const (
+	DeadLetterReplayedMsg = "已重新放入队列"
+	DeadLetterDeletedMsg  = "已删除"
+)
+
+// DeadLetterInfo 一条处理失败的消息
+type DeadLetterInfo struct {
+	ID    uint   `json:"id"`
+	Queue string `json:"queue"`
+	// 消息Key，重放时保持不变
+	Key string `json:"key"`
+	// 消息体的JSON
+	Payload    string     `json:"payload"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+}
+
+type DeadLetterListResponse struct {
+	CommonResponse
+	DeadLetters []DeadLetterInfo `json:"dead_letters"`
+	Total       int64            `json:"total"`
+}
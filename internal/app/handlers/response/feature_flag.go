@@ -0,0 +1,41 @@
+package response
+
+// errors
+const (
+	ErrFeatureDisabled       = "功能未开放"
+	ErrFeatureFlagNotFound   = "功能开关不存在或没有在后台修改过"
+	ErrFeatureFlagName       = "开关名只能包含小写字母、数字和下划线，最长64个字符"
+	ErrFeatureFlagPercentage = "灰度比例需要在0到100之间"
+)
+
+// success response
+const (
+	FeatureFlagUpdatedMsg = "功能开关已更新"
+	FeatureFlagDeletedMsg = "已删除，恢复为配置文件中的设置"
+)
+
+// SetFeatureFlagRequest 设置功能开关
+type SetFeatureFlagRequest struct {
+	Enabled bool `json:"enabled"`
+	// 按用户ID灰度的比例，0到100，不传时对所有用户开启
+	Percentage *int `json:"percentage"`
+	// 不受灰度比例限制的用户ID
+	Users       []uint `json:"users"`
+	Description string `json:"description"`
+}
+
+// FeatureFlagInfo 当前生效的功能开关
+type FeatureFlagInfo struct {
+	Name        string `json:"name"`
+	Enabled     bool   `json:"enabled"`
+	Percentage  int    `json:"percentage"`
+	Users       []uint `json:"users"`
+	Description string `json:"description"`
+	// config(配置文件) 或 database(后台修改)
+	Source string `json:"source"`
+}
+
+type FeatureFlagListResponse struct {
+	CommonResponse
+	FeatureFlags []FeatureFlagInfo `json:"feature_flags"`
+}
@@ -0,0 +1,35 @@
+package response
+
+import "time"
+
+// errors
+const (
+	ErrPrivateAccount        = "该账号为私密账号，关注后才能查看"
+	ErrFollowRequestNotFound = "关注请求不存在"
+)
+
+// success response
+const (
+	FollowRequestedMsg       = "已发送关注请求，等待对方同意"
+	FollowRequestApprovedMsg = "已同意关注请求"
+	FollowRequestRejectedMsg = "已拒绝关注请求"
+	PrivacyUpdatedMsg        = "隐私设置已更新"
+)
+
+// PrivacyRequest 隐私设置
+type PrivacyRequest struct {
+	IsPrivate *bool `json:"is_private" form:"is_private" binding:"required"`
+}
+
+// FollowRequestInfo 一条待处理的关注请求
+type FollowRequestInfo struct {
+	ID        uint      `json:"id"`
+	User      *UserInfo `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FollowRequestListResponse struct {
+	CommonResponse
+	Requests []FollowRequestInfo `json:"requests"`
+	Total    int64               `json:"total"`
+}
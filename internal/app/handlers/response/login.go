@@ -1,7 +1,20 @@
 package response
 
+// errors
+const (
+	// 用户不存在和密码错误返回同样的错误，避免泄露用户名是否存在
+	ErrLoginFailed = "用户名或密码错误"
+	ErrLoginLocked = "登录失败次数过多，请稍后再试"
+	// 以下两个错误只在密码正确时返回
+	ErrUserBanned            = "账号已被封禁"
+	ErrPasswordResetRequired = "管理员要求重置密码，请通过邮件中的链接重置后再登录"
+)
+
 type LoginResponse struct {
 	CommonResponse
-	UserID int    `json:"user_id"`
-	Token  string `json:"token"`
+	UserID       int    `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// access token剩余有效期,单位秒
+	ExpiresIn int64 `json:"expires_in"`
 }
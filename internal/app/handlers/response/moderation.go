@@ -0,0 +1,29 @@
+package response
+
+// errors
+const (
+	ErrPermissionDenied = "没有权限"
+	ErrInvalidRole      = "角色不合法"
+	ErrChangeOwnRole    = "不能修改自己的角色"
+	ErrReviewNotFound   = "书评不存在"
+	ErrCommentNotFound  = "评论不存在"
+)
+
+// success response
+const (
+	HideSuccessMsg   = "已隐藏"
+	UnhideSuccessMsg = "已恢复显示"
+	RoleUpdatedMsg   = "角色已更新"
+)
+
+// ModerationRequest 版主操作请求
+type ModerationRequest struct {
+	// 操作原因，写入审计日志
+	Reason string `json:"reason" form:"reason"`
+}
+
+// SetRoleRequest 修改用户角色请求
+type SetRoleRequest struct {
+	// user, moderator, admin
+	Role string `json:"role" form:"role" binding:"required"`
+}
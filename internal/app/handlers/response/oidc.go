@@ -0,0 +1,16 @@
+package response
+
+// errors
+const (
+	ErrOIDCProviderNotFound = "不支持的登录方式"
+	ErrOIDCStateInvalid     = "登录请求无效或已过期"
+	ErrOIDCLoginFailed      = "第三方登录失败"
+	ErrIdentityLinked       = "该第三方账号已绑定其他用户"
+)
+
+// OIDCAuthURLResponse 第三方登录授权地址
+type OIDCAuthURLResponse struct {
+	CommonResponse
+	// 前端跳转到该地址完成授权
+	AuthURL string `json:"auth_url"`
+}
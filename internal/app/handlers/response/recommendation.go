@@ -0,0 +1,18 @@
+package response
+
+// errors
+const (
+	ErrRecommendationDisabled = "推荐服务未启用"
+	ErrChatWithBook           = "对话服务异常，请稍后再试"
+)
+
+// ChatWithBookRequest 与图书对话
+type ChatWithBookRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+type ChatWithBookResponse struct {
+	CommonResponse
+	// 模型的回复
+	Response string `json:"response"`
+}
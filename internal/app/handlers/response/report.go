@@ -0,0 +1,78 @@
+package response
+
+import "time"
+
+// errors
+const (
+	ErrReportReason    = "举报原因不合法"
+	ErrReportTarget    = "举报对象不存在"
+	ErrReportSelf      = "不能举报自己"
+	ErrReportDuplicate = "你已经举报过了，请等待处理"
+	ErrReportNotFound  = "举报不存在"
+	ErrReportResolved  = "举报已处理"
+	ErrReportStatus    = "处理结果不合法"
+)
+
+// success response
+const (
+	ReportSuccessMsg     = "举报成功，我们会尽快处理"
+	ReportResolvedMsg    = "举报已处理"
+	NotificationsReadMsg = "已全部标记为已读"
+)
+
+// ReportRequest 举报请求
+type ReportRequest struct {
+	// review, comment, user
+	TargetType string `json:"target_type" form:"target_type" binding:"required"`
+	TargetID   uint   `json:"target_id" form:"target_id" binding:"required"`
+	// spam, harassment, hate, sexual, violence, misinformation, other
+	Reason string `json:"reason" form:"reason" binding:"required"`
+	Detail string `json:"detail" form:"detail" binding:"max=500"`
+}
+
+// ResolveReportRequest 处理举报请求，同一对象上所有未处理的举报一并处理
+type ResolveReportRequest struct {
+	// actioned: 违规，隐藏书评或评论; dismissed: 不违规，恢复被自动隐藏的内容
+	Status string `json:"status" form:"status" binding:"required"`
+	Note   string `json:"note" form:"note" binding:"max=500"`
+}
+
+// ReportInfo 审核队列中的一条举报
+type ReportInfo struct {
+	ID           uint       `json:"id"`
+	ReporterID   uint       `json:"reporter_id"`
+	TargetType   string     `json:"target_type"`
+	TargetID     uint       `json:"target_id"`
+	TargetUserID uint       `json:"target_user_id"`
+	Reason       string     `json:"reason"`
+	Detail       string     `json:"detail"`
+	Status       string     `json:"status"`
+	ResolvedBy   uint       `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	Resolution   string     `json:"resolution,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ReportListResponse struct {
+	CommonResponse
+	Reports []ReportInfo `json:"reports"`
+	Total   int64        `json:"total"`
+}
+
+// NotificationInfo 一条站内通知
+type NotificationInfo struct {
+	ID        uint       `json:"id"`
+	Type      string     `json:"type"`
+	Content   string     `json:"content"`
+	RefType   string     `json:"ref_type,omitempty"`
+	RefID     uint       `json:"ref_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	CommonResponse
+	Notifications []NotificationInfo `json:"notifications"`
+	Total         int64              `json:"total"`
+	Unread        int64              `json:"unread"`
+}
@@ -1,6 +1,33 @@
 package response
 
-import "github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+import (
+	"time"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+)
+
+// errors
+const (
+	ErrReviewNotDraft   = "只有草稿可以执行此操作"
+	ErrReviewTitleEmpty = "发布前请填写标题和内容"
+	ErrRevisionNotFound = "历史版本不存在"
+)
+
+// success response
+const (
+	DraftSavedMsg     = "草稿已保存"
+	ReviewScheduleMsg = "已设置定时发布"
+	ReviewPublishMsg  = "发布成功"
+	ReviewRollbackMsg = "已恢复到历史版本"
+
+	SpoilerPreferenceUpdatedMsg = "剧透显示设置已更新"
+)
+
+// SpoilerPreferenceRequest 剧透显示设置
+type SpoilerPreferenceRequest struct {
+	// 总是显示剧透，列表和信息流中也返回含剧透书评的正文
+	RevealSpoilers *bool `json:"reveal_spoilers" form:"reveal_spoilers" binding:"required"`
+}
 
 // ReviewResponse 书评响应结构（单个书评）
 type ReviewResponse struct {
@@ -20,7 +47,9 @@ type ReviewListResponse struct {
 type ReviewInfo struct {
 	ID           uint        `json:"id"`
 	Title        string      `json:"title"`
-	Content      string      `json:"content"`
+	Content      string      `json:"content"`                // Markdown 原文
+	ContentHTML  string      `json:"content_html"`           // 渲染后的HTML，已过滤，可以直接显示
+	Excerpt      string      `json:"excerpt"`                // 纯文本摘要，用于信息流卡片
 	BookISBN     string      `json:"book_isbn,omitempty"`
 	BookTitle    string      `json:"book_title,omitempty"`
 	Images       []string    `json:"images,omitempty"`       // JSON 数组解析后的图片列表
@@ -34,6 +63,15 @@ type ReviewInfo struct {
 	CollectCount uint        `json:"collect_count"`
 	CreatedAt    int64       `json:"created_at"`             // Unix 时间戳
 	UpdatedAt    int64       `json:"updated_at"`
+	Status       string      `json:"status"`                 // draft, scheduled, published
+	Visibility   string      `json:"visibility"`             // public, unlisted, followers, private
+	ScheduledAt  int64       `json:"scheduled_at,omitempty"` // 定时发布时间
+	Edited       bool        `json:"edited"`                 // 发布后是否修改过
+	EditedAt     int64       `json:"edited_at,omitempty"`    // 最后一次修改时间
+	HasSpoilers  bool        `json:"has_spoilers"`           // 作者标记整篇含剧透
+	// 含剧透的书评在列表中不返回正文，需要查看详情或开启"总是显示剧透"
+	SpoilerHidden bool   `json:"spoiler_hidden,omitempty"`
+	Snippet       string `json:"snippet,omitempty"` // 搜索结果片段，不含剧透
 
 	// 当前用户与书评的关系（需要传入当前用户ID）
 	IsLiked      bool        `json:"is_liked,omitempty"`     // 是否已点赞
@@ -58,22 +96,74 @@ type UserListResponse struct {
 
 // CreateReviewRequest 创建书评请求
 type CreateReviewRequest struct {
-	Title     string   `json:"title" binding:"required,min=1,max=200"`
-	Content   string   `json:"content" binding:"required,min=1,max=5000"`
+	Title     string   `json:"title" binding:"required_unless=Status draft,max=200"` // 草稿可以先不填
+	Content   string   `json:"content" binding:"required_unless=Status draft,max=50000"` // 支持Markdown
 	BookISBN  string   `json:"book_isbn,omitempty"`
 	BookTitle string   `json:"book_title,omitempty"`
 	Images    []string `json:"images,omitempty" binding:"max=9"`       // 最多9张图片
 	Rating    float64  `json:"rating" binding:"min=0,max=10"`
 	Tags      []string `json:"tags,omitempty" binding:"max=10"`        // 最多10个标签
+	// 整篇书评含剧透，列表和信息流中默认隐藏正文和摘要
+	HasSpoilers bool `json:"has_spoilers,omitempty"`
+	// draft: 保存为草稿; published: 发布(默认)
+	Status     string `json:"status,omitempty" binding:"omitempty,oneof=draft published"`
+	Visibility string `json:"visibility,omitempty" binding:"omitempty,oneof=public unlisted followers private"`
+	// 定时发布的Unix时间戳，晚于当前时间时书评在该时间自动发布
+	PublishAt int64 `json:"publish_at,omitempty"`
 }
 
 // UpdateReviewRequest 更新书评请求
 type UpdateReviewRequest struct {
 	Title     *string   `json:"title,omitempty" binding:"omitempty,min=1,max=200"`
-	Content   *string   `json:"content,omitempty" binding:"omitempty,min=1,max=5000"`
+	Content   *string   `json:"content,omitempty" binding:"omitempty,min=1,max=50000"`
+	Images    *[]string `json:"images,omitempty" binding:"omitempty,max=9"`
+	Rating    *float64  `json:"rating,omitempty" binding:"omitempty,min=0,max=10"`
+	Tags      *[]string `json:"tags,omitempty" binding:"omitempty,max=10"`
+	Visibility *string  `json:"visibility,omitempty" binding:"omitempty,oneof=public unlisted followers private"`
+	HasSpoilers *bool   `json:"has_spoilers,omitempty"`
+}
+
+// AutosaveDraftRequest 自动保存草稿，只更新传入的字段，内容可以为空
+type AutosaveDraftRequest struct {
+	Title     *string   `json:"title,omitempty" binding:"omitempty,max=200"`
+	Content   *string   `json:"content,omitempty" binding:"omitempty,max=50000"`
+	BookISBN  *string   `json:"book_isbn,omitempty" binding:"omitempty,max=20"`
+	BookTitle *string   `json:"book_title,omitempty" binding:"omitempty,max=200"`
 	Images    *[]string `json:"images,omitempty" binding:"omitempty,max=9"`
 	Rating    *float64  `json:"rating,omitempty" binding:"omitempty,min=0,max=10"`
 	Tags      *[]string `json:"tags,omitempty" binding:"omitempty,max=10"`
+	HasSpoilers *bool   `json:"has_spoilers,omitempty"`
+}
+
+// PublishReviewRequest 发布草稿
+type PublishReviewRequest struct {
+	Visibility string `json:"visibility,omitempty" binding:"omitempty,oneof=public unlisted followers private"`
+	// 定时发布的Unix时间戳，不传或早于当前时间时立即发布
+	PublishAt int64 `json:"publish_at,omitempty"`
+}
+
+// ReviewRevisionInfo 书评的一个历史版本
+type ReviewRevisionInfo struct {
+	ID        uint      `json:"id"`
+	Version   int       `json:"version"`
+	EditorID  uint      `json:"editor_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Rating    float64   `json:"rating"`
+	Diff      string    `json:"diff,omitempty"` // 与上一版本的差异，版本1为空
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReviewRevisionListResponse struct {
+	CommonResponse
+	Revisions []ReviewRevisionInfo `json:"revisions"`
+	Total     int64                `json:"total"`
+}
+
+// RollbackReviewRequest 回滚到历史版本
+type RollbackReviewRequest struct {
+	RevisionID uint `json:"revision_id" form:"revision_id" binding:"required"`
 }
 
 // FeedResponse 书评流响应（发现页、关注页）
@@ -84,8 +174,12 @@ type FeedResponse struct {
 	HasMore  bool          `json:"has_more"`            // 是否还有更多
 }
 
-// ConvertReviewToInfo 将数据库模型转换为响应结构
-func ConvertReviewToInfo(review *models.BookReviewModel, currentUserID uint) *ReviewInfo {
+// SpoilerHiddenExcerpt 整篇含剧透的书评在列表中代替摘要的文字
+const SpoilerHiddenExcerpt = "这篇书评含有剧透，点开查看全文"
+
+// ConvertReviewToInfo 将数据库模型转换为响应结构。
+// revealSpoilers为false时隐藏含剧透书评的正文，整篇含剧透时摘要也隐藏，作者本人总是可见
+func ConvertReviewToInfo(review *models.BookReviewModel, currentUserID uint, revealSpoilers bool) *ReviewInfo {
 	if review == nil {
 		return nil
 	}
@@ -94,6 +188,8 @@ func ConvertReviewToInfo(review *models.BookReviewModel, currentUserID uint) *Re
 		ID:           review.ID,
 		Title:        review.Title,
 		Content:      review.Content,
+		ContentHTML:  review.ContentHTML,
+		Excerpt:      review.Excerpt,
 		BookISBN:     review.BookISBN,
 		BookTitle:    review.BookTitle,
 		CoverURL:     review.CoverURL,
@@ -104,6 +200,24 @@ func ConvertReviewToInfo(review *models.BookReviewModel, currentUserID uint) *Re
 		CollectCount: review.CollectCount,
 		CreatedAt:    review.CreatedAt.Unix(),
 		UpdatedAt:    review.UpdatedAt.Unix(),
+		Status:       review.Status,
+		Visibility:   review.Visibility,
+		HasSpoilers:  review.HasSpoilers,
+	}
+	if !revealSpoilers && review.ContainsSpoilers() && review.AuthorID != currentUserID {
+		info.Content = ""
+		info.ContentHTML = ""
+		info.SpoilerHidden = true
+		if review.HasSpoilers {
+			info.Excerpt = SpoilerHiddenExcerpt
+		}
+	}
+	if review.ScheduledAt != nil {
+		info.ScheduledAt = review.ScheduledAt.Unix()
+	}
+	if review.EditedAt != nil {
+		info.Edited = true
+		info.EditedAt = review.EditedAt.Unix()
 	}
 
 	// 解析 Images JSON
@@ -0,0 +1,19 @@
+package response
+
+// errors
+const (
+	ErrContentRejected  = "内容包含不允许发布的信息，请修改后再试"
+	ErrTextFilterReload = "词库加载失败，继续使用旧词库"
+)
+
+// success response
+const (
+	ContentHeldMsg        = "发布成功，内容审核通过后将公开显示"
+	TextFilterReloadedMsg = "词库已重新加载"
+)
+
+// TextFilterReloadResponse 重新加载词库的结果
+type TextFilterReloadResponse struct {
+	CommonResponse
+	WordCount int `json:"word_count"`
+}
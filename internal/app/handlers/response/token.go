@@ -0,0 +1,24 @@
+package response
+
+// errors
+const (
+	ErrRefreshTokenInvalid = "refresh token无效或已过期"
+	ErrRefreshTokenReused  = "refresh token已被使用,该会话已被吊销"
+	ErrTokenRevoked        = "token已被吊销"
+)
+
+// success response
+const (
+	LogoutSuccessMsg    = "已退出登录"
+	LogoutAllSuccessMsg = "已退出所有设备"
+)
+
+// TokenResponse 刷新token的响应
+type TokenResponse struct {
+	CommonResponse
+	UserID       int    `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// access token剩余有效期,单位秒
+	ExpiresIn int64 `json:"expires_in"`
+}
@@ -3,11 +3,13 @@ package review
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
 	"github.com/sylvia-ymlin/Coconut-book-community/pkg/utils"
 )
 
@@ -15,7 +17,7 @@ var logger = utils.NewLogger("review_handler")
 
 // CreateReviewHandler 创建书评
 // @Summary 创建书评
-// @Description 用户发布一条图文书评
+// @Description 用户发布一条图文书评，也可以保存为草稿或设置定时发布
 // @Tags Review
 // @Accept json
 // @Produce json
@@ -25,7 +27,7 @@ var logger = utils.NewLogger("review_handler")
 // @Failure 400 {object} response.CommonResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/reviews [post]
-func CreateReviewHandler(c *gin.Context) {
+func (h *Handler) CreateReviewHandler(c *gin.Context) {
 	// 获取当前用户ID（从JWT中间件设置）
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -47,6 +49,37 @@ func CreateReviewHandler(c *gin.Context) {
 		return
 	}
 
+	// 发布状态：草稿、定时发布或立即发布
+	status := req.Status
+	if status == "" {
+		status = models.ReviewStatusPublished
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = models.ReviewVisibilityPublic
+	}
+	var scheduledAt *time.Time
+	if status == models.ReviewStatusPublished && req.PublishAt > time.Now().Unix() {
+		publishAt := time.Unix(req.PublishAt, 0)
+		scheduledAt = &publishAt
+		status = models.ReviewStatusScheduled
+	}
+
+	// 文本过滤，草稿在发布时再检查
+	title, content := req.Title, req.Content
+	var check textfilter.Result
+	if status != models.ReviewStatusDraft {
+		title, content, check = services.ModerateReview(userID.(uint), 0, req.Title, req.Content)
+		if check.Action == textfilter.ActionReject {
+			logger.Printf("User %d review rejected by text filter: %v", userID, check.Reasons)
+			c.JSON(http.StatusBadRequest, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrContentRejected,
+			})
+			return
+		}
+	}
+
 	// 将图片数组转换为JSON字符串
 	imagesJSON := "[]"
 	if len(req.Images) > 0 {
@@ -71,20 +104,31 @@ func CreateReviewHandler(c *gin.Context) {
 
 	// 创建书评模型
 	review := models.BookReviewModel{
-		Title:     req.Title,
-		Content:   req.Content,
-		BookISBN:  req.BookISBN,
-		BookTitle: req.BookTitle,
-		Images:    imagesJSON,
-		CoverURL:  coverURL,
-		Rating:    req.Rating,
-		Tags:      tagsJSON,
-		AuthorID:  userID.(uint),
+		Title:       title,
+		Content:     content,
+		HasSpoilers: req.HasSpoilers,
+		BookISBN:    req.BookISBN,
+		BookTitle:   req.BookTitle,
+		Images:      imagesJSON,
+		CoverURL:    coverURL,
+		Rating:      req.Rating,
+		Tags:        tagsJSON,
+		AuthorID:    userID.(uint),
+
+		Status:      status,
+		Visibility:  visibility,
+		ScheduledAt: scheduledAt,
+	}
+	// 正文按Markdown渲染
+	services.RenderReviewContent(&review)
+	// 需要人工审核的内容先隐藏
+	if check.Action == textfilter.ActionHold {
+		now := time.Now()
+		review.HiddenAt = &now
 	}
 
 	// 保存到数据库
-	db := database.GetMysqlDB()
-	if err := db.Create(&review).Error; err != nil {
+	if err := services.CreateReview(&review); err != nil {
 		logger.Printf("Failed to create review: %v", err)
 		c.JSON(http.StatusInternalServerError, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -93,22 +137,28 @@ func CreateReviewHandler(c *gin.Context) {
 		return
 	}
 
+	msg := "创建成功"
+	switch {
+	case check.Action == textfilter.ActionHold:
+		services.HoldForReview(models.ReportTargetReview, review.ID, review.AuthorID, check)
+		msg = response.ContentHeldMsg
+	case status == models.ReviewStatusDraft:
+		msg = response.DraftSavedMsg
+	case status == models.ReviewStatusScheduled:
+		msg = response.ReviewScheduleMsg
+	}
+
 	// 预加载作者信息
-	db.Preload("Author").First(&review, review.ID)
+	h.DB.Preload("Author").First(&review, review.ID)
 
 	// 返回结果
 	c.JSON(http.StatusOK, response.ReviewResponse{
 		CommonResponse: response.CommonResponse{
 			StatusCode: response.Success,
-			StatusMsg:  "创建成功",
+			StatusMsg:  msg,
 		},
-		Review: response.ConvertReviewToInfo(&review, userID.(uint)),
+		Review: response.ConvertReviewToInfo(&review, userID.(uint), true),
 	})
 
 	logger.Printf("User %d created review %d: %s", userID, review.ID, review.Title)
-
-	// TODO: 异步任务
-	// - 更新用户统计
-	// - 触发推荐系统更新
-	// - 通知关注者（如果需要）
 }
@@ -0,0 +1,302 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
+)
+
+// GetDraftListHandler 我的草稿
+// @Summary 我的草稿
+// @Description 当前用户的草稿和等待定时发布的书评，按最后修改时间倒序
+// @Tags Review
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.ReviewListResponse
+// @Router /api/reviews/drafts [get]
+func (h *Handler) GetDraftListHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := h.DB
+	query := db.Model(&models.BookReviewModel{}).
+		Where("author_id = ? AND status IN ?", userID, []string{models.ReviewStatusDraft, models.ReviewStatusScheduled})
+	var total int64
+	query.Count(&total)
+
+	var reviews []models.BookReviewModel
+	if err := query.Preload("Author").
+		Order("updated_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&reviews).Error; err != nil {
+		logger.Printf("Failed to query drafts: %v", err)
+		c.JSON(http.StatusInternalServerError, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "查询失败",
+		})
+		return
+	}
+
+	reviewInfos := make([]*response.ReviewInfo, 0, len(reviews))
+	for i := range reviews {
+		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID, true))
+	}
+	c.JSON(http.StatusOK, response.ReviewListResponse{
+		CommonResponse: response.CommonResponse{
+			StatusCode: response.Success,
+			StatusMsg:  "查询成功",
+		},
+		Reviews: reviewInfos,
+		Total:   total,
+	})
+}
+
+// loadOwnReview 查询当前用户自己的书评，失败时已经写入响应
+func (h *Handler) loadOwnReview(c *gin.Context, userID uint) (*models.BookReviewModel, bool) {
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "无效的书评ID",
+		})
+		return nil, false
+	}
+	var review models.BookReviewModel
+	if err := h.DB.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return nil, false
+	}
+	if review.AuthorID != userID {
+		c.JSON(http.StatusForbidden, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "无权限修改此书评",
+		})
+		return nil, false
+	}
+	return &review, true
+}
+
+// AutosaveDraftHandler 自动保存草稿
+// @Summary 自动保存草稿
+// @Description 编辑器定时保存草稿，只更新传入的字段，不做内容检查(发布时检查)
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "书评ID"
+// @Param draft body response.AutosaveDraftRequest true "草稿内容"
+// @Success 200 {object} response.ReviewResponse
+// @Failure 400 {object} response.CommonResponse
+// @Router /api/reviews/{id}/draft [put]
+func (h *Handler) AutosaveDraftHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	review, ok := h.loadOwnReview(c, userID)
+	if !ok {
+		return
+	}
+	if review.Status != models.ReviewStatusDraft {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrReviewNotDraft,
+		})
+		return
+	}
+
+	var req response.AutosaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if req.Content != nil {
+		services.SetReviewContent(updates, *req.Content)
+	}
+	if req.BookISBN != nil {
+		updates["book_isbn"] = *req.BookISBN
+	}
+	if req.HasSpoilers != nil {
+		updates[models.BookReviewModelTable_HasSpoilers] = *req.HasSpoilers
+	}
+	if req.BookTitle != nil {
+		updates["book_title"] = *req.BookTitle
+	}
+	if req.Images != nil {
+		if bytes, err := json.Marshal(*req.Images); err == nil {
+			updates["images"] = string(bytes)
+			coverURL := ""
+			if len(*req.Images) > 0 {
+				coverURL = (*req.Images)[0]
+			}
+			updates["cover_url"] = coverURL
+		}
+	}
+	if req.Rating != nil {
+		updates["rating"] = *req.Rating
+	}
+	if req.Tags != nil {
+		if bytes, err := json.Marshal(*req.Tags); err == nil {
+			updates["tags"] = string(bytes)
+		}
+	}
+
+	db := h.DB
+	if len(updates) > 0 {
+		if err := db.Model(review).Updates(updates).Error; err != nil {
+			logger.Printf("Failed to autosave draft %d: %v", review.ID, err)
+			c.JSON(http.StatusInternalServerError, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  "保存失败",
+			})
+			return
+		}
+	}
+	db.Preload("Author").First(review, review.ID)
+
+	c.JSON(http.StatusOK, response.ReviewResponse{
+		CommonResponse: response.CommonResponse{
+			StatusCode: response.Success,
+			StatusMsg:  response.DraftSavedMsg,
+		},
+		Review: response.ConvertReviewToInfo(review, userID, true),
+	})
+}
+
+// PublishReviewHandler 发布草稿
+// @Summary 发布草稿
+// @Description 立即发布草稿或设置定时发布，已设置定时发布的书评可以重新设置时间或立即发布
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "书评ID"
+// @Param body body response.PublishReviewRequest false "可见范围和定时发布时间"
+// @Success 200 {object} response.ReviewResponse
+// @Failure 400 {object} response.CommonResponse
+// @Router /api/reviews/{id}/publish [post]
+func (h *Handler) PublishReviewHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	review, ok := h.loadOwnReview(c, userID)
+	if !ok {
+		return
+	}
+	if review.IsPublished() {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrReviewNotDraft,
+		})
+		return
+	}
+
+	// 请求体可以为空
+	var req response.PublishReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(review.Title) == "" || strings.TrimSpace(review.Content) == "" {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrReviewTitleEmpty,
+		})
+		return
+	}
+
+	// 草稿保存时没有检查，发布前检查全部内容
+	title, content, check := services.ModerateReview(userID, review.ID, review.Title, review.Content)
+	if check.Action == textfilter.ActionReject {
+		logger.Printf("User %d draft %d rejected by text filter: %v", userID, review.ID, check.Reasons)
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrContentRejected,
+		})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"title": title,
+	}
+	services.SetReviewContent(updates, content)
+	if req.Visibility != "" {
+		updates[models.BookReviewModelTable_Visibility] = req.Visibility
+	}
+	msg := response.ReviewPublishMsg
+	if req.PublishAt > now.Unix() {
+		updates[models.BookReviewModelTable_Status] = models.ReviewStatusScheduled
+		updates[models.BookReviewModelTable_ScheduledAt] = time.Unix(req.PublishAt, 0)
+		msg = response.ReviewScheduleMsg
+	} else {
+		// 以发布时间作为创建时间，信息流按发布顺序排列
+		updates[models.BookReviewModelTable_Status] = models.ReviewStatusPublished
+		updates[models.BookReviewModelTable_ScheduledAt] = nil
+		updates[models.BookReviewModelTable_CreatedAt] = now
+	}
+	if check.Action == textfilter.ActionHold && review.HiddenAt == nil {
+		updates["hidden_at"] = now
+		updates["hidden_by"] = 0
+	}
+
+	if err := services.PublishReview(review, updates); err != nil {
+		if err.Error() == response.ErrReviewNotDraft {
+			c.JSON(http.StatusBadRequest, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrReviewNotDraft,
+			})
+			return
+		}
+		logger.Printf("Failed to publish review %d: %v", review.ID, err)
+		c.JSON(http.StatusInternalServerError, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "发布失败",
+		})
+		return
+	}
+	if check.Action == textfilter.ActionHold {
+		services.HoldForReview(models.ReportTargetReview, review.ID, review.AuthorID, check)
+		msg = response.ContentHeldMsg
+	}
+	h.DB.Preload("Author").First(review, review.ID)
+
+	c.JSON(http.StatusOK, response.ReviewResponse{
+		CommonResponse: response.CommonResponse{
+			StatusCode: response.Success,
+			StatusMsg:  msg,
+		},
+		Review: response.ConvertReviewToInfo(review, userID, true),
+	})
+
+	logger.Printf("User %d published review %d (%s)", userID, review.ID, updates[models.BookReviewModelTable_Status])
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 )
 
 // GetDiscoveryFeedHandler 获取发现页书评流（个性化推荐）
@@ -17,11 +17,12 @@ import (
 // @Tags Feed
 // @Accept json
 // @Produce json
+// @Param Authorization header string false "Bearer {token}，登录后过滤被屏蔽用户的书评"
 // @Param latest_time query int false "最新一条的时间戳（用于下拉刷新，传0获取最新）"
 // @Param page_size query int false "每页数量（默认20）"
 // @Success 200 {object} response.FeedResponse
 // @Router /api/feed [get]
-func GetDiscoveryFeedHandler(c *gin.Context) {
+func (h *Handler) GetDiscoveryFeedHandler(c *gin.Context) {
 	// 获取当前用户ID（可选，用于个性化推荐）
 	currentUserID, _ := c.Get("user_id")
 	var userID uint = 0
@@ -37,8 +38,9 @@ func GetDiscoveryFeedHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	db := database.GetMysqlDB()
-	query := db.Model(&models.BookReviewModel{})
+	db := h.DB
+	query := db.Model(&models.BookReviewModel{}).
+		Scopes(models.NotHidden, services.VisibleReviews(userID))
 
 	// 如果传了 latest_time，获取该时间之前的书评
 	if latestTime > 0 {
@@ -78,10 +80,11 @@ func GetDiscoveryFeedHandler(c *gin.Context) {
 		nextTime = reviews[len(reviews)-1].CreatedAt.Unix()
 	}
 
-	// 转换为响应格式
+	// 转换为响应格式，按用户设置隐藏剧透
+	reveal := services.RevealSpoilers(userID)
 	reviewInfos := make([]*response.ReviewInfo, 0, len(reviews))
 	for i := range reviews {
-		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID))
+		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID, reveal))
 	}
 
 	c.JSON(http.StatusOK, response.FeedResponse{
@@ -107,7 +110,7 @@ func GetDiscoveryFeedHandler(c *gin.Context) {
 // @Success 200 {object} response.FeedResponse
 // @Failure 401 {object} response.CommonResponse
 // @Router /api/feed/following [get]
-func GetFollowingFeedHandler(c *gin.Context) {
+func (h *Handler) GetFollowingFeedHandler(c *gin.Context) {
 	// 获取当前用户ID（必须登录）
 	currentUserID, exists := c.Get("user_id")
 	if !exists {
@@ -127,7 +130,7 @@ func GetFollowingFeedHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	db := database.GetMysqlDB()
+	db := h.DB
 
 	// 查询当前用户关注的用户ID列表
 	var user models.UserModel
@@ -160,7 +163,9 @@ func GetFollowingFeedHandler(c *gin.Context) {
 	}
 
 	// 查询关注用户的书评
+	// 关注的人中被静音的不出现在关注页，由 VisibleReviews 排除
 	query := db.Model(&models.BookReviewModel{}).
+		Scopes(models.NotHidden, services.VisibleReviews(userID)).
 		Where("author_id IN ?", followerIDs)
 
 	// 如果传了 latest_time，获取该时间之前的书评
@@ -199,10 +204,11 @@ func GetFollowingFeedHandler(c *gin.Context) {
 		nextTime = reviews[len(reviews)-1].CreatedAt.Unix()
 	}
 
-	// 转换为响应格式
+	// 转换为响应格式，按用户设置隐藏剧透
+	reveal := services.RevealSpoilers(userID)
 	reviewInfos := make([]*response.ReviewInfo, 0, len(reviews))
 	for i := range reviews {
-		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID))
+		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID, reveal))
 	}
 
 	c.JSON(http.StatusOK, response.FeedResponse{
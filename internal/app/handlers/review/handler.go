@@ -0,0 +1,13 @@
+package review
+
+import "gorm.io/gorm"
+
+// Handler 书评、草稿、修改历史和信息流的接口
+type Handler struct {
+	DB *gorm.DB
+}
+
+// NewHandler 使用db查询和更新书评
+func NewHandler(db *gorm.DB) *Handler {
+	return &Handler{DB: db}
+}
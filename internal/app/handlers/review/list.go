@@ -3,11 +3,12 @@ package review
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 )
 
 // GetReviewListHandler 获取书评列表
@@ -18,12 +19,14 @@ import (
 // @Produce json
 // @Param page query int false "页码（默认1）"
 // @Param page_size query int false "每页数量（默认20，最大100）"
-// @Param user_id query int false "筛选指定用户的书评"
+// @Param Authorization header string false "Bearer {token}，查看私密账号的书评需要登录"
+// @Param user_id query int false "筛选指定用户的书评，私密账号只对粉丝可见"
 // @Param book_isbn query string false "筛选指定图书的书评"
 // @Param order_by query string false "排序方式：latest(最新)、popular(最热)、rating(评分)"
+// @Param keyword query string false "搜索标题和正文，结果带不含剧透的片段"
 // @Success 200 {object} response.ReviewListResponse
 // @Router /api/reviews [get]
-func GetReviewListHandler(c *gin.Context) {
+func (h *Handler) GetReviewListHandler(c *gin.Context) {
 	// 获取当前用户ID（可选，用于判断点赞/收藏状态）
 	currentUserID, _ := c.Get("user_id")
 	var userID uint = 0
@@ -44,18 +47,31 @@ func GetReviewListHandler(c *gin.Context) {
 	filterUserID, _ := strconv.Atoi(c.Query("user_id"))
 	filterBookISBN := c.Query("book_isbn")
 	orderBy := c.DefaultQuery("order_by", "latest")
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	reveal := services.RevealSpoilers(userID)
 
 	// 构建查询
-	db := database.GetMysqlDB()
-	query := db.Model(&models.BookReviewModel{})
+	db := h.DB
+	query := db.Model(&models.BookReviewModel{}).Scopes(models.NotHidden, services.VisibleReviews(userID))
 
 	// 筛选条件
 	if filterUserID > 0 {
+		// 私密账号的书评只对粉丝可见
+		if !services.CanViewUserContent(userID, uint(filterUserID)) {
+			c.JSON(http.StatusForbidden, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrPrivateAccount,
+			})
+			return
+		}
 		query = query.Where("author_id = ?", filterUserID)
 	}
 	if filterBookISBN != "" {
 		query = query.Where("book_isbn = ?", filterBookISBN)
 	}
+	if keyword != "" {
+		query = query.Scopes(services.MatchReviewKeyword(keyword, userID, reveal))
+	}
 
 	// 排序
 	switch orderBy {
@@ -93,7 +109,11 @@ func GetReviewListHandler(c *gin.Context) {
 	// 转换为响应格式
 	reviewInfos := make([]*response.ReviewInfo, 0, len(reviews))
 	for i := range reviews {
-		reviewInfos = append(reviewInfos, response.ConvertReviewToInfo(&reviews[i], userID))
+		info := response.ConvertReviewToInfo(&reviews[i], userID, reveal)
+		if keyword != "" {
+			info.Snippet = services.ReviewSnippet(&reviews[i], keyword, userID, reveal)
+		}
+		reviewInfos = append(reviewInfos, info)
 	}
 
 	// 返回结果
@@ -109,15 +129,16 @@ func GetReviewListHandler(c *gin.Context) {
 
 // GetReviewDetailHandler 获取书评详情
 // @Summary 获取书评详情
-// @Description 获取单条书评的详细信息
+// @Description 获取单条书评的详细信息，不公开列出的书评也可以通过ID查看
 // @Tags Review
 // @Accept json
 // @Produce json
+// @Param Authorization header string false "Bearer {token}，查看草稿和仅粉丝可见的书评需要登录"
 // @Param id path int true "书评ID"
 // @Success 200 {object} response.ReviewResponse
 // @Failure 404 {object} response.CommonResponse
 // @Router /api/reviews/{id} [get]
-func GetReviewDetailHandler(c *gin.Context) {
+func (h *Handler) GetReviewDetailHandler(c *gin.Context) {
 	// 获取当前用户ID（可选）
 	currentUserID, _ := c.Get("user_id")
 	var userID uint = 0
@@ -136,9 +157,9 @@ func GetReviewDetailHandler(c *gin.Context) {
 	}
 
 	// 查询书评
-	db := database.GetMysqlDB()
+	db := h.DB
 	var review models.BookReviewModel
-	if err := db.Preload("Author").First(&review, reviewID).Error; err != nil {
+	if err := db.Preload("Author").Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil {
 		logger.Printf("Review not found: %d", reviewID)
 		c.JSON(http.StatusNotFound, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -147,12 +168,21 @@ func GetReviewDetailHandler(c *gin.Context) {
 		return
 	}
 
+	// 草稿、仅粉丝或仅自己可见的书评，无权查看时当作不存在
+	if !services.CanViewReview(userID, &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
 	// 异步更新浏览次数
-	go func() {
+	services.Async(func() {
 		db.Model(&models.BookReviewModel{}).
 			Where("id = ?", reviewID).
 			UpdateColumn("view_count", db.Raw("view_count + 1"))
-	}()
+	})
 
 	// 返回结果
 	c.JSON(http.StatusOK, response.ReviewResponse{
@@ -160,6 +190,6 @@ func GetReviewDetailHandler(c *gin.Context) {
 			StatusCode: response.Success,
 			StatusMsg:  "查询成功",
 		},
-		Review: response.ConvertReviewToInfo(&review, userID),
+		Review: response.ConvertReviewToInfo(&review, userID, true),
 	})
 }
@@ -0,0 +1,131 @@
+package review
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+)
+
+// GetReviewRevisionsHandler 书评修改历史
+// @Summary 书评修改历史
+// @Description 按版本倒序返回书评发布后的修改记录，每个版本包含修改人、时间、内容和与上一版本的差异
+// @Tags Review
+// @Produce json
+// @Param Authorization header string false "Bearer {token}"
+// @Param id path int true "书评ID"
+// @Param page query int false "页码（默认1）"
+// @Param page_size query int false "每页数量（默认20，最大100）"
+// @Success 200 {object} response.ReviewRevisionListResponse
+// @Failure 404 {object} response.CommonResponse
+// @Router /api/reviews/{id}/revisions [get]
+func (h *Handler) GetReviewRevisionsHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "无效的书评ID",
+		})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// 能看到书评的人才能看修改历史
+	var review models.BookReviewModel
+	if err := h.DB.Scopes(models.NotHidden).First(&review, reviewID).Error; err != nil ||
+		!services.CanViewReview(userID, &review) {
+		c.JSON(http.StatusNotFound, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  "书评不存在",
+		})
+		return
+	}
+
+	revisions, total, err := services.ListReviewRevisions(review.ID, page, pageSize)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	var res response.ReviewRevisionListResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.Total = total
+	res.Revisions = make([]response.ReviewRevisionInfo, 0, len(revisions))
+	for _, r := range revisions {
+		res.Revisions = append(res.Revisions, response.ReviewRevisionInfo{
+			ID:        r.ID,
+			Version:   r.Version,
+			EditorID:  r.EditorID,
+			Title:     r.Title,
+			Content:   r.Content,
+			Rating:    r.Rating,
+			Diff:      r.Diff,
+			Note:      r.Note,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// RollbackReviewHandler 恢复到历史版本
+// @Summary 恢复到历史版本
+// @Description 作者或版主把书评恢复到某个历史版本，恢复操作会保存为一个新版本
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Param id path int true "书评ID"
+// @Param body body response.RollbackReviewRequest true "历史版本ID"
+// @Success 200 {object} response.ReviewResponse
+// @Failure 400 {object} response.CommonResponse
+// @Router /api/reviews/{id}/rollback [post]
+func (h *Handler) RollbackReviewHandler(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req response.RollbackReviewRequest
+	if err != nil || c.ShouldBind(&req) != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			StatusCode: response.Failed,
+			StatusMsg:  response.ErrInvalidParams,
+		})
+		return
+	}
+
+	review, err := services.RollbackReview(userID, uint(reviewID), req.RevisionID)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	// 版主回滚别人的书评需要留下记录
+	if review.AuthorID != userID {
+		audit.Record(audit.Event{
+			Action:     audit.ActionReviewRollback,
+			ActorID:    userID,
+			TargetType: audit.TargetReview,
+			TargetID:   review.ID,
+			IP:         c.ClientIP(),
+			Detail:     "revision_id=" + strconv.FormatUint(uint64(req.RevisionID), 10),
+		})
+	}
+
+	h.DB.Preload("Author").First(&review, review.ID)
+	c.JSON(http.StatusOK, response.ReviewResponse{
+		CommonResponse: response.CommonResponse{
+			StatusCode: response.Success,
+			StatusMsg:  response.ReviewRollbackMsg,
+		},
+		Review: response.ConvertReviewToInfo(&review, userID, true),
+	})
+}
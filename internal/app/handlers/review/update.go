@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
 )
 
 // UpdateReviewHandler 更新书评
@@ -25,7 +28,7 @@ import (
 // @Failure 403 {object} response.CommonResponse
 // @Failure 404 {object} response.CommonResponse
 // @Router /api/reviews/{id} [put]
-func UpdateReviewHandler(c *gin.Context) {
+func (h *Handler) UpdateReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -47,7 +50,7 @@ func UpdateReviewHandler(c *gin.Context) {
 	}
 
 	// 查询书评
-	db := database.GetMysqlDB()
+	db := h.DB
 	var review models.BookReviewModel
 	if err := db.First(&review, reviewID).Error; err != nil {
 		c.JSON(http.StatusNotFound, response.CommonResponse{
@@ -76,14 +79,40 @@ func UpdateReviewHandler(c *gin.Context) {
 		return
 	}
 
+	// 文本过滤，只检查本次修改的字段，草稿在发布时再检查
+	var newTitle, newContent string
+	if req.Title != nil {
+		newTitle = *req.Title
+	}
+	if req.Content != nil {
+		newContent = *req.Content
+	}
+	var check textfilter.Result
+	if review.Status != models.ReviewStatusDraft {
+		newTitle, newContent, check = services.ModerateReview(userID.(uint), review.ID, newTitle, newContent)
+		if check.Action == textfilter.ActionReject {
+			logger.Printf("User %d review %d update rejected by text filter: %v", userID, reviewID, check.Reasons)
+			c.JSON(http.StatusBadRequest, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrContentRejected,
+			})
+			return
+		}
+	}
+
 	// 更新字段
 	updates := make(map[string]interface{})
 
 	if req.Title != nil {
-		updates["title"] = *req.Title
+		updates["title"] = newTitle
 	}
 	if req.Content != nil {
-		updates["content"] = *req.Content
+		services.SetReviewContent(updates, newContent)
+	}
+	// 需要人工审核时先隐藏，已被版主隐藏的保持不变
+	if check.Action == textfilter.ActionHold && review.HiddenAt == nil {
+		updates["hidden_at"] = time.Now()
+		updates["hidden_by"] = 0
 	}
 	if req.Images != nil {
 		if bytes, err := json.Marshal(*req.Images); err == nil {
@@ -102,10 +131,22 @@ func UpdateReviewHandler(c *gin.Context) {
 			updates["tags"] = string(bytes)
 		}
 	}
+	// 发布后修改内容要标记为已编辑并保存历史版本，草稿不记录
+	recordRevision := review.Status != models.ReviewStatusDraft &&
+		(req.Title != nil || req.Content != nil || req.Images != nil || req.Rating != nil || req.Tags != nil)
+	if recordRevision {
+		updates[models.BookReviewModelTable_EditedAt] = time.Now()
+	}
+	if req.Visibility != nil {
+		updates[models.BookReviewModelTable_Visibility] = *req.Visibility
+	}
+	if req.HasSpoilers != nil {
+		updates[models.BookReviewModelTable_HasSpoilers] = *req.HasSpoilers
+	}
 
 	// 执行更新
 	if len(updates) > 0 {
-		if err := db.Model(&review).Updates(updates).Error; err != nil {
+		if _, err := services.EditReview(review.ID, userID.(uint), updates, recordRevision, ""); err != nil {
 			logger.Printf("Failed to update review: %v", err)
 			c.JSON(http.StatusInternalServerError, response.CommonResponse{
 				StatusCode: response.Failed,
@@ -115,6 +156,12 @@ func UpdateReviewHandler(c *gin.Context) {
 		}
 	}
 
+	msg := "更新成功"
+	if check.Action == textfilter.ActionHold {
+		services.HoldForReview(models.ReportTargetReview, review.ID, review.AuthorID, check)
+		msg = response.ContentHeldMsg
+	}
+
 	// 重新加载书评（包含作者信息）
 	db.Preload("Author").First(&review, reviewID)
 
@@ -122,9 +169,9 @@ func UpdateReviewHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response.ReviewResponse{
 		CommonResponse: response.CommonResponse{
 			StatusCode: response.Success,
-			StatusMsg:  "更新成功",
+			StatusMsg:  msg,
 		},
-		Review: response.ConvertReviewToInfo(&review, userID.(uint)),
+		Review: response.ConvertReviewToInfo(&review, userID.(uint), true),
 	})
 
 	logger.Printf("User %d updated review %d", userID, reviewID)
@@ -142,7 +189,7 @@ func UpdateReviewHandler(c *gin.Context) {
 // @Failure 403 {object} response.CommonResponse
 // @Failure 404 {object} response.CommonResponse
 // @Router /api/reviews/{id} [delete]
-func DeleteReviewHandler(c *gin.Context) {
+func (h *Handler) DeleteReviewHandler(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -164,7 +211,7 @@ func DeleteReviewHandler(c *gin.Context) {
 	}
 
 	// 查询书评
-	db := database.GetMysqlDB()
+	db := h.DB
 	var review models.BookReviewModel
 	if err := db.First(&review, reviewID).Error; err != nil {
 		c.JSON(http.StatusNotFound, response.CommonResponse{
@@ -174,8 +221,9 @@ func DeleteReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// 检查权限
-	if review.AuthorID != userID.(uint) {
+	// 检查权限（作者本人或版主）
+	isOwner := review.AuthorID == userID.(uint)
+	if !isOwner && !services.UserHasPermission(userID.(uint), models.PermModerateReview) {
 		c.JSON(http.StatusForbidden, response.CommonResponse{
 			StatusCode: response.Failed,
 			StatusMsg:  "无权限删除此书评",
@@ -183,8 +231,8 @@ func DeleteReviewHandler(c *gin.Context) {
 		return
 	}
 
-	// 软删除
-	if err := db.Delete(&review).Error; err != nil {
+	// 软删除，同时更新用户统计
+	if err := services.AdminDeleteReview(review.ID); err != nil {
 		logger.Printf("Failed to delete review: %v", err)
 		c.JSON(http.StatusInternalServerError, response.CommonResponse{
 			StatusCode: response.Failed,
@@ -199,8 +247,17 @@ func DeleteReviewHandler(c *gin.Context) {
 	})
 
 	logger.Printf("User %d deleted review %d", userID, reviewID)
+	if !isOwner {
+		var req response.ModerationRequest
+		c.ShouldBind(&req)
+		audit.Record(audit.Event{
+			Action:     audit.ActionReviewDelete,
+			ActorID:    userID.(uint),
+			TargetType: audit.TargetReview,
+			TargetID:   review.ID,
+			IP:         c.ClientIP(),
+			Detail:     req.Reason,
+		})
+	}
 
-	// TODO: 异步任务
-	// - 删除相关的点赞、评论、收藏记录
-	// - 更新用户统计
 }
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/middleware"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// VerifyEmailDTO 验证邮箱请求
+type VerifyEmailDTO struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// ForgotPasswordDTO 忘记密码请求
+type ForgotPasswordDTO struct {
+	Email string `json:"email" form:"email" binding:"required"`
+}
+
+// ResetPasswordDTO 重置密码请求
+type ResetPasswordDTO struct {
+	Token    string `json:"token" form:"token" binding:"required"`
+	Password string `json:"password" form:"password" binding:"required"`
+}
+
+// VerifyEmailHandler 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用验证邮件中的token验证邮箱，token只能使用一次
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body VerifyEmailDTO true "验证token"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/email/verify [post]
+func VerifyEmailHandler(c *gin.Context) {
+	var req VerifyEmailDTO
+	if err := c.ShouldBind(&req); err != nil {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	if err := services.VerifyEmail(req.Token); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.EmailVerifiedMsg)
+}
+
+// ResendVerifyEmailHandler 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 向当前用户的邮箱重新发送验证邮件
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.CommonResponse
+// @Router /api/email/verify/resend [post]
+func ResendVerifyEmailHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	if err := services.SendVerifyEmail(user.ID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.VerifyMailSentMsg)
+}
+
+// ForgotPasswordHandler 忘记密码
+// @Summary 忘记密码
+// @Description 向已验证的邮箱发送重置密码邮件，邮箱未注册时同样返回成功
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordDTO true "邮箱"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/password/forgot [post]
+func ForgotPasswordHandler(c *gin.Context) {
+	var req ForgotPasswordDTO
+	if err := c.ShouldBind(&req); err != nil {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	if err := services.RequestPasswordReset(req.Email); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.ResetMailSentMsg)
+}
+
+// ResetPasswordHandler 重置密码
+// @Summary 重置密码
+// @Description 使用重置邮件中的token设置新密码，成功后该用户所有设备需要重新登录
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordDTO true "token和新密码"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/password/reset [post]
+func ResetPasswordHandler(c *gin.Context) {
+	var req ResetPasswordDTO
+	if err := c.ShouldBind(&req); err != nil {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	userID, err := services.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if err := middleware.LogoutAllSessions(userID); err != nil {
+		// 密码已经修改成功，吊销失败只记录日志
+		logrus.Error("logout all sessions after password reset failed, err: ", err)
+	}
+	response.ResponseSuccess(c, response.PasswordResetMsg)
+}
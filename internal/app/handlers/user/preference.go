@@ -0,0 +1,32 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// UpdateSpoilerPreferenceHandler 设置剧透显示
+// @Summary 设置剧透显示
+// @Description 默认在书评列表和信息流中隐藏含剧透书评的正文，开启后总是显示
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body response.SpoilerPreferenceRequest true "剧透显示设置"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/users/me/spoiler-preference [put]
+func UpdateSpoilerPreferenceHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	var req response.SpoilerPreferenceRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	if err := services.SetRevealSpoilers(user.ID, *req.RevealSpoilers); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.SpoilerPreferenceUpdatedMsg)
+}
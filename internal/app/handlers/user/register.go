@@ -13,11 +13,14 @@ import (
 type RegisterUserDTO struct {
 	Username string `json:"username" form:"username" binding:"required"`
 	Password string `json:"password" form:"password" binding:"required"`
+	// 可选，填写后发送验证邮件
+	Email string `json:"email" form:"email"`
 }
 
 const (
 	RegisterUserDTO_Username = "username"
 	RegisterUserDTO_Password = "password"
+	RegisterUserDTO_Email    = "email"
 )
 
 // UserRegisterHandler registers a new user
@@ -28,6 +31,7 @@ const (
 // @Produce json
 // @Param username query string true "Username"
 // @Param password query string true "Password"
+// @Param email query string false "Email, a verification mail is sent if set"
 // @Success 200 {object} response.RegisterResponse
 // @Failure 400 {object} response.CommonResponse
 // @Router /user/register/ [post]
@@ -50,8 +54,9 @@ func UserRegisterHandler(c *gin.Context) {
 		c.JSON(http.StatusOK, res)
 		return
 	}
+	registerRequest.Email = c.Query(RegisterUserDTO_Email)
 
-	res, err := services.CreateUser(registerRequest.Username, registerRequest.Password)
+	res, err := services.CreateUser(registerRequest.Username, registerRequest.Password, registerRequest.Email)
 	if err != nil {
 		res.CommonResponse.StatusCode = response.Failed
 		switch err.Error() {
@@ -61,6 +66,8 @@ func UserRegisterHandler(c *gin.Context) {
 			res.CommonResponse.StatusMsg = response.ErrInvalidPassword
 		case response.ErrInvalidUsername:
 			res.CommonResponse.StatusMsg = response.ErrInvalidUsername
+		case response.ErrInvalidEmail, response.ErrEmailExists:
+			res.CommonResponse.StatusMsg = err.Error()
 		default:
 			res.CommonResponse.StatusMsg = response.ErrServerInternal
 		}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// RequireFeature 功能开关对当前用户关闭时返回404，开关修改后立即生效。
+// 按用户灰度的功能需要放在 JWTMiddleWare 之后，否则按未登录用户判断
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.FeatureEnabled(name, c.GetUint(app.UserIDKeyName)) {
+			c.AbortWithStatusJSON(http.StatusNotFound, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrFeatureDisabled,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/config"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
@@ -18,43 +21,157 @@ import (
 
 var JwtAuth *myjwt.CryptJWT
 
+// Revocations token吊销列表
+var Revocations RevocationList
+
 var once sync.Once
 
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// access token和refresh token的有效期
+var (
+	accessTokenTTL  = defaultAccessTokenTTL
+	refreshTokenTTL = defaultRefreshTokenTTL
+)
+
 func InitJwt() {
 	once.Do(func() {
 		jwtConfig := config.GetJwtConfig()
-		SigningKey, err := utils.HexStrToBytes(jwtConfig.SignKeyHex)
+		keyring, err := newKeyring(jwtConfig)
 		if err != nil {
 			logrus.Panic("初始化jwt失败, error:" + err.Error())
 		}
-		var cryptoer *utils.CbcAESCrypt
-		cryptoer, err = utils.NewAESCrypt(jwtConfig.SecretHex)
+		cryptoer, err := newCryptoer(jwtConfig)
 		if err != nil {
 			logrus.Panic("初始化jwt失败, error:" + err.Error())
 		}
-		JwtAuth = myjwt.NewJWT(SigningKey, cryptoer)
+		accessTokenTTL = parseTTL(jwtConfig.AccessTokenTTL, defaultAccessTokenTTL)
+		refreshTokenTTL = parseTTL(jwtConfig.RefreshTokenTTL, defaultRefreshTokenTTL)
+		JwtAuth = myjwt.NewJWTWithKeyring(keyring, cryptoer)
+		JwtAuth.SetTokenTTL(accessTokenTTL)
+		Revocations = initRevocationList()
 	})
 }
 
+// newKeyring 旧的jwt_sign_key_hex作为kid为空的密钥，其余密钥按生效时间轮换
+func newKeyring(jwtConfig config.JwtConfig) (*myjwt.Keyring, error) {
+	keyring, _ := myjwt.NewKeyring()
+	if jwtConfig.SignKeyHex != "" {
+		key, err := utils.HexStrToBytes(jwtConfig.SignKeyHex)
+		if err != nil {
+			return nil, err
+		}
+		if err := keyring.AddKey(myjwt.SigningKey{Key: key}); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range jwtConfig.SignKeys {
+		if k.Kid == "" {
+			return nil, errors.New("jwt_sign_keys: kid is required")
+		}
+		key, err := utils.HexStrToBytes(k.SignKeyHex)
+		if err != nil {
+			return nil, fmt.Errorf("jwt_sign_keys[%s]: %w", k.Kid, err)
+		}
+		activateAt, err := parseActivateAt(k.ActivateAt)
+		if err != nil {
+			return nil, fmt.Errorf("jwt_sign_keys[%s]: %w", k.Kid, err)
+		}
+		if err := keyring.AddKey(myjwt.SigningKey{KID: k.Kid, Key: key, ActivateAt: activateAt}); err != nil {
+			return nil, fmt.Errorf("jwt_sign_keys[%s]: %w", k.Kid, err)
+		}
+	}
+	if _, err := keyring.SigningKey(time.Now()); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// newCryptoer 配置了jwt_secret_keys时使用AES-GCM，旧的CBC密钥只用于解密
+func newCryptoer(jwtConfig config.JwtConfig) (myjwt.Cryptoer, error) {
+	var legacy *utils.CbcAESCrypt
+	if jwtConfig.SecretHex != "" {
+		var err error
+		if legacy, err = utils.NewAESCrypt(jwtConfig.SecretHex); err != nil {
+			return nil, err
+		}
+	}
+	if len(jwtConfig.SecretKeys) == 0 {
+		if legacy == nil {
+			return nil, errors.New("jwt_secret_hex or jwt_secret_keys is required")
+		}
+		return legacy, nil
+	}
+	gcm := &utils.GcmAESCrypt{}
+	gcm.SetLegacy(legacy)
+	for _, k := range jwtConfig.SecretKeys {
+		activateAt, err := parseActivateAt(k.ActivateAt)
+		if err != nil {
+			return nil, fmt.Errorf("jwt_secret_keys[%d]: %w", k.Version, err)
+		}
+		if err := gcm.AddKey(k.Version, k.SecretHex, activateAt); err != nil {
+			return nil, fmt.Errorf("jwt_secret_keys[%d]: %w", k.Version, err)
+		}
+	}
+	// 确认至少有一把已生效的加密密钥
+	if _, err := gcm.Encrypt("ping"); err != nil {
+		return nil, err
+	}
+	return gcm, nil
+}
+
+func parseActivateAt(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func parseTTL(raw string, defaultTTL time.Duration) time.Duration {
+	if raw == "" {
+		return defaultTTL
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		logrus.Warnf("invalid token ttl %q, using %v", raw, defaultTTL)
+		return defaultTTL
+	}
+	return ttl
+}
+
 // JWTMiddleWare 鉴权中间件，鉴权并设置user
 func JWTMiddleWare(omitPaths ...string) gin.HandlerFunc {
 	InitJwt()
 
 	return func(c *gin.Context) {
-		tokenStr, ok := c.GetQuery("token")
-		//如果是忽略的路径，直接跳过
+		tokenStr, ok := getTokenFromRequest(c)
+		//如果是忽略的路径，登录可选：没有token或token无效、过期、已吊销时按未登录处理
+		optional := false
 		for _, path := range omitPaths {
-			if c.FullPath() == path && !ok {
-				logrus.Debug(path, "跳过鉴权")
-				c.Next()
-				return
+			if c.FullPath() == path {
+				optional = true
+				break
 			}
 		}
-		if tokenStr == "" {
-			tokenStr = c.PostForm("token")
+		if optional && !ok {
+			logrus.Debug(c.FullPath(), "跳过鉴权")
+			c.Next()
+			return
 		}
 		//验证token
 		CustomClaims, err := JwtAuth.ParseToken(tokenStr)
+		var id uint64
+		if err == nil {
+			id, _ = strconv.ParseUint(CustomClaims.ID, 10, 64)
+		}
+		if optional && (err != nil || isTokenRevoked(uint(id), CustomClaims)) {
+			logrus.Debug(c.FullPath(), "token无效，按未登录处理")
+			c.Next()
+			return
+		}
 		//如果token过期，返回错误信息
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			c.JSON(http.StatusOK, response.CommonResponse{
@@ -75,19 +192,66 @@ func JWTMiddleWare(omitPaths ...string) gin.HandlerFunc {
 			return
 		}
 
+		//如果token已被吊销(退出登录)，返回错误信息
+		if isTokenRevoked(uint(id), CustomClaims) {
+			c.JSON(http.StatusOK, response.CommonResponse{
+				StatusCode: response.TokenExpired,
+				StatusMsg:  response.ErrTokenRevoked,
+			})
+			c.Abort() //阻止执行
+			return
+		}
+
 		var user app.User
-		id, _ := strconv.ParseUint(CustomClaims.ID, 10, 64)
 		user.ID = uint(id)
 		user.Username = CustomClaims.Username
+		user.Token = tokenStr
 		user.Claims = CustomClaims
-		c.Set("user", user)
+		c.Set(app.UserKeyName, user)
+		c.Set(app.UserIDKeyName, user.ID)
 		c.Next()
 	}
 }
 
+// getTokenFromRequest 依次从 Authorization: Bearer、query 和 form 中获取token
+func getTokenFromRequest(c *gin.Context) (string, bool) {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		if token, found := strings.CutPrefix(auth, "Bearer "); found && token != "" {
+			return token, true
+		}
+	}
+	if token, ok := c.GetQuery("token"); ok {
+		return token, true
+	}
+	if token, ok := c.GetPostForm("token"); ok {
+		return token, true
+	}
+	return "", false
+}
+
+func isTokenRevoked(userID uint, claims *myjwt.CustomClaims) bool {
+	if claims.SessionID != "" && Revocations.IsSessionRevoked(claims.SessionID) {
+		return true
+	}
+	revokedAt, ok := Revocations.UserRevokedAt(userID)
+	if !ok {
+		return false
+	}
+	// iat只精确到秒，吊销时间也按秒截断。吊销同一秒内签发的token不算被吊销，
+	// 否则退出所有设备后立即重新登录拿到的token会一直无效。同一秒内更早的会话token已经按会话吊销
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revokedAt.Truncate(time.Second))
+}
+
+// CreateToken 签发不属于任何登录会话的access token
 func CreateToken(id uint, username string) (string, error) {
+	return createAccessToken(id, username, "")
+}
+
+func createAccessToken(id uint, username string, sessionID string) (string, error) {
+	InitJwt()
 	claims := myjwt.CustomClaims{
-		Username: username,
+		Username:  username,
+		SessionID: sessionID,
 	}
 	claims.ID = strconv.FormatUint(uint64(id), 10)
 	return JwtAuth.CreateToken(claims)
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	myjwt "github.com/sylvia-ymlin/Coconut-book-community/pkg/jwt"
+)
+
+type fakeRevocations struct {
+	sessions  map[string]bool
+	revokedAt map[uint]time.Time
+}
+
+func (f *fakeRevocations) RevokeSession(sessionID string, _ time.Duration) error {
+	f.sessions[sessionID] = true
+	return nil
+}
+
+func (f *fakeRevocations) IsSessionRevoked(sessionID string) bool {
+	return f.sessions[sessionID]
+}
+
+func (f *fakeRevocations) RevokeUser(userID uint, at time.Time, _ time.Duration) error {
+	f.revokedAt[userID] = at
+	return nil
+}
+
+func (f *fakeRevocations) UserRevokedAt(userID uint) (time.Time, bool) {
+	at, ok := f.revokedAt[userID]
+	return at, ok
+}
+
+func useFakeRevocations(t *testing.T) *fakeRevocations {
+	fake := &fakeRevocations{sessions: map[string]bool{}, revokedAt: map[uint]time.Time{}}
+	old := Revocations
+	Revocations = fake
+	t.Cleanup(func() { Revocations = old })
+	return fake
+}
+
+func claimsIssuedAt(at time.Time, sessionID string) *myjwt.CustomClaims {
+	return &myjwt.CustomClaims{
+		SessionID:        sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(at)},
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	fake := useFakeRevocations(t)
+	revokedAt := time.Date(2024, 1, 1, 12, 0, 0, 700*int(time.Millisecond), time.UTC)
+	fake.RevokeUser(1, revokedAt, time.Hour)
+	fake.RevokeSession("revoked", time.Hour)
+
+	assert.True(t, isTokenRevoked(1, claimsIssuedAt(revokedAt.Add(-time.Second), "")))
+	// 吊销之后同一秒内重新登录签发的token有效
+	assert.False(t, isTokenRevoked(1, claimsIssuedAt(revokedAt.Add(200*time.Millisecond), "")))
+	assert.False(t, isTokenRevoked(1, claimsIssuedAt(revokedAt.Add(time.Second), "")))
+	// 同一秒内更早的会话token按会话吊销
+	assert.True(t, isTokenRevoked(1, claimsIssuedAt(revokedAt, "revoked")))
+	assert.True(t, isTokenRevoked(1, &myjwt.CustomClaims{}))
+	assert.False(t, isTokenRevoked(2, claimsIssuedAt(revokedAt.Add(-time.Hour), "")))
+}
+
+func useTestJwt(t *testing.T) {
+	once.Do(func() {})
+	old := JwtAuth
+	JwtAuth = myjwt.NewJWT([]byte("0123456789abcdef"), nil)
+	JwtAuth.SetTokenTTL(time.Minute)
+	t.Cleanup(func() { JwtAuth = old })
+}
+
+func TestJWTMiddleWare_OptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useTestJwt(t)
+	fake := useFakeRevocations(t)
+
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", c.GetUint(app.UserIDKeyName))
+	}
+	router.GET("/public", JWTMiddleWare("/public"), handler)
+	router.GET("/private", JWTMiddleWare(), handler)
+
+	sign := func(claims myjwt.CustomClaims) string {
+		claims.ID = "1"
+		token, err := JwtAuth.CreateToken(claims)
+		assert.NoError(t, err)
+		return token
+	}
+	valid := sign(myjwt.CustomClaims{Username: "alice"})
+	expiredClaims := myjwt.CustomClaims{Username: "alice"}
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired := sign(expiredClaims)
+	revoked := sign(myjwt.CustomClaims{Username: "alice", SessionID: "revoked"})
+	fake.RevokeSession("revoked", time.Hour)
+
+	do := func(path, token string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// 登录可选的接口，token无效、过期或已吊销时按未登录处理
+	assert.Equal(t, "0", do("/public", ""))
+	assert.Equal(t, "1", do("/public", valid))
+	assert.Equal(t, "0", do("/public", "not-a-token"))
+	assert.Equal(t, "0", do("/public", expired))
+	assert.Equal(t, "0", do("/public", revoked))
+
+	// 需要登录的接口仍然返回错误
+	assert.Equal(t, "1", do("/private", valid))
+	assert.Contains(t, do("/private", ""), "status_code")
+	assert.Contains(t, do("/private", expired), "status_code")
+	assert.Contains(t, do("/private", revoked), "status_code")
+}
@@ -3,10 +3,14 @@ package middleware
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
 	"github.com/sylvia-ymlin/Coconut-book-community/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -14,67 +18,137 @@ import (
 
 // LoginRequestDto 登录请求
 type LoginRequestDto struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
 // UserLoginHandler 用户登录
+// @Summary 用户登录
+// @Description 使用JSON body中的用户名和密码登录。连续失败会按账号和IP锁定，锁定期间返回429
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body LoginRequestDto true "用户名和密码"
+// @Success 200 {object} response.LoginResponse
+// @Failure 429 {object} response.CommonResponse
+// @Router /api/login [post]
 func UserLoginHandler(c *gin.Context) {
 	var loginRequest LoginRequestDto
-	var res response.LoginResponse
-	// if err := c.ShouldBindJSON(&loginRequest); err != nil {
-	// 	res.CommonResponse.StatusCode = response.Failed
-	// 	res.CommonResponse.StatusMsg = response.ErrInvalidParams
-	// 	c.JSON(http.StatusOK, res)
-	// 	return
-	// }
+	if err := c.ShouldBindJSON(&loginRequest); err != nil {
+		var res response.LoginResponse
+		res.CommonResponse.StatusCode = response.Failed
+		res.CommonResponse.StatusMsg = response.ErrInvalidParams
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	handleLogin(c, loginRequest)
+}
+
+// LegacyUserLoginHandler 旧版登录接口，从query中读取用户名和密码
+// Deprecated: 密码会出现在URL和访问日志中，请使用 UserLoginHandler
+func LegacyUserLoginHandler(c *gin.Context) {
+	var loginRequest LoginRequestDto
 	var ok1, ok2 bool
 	loginRequest.Username, ok1 = c.GetQuery("username")
 	loginRequest.Password, ok2 = c.GetQuery("password")
 	if !ok1 || !ok2 {
+		var res response.LoginResponse
 		res.CommonResponse.StatusCode = response.Failed
 		res.CommonResponse.StatusMsg = response.ErrInvalidParams
 		c.JSON(http.StatusOK, res)
 		return
 	}
+	handleLogin(c, loginRequest)
+}
+
+func handleLogin(c *gin.Context, loginRequest LoginRequestDto) {
+	ip := c.ClientIP()
+	limiter := GetLoginLimiter()
+	if wait, locked := limiter.Check(loginRequest.Username, ip); locked {
+		audit.Log(audit.Event{Action: audit.ActionLoginLocked, Target: loginRequest.Username, IP: ip, Detail: "login rejected while locked"})
+		respondLoginLocked(c, wait)
+		return
+	}
+
 	res, err := Login(loginRequest.Username, loginRequest.Password)
 	if err != nil {
 		res.CommonResponse.StatusCode = response.Failed
-		switch err.Error() {
-		case response.ErrUserNotExists:
-			res.CommonResponse.StatusMsg = response.ErrUserNotExists
-		case response.ErrUserPassword:
-			res.CommonResponse.StatusMsg = response.ErrUserPassword
-		default:
+		if err.Error() == response.ErrUserBanned || err.Error() == response.ErrPasswordResetRequired {
+			audit.Log(audit.Event{Action: audit.ActionLoginFailed, Target: loginRequest.Username, IP: ip, Detail: err.Error()})
+			res.CommonResponse.StatusMsg = err.Error()
+			c.JSON(http.StatusOK, res)
+			return
+		}
+		if err.Error() != response.ErrLoginFailed {
 			res.CommonResponse.StatusMsg = response.ErrServerInternal
+			c.JSON(http.StatusOK, res)
+			return
 		}
+		audit.Log(audit.Event{Action: audit.ActionLoginFailed, Target: loginRequest.Username, IP: ip, Detail: "invalid username or password"})
+		if wait, locked := limiter.Fail(loginRequest.Username, ip); locked {
+			audit.Log(audit.Event{Action: audit.ActionLoginLocked, Target: loginRequest.Username, IP: ip, Detail: "locked for " + wait.String()})
+		}
+		res.CommonResponse.StatusMsg = response.ErrLoginFailed
 		c.JSON(http.StatusOK, res)
 		return
 	}
+	limiter.Succeed(loginRequest.Username)
+	audit.Log(audit.Event{Action: audit.ActionLoginSuccess, ActorID: uint(res.UserID), Target: loginRequest.Username, IP: ip})
 	res.CommonResponse.StatusCode = response.Success
 	app.ZeroCheck(res.UserID)
 	c.JSON(http.StatusOK, res)
 }
 
+func respondLoginLocked(c *gin.Context, wait time.Duration) {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, response.CommonResponse{
+		StatusCode: response.Failed,
+		StatusMsg:  response.ErrLoginLocked,
+	})
+}
+
+// dummyPasswordHash 用户不存在时也做一次bcrypt比对，避免通过响应时间判断用户名是否存在
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+func getDummyPasswordHash() string {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash = utils.BcryptHash("dummy-password-for-timing")
+	})
+	return dummyPasswordHash
+}
+
+// Login 校验用户名密码并签发token。
+// 用户不存在和密码错误都返回 response.ErrLoginFailed，
+// 密码正确但账号被封禁或需要重置密码时返回对应的错误
 func Login(username string, password string) (response.LoginResponse, error) {
 	var res response.LoginResponse
 	user, err := services.QueryUserByUsername(username)
 	if err != nil {
 		if err.Error() == response.ErrUserNotExists {
-			return res, errors.New(response.ErrUserNotExists)
+			utils.BcryptMatch(getDummyPasswordHash(), password)
+			return res, errors.New(response.ErrLoginFailed)
 		}
 		logrus.Error("QueryUserByUsername error: ", err)
 		return res, errors.New(response.ErrServerInternal)
 	}
 	if !utils.BcryptMatch(user.Password, password) {
-		return res, errors.New(response.ErrUserPassword)
+		return res, errors.New(response.ErrLoginFailed)
+	}
+	if err := services.CheckLoginAllowed(user); err != nil {
+		return res, err
 	}
-	token, err := CreateToken(user.ID, user.Username)
+	pair, err := IssueTokenPair(user.ID, user.Username)
 	if err != nil {
-		logrus.Error("CreateToken error: ", err)
+		logrus.Error("IssueTokenPair error: ", err)
 		return res, errors.New(response.ErrServerInternal)
 	}
-	res.Token = token
+	res.Token = pair.AccessToken
+	res.RefreshToken = pair.RefreshToken
+	res.ExpiresIn = pair.ExpiresIn
 	res.UserID = int(user.ID)
 	return res, nil
 }
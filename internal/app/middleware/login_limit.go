@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/cache"
+)
+
+const (
+	loginAccountKeyFormat = "auth:login:account:%s"
+	loginIPKeyFormat      = "auth:login:ip:%s"
+	// 失败次数用原子自增，多个请求同时失败不会丢失计数；锁定截止时间单独保存
+	loginFailuresSuffix = ":failures"
+	loginLockedSuffix   = ":locked"
+)
+
+const (
+	defaultAccountMaxFailures = 5
+	defaultIPMaxFailures      = 20
+	defaultBaseLockout        = time.Minute
+	defaultMaxLockout         = time.Hour
+	defaultFailureWindow      = 24 * time.Hour
+)
+
+// LoginLimiter 按账号和IP记录登录失败次数，超过阈值后指数退避锁定。
+// 不存在的用户名同样计数，锁定与否不会泄露用户名是否存在。
+type LoginLimiter struct {
+	cache *cache.HybridCache
+	// 配置热更新时整体替换
+	settings atomic.Pointer[loginLimitSettings]
+}
+
+// loginLimitSettings 登录限制的阈值和时长
+type loginLimitSettings struct {
+	accountMaxFailures int
+	ipMaxFailures      int
+	baseLockout        time.Duration
+	maxLockout         time.Duration
+	failureWindow      time.Duration
+}
+
+func newLoginLimitSettings(conf config.LoginLimitConfig) *loginLimitSettings {
+	return &loginLimitSettings{
+		accountMaxFailures: positiveOr(conf.AccountMaxFailures, defaultAccountMaxFailures),
+		ipMaxFailures:      positiveOr(conf.IPMaxFailures, defaultIPMaxFailures),
+		baseLockout:        parseTTL(conf.BaseLockout, defaultBaseLockout),
+		maxLockout:         parseTTL(conf.MaxLockout, defaultMaxLockout),
+		failureWindow:      parseTTL(conf.FailureWindow, defaultFailureWindow),
+	}
+}
+
+var (
+	loginLimiter     *LoginLimiter
+	loginLimiterOnce sync.Once
+)
+
+// GetLoginLimiter 按配置创建登录限制器
+func GetLoginLimiter() *LoginLimiter {
+	loginLimiterOnce.Do(func() {
+		loginLimiter = &LoginLimiter{cache: authCache()}
+		loginLimiter.Apply(config.GetLoginLimitConfig())
+	})
+	return loginLimiter
+}
+
+// Apply 使用新的阈值和时长，已经记录的失败次数和锁定不受影响
+func (l *LoginLimiter) Apply(conf config.LoginLimitConfig) {
+	l.settings.Store(newLoginLimitSettings(conf))
+}
+
+// Check 账号或IP是否处于锁定中，返回剩余锁定时间
+func (l *LoginLimiter) Check(username string, ip string) (time.Duration, bool) {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range l.keys(username, ip) {
+		var lockedUntil int64
+		if err := l.cache.Get(key+loginLockedSuffix, &lockedUntil); err != nil {
+			continue
+		}
+		if remaining := time.Unix(lockedUntil, 0).Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, wait > 0
+}
+
+// Fail 记录一次失败，返回本次失败后的锁定时长。
+// 失败次数从第一次失败开始在 failure_window 内累计
+func (l *LoginLimiter) Fail(username string, ip string) (time.Duration, bool) {
+	now := time.Now()
+	settings := l.settings.Load()
+	var lockout time.Duration
+	keys := l.keys(username, ip)
+	thresholds := []int{settings.accountMaxFailures, settings.ipMaxFailures}
+	for i, key := range keys {
+		failures, err := l.cache.IncrWithTTL(key+loginFailuresSuffix, settings.failureWindow)
+		if err != nil {
+			logrus.Error("count login failure failed, err: ", err)
+			continue
+		}
+		d := lockoutDuration(int(failures), thresholds[i], settings.baseLockout, settings.maxLockout)
+		if d <= 0 {
+			continue
+		}
+		if err := l.cache.Set(key+loginLockedSuffix, now.Add(d).Unix(), d); err != nil {
+			logrus.Error("save login lockout failed, err: ", err)
+		}
+		if d > lockout {
+			lockout = d
+		}
+	}
+	return lockout, lockout > 0
+}
+
+// Succeed 登录成功后清空账号的失败记录。
+// IP的记录不清空，避免攻击者用自己的账号登录来重置计数。
+func (l *LoginLimiter) Succeed(username string) {
+	key := fmt.Sprintf(loginAccountKeyFormat, username)
+	for _, k := range []string{key + loginFailuresSuffix, key + loginLockedSuffix} {
+		if err := l.cache.Delete(k); err != nil {
+			logrus.Error("reset login attempts failed, err: ", err)
+		}
+	}
+}
+
+func (l *LoginLimiter) keys(username string, ip string) []string {
+	return []string{
+		fmt.Sprintf(loginAccountKeyFormat, username),
+		fmt.Sprintf(loginIPKeyFormat, ip),
+	}
+}
+
+// lockoutDuration 失败次数达到阈值后锁定base，之后每多失败一次翻倍，最长max
+func lockoutDuration(failures int, threshold int, base time.Duration, max time.Duration) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	shift := failures - threshold
+	if shift >= 32 {
+		return max
+	}
+	d := base << shift
+	if d <= 0 || d > max {
+		return max
+	}
+	return d
+}
+
+func positiveOr(v int, defaultValue int) int {
+	if v <= 0 {
+		return defaultValue
+	}
+	return v
+}
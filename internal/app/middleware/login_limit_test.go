@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+)
+
+// newTestLoginLimiter 使用内存缓存(测试中没有Redis)，账号失败3次、IP失败10次后锁定
+func newTestLoginLimiter(t *testing.T) *LoginLimiter {
+	l := &LoginLimiter{cache: authCache()}
+	l.Apply(config.LoginLimitConfig{
+		AccountMaxFailures: 3,
+		IPMaxFailures:      10,
+		BaseLockout:        "1m",
+		MaxLockout:         "10m",
+		FailureWindow:      "1h",
+	})
+	return l
+}
+
+func TestLoginLimiterLocksAccount(t *testing.T) {
+	l := newTestLoginLimiter(t)
+	user, ip := t.Name(), t.Name()+"-ip"
+
+	for i := 0; i < 2; i++ {
+		_, locked := l.Fail(user, ip)
+		assert.False(t, locked)
+	}
+	_, locked := l.Check(user, ip)
+	assert.False(t, locked)
+
+	lockout, locked := l.Fail(user, ip)
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, lockout)
+	wait, locked := l.Check(user, "other-ip")
+	assert.True(t, locked)
+	assert.InDelta(t, time.Minute, wait, float64(2*time.Second))
+
+	// 之后每多失败一次锁定时间翻倍，最长max_lockout
+	lockout, _ = l.Fail(user, ip)
+	assert.Equal(t, 2*time.Minute, lockout)
+	for i := 0; i < 5; i++ {
+		lockout, _ = l.Fail(user, ip)
+	}
+	assert.Equal(t, 10*time.Minute, lockout)
+}
+
+func TestLoginLimiterLocksIP(t *testing.T) {
+	l := newTestLoginLimiter(t)
+	ip := t.Name() + "-ip"
+	for i := 0; i < 9; i++ {
+		_, locked := l.Fail(fmt.Sprintf("%s-%d", t.Name(), i), ip)
+		assert.False(t, locked)
+	}
+	_, locked := l.Fail(t.Name()+"-last", ip)
+	assert.True(t, locked)
+	_, locked = l.Check("someone-else", ip)
+	assert.True(t, locked)
+}
+
+func TestLoginLimiterSucceedResetsAccountOnly(t *testing.T) {
+	l := newTestLoginLimiter(t)
+	user, ip := t.Name(), t.Name()+"-ip"
+	for i := 0; i < 3; i++ {
+		l.Fail(user, ip)
+	}
+	l.Succeed(user)
+	_, locked := l.Check(user, "other-ip")
+	assert.False(t, locked)
+	_, locked = l.Fail(user, "other-ip")
+	assert.False(t, locked, "failures should restart from zero after success")
+
+	// IP的计数不因登录成功清空
+	for i := 0; i < 6; i++ {
+		l.Fail(fmt.Sprintf("%s-%d", t.Name(), i), ip)
+	}
+	_, locked = l.Check("someone-else", ip)
+	assert.False(t, locked)
+	_, locked = l.Fail("someone-else", ip)
+	assert.True(t, locked)
+}
+
+func TestLoginLimiterConcurrentFailures(t *testing.T) {
+	l := newTestLoginLimiter(t)
+	user := t.Name()
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Fail(user, fmt.Sprintf("%s-%d", t.Name(), i))
+		}(i)
+	}
+	wg.Wait()
+
+	// 并发的失败都被计数：再失败一次的锁定时间按第n+1次计算
+	lockout, locked := l.Fail(user, t.Name()+"-ip")
+	require.True(t, locked)
+	assert.Equal(t, 10*time.Minute, lockout)
+	failures, err := l.cache.IncrWithTTL(fmt.Sprintf(loginAccountKeyFormat, user)+loginFailuresSuffix, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(n+2), failures)
+}
+
+func TestLockoutDuration(t *testing.T) {
+	for _, tt := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 4, want: 0},
+		{failures: 5, want: time.Minute},
+		{failures: 6, want: 2 * time.Minute},
+		{failures: 10, want: 32 * time.Minute},
+		{failures: 11, want: time.Hour},
+		{failures: 100, want: time.Hour},
+	} {
+		assert.Equal(t, tt.want, lockoutDuration(tt.failures, 5, time.Minute, time.Hour), "failures=%d", tt.failures)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// RequirePermission 要求当前用户的角色拥有权限，需要放在 JWTMiddleWare 之后。
+// 角色每次从用户信息中读取，修改角色后立即生效。
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return requireRole(func(role string) bool {
+		return models.RoleHasPermission(role, perm)
+	})
+}
+
+// RequireRole 要求当前用户是给定角色之一，需要放在 JWTMiddleWare 之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return requireRole(func(role string) bool {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func requireRole(allowed func(role string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(app.UserKeyName)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.CommonResponse{
+				StatusCode: response.TokenExpired,
+				StatusMsg:  response.ErrUserNotLogin,
+			})
+			return
+		}
+		user := value.(app.User)
+		role, err := services.GetUserRole(user.ID)
+		if err != nil || !allowed(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.CommonResponse{
+				StatusCode: response.Failed,
+				StatusMsg:  response.ErrPermissionDenied,
+			})
+			return
+		}
+		c.Set(app.UserRoleKeyName, role)
+		c.Next()
+	}
+}
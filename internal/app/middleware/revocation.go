@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/cache"
+)
+
+// RevocationList token吊销列表。
+// 只需要保存到access token过期为止，过期的token本身就无法通过校验。
+type RevocationList interface {
+	// RevokeSession 吊销某个登录会话签发的所有access token
+	RevokeSession(sessionID string, ttl time.Duration) error
+	// IsSessionRevoked 会话是否已被吊销
+	IsSessionRevoked(sessionID string) bool
+	// RevokeUser 吊销用户在at所在的秒之前签发的所有access token(退出所有设备)
+	RevokeUser(userID uint, at time.Time, ttl time.Duration) error
+	// UserRevokedAt 返回用户最近一次全部吊销的时间
+	UserRevokedAt(userID uint) (time.Time, bool)
+}
+
+const (
+	revokedSessionKeyFormat = "auth:revoked:session:%s"
+	revokedUserKeyFormat    = "auth:revoked:user:%d"
+)
+
+// 鉴权状态(吊销列表、登录失败次数)内存缓存的大小(Redis不可用时使用)
+const authCacheSize = 10000
+
+// cacheRevocationList 基于HybridCache的吊销列表，
+// Redis可用时在多实例间共享，不可用时降级为进程内存。
+type cacheRevocationList struct {
+	cache *cache.HybridCache
+}
+
+// NewCacheRevocationList 创建基于HybridCache的吊销列表
+func NewCacheRevocationList(c *cache.HybridCache) RevocationList {
+	return &cacheRevocationList{cache: c}
+}
+
+func (r *cacheRevocationList) RevokeSession(sessionID string, ttl time.Duration) error {
+	return r.cache.Set(fmt.Sprintf(revokedSessionKeyFormat, sessionID), true, ttl)
+}
+
+func (r *cacheRevocationList) IsSessionRevoked(sessionID string) bool {
+	return r.cache.Exists(fmt.Sprintf(revokedSessionKeyFormat, sessionID))
+}
+
+func (r *cacheRevocationList) RevokeUser(userID uint, at time.Time, ttl time.Duration) error {
+	return r.cache.Set(fmt.Sprintf(revokedUserKeyFormat, userID), at.Unix(), ttl)
+}
+
+func (r *cacheRevocationList) UserRevokedAt(userID uint) (time.Time, bool) {
+	var unix int64
+	if err := r.cache.Get(fmt.Sprintf(revokedUserKeyFormat, userID), &unix); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+func initRevocationList() RevocationList {
+	return NewCacheRevocationList(authCache())
+}
+
+// authCache 返回全局HybridCache，未初始化时按默认大小初始化
+func authCache() *cache.HybridCache {
+	if cache.GetHybridCache() == nil {
+		if err := cache.InitHybridCache(authCacheSize); err != nil {
+			panic("初始化鉴权缓存失败, error:" + err.Error())
+		}
+	}
+	return cache.GetHybridCache()
+}
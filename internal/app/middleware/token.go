@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+)
+
+// RefreshTokenRequestDto 刷新token/退出登录请求
+type RefreshTokenRequestDto struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+}
+
+// TokenPair 一次登录或刷新签发的token
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// access token有效期,单位秒
+	ExpiresIn int64
+}
+
+// IssueTokenPair 开启新的登录会话，签发access token和refresh token
+func IssueTokenPair(id uint, username string) (TokenPair, error) {
+	InitJwt()
+	session, err := services.CreateRefreshSession(id, refreshTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return newTokenPair(id, username, session)
+}
+
+func newTokenPair(id uint, username string, session services.RefreshSession) (TokenPair, error) {
+	var pair TokenPair
+	accessToken, err := createAccessToken(id, username, session.SessionID)
+	if err != nil {
+		return pair, err
+	}
+	pair.AccessToken = accessToken
+	pair.RefreshToken = session.RefreshToken
+	pair.ExpiresIn = int64(accessTokenTTL / time.Second)
+	return pair, nil
+}
+
+// RefreshTokenHandler 使用refresh token换取新的token
+// @Summary 刷新token
+// @Description 使用refresh token换取新的access token，refresh token同时轮换；重复使用旧的refresh token会吊销整个会话
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body RefreshTokenRequestDto true "refresh token"
+// @Success 200 {object} response.TokenResponse
+// @Router /api/token/refresh [post]
+func RefreshTokenHandler(c *gin.Context) {
+	InitJwt()
+	var req RefreshTokenRequestDto
+	if err := c.ShouldBind(&req); err != nil || req.RefreshToken == "" {
+		response.ResponseError(c, response.ErrInvalidParams)
+		return
+	}
+	session, err := services.RotateRefreshToken(req.RefreshToken, refreshTokenTTL)
+	if err != nil {
+		if err.Error() == response.ErrRefreshTokenReused {
+			// 会话内已签发的access token一并吊销
+			if err := Revocations.RevokeSession(session.SessionID, accessTokenTTL); err != nil {
+				logrus.Error("revoke session failed, err: ", err)
+			}
+		}
+		c.JSON(http.StatusOK, response.CommonResponse{
+			StatusCode: response.TokenExpired,
+			StatusMsg:  err.Error(),
+		})
+		return
+	}
+	user, err := services.GetUserById(session.UserID)
+	if err != nil {
+		response.ResponseError(c, response.ErrServerInternal)
+		return
+	}
+	pair, err := newTokenPair(user.ID, user.Username, session)
+	if err != nil {
+		logrus.Error("CreateToken error: ", err)
+		response.ResponseError(c, response.ErrServerInternal)
+		return
+	}
+	var res response.TokenResponse
+	res.StatusCode = response.Success
+	res.StatusMsg = response.SuccessMsg
+	res.UserID = int(user.ID)
+	res.Token = pair.AccessToken
+	res.RefreshToken = pair.RefreshToken
+	res.ExpiresIn = pair.ExpiresIn
+	c.JSON(http.StatusOK, res)
+}
+
+// LogoutHandler 退出当前设备
+// @Summary 退出登录
+// @Description 吊销当前会话的access token和refresh token
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body RefreshTokenRequestDto false "refresh token(旧token没有会话信息时使用)"
+// @Success 200 {object} response.CommonResponse
+// @Router /api/logout [post]
+func LogoutHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	sessionID := user.Claims.SessionID
+	if sessionID == "" {
+		var req RefreshTokenRequestDto
+		c.ShouldBind(&req)
+		if req.RefreshToken == "" {
+			response.ResponseError(c, response.ErrInvalidParams)
+			return
+		}
+		ownerID, sid, err := services.QueryRefreshSessionOwner(req.RefreshToken)
+		if err != nil || ownerID != user.ID {
+			response.ResponseError(c, response.ErrRefreshTokenInvalid)
+			return
+		}
+		sessionID = sid
+	}
+	if err := services.RevokeRefreshSession(sessionID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	if err := Revocations.RevokeSession(sessionID, accessTokenTTL); err != nil {
+		logrus.Error("revoke session failed, err: ", err)
+		response.ResponseError(c, response.ErrServerInternal)
+		return
+	}
+	response.ResponseSuccess(c, response.LogoutSuccessMsg)
+}
+
+// LogoutAllHandler 退出所有设备
+// @Summary 退出所有设备
+// @Description 吊销用户所有会话的access token和refresh token
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.CommonResponse
+// @Router /api/logout/all [post]
+func LogoutAllHandler(c *gin.Context) {
+	user := c.MustGet(app.UserKeyName).(app.User)
+	if err := LogoutAllSessions(user.ID); err != nil {
+		response.ResponseError(c, err.Error())
+		return
+	}
+	response.ResponseSuccess(c, response.LogoutAllSuccessMsg)
+}
+
+// LogoutAllSessions 吊销用户所有的token，修改密码等场景也可以调用
+func LogoutAllSessions(userID uint) error {
+	InitJwt()
+	sessionIDs, err := services.RevokeAllRefreshSessions(userID)
+	if err != nil {
+		return err
+	}
+	// 按会话吊销是精确的；按时间吊销只精确到秒，用于不属于会话的token
+	for _, sessionID := range sessionIDs {
+		if err := Revocations.RevokeSession(sessionID, accessTokenTTL); err != nil {
+			logrus.Error("revoke session failed, err: ", err)
+			return errors.New(response.ErrServerInternal)
+		}
+	}
+	if err := Revocations.RevokeUser(userID, time.Now(), accessTokenTTL); err != nil {
+		logrus.Error("revoke user tokens failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	AuditLogModelTableName        = "audit_log_models"
+	AuditLogModelTable_ActorID    = "actor_id"
+	AuditLogModelTable_Action     = "action"
+	AuditLogModelTable_TargetType = "target_type"
+	AuditLogModelTable_TargetID   = "target_id"
+	AuditLogModelTable_CreatedAt  = "created_at"
+)
+
+// AuditLogModel 特权操作的审计日志，只追加不修改
+type AuditLogModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index;not null"`
+	// 操作者用户ID
+	ActorID uint   `gorm:"index;not null"`
+	Action  string `gorm:"size:64;index;not null"`
+	// 操作对象类型，e.g. review, comment, user
+	TargetType string `gorm:"size:32;index:idx_audit_target"`
+	TargetID   uint   `gorm:"index:idx_audit_target"`
+	IP         string `gorm:"size:64"`
+	// 操作原因或补充信息
+	Detail string `gorm:"type:text"`
+}
+
+func (a *AuditLogModel) TableName() string {
+	return AuditLogModelTableName
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	UserBlockModelTableName      = "user_block_models"
+	UserBlockModelTable_UserID   = "user_id"
+	UserBlockModelTable_TargetID = "target_id"
+	UserBlockModelTable_Kind     = "kind"
+)
+
+// 屏蔽类型
+const (
+	// 拉黑：双方互相不能关注、评论、点赞，也看不到对方的内容
+	BlockKindBlock = "block"
+	// 静音：只是自己的信息流和评论列表中不再出现对方的内容
+	BlockKindMute = "mute"
+)
+
+// UserBlockModel 用户拉黑或静音的关系，同一对用户只有一条记录，拉黑覆盖静音
+type UserBlockModel struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	TargetID  uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Kind      string `gorm:"size:16;not null"`
+	// 被屏蔽的用户
+	Target UserModel `gorm:"foreignKey:TargetID"`
+}
+
+func (b *UserBlockModel) TableName() string {
+	return UserBlockModelTableName
+}
+
+func IsValidBlockKind(kind string) bool {
+	return kind == BlockKindBlock || kind == BlockKindMute
+}
+
+// ExcludeUsers 过滤userIDs发布的内容，column为作者字段，用于 db.Scopes
+func ExcludeUsers(column string, userIDs []uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(userIDs) == 0 {
+			return db
+		}
+		return db.Where(column+" NOT IN ?", userIDs)
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidBlockKind(t *testing.T) {
+	assert.True(t, IsValidBlockKind(BlockKindBlock))
+	assert.True(t, IsValidBlockKind(BlockKindMute))
+	assert.False(t, IsValidBlockKind(""))
+	assert.False(t, IsValidBlockKind("hide"))
+}
@@ -1,10 +1,14 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 const BookReviewTitleMaxByteLength = 199
 const BookReviewTitleMaxRuneLength = 60
-const BookReviewContentMaxLength = 5000
+const BookReviewContentMaxLength = 50000
 
 const (
 	BookReviewModelTableName              = "book_reviews"
@@ -14,8 +18,45 @@ const (
 	BookReviewModelTable_BookISBN         = "book_isbn"
 	BookReviewModelTable_LikesSlice       = "Likes"
 	BookReviewModelTable_CollectionsSlice = "Collections"
+	BookReviewModelTable_HiddenAt         = "hidden_at"
+	BookReviewModelTable_Status           = "status"
+	BookReviewModelTable_Visibility       = "visibility"
+	BookReviewModelTable_ScheduledAt      = "scheduled_at"
+	BookReviewModelTable_EditedAt         = "edited_at"
+	BookReviewModelTable_Content          = "content"
+	BookReviewModelTable_ContentHTML      = "content_html"
+	BookReviewModelTable_Excerpt          = "excerpt"
+	BookReviewModelTable_SearchText       = "search_text"
+	BookReviewModelTable_HasSpoilers      = "has_spoilers"
+	BookReviewModelTable_SpoilerMarked    = "spoiler_marked"
+	BookReviewModelTable_Title            = "title"
+)
+
+// 书评状态
+const (
+	ReviewStatusDraft = "draft"
+	// 等待定时发布
+	ReviewStatusScheduled = "scheduled"
+	ReviewStatusPublished = "published"
 )
 
+// 书评可见范围，只对已发布的书评生效，作者本人总是可见
+const (
+	ReviewVisibilityPublic = "public"
+	// 不出现在列表、信息流中，拿到链接的人可以查看
+	ReviewVisibilityUnlisted  = "unlisted"
+	ReviewVisibilityFollowers = "followers"
+	ReviewVisibilityPrivate   = "private"
+)
+
+func IsValidReviewVisibility(visibility string) bool {
+	switch visibility {
+	case ReviewVisibilityPublic, ReviewVisibilityUnlisted, ReviewVisibilityFollowers, ReviewVisibilityPrivate:
+		return true
+	}
+	return false
+}
+
 // BookReviewModel 图书书评模型
 // 用户可以发布关于某本书的图文书评（类似小红书）
 type BookReviewModel struct {
@@ -23,7 +64,16 @@ type BookReviewModel struct {
 
 	// 基本信息
 	Title   string `gorm:"size:200;not null"`              // 书评标题
-	Content string `gorm:"type:text;not null"`             // 书评内容（必填，主要内容）
+	Content string `gorm:"type:text;not null"`             // 书评内容（必填，主要内容，Markdown）
+
+	// 由 Content 渲染，保存时生成
+	ContentHTML string `gorm:"type:text"` // 过滤后的HTML
+	Excerpt     string `gorm:"size:1000"` // 纯文本摘要，用于信息流卡片
+	SearchText  string `gorm:"type:text"` // 去掉剧透的纯文本，用于搜索和搜索结果片段
+
+	// 剧透
+	HasSpoilers   bool `gorm:"not null;default:false"` // 作者标记整篇书评含剧透
+	SpoilerMarked bool `gorm:"not null;default:false"` // 正文中有剧透块或行内剧透
 
 	// 关联图书信息
 	BookISBN  string `gorm:"size:20;index"`                // 关联图书 ISBN（可选，用于推荐）
@@ -53,6 +103,16 @@ type BookReviewModel struct {
 
 	// 标签（可选，用于分类和搜索）
 	Tags string `gorm:"size:500"` // 标签列表（JSON 数组，如 ["悬疑", "推理", "经典"]）
+
+	// 审核
+	HiddenAt *time.Time `gorm:"index"` // 被版主隐藏的时间，为空表示正常显示
+	HiddenBy uint                       // 隐藏操作者ID
+
+	// 发布
+	Status      string     `gorm:"size:16;not null;default:published;index"` // draft, scheduled, published
+	Visibility  string     `gorm:"size:16;not null;default:public"`          // public, unlisted, followers, private
+	ScheduledAt *time.Time `gorm:"index"`                                    // 定时发布时间，只对 scheduled 有效
+	EditedAt    *time.Time // 发布后最后一次修改内容的时间，为空表示没有修改过
 }
 
 func (b *BookReviewModel) TableName() string {
@@ -62,19 +122,23 @@ func (b *BookReviewModel) TableName() string {
 // BookReviewCacheModel 书评缓存模型（用于 Redis）
 type BookReviewCacheModel struct {
 	gorm.Model
-	Title        string
-	Content      string
-	BookISBN     string
-	BookTitle    string
-	Images       string
-	CoverURL     string
-	Rating       float64
-	AuthorID     uint
-	LikeCount    uint
-	CommentCount uint
-	ViewCount    uint
-	CollectCount uint
-	Tags         string
+	Title         string
+	Content       string
+	ContentHTML   string
+	Excerpt       string
+	HasSpoilers   bool
+	SpoilerMarked bool
+	BookISBN      string
+	BookTitle     string
+	Images        string
+	CoverURL      string
+	Rating        float64
+	AuthorID      uint
+	LikeCount     uint
+	CommentCount  uint
+	ViewCount     uint
+	CollectCount  uint
+	Tags          string
 }
 
 func (b *BookReviewCacheModel) SetValue(other BookReviewModel) {
@@ -84,6 +148,10 @@ func (b *BookReviewCacheModel) SetValue(other BookReviewModel) {
 	b.DeletedAt = other.DeletedAt
 	b.Title = other.Title
 	b.Content = other.Content
+	b.ContentHTML = other.ContentHTML
+	b.Excerpt = other.Excerpt
+	b.HasSpoilers = other.HasSpoilers
+	b.SpoilerMarked = other.SpoilerMarked
 	b.BookISBN = other.BookISBN
 	b.BookTitle = other.BookTitle
 	b.Images = other.Images
@@ -104,6 +172,10 @@ func (b *BookReviewModel) SetValueFromCacheModel(other BookReviewCacheModel) {
 	b.DeletedAt = other.DeletedAt
 	b.Title = other.Title
 	b.Content = other.Content
+	b.ContentHTML = other.ContentHTML
+	b.Excerpt = other.Excerpt
+	b.HasSpoilers = other.HasSpoilers
+	b.SpoilerMarked = other.SpoilerMarked
 	b.BookISBN = other.BookISBN
 	b.BookTitle = other.BookTitle
 	b.Images = other.Images
@@ -117,6 +189,16 @@ func (b *BookReviewModel) SetValueFromCacheModel(other BookReviewCacheModel) {
 	b.Tags = other.Tags
 }
 
+// ContainsSpoilers 整篇标记为剧透或正文中有剧透标记
+func (b *BookReviewModel) ContainsSpoilers() bool {
+	return b.HasSpoilers || b.SpoilerMarked
+}
+
+// IsPublished 是否已发布，草稿和等待定时发布的书评只有作者能看到
+func (b *BookReviewModel) IsPublished() bool {
+	return b.Status == ReviewStatusPublished
+}
+
 // HasImages 是否有配图
 func (b *BookReviewModel) HasImages() bool {
 	return b.Images != "" && b.Images != "[]"
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewVisibilityAndStatus(t *testing.T) {
+	for _, v := range []string{ReviewVisibilityPublic, ReviewVisibilityUnlisted, ReviewVisibilityFollowers, ReviewVisibilityPrivate} {
+		assert.True(t, IsValidReviewVisibility(v), v)
+	}
+	assert.False(t, IsValidReviewVisibility(""))
+	assert.False(t, IsValidReviewVisibility("friends"))
+
+	review := BookReviewModel{Status: ReviewStatusPublished}
+	assert.True(t, review.IsPublished())
+	review.Status = ReviewStatusScheduled
+	assert.False(t, review.IsPublished())
+	review.Status = ReviewStatusDraft
+	assert.False(t, review.IsPublished())
+}
+
+func TestReviewContainsSpoilers(t *testing.T) {
+	review := BookReviewModel{}
+	assert.False(t, review.ContainsSpoilers())
+	review.SpoilerMarked = true
+	assert.True(t, review.ContainsSpoilers())
+	review = BookReviewModel{HasSpoilers: true}
+	assert.True(t, review.ContainsSpoilers())
+}
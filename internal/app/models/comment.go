@@ -2,6 +2,7 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,6 +14,7 @@ const (
 	CommentModelTable_UserID      = "user_id"
 	CommentModelTable_Content     = "content"
 	CommentModelPreload_Commenter = "Commenter"
+	CommentModelTable_HiddenAt    = "hidden_at"
 )
 
 // CommentModel 评论模型
@@ -25,6 +27,10 @@ type CommentModel struct {
 	Review   BookReviewModel      `gorm:"foreignKey:ReviewID"` // 关联的书评
 	// 使用前需确保里面有数据
 	Commenter UserModel `gorm:"foreignKey:UserID"` // 评论者信息
+	// 被版主隐藏的时间，为空表示正常显示
+	HiddenAt *time.Time `gorm:"index"`
+	// 隐藏操作者ID
+	HiddenBy uint
 }
 
 type CommentCacheModel struct {
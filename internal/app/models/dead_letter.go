@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	DeadLetterModelTableName        = "dead_letter_models"
+	DeadLetterModelTable_Queue      = "queue"
+	DeadLetterModelTable_ReplayedAt = "replayed_at"
+)
+
+// DeadLetterModel 消息队列中多次处理失败后放弃的消息，管理员检查后可以重放
+type DeadLetterModel struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	Queue      string    `gorm:"size:32;index;not null"`
+	MessageKey string    `gorm:"size:64;index;not null"`
+	// 消息体的JSON
+	Payload  string `gorm:"type:text;not null"`
+	Attempts int    `gorm:"not null"`
+	// 最后一次处理失败的错误
+	LastError  string `gorm:"type:text"`
+	ReplayedAt *time.Time
+}
+
+func (d *DeadLetterModel) TableName() string {
+	return DeadLetterModelTableName
+}
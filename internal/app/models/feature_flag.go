@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	FeatureFlagModelTableName  = "feature_flag_models"
+	FeatureFlagModelTable_Name = "name"
+)
+
+// 代码中使用的功能开关
+const (
+	// 与图书对话，需要启用推荐服务
+	FeatureChatWithBook = "chat_with_book"
+)
+
+// FeatureFlagModel 管理员在后台设置的功能开关，同名时覆盖配置文件中的开关
+type FeatureFlagModel struct {
+	Name    string `gorm:"primarykey;size:64"`
+	Enabled bool   `gorm:"not null"`
+	// 按用户ID灰度的比例，0到100
+	Percentage int `gorm:"not null"`
+	// 不受灰度比例限制的用户ID，逗号分隔
+	Users       string `gorm:"type:text"`
+	Description string `gorm:"size:255"`
+	// 最后修改的管理员
+	UpdatedBy uint
+	UpdatedAt time.Time
+}
+
+func (f *FeatureFlagModel) TableName() string {
+	return FeatureFlagModelTableName
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	FollowRequestModelTableName         = "follow_request_models"
+	FollowRequestModelTable_RequesterID = "requester_id"
+	FollowRequestModelTable_TargetID    = "target_id"
+	FollowRequestModelTable_CreatedAt   = "created_at"
+)
+
+// FollowRequestModel 关注私密账号时待对方处理的请求，同意或拒绝后删除
+type FollowRequestModel struct {
+	ID          uint      `gorm:"primarykey"`
+	CreatedAt   time.Time `gorm:"not null"`
+	RequesterID uint      `gorm:"uniqueIndex:idx_follow_request;not null"`
+	TargetID    uint      `gorm:"uniqueIndex:idx_follow_request;index;not null"`
+	// 发起请求的用户
+	Requester UserModel `gorm:"foreignKey:RequesterID"`
+}
+
+func (r *FollowRequestModel) TableName() string {
+	return FollowRequestModelTableName
+}
@@ -0,0 +1,8 @@
+package models
+
+import "gorm.io/gorm"
+
+// NotHidden 过滤被版主隐藏的书评或评论，用于 db.Scopes
+func NotHidden(db *gorm.DB) *gorm.DB {
+	return db.Where("hidden_at IS NULL")
+}
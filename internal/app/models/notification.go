@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	NotificationModelTableName       = "notification_models"
+	NotificationModelTable_UserID    = "user_id"
+	NotificationModelTable_ReadAt    = "read_at"
+	NotificationModelTable_CreatedAt = "created_at"
+)
+
+// 通知类型
+const (
+	NotificationReportResolved = "report_resolved"
+	NotificationFollowRequest  = "follow_request"
+	NotificationFollowApproved = "follow_approved"
+	NotificationFollowed       = "followed"
+	NotificationReviewLiked    = "review_liked"
+)
+
+// NotificationModel 站内通知
+type NotificationModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	// 接收者
+	UserID  uint   `gorm:"index:idx_notification_user;not null"`
+	Type    string `gorm:"size:32;not null"`
+	Content string `gorm:"size:500"`
+	// 关联对象，e.g. report
+	RefType string `gorm:"size:32"`
+	RefID   uint
+	// 阅读时间，为空表示未读
+	ReadAt *time.Time `gorm:"index:idx_notification_user"`
+}
+
+func (n *NotificationModel) TableName() string {
+	return NotificationModelTableName
+}
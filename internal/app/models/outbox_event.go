@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	OutboxEventModelTableName           = "outbox_event_models"
+	OutboxEventModelTable_ID            = "id"
+	OutboxEventModelTable_PublishedAt   = "published_at"
+	OutboxEventModelTable_Attempts      = "attempts"
+	OutboxEventModelTable_LastError     = "last_error"
+	OutboxEventModelTable_ClaimedAt     = "claimed_at"
+	OutboxEventModelTable_NextAttemptAt = "next_attempt_at"
+)
+
+// OutboxEventModel 和业务数据在同一个事务中写入的领域事件，提交后由relay发布到消息队列。
+// 事件之间不保证顺序，订阅者不能依赖先后关系
+type OutboxEventModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	Type      string    `gorm:"size:64;not null"`
+	// 事件内容的JSON
+	Payload string `gorm:"type:text;not null"`
+	// 发布时间，为空表示还没有发布
+	PublishedAt *time.Time `gorm:"index"`
+	// 发布失败的次数和最后一次的错误
+	Attempts  int    `gorm:"not null;default:0"`
+	LastError string `gorm:"type:text"`
+	// relay领取事件的时间，租约内其他实例不会再领取
+	ClaimedAt *time.Time
+	// 发布失败后下一次重试的时间
+	NextAttemptAt *time.Time
+}
+
+func (o *OutboxEventModel) TableName() string {
+	return OutboxEventModelTableName
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	ProcessedMessageModelTableName       = "processed_message_models"
+	ProcessedMessageModelTable_CreatedAt = "created_at"
+)
+
+// ProcessedMessageModel 已经生效的消息Key，和消息的效果在同一个事务中写入，
+// 重试或重放的消息发现Key已存在时直接跳过
+type ProcessedMessageModel struct {
+	Key       string    `gorm:"primarykey;size:64"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (p *ProcessedMessageModel) TableName() string {
+	return ProcessedMessageModelTableName
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	RefreshTokenModelTableName          = "refresh_token_models"
+	RefreshTokenModelTable_UserID       = "user_id"
+	RefreshTokenModelTable_SessionID    = "session_id"
+	RefreshTokenModelTable_TokenHash    = "token_hash"
+	RefreshTokenModelTable_RevokedAt    = "revoked_at"
+	RefreshTokenModelTable_ReplacedByID = "replaced_by_id"
+	RefreshTokenModelTable_ExpiresAt    = "expires_at"
+)
+
+// RefreshTokenModel 服务端保存的refresh token
+// 同一次登录产生的refresh token属于同一个SessionID(token family),
+// 每次刷新都会轮换出一个新的token,旧token被标记为已替换。
+type RefreshTokenModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	UserID    uint      `gorm:"index;not null"`
+	// 登录会话ID,同一会话内轮换的token共享
+	SessionID string `gorm:"size:64;index;not null"`
+	// token的sha256,不保存明文
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	// 被轮换后指向新token的ID,非0表示该token已被使用
+	ReplacedByID uint
+	// 被吊销的时间,为空表示未吊销
+	RevokedAt *time.Time
+}
+
+func (r *RefreshTokenModel) TableName() string {
+	return RefreshTokenModelTableName
+}
+
+// IsActive token未过期、未被吊销且未被轮换
+func (r *RefreshTokenModel) IsActive(now time.Time) bool {
+	return r.RevokedAt == nil && r.ReplacedByID == 0 && now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+const (
+	ReportModelTableName        = "report_models"
+	ReportModelTable_ReporterID = "reporter_id"
+	ReportModelTable_TargetType = "target_type"
+	ReportModelTable_TargetID   = "target_id"
+	ReportModelTable_Status     = "status"
+	ReportModelTable_CreatedAt  = "created_at"
+)
+
+// 举报对象类型
+const (
+	ReportTargetReview  = "review"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+// 举报处理状态
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// 举报原因
+const (
+	ReportReasonSpam           = "spam"
+	ReportReasonHarassment     = "harassment"
+	ReportReasonHate           = "hate"
+	ReportReasonSexual         = "sexual"
+	ReportReasonViolence       = "violence"
+	ReportReasonMisinformation = "misinformation"
+	ReportReasonOther          = "other"
+)
+
+var reportReasons = map[string]bool{
+	ReportReasonSpam:           true,
+	ReportReasonHarassment:     true,
+	ReportReasonHate:           true,
+	ReportReasonSexual:         true,
+	ReportReasonViolence:       true,
+	ReportReasonMisinformation: true,
+	ReportReasonOther:          true,
+}
+
+// IsValidReportReason 是否为已定义的举报原因
+func IsValidReportReason(reason string) bool {
+	return reportReasons[reason]
+}
+
+// IsValidReportTarget 是否为可以举报的对象类型
+func IsValidReportTarget(targetType string) bool {
+	return targetType == ReportTargetReview || targetType == ReportTargetComment || targetType == ReportTargetUser
+}
+
+// ReportModel 用户对书评、评论或用户的举报，同一用户对同一对象只能举报一次
+type ReportModel struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index;not null"`
+	UpdatedAt  time.Time
+	ReporterID uint   `gorm:"not null;uniqueIndex:idx_report_reporter_target"`
+	TargetType string `gorm:"size:32;not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target"`
+	TargetID   uint   `gorm:"not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target"`
+	// 被举报内容的作者，举报用户时为该用户
+	TargetUserID uint   `gorm:"index"`
+	Reason       string `gorm:"size:32;not null"`
+	Detail       string `gorm:"size:500"`
+	Status       string `gorm:"size:16;not null;default:open;index"`
+	// 处理人和处理时间，未处理时为空
+	ResolvedBy uint
+	ResolvedAt *time.Time
+	// 处理备注，只对版主可见
+	Resolution string `gorm:"size:500"`
+}
+
+func (r *ReportModel) TableName() string {
+	return ReportModelTableName
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportValidation(t *testing.T) {
+	t.Run("reasons", func(t *testing.T) {
+		assert.True(t, IsValidReportReason(ReportReasonSpam))
+		assert.True(t, IsValidReportReason(ReportReasonOther))
+		assert.False(t, IsValidReportReason(""))
+		assert.False(t, IsValidReportReason("dislike"))
+	})
+
+	t.Run("targets", func(t *testing.T) {
+		assert.True(t, IsValidReportTarget(ReportTargetReview))
+		assert.True(t, IsValidReportTarget(ReportTargetComment))
+		assert.True(t, IsValidReportTarget(ReportTargetUser))
+		assert.False(t, IsValidReportTarget("book"))
+	})
+
+	t.Run("moderators can handle reports", func(t *testing.T) {
+		assert.True(t, RoleHasPermission(RoleModerator, PermHandleReport))
+		assert.True(t, RoleHasPermission(RoleAdmin, PermHandleReport))
+		assert.False(t, RoleHasPermission(RoleUser, PermHandleReport))
+	})
+}
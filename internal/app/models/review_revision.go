@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	ReviewRevisionModelTableName      = "review_revision_models"
+	ReviewRevisionModelTable_ReviewID = "review_id"
+	ReviewRevisionModelTable_Version  = "version"
+)
+
+// ReviewRevisionModel 书评的一个历史版本。第一次修改时会先保存原始内容作为版本1，
+// 之后每次修改或回滚都保存修改后的内容和与上一版本的差异
+type ReviewRevisionModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	ReviewID  uint      `gorm:"uniqueIndex:idx_review_version;not null"`
+	Version   int       `gorm:"uniqueIndex:idx_review_version;not null"`
+	// 修改人，可能是作者或回滚的版主
+	EditorID uint `gorm:"not null"`
+
+	// 该版本的内容
+	Title   string  `gorm:"size:200"`
+	Content string  `gorm:"type:text"`
+	Images  string  `gorm:"type:text"`
+	Rating  float64 `gorm:"type:decimal(3,1)"`
+	Tags    string  `gorm:"size:500"`
+
+	// 与上一版本的差异
+	Diff string `gorm:"type:text"`
+	// 备注，例如回滚到哪个版本
+	Note string `gorm:"size:100"`
+}
+
+func (r *ReviewRevisionModel) TableName() string {
+	return ReviewRevisionModelTableName
+}
@@ -0,0 +1,51 @@
+package models
+
+// 用户角色
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission 权限，由角色决定
+type Permission string
+
+const (
+	// 隐藏/删除任意书评
+	PermModerateReview Permission = "review:moderate"
+	// 隐藏/删除任意评论
+	PermModerateComment Permission = "comment:moderate"
+	// 修改用户角色
+	PermManageRole Permission = "user:role"
+	// 查看审计日志
+	PermReadAudit Permission = "audit:read"
+	// 处理举报
+	PermHandleReport Permission = "report:handle"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermModerateReview, PermModerateComment, PermHandleReport},
+	RoleAdmin:     {PermModerateReview, PermModerateComment, PermHandleReport, PermManageRole, PermReadAudit},
+}
+
+// IsValidRole 是否为已定义的角色
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission 角色是否拥有权限，空角色视为普通用户
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions 角色拥有的所有权限
+func RolePermissions(role string) []Permission {
+	return append([]Permission(nil), rolePermissions[role]...)
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -13,6 +15,16 @@ const (
 	UserModelTable_CollectionsSlice = "Collections"
 	UserModelTable_FollowerCount    = "follower_count"
 	UserModelTable_FanCount         = "fan_count"
+	UserModelTable_Email            = "email"
+	UserModelTable_EmailVerifiedAt  = "email_verified_at"
+	UserModelTable_Password         = "password"
+	UserModelTable_Role             = "role"
+	UserModelTable_BannedAt         = "banned_at"
+	UserModelTable_BannedUntil      = "banned_until"
+	UserModelTable_BanReason        = "ban_reason"
+	UserModelTable_PasswordReset    = "password_reset_required"
+	UserModelTable_IsPrivate        = "is_private"
+	UserModelTable_RevealSpoilers   = "reveal_spoilers"
 )
 
 const DataBaseTimeFormat = "2006-01-02 15:04:05.000"
@@ -22,7 +34,22 @@ type UserModel struct {
 	gorm.Model
 	Username string `gorm:"uniqueIndex;size:100"`
 	Password string `gorm:"size:100"`
-	Email    string `gorm:"size:100"`
+	Email    string `gorm:"size:100;index"`
+	//邮箱验证时间，为空表示未验证
+	EmailVerifiedAt *time.Time
+	//角色: user, moderator, admin
+	Role string `gorm:"size:20;not null;default:user"`
+	//封禁时间，为空表示未封禁
+	BannedAt *time.Time `gorm:"index"`
+	//封禁到期时间，为空表示永久封禁
+	BannedUntil *time.Time
+	BanReason   string `gorm:"size:255"`
+	//管理员要求重置密码，重置前不能使用密码登录
+	PasswordResetRequired bool `gorm:"not null;default:false"`
+	//私密账号，关注需要本人同意，书评、收藏和关注列表只对粉丝可见
+	IsPrivate bool `gorm:"not null;default:false"`
+	//总是显示书评中的剧透，否则列表和信息流中隐藏
+	RevealSpoilers bool `gorm:"not null;default:false"`
 	//Phone    string `gorm:"uniqueIndex;size:50"`
 	//总关注数
 	FollowerCount uint `gorm:"type:int"`
@@ -50,6 +77,19 @@ func (u *UserModel) TableName() string {
 	return UserModelTableName
 }
 
+// IsBanned 用户在now时是否处于封禁状态
+func (u *UserModel) IsBanned(now time.Time) bool {
+	if u.BannedAt == nil {
+		return false
+	}
+	return u.BannedUntil == nil || now.Before(*u.BannedUntil)
+}
+
+// HasPermission 用户的角色是否拥有权限
+func (u *UserModel) HasPermission(perm Permission) bool {
+	return RoleHasPermission(u.Role, perm)
+}
+
 type UserCacheModel struct {
 	gorm.Model
 	Username string `gorm:"uniqueIndex;size:100"`
@@ -66,6 +106,8 @@ type UserCacheModel struct {
 	CollectionsCount uint `gorm:"type:int"`
 	//总视频数
 	VideosCount uint `gorm:"type:int"`
+	//角色
+	Role string
 }
 
 func (u *UserCacheModel) SetValue(user UserModel) {
@@ -80,6 +122,7 @@ func (u *UserCacheModel) SetValue(user UserModel) {
 	u.FanCount = user.FanCount
 	u.CommentCount = user.CommentCount
 	u.CollectionsCount = user.CollectionsCount
+	u.Role = user.Role
 }
 
 func (u *UserModel) SetValueFromCacheModel(user UserCacheModel) {
@@ -94,4 +137,5 @@ func (u *UserModel) SetValueFromCacheModel(user UserCacheModel) {
 	u.FanCount = user.FanCount
 	u.CommentCount = user.CommentCount
 	u.CollectionsCount = user.CollectionsCount
+	u.Role = user.Role
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	UserIdentityModelTableName      = "user_identity_models"
+	UserIdentityModelTable_UserID   = "user_id"
+	UserIdentityModelTable_Provider = "provider"
+	UserIdentityModelTable_Subject  = "subject"
+)
+
+// UserIdentityModel 第三方(OIDC)身份与本地用户的绑定
+type UserIdentityModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	UserID    uint      `gorm:"index;not null"`
+	// 配置中的提供方名字
+	Provider string `gorm:"size:64;not null;uniqueIndex:idx_identity_provider_subject"`
+	// id_token中的sub
+	Subject string `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject"`
+	// 绑定时提供方返回的邮箱
+	Email string `gorm:"size:100"`
+}
+
+func (i *UserIdentityModel) TableName() string {
+	return UserIdentityModelTableName
+}
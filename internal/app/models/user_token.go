@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	UserTokenModelTableName       = "user_token_models"
+	UserTokenModelTable_UserID    = "user_id"
+	UserTokenModelTable_Purpose   = "purpose"
+	UserTokenModelTable_TokenHash = "token_hash"
+	UserTokenModelTable_UsedAt    = "used_at"
+)
+
+// 一次性token的用途
+const (
+	UserTokenPurposeVerifyEmail   = "verify_email"
+	UserTokenPurposeResetPassword = "reset_password"
+)
+
+// UserTokenModel 邮件中发送的一次性token(邮箱验证、重置密码)
+type UserTokenModel struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	UserID    uint      `gorm:"index;not null"`
+	Purpose   string    `gorm:"size:32;not null"`
+	// token的sha256,不保存明文
+	TokenHash string `gorm:"size:64;uniqueIndex;not null"`
+	// 发送token时的邮箱,验证时邮箱已被修改则token失效
+	Email     string    `gorm:"size:100"`
+	ExpiresAt time.Time `gorm:"not null"`
+	// 使用时间,为空表示未使用
+	UsedAt *time.Time
+}
+
+func (t *UserTokenModel) TableName() string {
+	return UserTokenModelTableName
+}
+
+// IsUsable token未使用且未过期
+func (t *UserTokenModel) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
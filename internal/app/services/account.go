@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/mail"
+	"github.com/sylvia-ymlin/Coconut-book-community/utils"
+	"gorm.io/gorm"
+)
+
+// 邮件token明文的随机字节数
+const userTokenBytes = 32
+
+const (
+	defaultVerifyTokenTTL = 24 * time.Hour
+	defaultResetTokenTTL  = 30 * time.Minute
+	// 单封邮件的发送超时
+	mailSendTimeout = 30 * time.Second
+)
+
+var (
+	mailSender     mail.Sender
+	mailSenderOnce sync.Once
+)
+
+// GetMailSender 按配置创建邮件发送器
+func GetMailSender() mail.Sender {
+	mailSenderOnce.Do(func() {
+		if mailSender == nil {
+			mailSender = newMailSender(config.GetMailConfig())
+		}
+	})
+	return mailSender
+}
+
+// SetMailSender 替换邮件发送器，需要在第一次发送邮件前调用
+func SetMailSender(sender mail.Sender) {
+	mailSender = sender
+}
+
+func newMailSender(conf config.MailConfig) mail.Sender {
+	switch conf.Driver {
+	case "smtp":
+		return &mail.SMTPSender{
+			Host:     conf.Host,
+			Port:     conf.Port,
+			Username: conf.Username,
+			Password: conf.Password,
+			From:     conf.From,
+		}
+	case "file":
+		return &mail.FileSender{Path: conf.FilePath, From: conf.From}
+	default:
+		return &mail.FileSender{From: conf.From}
+	}
+}
+
+// CheckEmail 检查邮箱是否符合要求
+func CheckEmail(email string) bool {
+	if len(email) > 100 {
+		return false
+	}
+	addr, err := netmail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// QueryEmailVerifiedByOther 邮箱是否已被其他用户验证
+func QueryEmailVerifiedByOther(email string, userID uint) bool {
+	var count int64
+	db := database.GetMysqlDB()
+	err := db.Model(&models.UserModel{}).
+		Where(models.UserModelTable_Email+" = ? AND "+models.UserModelTable_EmailVerifiedAt+" IS NOT NULL AND id <> ?", email, userID).
+		Count(&count).Error
+	if err != nil {
+		logrus.Error("query email failed, err: ", err)
+		return true
+	}
+	return count > 0
+}
+
+// SendVerifyEmail 向用户当前的邮箱发送验证邮件
+func SendVerifyEmail(userID uint) error {
+	user, err := QueryUserById(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return errors.New(response.ErrEmailNotSet)
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New(response.ErrEmailAlreadyVerified)
+	}
+	conf := config.GetMailConfig()
+	rawToken, err := createUserToken(user.ID, models.UserTokenPurposeVerifyEmail, user.Email,
+		parseMailTokenTTL(conf.VerifyTokenTTL, defaultVerifyTokenTTL))
+	if err != nil {
+		return err
+	}
+	sendMailAsync(mail.Message{
+		To:      user.Email,
+		Subject: "验证你的邮箱",
+		Body: fmt.Sprintf("%s，你好：\n\n请点击以下链接验证邮箱：\n%s\n\n如果不是你本人操作，请忽略这封邮件。\n",
+			user.Username, mailLink(conf.LinkBaseURL, "/verify-email", rawToken)),
+	})
+	return nil
+}
+
+// VerifyEmail 使用邮件中的token验证邮箱
+func VerifyEmail(rawToken string) error {
+	var userID uint
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, rawToken, models.UserTokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		userID = token.UserID
+		var user models.UserModel
+		if err := tx.Where("id = ?", token.UserID).Take(&user).Error; err != nil {
+			return err
+		}
+		// 发送验证邮件后修改过邮箱
+		if user.Email != token.Email {
+			return errors.New(response.ErrVerifyTokenInvalid)
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		var count int64
+		err = tx.Model(&models.UserModel{}).
+			Where(models.UserModelTable_Email+" = ? AND "+models.UserModelTable_EmailVerifiedAt+" IS NOT NULL AND id <> ?", user.Email, user.ID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New(response.ErrEmailExists)
+		}
+		return tx.Model(&user).Update(models.UserModelTable_EmailVerifiedAt, time.Now()).Error
+	})
+	if err != nil {
+		switch err.Error() {
+		case response.ErrVerifyTokenInvalid, response.ErrEmailExists:
+			return err
+		}
+		logrus.Error("verify email failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	database.GetUserInfoCacher().Delete(userID)
+	return nil
+}
+
+// RequestPasswordReset 向已验证的邮箱发送重置密码邮件。
+// 邮箱不存在时同样返回成功，避免泄露邮箱是否注册。
+func RequestPasswordReset(email string) error {
+	if !CheckEmail(email) {
+		return errors.New(response.ErrInvalidEmail)
+	}
+	var user models.UserModel
+	db := database.GetMysqlDB()
+	err := db.Where(models.UserModelTable_Email+" = ? AND "+models.UserModelTable_EmailVerifiedAt+" IS NOT NULL", email).Take(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Debug("password reset requested for unknown email")
+			return nil
+		}
+		logrus.Error("query user by email failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return sendResetPasswordMail(user, "如果不是你本人操作，请忽略这封邮件，你的密码不会被修改。")
+}
+
+// sendResetPasswordMail 为用户的已验证邮箱签发重置token并发送邮件，note附在邮件末尾
+func sendResetPasswordMail(user models.UserModel, note string) error {
+	conf := config.GetMailConfig()
+	rawToken, err := createUserToken(user.ID, models.UserTokenPurposeResetPassword, user.Email,
+		parseMailTokenTTL(conf.ResetTokenTTL, defaultResetTokenTTL))
+	if err != nil {
+		return err
+	}
+	sendMailAsync(mail.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，你好：\n\n请点击以下链接重置密码，链接只能使用一次：\n%s\n\n%s\n",
+			user.Username, mailLink(conf.LinkBaseURL, "/reset-password", rawToken), note),
+	})
+	return nil
+}
+
+// ResetPassword 使用邮件中的token重置密码，返回被重置的用户ID。
+// 调用方需要吊销该用户已签发的token。
+func ResetPassword(rawToken string, rawPassword string) (uint, error) {
+	if !CheckPassword(rawPassword) {
+		return 0, errors.New(response.ErrInvalidPassword)
+	}
+	var userID uint
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, rawToken, models.UserTokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+		userID = token.UserID
+		err = tx.Model(&models.UserModel{}).Where("id = ?", token.UserID).
+			Updates(map[string]interface{}{
+				models.UserModelTable_Password:      utils.BcryptHash(rawPassword),
+				models.UserModelTable_PasswordReset: false,
+			}).Error
+		if err != nil {
+			return err
+		}
+		// 其他未使用的重置token一并作废
+		return tx.Model(&models.UserTokenModel{}).
+			Where(models.UserTokenModelTable_UserID+" = ? AND "+models.UserTokenModelTable_Purpose+" = ? AND "+models.UserTokenModelTable_UsedAt+" IS NULL",
+				token.UserID, models.UserTokenPurposeResetPassword).
+			Update(models.UserTokenModelTable_UsedAt, time.Now()).Error
+	})
+	if err != nil {
+		if err.Error() == response.ErrResetTokenInvalid {
+			return 0, err
+		}
+		logrus.Error("reset password failed, err: ", err)
+		return 0, errors.New(response.ErrServerInternal)
+	}
+	database.GetUserInfoCacher().Delete(userID)
+	return userID, nil
+}
+
+func createUserToken(userID uint, purpose string, email string, ttl time.Duration) (string, error) {
+	rawToken, err := randomHex(userTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	token := models.UserTokenModel{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(rawToken),
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	db := database.GetMysqlDB()
+	if err := db.Create(&token).Error; err != nil {
+		logrus.Error("create user token failed, err: ", err)
+		return "", errors.New(response.ErrServerInternal)
+	}
+	return rawToken, nil
+}
+
+// consumeUserToken 校验并标记token已使用，token无效时返回对应用途的错误
+func consumeUserToken(tx *gorm.DB, rawToken string, purpose string) (models.UserTokenModel, error) {
+	var token models.UserTokenModel
+	invalid := errors.New(response.ErrVerifyTokenInvalid)
+	if purpose == models.UserTokenPurposeResetPassword {
+		invalid = errors.New(response.ErrResetTokenInvalid)
+	}
+	if rawToken == "" {
+		return token, invalid
+	}
+	err := tx.Where(models.UserTokenModelTable_TokenHash+" = ? AND "+models.UserTokenModelTable_Purpose+" = ?", hashToken(rawToken), purpose).
+		Take(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, invalid
+		}
+		return token, err
+	}
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return token, invalid
+	}
+	// 条件更新，保证token只能使用一次
+	result := tx.Model(&models.UserTokenModel{}).
+		Where("id = ? AND "+models.UserTokenModelTable_UsedAt+" IS NULL", token.ID).
+		Update(models.UserTokenModelTable_UsedAt, now)
+	if result.Error != nil {
+		return token, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return token, invalid
+	}
+	return token, nil
+}
+
+func sendMailAsync(msg mail.Message) {
+	sender := GetMailSender()
+	Async(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := sender.Send(ctx, msg); err != nil {
+			logrus.Errorf("send mail to %s failed, err: %v", msg.To, err)
+		}
+	})
+}
+
+func mailLink(baseURL string, path string, rawToken string) string {
+	return strings.TrimRight(baseURL, "/") + path + "?token=" + url.QueryEscape(rawToken)
+}
+
+func parseMailTokenTTL(raw string, defaultTTL time.Duration) time.Duration {
+	if raw == "" {
+		return defaultTTL
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		logrus.Warnf("invalid mail token ttl %q, using %v", raw, defaultTTL)
+		return defaultTTL
+	}
+	return ttl
+}
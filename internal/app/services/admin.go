@@ -0,0 +1,365 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdminUserFilter 管理后台搜索用户的条件，零值表示不过滤
+type AdminUserFilter struct {
+	// 匹配用户名或邮箱
+	Keyword string
+	Role    string
+	// 只返回封禁中的用户
+	BannedOnly bool
+}
+
+// SearchUsers 按条件分页搜索用户，page从1开始
+func SearchUsers(filter AdminUserFilter, page int, pageSize int) ([]models.UserModel, int64, error) {
+	var users []models.UserModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.UserModel{})
+	if filter.Keyword != "" {
+		like := "%" + filter.Keyword + "%"
+		query = query.Where("("+models.UserModelTable_Username+" LIKE ? OR "+models.UserModelTable_Email+" LIKE ?)", like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where(models.UserModelTable_Role+" = ?", filter.Role)
+	}
+	if filter.BannedOnly {
+		query = query.Scopes(bannedUsers(time.Now()))
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count users failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	if err != nil {
+		logrus.Error("search users failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return users, total, nil
+}
+
+// BanUser 封禁用户，until为nil表示永久封禁。调用方需要吊销该用户已签发的token
+func BanUser(actorID uint, targetID uint, until *time.Time, reason string) error {
+	if actorID == targetID {
+		return errors.New(response.ErrBanSelf)
+	}
+	user, err := QueryUserById(targetID)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return errors.New(response.ErrUserNotExists)
+	}
+	if user.Role == models.RoleAdmin {
+		return errors.New(response.ErrBanAdmin)
+	}
+	now := time.Now()
+	return updateUserColumns(targetID, map[string]interface{}{
+		models.UserModelTable_BannedAt:    &now,
+		models.UserModelTable_BannedUntil: until,
+		models.UserModelTable_BanReason:   reason,
+	})
+}
+
+// UnbanUser 解封用户
+func UnbanUser(targetID uint) error {
+	return updateUserColumns(targetID, map[string]interface{}{
+		models.UserModelTable_BannedAt:    nil,
+		models.UserModelTable_BannedUntil: nil,
+		models.UserModelTable_BanReason:   "",
+	})
+}
+
+// ForcePasswordReset 要求用户重置密码并发送重置邮件，重置前不能使用旧密码登录。
+// 调用方需要吊销该用户已签发的token
+func ForcePasswordReset(targetID uint) error {
+	user, err := QueryUserById(targetID)
+	if err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return errors.New(response.ErrUserNotExists)
+	}
+	if user.Email == "" || user.EmailVerifiedAt == nil {
+		return errors.New(response.ErrResetNoEmail)
+	}
+	if err := updateUserColumns(targetID, map[string]interface{}{models.UserModelTable_PasswordReset: true}); err != nil {
+		return err
+	}
+	return sendResetPasswordMail(user, "管理员要求你重置密码，重置前无法使用旧密码登录。")
+}
+
+// bannedUsers 与 models.UserModel.IsBanned 一致的查询条件
+func bannedUsers(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(models.UserModelTable_BannedAt+" IS NOT NULL AND ("+
+			models.UserModelTable_BannedUntil+" IS NULL OR "+models.UserModelTable_BannedUntil+" > ?)", now)
+	}
+}
+
+func updateUserColumns(userID uint, columns map[string]interface{}) error {
+	result := database.GetMysqlDB().Model(&models.UserModel{}).Where("id = ?", userID).Updates(columns)
+	if result.Error != nil {
+		logrus.Error("update user failed, err: ", result.Error)
+		return errors.New(response.ErrServerInternal)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(response.ErrUserNotExists)
+	}
+	database.GetUserInfoCacher().Delete(userID)
+	return nil
+}
+
+// AdminDeleteReview 软删除书评，作者、版主和管理员删除书评都使用它。
+// 书评下的评论和收藏不再计入评论者的评论数和收藏者的收藏数，与删除在同一个事务中更新
+func AdminDeleteReview(reviewID uint) error {
+	return setReviewDeleted(reviewID, true)
+}
+
+// AdminRestoreReview 恢复被软删除的书评，重新计算书评的点赞、评论和收藏数，
+// 并把书评下的评论和收藏计回用户统计
+func AdminRestoreReview(reviewID uint) error {
+	return setReviewDeleted(reviewID, false)
+}
+
+func setReviewDeleted(reviewID uint, deleted bool) error {
+	var affectedUsers []uint
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", reviewID)
+		if deleted {
+			query = query.Where("deleted_at IS NULL")
+		} else {
+			query = query.Where("deleted_at IS NOT NULL")
+		}
+		var review models.BookReviewModel
+		if err := query.Take(&review).Error; err != nil {
+			return err
+		}
+		sign := 1
+		if deleted {
+			if err := tx.Delete(&review).Error; err != nil {
+				return err
+			}
+			sign = -1
+		} else {
+			if err := tx.Unscoped().Model(&review).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			if err := recountReview(tx, reviewID); err != nil {
+				return err
+			}
+		}
+		var err error
+		affectedUsers, err = adjustReviewUserStats(tx, reviewID, sign)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if deleted {
+				return errors.New(response.ErrReviewNotFound)
+			}
+			return errors.New(response.ErrReviewNotDeleted)
+		}
+		logrus.Error("update review deleted state failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	for _, userID := range affectedUsers {
+		database.GetUserInfoCacher().Delete(userID)
+	}
+	return nil
+}
+
+// adjustReviewUserStats 把书评下每个用户的可见评论数和收藏加到用户统计上，sign为1或-1，返回统计变化的用户
+func adjustReviewUserStats(tx *gorm.DB, reviewID uint, sign int) ([]uint, error) {
+	var commenters []struct {
+		UserID uint
+		Count  int
+	}
+	err := tx.Model(&models.CommentModel{}).Scopes(models.NotHidden).Select("user_id, COUNT(*) AS count").
+		Where(models.CommentModelTable_ReviewID+" = ?", reviewID).Group("user_id").Scan(&commenters).Error
+	if err != nil {
+		return nil, err
+	}
+	var users []uint
+	for _, c := range commenters {
+		err := tx.Model(&models.UserModel{}).Where("id = ?", c.UserID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + ?", sign*c.Count)).Error
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, c.UserID)
+	}
+
+	var collectors []uint
+	err = tx.Model(&models.UserCollectionModel{}).Where("review_id = ?", reviewID).Pluck("user_id", &collectors).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(collectors) > 0 {
+		err := tx.Model(&models.UserModel{}).Where("id IN ?", collectors).
+			UpdateColumn("collections_count", gorm.Expr("collections_count + ?", sign)).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(users, collectors...), nil
+}
+
+// recountReview 按点赞、可见评论和收藏记录重新计算书评的计数
+func recountReview(tx *gorm.DB, reviewID uint) error {
+	var likes, comments, collections int64
+	if err := tx.Model(&models.UserLikeModel{}).Where("review_id = ?", reviewID).Count(&likes).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.CommentModel{}).Scopes(models.NotHidden).Where("review_id = ?", reviewID).Count(&comments).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.UserCollectionModel{}).Where("review_id = ?", reviewID).Count(&collections).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.BookReviewModel{}).Where("id = ?", reviewID).UpdateColumns(map[string]interface{}{
+		"like_count":    likes,
+		"comment_count": comments,
+		"collect_count": collections,
+	}).Error
+}
+
+// AdminDeleteComment 软删除评论，评论者、版主和管理员删除评论都使用它。同步更新书评和评论者的评论数
+func AdminDeleteComment(commentID uint) error {
+	return setCommentDeleted(commentID, true)
+}
+
+// AdminRestoreComment 恢复被软删除的评论，同步更新书评和评论者的评论数
+func AdminRestoreComment(commentID uint) error {
+	return setCommentDeleted(commentID, false)
+}
+
+func setCommentDeleted(commentID uint, deleted bool) error {
+	var comment models.CommentModel
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", commentID)
+		if deleted {
+			query = query.Where("deleted_at IS NULL")
+		} else {
+			query = query.Where("deleted_at IS NOT NULL")
+		}
+		if err := query.Take(&comment).Error; err != nil {
+			return err
+		}
+		delta := 1
+		if deleted {
+			if err := tx.Delete(&comment).Error; err != nil {
+				return err
+			}
+			delta = -1
+		} else if err := tx.Unscoped().Model(&comment).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		// 被隐藏的评论不计入评论数
+		if comment.HiddenAt != nil {
+			return nil
+		}
+		return adjustCommentCount(tx, comment.ReviewID, comment.UserID, delta)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if deleted {
+				return errors.New(response.ErrCommentNotFound)
+			}
+			return errors.New(response.ErrCommentNotDeleted)
+		}
+		logrus.Error("update comment deleted state failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	database.GetUserInfoCacher().Delete(comment.UserID)
+	return nil
+}
+
+// AuditLogStore 把审计日志保存到审计日志表，实现 audit.Store
+type AuditLogStore struct{}
+
+func (AuditLogStore) Save(e audit.Event) error {
+	return database.GetMysqlDB().Create(&models.AuditLogModel{
+		ActorID:    e.ActorID,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.IP,
+		Detail:     e.Detail,
+	}).Error
+}
+
+// AuditLogFilter 查询审计日志的条件，零值表示不过滤
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+}
+
+// QueryAuditLogs 按时间倒序分页查询审计日志，page从1开始
+func QueryAuditLogs(filter AuditLogFilter, page int, pageSize int) ([]models.AuditLogModel, int64, error) {
+	var logs []models.AuditLogModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.AuditLogModel{})
+	if filter.ActorID != 0 {
+		query = query.Where(models.AuditLogModelTable_ActorID+" = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where(models.AuditLogModelTable_Action+" = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where(models.AuditLogModelTable_TargetType+" = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where(models.AuditLogModelTable_TargetID+" = ?", filter.TargetID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count audit logs failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Order(models.AuditLogModelTable_CreatedAt + " DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error
+	if err != nil {
+		logrus.Error("query audit logs failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return logs, total, nil
+}
+
+// GetSiteCounts 统计用户、书评、评论等数量
+func GetSiteCounts() (map[string]int64, error) {
+	db := database.GetMysqlDB()
+	counts := make(map[string]int64)
+	queries := map[string]*gorm.DB{
+		"users":        db.Model(&models.UserModel{}),
+		"banned_users": db.Model(&models.UserModel{}).Scopes(bannedUsers(time.Now())),
+		"reviews":      db.Model(&models.BookReviewModel{}),
+		"comments":     db.Model(&models.CommentModel{}),
+		"hidden_reviews": db.Model(&models.BookReviewModel{}).
+			Where(models.BookReviewModelTable_HiddenAt + " IS NOT NULL"),
+		"hidden_comments": db.Model(&models.CommentModel{}).
+			Where(models.CommentModelTable_HiddenAt + " IS NOT NULL"),
+	}
+	for name, query := range queries {
+		var n int64
+		if err := query.Count(&n).Error; err != nil {
+			logrus.Errorf("count %s failed, err: %v", name, err)
+			return nil, errors.New(response.ErrServerInternal)
+		}
+		counts[name] = n
+	}
+	return counts, nil
+}
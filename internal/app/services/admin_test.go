@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"gorm.io/gorm"
+)
+
+func createTestUser(t *testing.T, db *gorm.DB, username string) models.UserModel {
+	user := models.UserModel{Username: username}
+	require.NoError(t, db.Create(&user).Error)
+	return user
+}
+
+func createTestReview(t *testing.T, db *gorm.DB, authorID uint) models.BookReviewModel {
+	review := models.BookReviewModel{Title: "title", Content: "content", AuthorID: authorID}
+	require.NoError(t, db.Create(&review).Error)
+	return review
+}
+
+func reloadUser(t *testing.T, db *gorm.DB, id uint) models.UserModel {
+	var user models.UserModel
+	require.NoError(t, db.Take(&user, id).Error)
+	return user
+}
+
+func TestAdminDeleteAndRestoreReview(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	review := createTestReview(t, db, author.ID)
+	for _, c := range []models.CommentModel{
+		{ReviewID: review.ID, UserID: alice.ID, Content: "a1"},
+		{ReviewID: review.ID, UserID: alice.ID, Content: "a2"},
+		{ReviewID: review.ID, UserID: bob.ID, Content: "b1"},
+	} {
+		require.NoError(t, db.Create(&c).Error)
+	}
+	require.NoError(t, db.Create(&models.UserCollectionModel{UserID: bob.ID, ReviewID: review.ID}).Error)
+	require.NoError(t, db.Create(&models.UserLikeModel{UserID: alice.ID, ReviewID: review.ID}).Error)
+	require.NoError(t, db.Model(&models.UserModel{}).Where("id = ?", alice.ID).Update("comment_count", 5).Error)
+	require.NoError(t, db.Model(&models.UserModel{}).Where("id = ?", bob.ID).
+		Updates(map[string]interface{}{"comment_count": 1, "collections_count": 2}).Error)
+
+	require.NoError(t, AdminDeleteReview(review.ID))
+	assert.Equal(t, uint(3), reloadUser(t, db, alice.ID).CommentCount)
+	assert.Equal(t, uint(0), reloadUser(t, db, bob.ID).CommentCount)
+	assert.Equal(t, uint(1), reloadUser(t, db, bob.ID).CollectionsCount)
+	assert.EqualError(t, AdminDeleteReview(review.ID), response.ErrReviewNotFound)
+
+	require.NoError(t, AdminRestoreReview(review.ID))
+	assert.Equal(t, uint(5), reloadUser(t, db, alice.ID).CommentCount)
+	assert.Equal(t, uint(1), reloadUser(t, db, bob.ID).CommentCount)
+	assert.Equal(t, uint(2), reloadUser(t, db, bob.ID).CollectionsCount)
+	var restored models.BookReviewModel
+	require.NoError(t, db.Take(&restored, review.ID).Error)
+	assert.Equal(t, uint(1), restored.LikeCount)
+	assert.Equal(t, uint(3), restored.CommentCount)
+	assert.Equal(t, uint(1), restored.CollectCount)
+	assert.EqualError(t, AdminRestoreReview(review.ID), response.ErrReviewNotDeleted)
+}
+
+func TestCheckLoginAllowed(t *testing.T) {
+	assert.NoError(t, CheckLoginAllowed(models.UserModel{}))
+	assert.EqualError(t, CheckLoginAllowed(models.UserModel{PasswordResetRequired: true}), response.ErrPasswordResetRequired)
+}
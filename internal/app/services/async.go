@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// asyncTasks 请求返回后在后台执行的写入，例如浏览次数和邮件，关闭服务时等待完成
+var asyncTasks sync.WaitGroup
+
+// Async 在后台执行fn，不影响请求的响应时间
+func Async(fn func()) {
+	asyncTasks.Add(1)
+	go func() {
+		defer asyncTasks.Done()
+		fn()
+	}()
+}
+
+// WaitAsync 等待后台任务完成，ctx结束时返回ctx的错误。HTTP服务停止之后调用
+func WaitAsync(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		asyncTasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm/clause"
+)
+
+// BlockUser 拉黑或静音targetID，已有记录时改为新的类型。
+// 拉黑会同时解除双方之间的关注关系和待处理的关注请求
+func BlockUser(userID uint, targetID uint, kind string) error {
+	if !models.IsValidBlockKind(kind) {
+		return errors.New(response.ErrBlockKind)
+	}
+	if userID == targetID {
+		return errors.New(response.ErrBlockSelf)
+	}
+	target, err := QueryUserById(targetID)
+	if err != nil {
+		return err
+	}
+	if target.ID == 0 {
+		return errors.New(response.ErrUserNotExists)
+	}
+	block := models.UserBlockModel{UserID: userID, TargetID: targetID, Kind: kind}
+	err = database.GetMysqlDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: models.UserBlockModelTable_UserID}, {Name: models.UserBlockModelTable_TargetID}},
+		DoUpdates: clause.AssignmentColumns([]string{models.UserBlockModelTable_Kind, "updated_at"}),
+	}).Create(&block).Error
+	if err != nil {
+		logrus.Error("block user failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	if kind == models.BlockKindBlock {
+		unfollowIfFollowing(userID, targetID)
+		unfollowIfFollowing(targetID, userID)
+		CancelFollowRequest(userID, targetID)
+		CancelFollowRequest(targetID, userID)
+	}
+	return nil
+}
+
+// unfollowIfFollowing 没有关注时UnfollowUser会返回错误，这里先查一次避免记录错误日志
+func unfollowIfFollowing(userID uint, toUserID uint) {
+	if !IsFollowing(userID, toUserID) {
+		return
+	}
+	if err := UnfollowUser("", userID, toUserID); err != nil {
+		logrus.Errorf("remove follow %d -> %d failed, err: %v", userID, toUserID, err)
+	}
+}
+
+// UnblockUser 解除拉黑或静音，没有记录时什么也不做
+func UnblockUser(userID uint, targetID uint) error {
+	err := database.GetMysqlDB().
+		Where(models.UserBlockModelTable_UserID+" = ? AND "+models.UserBlockModelTable_TargetID+" = ?", userID, targetID).
+		Delete(&models.UserBlockModel{}).Error
+	if err != nil {
+		logrus.Error("unblock user failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// ListBlocks 按时间倒序分页查询用户屏蔽的人，kind为空时返回全部
+func ListBlocks(userID uint, kind string, page int, pageSize int) ([]models.UserBlockModel, int64, error) {
+	var blocks []models.UserBlockModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.UserBlockModel{}).Where(models.UserBlockModelTable_UserID+" = ?", userID)
+	if kind != "" {
+		query = query.Where(models.UserBlockModelTable_Kind+" = ?", kind)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count blocks failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Preload("Target").Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&blocks).Error
+	if err != nil {
+		logrus.Error("query blocks failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return blocks, total, nil
+}
+
+// IsBlocked 两个用户之间任意一方拉黑了对方。查询失败时按未拉黑处理
+func IsBlocked(userID uint, otherID uint) bool {
+	if userID == 0 || otherID == 0 || userID == otherID {
+		return false
+	}
+	var count int64
+	err := database.GetMysqlDB().Model(&models.UserBlockModel{}).
+		Where("(("+models.UserBlockModelTable_UserID+" = ? AND "+models.UserBlockModelTable_TargetID+" = ?) OR ("+
+			models.UserBlockModelTable_UserID+" = ? AND "+models.UserBlockModelTable_TargetID+" = ?)) AND "+
+			models.UserBlockModelTable_Kind+" = ?",
+			userID, otherID, otherID, userID, models.BlockKindBlock).
+		Count(&count).Error
+	if err != nil {
+		logrus.Error("query block failed, err: ", err)
+		return false
+	}
+	return count > 0
+}
+
+// HiddenUserIDs viewer不应该看到其内容的用户：自己拉黑或静音的人，以及拉黑了自己的人。
+// 未登录时返回nil
+func HiddenUserIDs(viewerID uint) []uint {
+	if viewerID == 0 {
+		return nil
+	}
+	db := database.GetMysqlDB()
+	var blocked, blockedBy []uint
+	err := db.Model(&models.UserBlockModel{}).Where(models.UserBlockModelTable_UserID+" = ?", viewerID).
+		Pluck(models.UserBlockModelTable_TargetID, &blocked).Error
+	if err == nil {
+		err = db.Model(&models.UserBlockModel{}).
+			Where(models.UserBlockModelTable_TargetID+" = ? AND "+models.UserBlockModelTable_Kind+" = ?", viewerID, models.BlockKindBlock).
+			Pluck(models.UserBlockModelTable_UserID, &blockedBy).Error
+	}
+	if err != nil {
+		logrus.Error("query hidden users failed, err: ", err)
+	}
+	return append(blocked, blockedBy...)
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-func CommentVideo(videoId uint, commenterID uint, commentText string) error {
+// CommentVideo 发表评论。key为消息Key，同一个key只会生效一次，重试时不会重复发表
+func CommentVideo(key string, videoId uint, commenterID uint, commentText string) error {
 	if videoId == 0 || commenterID == 0 {
 		logrus.Error("comment video failed, videoId or commenterID is 0, videoId: ", videoId, " commenterID: ", commenterID)
 	}
@@ -17,8 +19,10 @@ func CommentVideo(videoId uint, commenterID uint, commentText string) error {
 	comment.Content = commentText
 	comment.UserID = commenterID
 	comment.Commenter.ID = commenterID
-	err := database.GetMysqlDB().Create(&comment).Error
-	if err != nil {
+	applied, err := processOnce(key, func(tx *gorm.DB) error {
+		return tx.Create(&comment).Error
+	})
+	if err != nil || !applied {
 		return err
 	}
 	AddVideoCommentToCache(videoId, comment)
@@ -54,20 +58,23 @@ func DeleteVideoCommentFromCache(videoId uint, commentId uint, commenterID uint)
 	}
 }
 
-func DeleteComment(commentId uint, commenterID uint) error {
+// DeleteComment 删除自己的评论。key为消息Key，同一个key只会生效一次
+func DeleteComment(key string, commentId uint, commenterID uint) error {
 	var comment models.CommentModel
-	err := database.GetMysqlDB().Where("id = ?", commentId).First(&comment).Error
-	if err != nil {
-		return err
-	}
-	if comment.ID == 0 {
-		return errors.New(ErrDeleteNotExists)
-	}
-	if comment.UserID != commenterID {
-		return errors.New(ErrDeleteNotOwner)
-	}
-	err = database.GetMysqlDB().Delete(&comment).Error
-	if err != nil {
+	applied, err := processOnce(key, func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", commentId).First(&comment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(ErrDeleteNotExists)
+		}
+		if err != nil {
+			return err
+		}
+		if comment.UserID != commenterID {
+			return errors.New(ErrDeleteNotOwner)
+		}
+		return tx.Delete(&comment).Error
+	})
+	if err != nil || !applied {
 		return err
 	}
 	DeleteVideoCommentFromCache(comment.VideoID, commentId, commenterID)
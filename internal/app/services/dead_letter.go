@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+	"gorm.io/gorm"
+)
+
+// SaveDeadLetter 保存消息队列放弃的消息。返回错误时消息留在队列中稍后再保存
+func SaveDeadLetter(d messageQueue.DeadLetter) error {
+	dead := models.DeadLetterModel{
+		CreatedAt:  d.FailedAt,
+		Queue:      d.Queue,
+		MessageKey: d.Key,
+		Payload:    string(d.Body),
+		Attempts:   d.Attempts,
+		LastError:  d.Error,
+	}
+	if err := database.GetMysqlDB().Create(&dead).Error; err != nil {
+		return err
+	}
+	logrus.Warnf("message %s of queue %s moved to dead letters after %d attempts, err: %s", d.Key, d.Queue, d.Attempts, d.Error)
+	return nil
+}
+
+// QueryDeadLetters 按时间倒序查询死信，queue为空时查询所有队列，默认不包括已经重放的
+func QueryDeadLetters(queue string, includeReplayed bool, page int, pageSize int) ([]models.DeadLetterModel, int64, error) {
+	var letters []models.DeadLetterModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.DeadLetterModel{})
+	if queue != "" {
+		query = query.Where(models.DeadLetterModelTable_Queue+" = ?", queue)
+	}
+	if !includeReplayed {
+		query = query.Where(models.DeadLetterModelTable_ReplayedAt + " IS NULL")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count dead letters failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&letters).Error
+	if err != nil {
+		logrus.Error("query dead letters failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return letters, total, nil
+}
+
+// GetDeadLetter 查询一条死信
+func GetDeadLetter(id uint) (models.DeadLetterModel, error) {
+	var dead models.DeadLetterModel
+	err := database.GetMysqlDB().Take(&dead, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dead, errors.New(response.ErrDeadLetterNotFound)
+	}
+	if err != nil {
+		logrus.Error("query dead letter failed, err: ", err)
+		return dead, errors.New(response.ErrServerInternal)
+	}
+	return dead, nil
+}
+
+// MarkDeadLetterReplayed 记录死信已经重新放入队列
+func MarkDeadLetterReplayed(id uint) error {
+	err := database.GetMysqlDB().Model(&models.DeadLetterModel{ID: id}).
+		Update(models.DeadLetterModelTable_ReplayedAt, time.Now()).Error
+	if err != nil {
+		logrus.Error("mark dead letter replayed failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// DeleteDeadLetter 删除确认不需要处理的死信
+func DeleteDeadLetter(id uint) error {
+	result := database.GetMysqlDB().Delete(&models.DeadLetterModel{}, id)
+	if result.Error != nil {
+		logrus.Error("delete dead letter failed, err: ", result.Error)
+		return errors.New(response.ErrServerInternal)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(response.ErrDeadLetterNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package services
+
+// 服务返回的错误信息，调用方用 err.Error() 判断
+const (
+	// 重复插入
+	ErrDuplicate = "重复插入"
+	// 删除不存在的记录
+	ErrDeleteNotExists = "删除不存在的记录"
+	ErrDeleteNotOwner  = "删除的不是自己的记录"
+)
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// RegisterEventSubscribers 注册所有领域事件的订阅者，启动时调用一次。
+// 点赞数、关注数和粉丝数在写入事件的事务中更新，搜索用的search_text和书评一起保存，
+// 都不依赖订阅者
+func RegisterEventSubscribers() {
+	// Prometheus中的事件数
+	for _, eventType := range events.Types {
+		events.Subscribe(eventType, "metrics", countEvent)
+	}
+
+	// 缓存失效
+	events.Subscribe(events.UserFollowed, "user_cache", invalidateFollowCache)
+	events.Subscribe(events.UserUnfollowed, "user_cache", invalidateFollowCache)
+	events.Subscribe(events.ReviewLiked, "like_cache", invalidateLikeCache)
+	events.Subscribe(events.ReviewUnliked, "like_cache", invalidateLikeCache)
+
+	// 站内通知
+	events.Subscribe(events.UserFollowed, "notification", notifyFollowed)
+	events.Subscribe(events.ReviewLiked, "notification", notifyReviewLiked)
+}
+
+func countEvent(e events.Event) error {
+	metrics.DomainEventsTotal.WithLabelValues(e.Type).Inc()
+	return nil
+}
+
+// invalidateFollowCache 双方的关注数和粉丝数变了，下次读取时从数据库加载
+func invalidateFollowCache(e events.Event) error {
+	var payload events.FollowPayload
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+	userCacher := database.GetUserInfoCacher()
+	userCacher.Delete(payload.UserID)
+	userCacher.Delete(payload.ToUserID)
+	return nil
+}
+
+// invalidateLikeCache 用户的点赞列表变了
+func invalidateLikeCache(e events.Event) error {
+	var payload events.ReviewLikePayload
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+	database.GetUserFavoriteCacher().Delete(payload.UserID)
+	return nil
+}
+
+func notifyFollowed(e events.Event) error {
+	var payload events.FollowPayload
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+	follower, err := GetUserById(payload.UserID)
+	if err != nil {
+		return err
+	}
+	return notifyOnce(e, models.NotificationModel{
+		UserID:  payload.ToUserID,
+		Type:    models.NotificationFollowed,
+		Content: fmt.Sprintf("%s 关注了你", follower.Username),
+		RefType: "user",
+		RefID:   payload.UserID,
+	})
+}
+
+func notifyReviewLiked(e events.Event) error {
+	var payload events.ReviewLikePayload
+	if err := e.Decode(&payload); err != nil {
+		return err
+	}
+	var review models.BookReviewModel
+	err := database.GetMysqlDB().Select("id", models.BookReviewModelTable_AuthorID, models.BookReviewModelTable_Title).
+		Take(&review, payload.ReviewID).Error
+	if err == gorm.ErrRecordNotFound {
+		// 书评已经删除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 给自己点赞不通知
+	if review.AuthorID == payload.UserID {
+		return nil
+	}
+	liker, err := GetUserById(payload.UserID)
+	if err != nil {
+		return err
+	}
+	return notifyOnce(e, models.NotificationModel{
+		UserID:  review.AuthorID,
+		Type:    models.NotificationReviewLiked,
+		Content: fmt.Sprintf("%s 赞了你的书评《%s》", liker.Username, review.Title),
+		RefType: "review",
+		RefID:   review.ID,
+	})
+}
+
+// notifyOnce 创建通知，同一个事件重新投递时不会重复通知
+func notifyOnce(e events.Event, notification models.NotificationModel) error {
+	key := fmt.Sprintf("event:%d:%s", e.ID, notification.Type)
+	_, err := processOnce(key, func(tx *gorm.DB) error {
+		return tx.Create(&notification).Error
+	})
+	return err
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/featureflag"
+	"gorm.io/gorm/clause"
+)
+
+const defaultFeatureFlagRefreshInterval = 30 * time.Second
+
+// featureFlags 当前生效的功能开关，由配置文件和数据库中的开关合并而来
+var featureFlags = featureflag.NewStore()
+
+var (
+	// 最近一次从数据库读取的开关，读取数据库失败时和配置文件中的开关合并
+	featureFlagOverrides     []featureflag.Flag
+	featureFlagOverridesLock sync.Mutex
+)
+
+// FeatureEnabled 功能name对用户是否开启，未登录时userID为0。没有定义的功能视为关闭
+func FeatureEnabled(name string, userID uint) bool {
+	return featureFlags.Enabled(name, userID)
+}
+
+// ListFeatureFlags 当前生效的所有功能开关
+func ListFeatureFlags() []featureflag.Flag {
+	return featureFlags.List()
+}
+
+// LoadFeatureFlags 重新读取配置文件和数据库中的开关。读取数据库失败时返回错误，
+// 数据库中的开关使用上一次读取的结果，配置文件的修改仍然生效
+func LoadFeatureFlags() error {
+	overrides, err := queryFeatureFlagOverrides()
+	featureFlagOverridesLock.Lock()
+	defer featureFlagOverridesLock.Unlock()
+	if err == nil {
+		featureFlagOverrides = overrides
+	}
+	featureFlags.Replace(featureflag.Merge(configuredFeatureFlags(config.GetFeatureFlagsConfig()), featureFlagOverrides))
+	return err
+}
+
+// queryFeatureFlagOverrides 数据库中管理员设置的开关
+func queryFeatureFlagOverrides() ([]featureflag.Flag, error) {
+	var rows []models.FeatureFlagModel
+	if err := database.GetMysqlDB().Find(&rows).Error; err != nil {
+		logrus.Error("query feature flags failed, err: ", err)
+		return nil, errors.New(response.ErrServerInternal)
+	}
+	overrides := make([]featureflag.Flag, 0, len(rows))
+	for _, row := range rows {
+		overrides = append(overrides, featureflag.Flag{
+			Name:        row.Name,
+			Enabled:     row.Enabled,
+			Percentage:  row.Percentage,
+			Users:       parseFlagUsers(row.Users),
+			Description: row.Description,
+			Source:      featureflag.SourceDatabase,
+		})
+	}
+	return overrides, nil
+}
+
+// configuredFeatureFlags 配置文件中的开关，没有配置灰度比例时对所有用户开启
+func configuredFeatureFlags(conf config.FeatureFlagsConfig) []featureflag.Flag {
+	flags := make([]featureflag.Flag, 0, len(conf.Flags))
+	for name, f := range conf.Flags {
+		percentage := featureflag.FullRollout
+		if f.Percentage != nil {
+			percentage = *f.Percentage
+		}
+		flags = append(flags, featureflag.Flag{
+			Name:        name,
+			Enabled:     f.Enabled,
+			Percentage:  percentage,
+			Users:       f.Users,
+			Description: f.Description,
+			Source:      featureflag.SourceConfig,
+		})
+	}
+	return flags
+}
+
+// SetFeatureFlag 在数据库中保存开关，覆盖配置文件中的同名开关，当前实例立即生效
+func SetFeatureFlag(flag featureflag.Flag, actorID uint) error {
+	if !featureflag.ValidName(flag.Name) {
+		return errors.New(response.ErrFeatureFlagName)
+	}
+	if flag.Percentage < 0 || flag.Percentage > featureflag.FullRollout {
+		return errors.New(response.ErrFeatureFlagPercentage)
+	}
+	row := models.FeatureFlagModel{
+		Name:        flag.Name,
+		Enabled:     flag.Enabled,
+		Percentage:  flag.Percentage,
+		Users:       formatFlagUsers(flag.Users),
+		Description: flag.Description,
+		UpdatedBy:   actorID,
+	}
+	err := database.GetMysqlDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: models.FeatureFlagModelTable_Name}},
+		UpdateAll: true,
+	}).Create(&row).Error
+	if err != nil {
+		logrus.Error("save feature flag failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return LoadFeatureFlags()
+}
+
+// DeleteFeatureFlag 删除数据库中的开关，恢复为配置文件中的设置，配置文件中也没有时功能关闭
+func DeleteFeatureFlag(name string) error {
+	result := database.GetMysqlDB().Delete(&models.FeatureFlagModel{Name: name})
+	if result.Error != nil {
+		logrus.Error("delete feature flag failed, err: ", result.Error)
+		return errors.New(response.ErrServerInternal)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(response.ErrFeatureFlagNotFound)
+	}
+	return LoadFeatureFlags()
+}
+
+// RunFeatureFlagRefresh 定期从数据库刷新开关，使其他实例上的修改生效，ctx结束时退出
+func RunFeatureFlagRefresh(ctx context.Context) {
+	interval := defaultFeatureFlagRefreshInterval
+	if d, err := time.ParseDuration(config.GetFeatureFlagsConfig().RefreshInterval); err == nil && d > 0 {
+		interval = d
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			LoadFeatureFlags()
+		}
+	}
+}
+
+func formatFlagUsers(users []uint) string {
+	ids := make([]string, 0, len(users))
+	for _, id := range users {
+		ids = append(ids, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(ids, ",")
+}
+
+func parseFlagUsers(s string) []uint {
+	var users []uint
+	for _, field := range strings.Split(s, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err == nil && id > 0 {
+			users = append(users, uint(id))
+		}
+	}
+	return users
+}
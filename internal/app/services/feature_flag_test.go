@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/featureflag"
+)
+
+func setFeatureFlagsConfig(t *testing.T, flags map[string]config.FeatureFlagConfig) {
+	old := config.Get()
+	t.Cleanup(func() { config.Set(old) })
+	conf := old
+	conf.FeatureFlags.Flags = flags
+	config.Set(conf)
+}
+
+func TestLoadFeatureFlags_KeepsConfigChangesWhenDatabaseFails(t *testing.T) {
+	db := dbtest.Open(t)
+	setFeatureFlagsConfig(t, map[string]config.FeatureFlagConfig{"from_config": {Enabled: false}})
+	require.NoError(t, SetFeatureFlag(featureflag.Flag{Name: "from_db", Enabled: true, Percentage: featureflag.FullRollout}, 1))
+	assert.True(t, FeatureEnabled("from_db", 1))
+	assert.False(t, FeatureEnabled("from_config", 1))
+
+	// 数据库不可用时修改配置文件
+	require.NoError(t, db.Migrator().DropTable(&models.FeatureFlagModel{}))
+	setFeatureFlagsConfig(t, map[string]config.FeatureFlagConfig{"from_config": {Enabled: true}})
+	assert.Error(t, LoadFeatureFlags())
+
+	assert.True(t, FeatureEnabled("from_config", 1))
+	// 数据库中的开关使用上一次读取的结果
+	assert.True(t, FeatureEnabled("from_db", 1))
+}
@@ -1,104 +1,110 @@
 package services
 
 import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func FollowUser(userID, toUserID uint) error {
-	// 更新db - follow表
-	db := database.GetMysqlDB()
-	tx := db.Begin()
+// FollowUser userID关注toUserID。key为消息Key，同一个key只会生效一次，为空时不检查
+func FollowUser(key string, userID, toUserID uint) error {
+	// 拉黑的双方不能互相关注
+	if IsBlocked(userID, toUserID) {
+		return errors.New(response.ErrBlocked)
+	}
+
+	applied, err := processOnce(key, func(tx *gorm.DB) error {
+		return createFollow(tx, userID, toUserID)
+	})
+	if err != nil || !applied {
+		return err
+	}
+	wakeOutboxRelay()
+	return nil
+}
 
+// createFollow 在tx中创建关注关系、更新计数并写入事件，已经关注时返回ErrDuplicate
+func createFollow(tx *gorm.DB, userID, toUserID uint) error {
+	// 更新db - follow表，已经关注时不重复计数
 	var follow models.UserFollowerModel
 	follow.UserID = userID
 	follow.FollowerID = toUserID
-	err := tx.Create(&follow).Error
-	if err != nil {
-		tx.Rollback()
-		return err
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	if result.Error != nil {
+		return result.Error
 	}
-	// 更新db - user表
-	var user models.UserModel
-	user.ID = userID
-	follower_count := models.UserModelTable_FollowerCount
-	err = tx.Model(&user).Update(follower_count, gorm.Expr(follower_count+"+?", 1)).Error
-	if err != nil {
-		tx.Rollback()
-		return err
+	if result.RowsAffected == 0 {
+		return errors.New(ErrDuplicate)
 	}
-	var toUser models.UserModel
-	toUser.ID = toUserID
-	fan_count := models.UserModelTable_FanCount
-	err = tx.Model(&toUser).Update(fan_count, gorm.Expr(fan_count+"+?", 1)).Error
-	if err != nil {
-		tx.Rollback()
+	// 更新db - user表
+	if err := updateFollowCounts(tx, userID, toUserID, 1); err != nil {
 		return err
 	}
-	tx.Commit()
+	// 缓存失效和通知由事件的订阅者处理
+	return writeEvent(tx, events.UserFollowed, events.FollowPayload{UserID: userID, ToUserID: toUserID})
+}
 
-	// 更新缓存
-	userCacher := database.GetUserInfoCacher()
-	userCache, exist := userCacher.Get(userID)
-	if exist {
-		userCache.FollowerCount++
-		userCacher.Set(userID, userCache)
-	}
-	toUserCache, exist := userCacher.Get(toUserID)
-	if exist {
-		toUserCache.FanCount++
-		userCacher.Set(toUserID, toUserCache)
+// UnfollowUser userID取消关注toUserID。没有关注时返回ErrDeleteNotExists，
+// key为消息Key，同一个key只会生效一次，为空时不检查
+func UnfollowUser(key string, userID, toUserID uint) error {
+	applied, err := processOnce(key, func(tx *gorm.DB) error {
+		// 更新db - follow表
+		var follow models.UserFollowerModel
+		follow.UserID = userID
+		follow.FollowerID = toUserID
+		result := tx.Delete(&follow)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ErrDeleteNotExists)
+		}
+		// 更新db - user表
+		if err := updateFollowCounts(tx, userID, toUserID, -1); err != nil {
+			return err
+		}
+		return writeEvent(tx, events.UserUnfollowed, events.FollowPayload{UserID: userID, ToUserID: toUserID})
+	})
+	if err != nil || !applied {
+		return err
 	}
+	wakeOutboxRelay()
 	return nil
 }
 
-func UnfollowUser(userID, toUserID uint) error {
-	// 更新db - follow表
-	db := database.GetMysqlDB()
-	tx := db.Begin()
-
-	var follow models.UserFollowerModel
-	follow.UserID = userID
-	follow.FollowerID = toUserID
-	err := tx.Delete(&follow).Error
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	// 更新db - user表
+// updateFollowCounts 修改userID的关注数和toUserID的粉丝数
+func updateFollowCounts(tx *gorm.DB, userID, toUserID uint, delta int) error {
 	var user models.UserModel
 	user.ID = userID
 	follower_count := models.UserModelTable_FollowerCount
-	err = tx.Model(&user).Update(follower_count, gorm.Expr(follower_count+"-?", 1)).Error
+	err := tx.Model(&user).Update(follower_count, gorm.Expr(follower_count+"+?", delta)).Error
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 	var toUser models.UserModel
 	toUser.ID = toUserID
 	fan_count := models.UserModelTable_FanCount
-	err = tx.Model(&toUser).Update(fan_count, gorm.Expr(fan_count+"-?", 1)).Error
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	tx.Commit()
+	return tx.Model(&toUser).Update(fan_count, gorm.Expr(fan_count+"+?", delta)).Error
+}
 
-	// 更新缓存
-	userCacher := database.GetUserInfoCacher()
-	userCache, exist := userCacher.Get(userID)
-	if exist {
-		userCache.FollowerCount--
-		userCacher.Set(userID, userCache)
-	}
-	toUserCache, exist := userCacher.Get(toUserID)
-	if exist {
-		toUserCache.FanCount--
-		userCacher.Set(toUserID, toUserCache)
+// IsFollowing userID是否关注了toUserID
+func IsFollowing(userID, toUserID uint) bool {
+	var count int64
+	err := database.GetMysqlDB().Model(&models.UserFollowerModel{}).
+		Where(models.UserFollowerModelTable_UserID+" = ? AND "+models.UserFollowerModelTable_FollowerID+" = ?", userID, toUserID).
+		Count(&count).Error
+	if err != nil {
+		logrus.Error("query follow failed, err: ", err)
+		return false
 	}
-	return nil
+	return count > 0
 }
 
 func QueryFollowUserListByUserID(userID uint) ([]models.UserModel, error) {
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// isPrivateAccount 用户缓存中没有隐私设置，直接查库。用户不存在时按公开处理
+func isPrivateAccount(userID uint) bool {
+	var user models.UserModel
+	err := database.GetMysqlDB().Select("id", models.UserModelTable_IsPrivate).Take(&user, userID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.Error("query user privacy failed, err: ", err)
+	}
+	return user.IsPrivate
+}
+
+// CanViewUserContent viewer能否查看owner的书评、收藏和关注列表。
+// 本人总是可以；互相拉黑时不可以；私密账号只对粉丝可见。viewer为0表示未登录
+func CanViewUserContent(viewerID uint, ownerID uint) bool {
+	if viewerID != 0 && viewerID == ownerID {
+		return true
+	}
+	if IsBlocked(viewerID, ownerID) {
+		return false
+	}
+	if !isPrivateAccount(ownerID) {
+		return true
+	}
+	return viewerID != 0 && IsFollowing(viewerID, ownerID)
+}
+
+// SetAccountPrivate 修改私密账号设置，改为公开时自动同意所有待处理的关注请求
+func SetAccountPrivate(userID uint, private bool) error {
+	err := database.GetMysqlDB().Model(&models.UserModel{}).Where("id = ?", userID).
+		Update(models.UserModelTable_IsPrivate, private).Error
+	if err != nil {
+		logrus.Error("update user privacy failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	if private {
+		return nil
+	}
+	var requestIDs []uint
+	database.GetMysqlDB().Model(&models.FollowRequestModel{}).
+		Where(models.FollowRequestModelTable_TargetID+" = ?", userID).
+		Pluck("id", &requestIDs)
+	for _, id := range requestIDs {
+		if err := ApproveFollowRequest(userID, id); err != nil {
+			logrus.Errorf("approve follow request %d failed, err: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// RequestFollow 关注私密账号时创建关注请求并通知对方，返回是否需要等待对方同意。
+// 对方是公开账号或已经关注时返回false，由调用方直接关注
+func RequestFollow(userID uint, toUserID uint) (bool, error) {
+	if userID == toUserID || !isPrivateAccount(toUserID) || IsFollowing(userID, toUserID) {
+		return false, nil
+	}
+	if IsBlocked(userID, toUserID) {
+		return false, errors.New(response.ErrBlocked)
+	}
+	request := models.FollowRequestModel{RequesterID: userID, TargetID: toUserID}
+	result := database.GetMysqlDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&request)
+	if result.Error != nil {
+		logrus.Error("create follow request failed, err: ", result.Error)
+		return false, errors.New(response.ErrServerInternal)
+	}
+	// 重复请求不再通知
+	if result.RowsAffected > 0 {
+		requester, _ := GetUserById(userID)
+		NotifyUsers([]uint{toUserID}, models.NotificationFollowRequest,
+			fmt.Sprintf("%s 请求关注你", requester.Username), "follow_request", request.ID)
+	}
+	return true, nil
+}
+
+// CancelFollowRequest 撤回发给toUserID的关注请求，没有请求时什么也不做
+func CancelFollowRequest(userID uint, toUserID uint) error {
+	err := database.GetMysqlDB().
+		Where(models.FollowRequestModelTable_RequesterID+" = ? AND "+models.FollowRequestModelTable_TargetID+" = ?", userID, toUserID).
+		Delete(&models.FollowRequestModel{}).Error
+	if err != nil {
+		logrus.Error("cancel follow request failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// ListFollowRequests 按时间倒序分页查询发给userID的关注请求
+func ListFollowRequests(userID uint, page int, pageSize int) ([]models.FollowRequestModel, int64, error) {
+	var requests []models.FollowRequestModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.FollowRequestModel{}).
+		Where(models.FollowRequestModelTable_TargetID+" = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count follow requests failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Preload("Requester").Order(models.FollowRequestModelTable_CreatedAt + " DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	if err != nil {
+		logrus.Error("query follow requests failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return requests, total, nil
+}
+
+// takeFollowRequest 在tx中删除发给userID的关注请求并返回它，并发处理同一请求时只有一个成功
+func takeFollowRequest(tx *gorm.DB, userID uint, requestID uint) (models.FollowRequestModel, error) {
+	var request models.FollowRequestModel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND "+models.FollowRequestModelTable_TargetID+" = ?", requestID, userID).Take(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return request, errors.New(response.ErrFollowRequestNotFound)
+	}
+	if err != nil {
+		logrus.Error("query follow request failed, err: ", err)
+		return request, errors.New(response.ErrServerInternal)
+	}
+	result := tx.Delete(&request)
+	if result.Error != nil {
+		logrus.Error("delete follow request failed, err: ", result.Error)
+		return request, errors.New(response.ErrServerInternal)
+	}
+	if result.RowsAffected == 0 {
+		return request, errors.New(response.ErrFollowRequestNotFound)
+	}
+	return request, nil
+}
+
+// ApproveFollowRequest 同意关注请求并通知请求者。删除请求和关注在同一个事务中，关注失败时请求保留
+func ApproveFollowRequest(userID uint, requestID uint) error {
+	var request models.FollowRequestModel
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		if request, err = takeFollowRequest(tx, userID, requestID); err != nil {
+			return err
+		}
+		if IsBlocked(request.RequesterID, userID) {
+			return errors.New(response.ErrBlocked)
+		}
+		err = createFollow(tx, request.RequesterID, userID)
+		if err != nil && err.Error() != ErrDuplicate {
+			logrus.Error("follow after approval failed, err: ", err)
+			return errors.New(response.ErrServerInternal)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	wakeOutboxRelay()
+	target, _ := GetUserById(userID)
+	NotifyUsers([]uint{request.RequesterID}, models.NotificationFollowApproved,
+		fmt.Sprintf("%s 同意了你的关注请求", target.Username), "user", userID)
+	return nil
+}
+
+// RejectFollowRequest 拒绝关注请求，不通知请求者
+func RejectFollowRequest(userID uint, requestID uint) error {
+	return database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		_, err := takeFollowRequest(tx, userID, requestID)
+		return err
+	})
+}
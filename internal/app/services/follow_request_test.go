@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"gorm.io/gorm"
+)
+
+func createPrivateUser(t *testing.T, db *gorm.DB, username string) models.UserModel {
+	user := createTestUser(t, db, username)
+	require.NoError(t, SetAccountPrivate(user.ID, true))
+	return user
+}
+
+func pendingFollowRequest(t *testing.T, db *gorm.DB, requesterID uint, targetID uint) models.FollowRequestModel {
+	var request models.FollowRequestModel
+	require.NoError(t, db.Where(models.FollowRequestModelTable_RequesterID+" = ? AND "+models.FollowRequestModelTable_TargetID+" = ?",
+		requesterID, targetID).Take(&request).Error)
+	return request
+}
+
+func TestApproveFollowRequest(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := createPrivateUser(t, db, "owner")
+	alice := createTestUser(t, db, "alice")
+
+	pending, err := RequestFollow(alice.ID, owner.ID)
+	require.NoError(t, err)
+	require.True(t, pending)
+	assert.False(t, CanViewUserContent(alice.ID, owner.ID))
+
+	request := pendingFollowRequest(t, db, alice.ID, owner.ID)
+	require.NoError(t, ApproveFollowRequest(owner.ID, request.ID))
+	assert.True(t, IsFollowing(alice.ID, owner.ID))
+	assert.True(t, CanViewUserContent(alice.ID, owner.ID))
+	assert.Equal(t, uint(1), reloadUser(t, db, alice.ID).FollowerCount)
+	assert.Equal(t, uint(1), reloadUser(t, db, owner.ID).FanCount)
+
+	// 同一个请求只能处理一次
+	err = ApproveFollowRequest(owner.ID, request.ID)
+	assert.EqualError(t, err, response.ErrFollowRequestNotFound)
+	assert.Equal(t, uint(1), reloadUser(t, db, owner.ID).FanCount)
+}
+
+func TestApproveFollowRequest_KeepsRequestWhenFollowFails(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := createPrivateUser(t, db, "owner")
+	alice := createTestUser(t, db, "alice")
+
+	_, err := RequestFollow(alice.ID, owner.ID)
+	require.NoError(t, err)
+	request := pendingFollowRequest(t, db, alice.ID, owner.ID)
+	require.NoError(t, db.Create(&models.UserBlockModel{UserID: owner.ID, TargetID: alice.ID, Kind: models.BlockKindBlock}).Error)
+
+	err = ApproveFollowRequest(owner.ID, request.ID)
+	assert.EqualError(t, err, response.ErrBlocked)
+	assert.False(t, IsFollowing(alice.ID, owner.ID))
+	// 关注失败时请求没有被删除
+	pendingFollowRequest(t, db, alice.ID, owner.ID)
+}
+
+func TestRejectFollowRequest(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := createPrivateUser(t, db, "owner")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	_, err := RequestFollow(alice.ID, owner.ID)
+	require.NoError(t, err)
+	request := pendingFollowRequest(t, db, alice.ID, owner.ID)
+
+	// 只能处理发给自己的请求
+	assert.EqualError(t, RejectFollowRequest(bob.ID, request.ID), response.ErrFollowRequestNotFound)
+	require.NoError(t, RejectFollowRequest(owner.ID, request.ID))
+	assert.False(t, IsFollowing(alice.ID, owner.ID))
+	assert.EqualError(t, RejectFollowRequest(owner.ID, request.ID), response.ErrFollowRequestNotFound)
+}
+
+func TestSetAccountPublicApprovesPendingRequests(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := createPrivateUser(t, db, "owner")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	for _, u := range []models.UserModel{alice, bob} {
+		_, err := RequestFollow(u.ID, owner.ID)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, SetAccountPrivate(owner.ID, false))
+	assert.True(t, IsFollowing(alice.ID, owner.ID))
+	assert.True(t, IsFollowing(bob.ID, owner.ID))
+	assert.Equal(t, uint(2), reloadUser(t, db, owner.ID).FanCount)
+	var count int64
+	db.Model(&models.FollowRequestModel{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestCanViewUserContent(t *testing.T) {
+	db := dbtest.Open(t)
+	owner := createPrivateUser(t, db, "owner")
+	public := createTestUser(t, db, "public")
+	alice := createTestUser(t, db, "alice")
+	require.NoError(t, FollowUser("", alice.ID, owner.ID))
+
+	assert.True(t, CanViewUserContent(owner.ID, owner.ID))
+	assert.True(t, CanViewUserContent(alice.ID, owner.ID))
+	assert.False(t, CanViewUserContent(public.ID, owner.ID))
+	assert.False(t, CanViewUserContent(0, owner.ID))
+	assert.True(t, CanViewUserContent(0, public.ID))
+
+	// 私密账号的公开书评也只对粉丝可见
+	review := createTestReview(t, db, owner.ID)
+	assert.True(t, CanViewReview(alice.ID, &review))
+	assert.False(t, CanViewReview(public.ID, &review))
+	assert.False(t, CanViewReview(0, &review))
+}
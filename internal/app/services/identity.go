@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/pkg/oidc"
+	"github.com/sylvia-ymlin/Coconut-book-community/utils"
+	"gorm.io/gorm"
+)
+
+const oidcStateKeyFormat = "auth:oidc:state:%s"
+
+// OIDCStateTTL 从跳转到提供方到回调的最长时间
+const OIDCStateTTL = 10 * time.Minute
+
+// 自动生成用户名冲突时的重试次数
+const oidcUsernameRetries = 5
+
+// oidcLoginState 发起登录时保存的状态，回调时取出校验
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// 非0表示已登录用户绑定第三方账号
+	LinkUserID uint `json:"link_user_id"`
+	// 发起登录的浏览器cookie中随机值的哈希，回调必须来自同一个浏览器
+	BindingHash string `json:"binding_hash"`
+}
+
+var (
+	oidcProviders     map[string]*oidc.Provider
+	oidcProvidersOnce sync.Once
+)
+
+// GetOIDCProvider 按名字获取配置中的OIDC提供方
+func GetOIDCProvider(name string) (*oidc.Provider, bool) {
+	oidcProvidersOnce.Do(func() {
+		oidcProviders = make(map[string]*oidc.Provider)
+		for _, conf := range config.GetOIDCProviderConfigs() {
+			oidcProviders[conf.Name] = oidc.NewProvider(oidc.Config{
+				Issuer:       conf.Issuer,
+				ClientID:     conf.ClientID,
+				ClientSecret: conf.ClientSecret,
+				RedirectURL:  conf.RedirectURL,
+				Scopes:       conf.Scopes,
+				AuthURL:      conf.AuthURL,
+				TokenURL:     conf.TokenURL,
+				JWKSURL:      conf.JWKSURL,
+			})
+		}
+	})
+	provider, ok := oidcProviders[name]
+	return provider, ok
+}
+
+// StartOIDCLogin 生成state、nonce和PKCE verifier，返回提供方的授权地址和浏览器绑定值。
+// 绑定值需要保存在发起登录的浏览器的cookie中，回调时原样提交，防止登录CSRF；
+// linkUserID非0时，回调成功后把第三方账号绑定到该用户。
+func StartOIDCLogin(ctx context.Context, providerName string, linkUserID uint) (authURL string, binding string, err error) {
+	provider, ok := GetOIDCProvider(providerName)
+	if !ok {
+		return "", "", errors.New(response.ErrOIDCProviderNotFound)
+	}
+	var state oidcLoginState
+	state.Provider = providerName
+	state.LinkUserID = linkUserID
+	rawState, err := oidc.RandomString()
+	if err != nil {
+		return "", "", errors.New(response.ErrServerInternal)
+	}
+	if state.Nonce, err = oidc.RandomString(); err != nil {
+		return "", "", errors.New(response.ErrServerInternal)
+	}
+	if state.CodeVerifier, err = oidc.RandomString(); err != nil {
+		return "", "", errors.New(response.ErrServerInternal)
+	}
+	if binding, err = oidc.RandomString(); err != nil {
+		return "", "", errors.New(response.ErrServerInternal)
+	}
+	state.BindingHash = hashToken(binding)
+	authURL, err = provider.AuthCodeURL(ctx, rawState, state.Nonce, oidc.S256Challenge(state.CodeVerifier))
+	if err != nil {
+		logrus.Errorf("oidc provider %s unavailable, err: %v", providerName, err)
+		return "", "", errors.New(response.ErrOIDCLoginFailed)
+	}
+	if err := saveOIDCState(rawState, state); err != nil {
+		return "", "", err
+	}
+	return authURL, binding, nil
+}
+
+func saveOIDCState(rawState string, state oidcLoginState) error {
+	if err := GetUserCacheService().cache.Set(fmt.Sprintf(oidcStateKeyFormat, rawState), state, OIDCStateTTL); err != nil {
+		logrus.Error("save oidc state failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// takeOIDCState 取出state并校验提供方、浏览器绑定值和当前登录的用户。
+// state只能使用一次，取出和删除是原子的，并发的回调只有一个能通过；
+// 绑定账号时回调必须由发起绑定的用户登录后提交，否则受害者授权后第三方账号会绑定到攻击者的账号
+func takeOIDCState(providerName string, rawState string, binding string, currentUserID uint) (oidcLoginState, error) {
+	var state oidcLoginState
+	if rawState == "" || GetUserCacheService().cache.Take(fmt.Sprintf(oidcStateKeyFormat, rawState), &state) != nil ||
+		state.Provider != providerName {
+		return state, errors.New(response.ErrOIDCStateInvalid)
+	}
+	if binding == "" || state.BindingHash == "" ||
+		subtle.ConstantTimeCompare([]byte(hashToken(binding)), []byte(state.BindingHash)) != 1 {
+		return state, errors.New(response.ErrOIDCStateInvalid)
+	}
+	if state.LinkUserID != 0 && state.LinkUserID != currentUserID {
+		return state, errors.New(response.ErrOIDCStateInvalid)
+	}
+	return state, nil
+}
+
+// FinishOIDCLogin 校验回调的state和浏览器绑定值，用code换取id_token，返回对应的本地用户。
+// currentUserID为回调请求中登录的用户，未登录时为0
+func FinishOIDCLogin(ctx context.Context, providerName string, code string, rawState string, binding string, currentUserID uint) (models.UserModel, error) {
+	var user models.UserModel
+	provider, ok := GetOIDCProvider(providerName)
+	if !ok {
+		return user, errors.New(response.ErrOIDCProviderNotFound)
+	}
+	state, err := takeOIDCState(providerName, rawState, binding, currentUserID)
+	if err != nil {
+		return user, err
+	}
+
+	token, err := provider.Exchange(ctx, code, state.CodeVerifier)
+	if err != nil {
+		logrus.Warnf("oidc %s exchange failed, err: %v", providerName, err)
+		return user, errors.New(response.ErrOIDCLoginFailed)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		logrus.Warnf("oidc %s id_token rejected, err: %v", providerName, err)
+		return user, errors.New(response.ErrOIDCLoginFailed)
+	}
+	user, err = LoginWithIdentity(providerName, claims, state.LinkUserID)
+	if err != nil {
+		return user, err
+	}
+	return user, CheckLoginAllowed(user)
+}
+
+// CheckLoginAllowed 封禁中或被管理员要求重置密码的用户不能登录，密码登录和第三方登录都需要检查。
+// 第三方登录不使用密码，但重置前的登录状态可能已经泄露，同样需要先重置
+func CheckLoginAllowed(user models.UserModel) error {
+	if user.IsBanned(time.Now()) {
+		return errors.New(response.ErrUserBanned)
+	}
+	if user.PasswordResetRequired {
+		return errors.New(response.ErrPasswordResetRequired)
+	}
+	return nil
+}
+
+// LoginWithIdentity 按第三方身份查找本地用户，没有绑定时依次:
+// 绑定到linkUserID；绑定到邮箱相同且双方都已验证邮箱的用户；创建新用户。
+func LoginWithIdentity(providerName string, claims oidc.Claims, linkUserID uint) (models.UserModel, error) {
+	var user models.UserModel
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentityModel
+		err := tx.Where(models.UserIdentityModelTable_Provider+" = ? AND "+models.UserIdentityModelTable_Subject+" = ?", providerName, claims.Subject).
+			Take(&identity).Error
+		if err == nil {
+			if linkUserID != 0 && identity.UserID != linkUserID {
+				return errors.New(response.ErrIdentityLinked)
+			}
+			return tx.Where("id = ?", identity.UserID).Take(&user).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		switch {
+		case linkUserID != 0:
+			err = tx.Where("id = ?", linkUserID).Take(&user).Error
+		case claims.EmailVerified && claims.Email != "":
+			err = tx.Where(models.UserModelTable_Email+" = ? AND "+models.UserModelTable_EmailVerifiedAt+" IS NOT NULL", claims.Email).
+				Take(&user).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				user, err = createUserForIdentity(tx, providerName, claims)
+			}
+		default:
+			user, err = createUserForIdentity(tx, providerName, claims)
+		}
+		if err != nil {
+			return err
+		}
+		identity = models.UserIdentityModel{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		if err.Error() == response.ErrIdentityLinked {
+			return user, err
+		}
+		logrus.Error("login with identity failed, err: ", err)
+		return user, errors.New(response.ErrServerInternal)
+	}
+	return user, nil
+}
+
+// createUserForIdentity 为第三方账号创建本地用户，密码随机，用户可以通过找回密码设置
+func createUserForIdentity(tx *gorm.DB, providerName string, claims oidc.Claims) (models.UserModel, error) {
+	rawPassword, err := randomHex(userTokenBytes)
+	if err != nil {
+		return models.UserModel{}, err
+	}
+	user := models.UserModel{Password: utils.BcryptHash(rawPassword)}
+	if claims.EmailVerified && claims.Email != "" && CheckEmail(claims.Email) {
+		var count int64
+		err := tx.Model(&models.UserModel{}).
+			Where(models.UserModelTable_Email+" = ? AND "+models.UserModelTable_EmailVerifiedAt+" IS NOT NULL", claims.Email).
+			Count(&count).Error
+		if err != nil {
+			return user, err
+		}
+		if count == 0 {
+			now := time.Now()
+			user.Email = claims.Email
+			user.EmailVerifiedAt = &now
+		}
+	}
+	base := identityUsername(providerName, claims)
+	for i := 0; i < oidcUsernameRetries; i++ {
+		user.Username = base
+		if i > 0 {
+			suffix, err := randomHex(2)
+			if err != nil {
+				return user, err
+			}
+			user.Username = base + "_" + suffix
+		}
+		var count int64
+		if err := tx.Model(&models.UserModel{}).Unscoped().Where(models.UserModelTable_Username+" = ?", user.Username).Count(&count).Error; err != nil {
+			return user, err
+		}
+		if count == 0 {
+			return user, tx.Create(&user).Error
+		}
+	}
+	return user, fmt.Errorf("no available username for %s", base)
+}
+
+// identityUsername 从preferred_username、name或邮箱前缀生成合法的用户名
+func identityUsername(providerName string, claims oidc.Claims) string {
+	candidates := []string{claims.PreferredUsername, claims.Name}
+	if local, _, found := strings.Cut(claims.Email, "@"); found {
+		candidates = append(candidates, local)
+	}
+	for _, c := range candidates {
+		c = strings.Join(strings.Fields(c), "_")
+		// 留出冲突时追加后缀的长度
+		for len(c) > 26 {
+			_, size := utf8.DecodeLastRuneInString(c)
+			c = c[:len(c)-size]
+		}
+		if CheckUsername(c) {
+			return c
+		}
+	}
+	return providerName + "_user"
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+)
+
+func saveTestOIDCState(t *testing.T, rawState string, binding string, linkUserID uint) {
+	require.NoError(t, saveOIDCState(rawState, oidcLoginState{
+		Provider:    "test",
+		LinkUserID:  linkUserID,
+		BindingHash: hashToken(binding),
+	}))
+}
+
+func TestTakeOIDCState(t *testing.T) {
+	saveTestOIDCState(t, "state-ok", "binding", 0)
+	_, err := takeOIDCState("test", "state-ok", "binding", 0)
+	require.NoError(t, err)
+
+	// state只能使用一次
+	_, err = takeOIDCState("test", "state-ok", "binding", 0)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+}
+
+func TestTakeOIDCState_Binding(t *testing.T) {
+	// 没有发起登录的浏览器的cookie，例如攻击者把自己的回调地址发给受害者
+	saveTestOIDCState(t, "state-missing", "binding", 0)
+	_, err := takeOIDCState("test", "state-missing", "", 0)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+
+	saveTestOIDCState(t, "state-mismatch", "binding", 0)
+	_, err = takeOIDCState("test", "state-mismatch", "other", 0)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+
+	saveTestOIDCState(t, "state-provider", "binding", 0)
+	_, err = takeOIDCState("other", "state-provider", "binding", 0)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+}
+
+func TestTakeOIDCState_LinkRequiresSameUser(t *testing.T) {
+	saveTestOIDCState(t, "link-anonymous", "binding", 7)
+	_, err := takeOIDCState("test", "link-anonymous", "binding", 0)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+
+	saveTestOIDCState(t, "link-other", "binding", 7)
+	_, err = takeOIDCState("test", "link-other", "binding", 8)
+	assert.EqualError(t, err, response.ErrOIDCStateInvalid)
+
+	saveTestOIDCState(t, "link-ok", "binding", 7)
+	state, err := takeOIDCState("test", "link-ok", "binding", 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), state.LinkUserID)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LikeReview 点赞书评。点赞记录、书评的点赞数和 ReviewLiked 事件在同一个事务中写入，
+// 已经点过赞时返回ErrDuplicate
+func LikeReview(userID, reviewID uint) error {
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		like := models.UserLikeModel{UserID: userID, ReviewID: reviewID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ErrDuplicate)
+		}
+		if err := updateLikeCount(tx, reviewID, 1); err != nil {
+			return err
+		}
+		return writeEvent(tx, events.ReviewLiked, events.ReviewLikePayload{ReviewID: reviewID, UserID: userID})
+	})
+	if err != nil {
+		return err
+	}
+	wakeOutboxRelay()
+	return nil
+}
+
+// UnlikeReview 取消点赞书评，没有点过赞时返回ErrDeleteNotExists
+func UnlikeReview(userID, reviewID uint) error {
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		user_id := models.UserLikeModelTable_UserID
+		review_id := models.UserLikeModelTable_ReviewID
+		result := tx.Where(user_id+" = ? AND "+review_id+" = ?", userID, reviewID).Delete(&models.UserLikeModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ErrDeleteNotExists)
+		}
+		if err := updateLikeCount(tx, reviewID, -1); err != nil {
+			return err
+		}
+		return writeEvent(tx, events.ReviewUnliked, events.ReviewLikePayload{ReviewID: reviewID, UserID: userID})
+	})
+	if err != nil {
+		return err
+	}
+	wakeOutboxRelay()
+	return nil
+}
+
+// updateLikeCount 修改书评的点赞数，不更新updated_at
+func updateLikeCount(tx *gorm.DB, reviewID uint, delta int) error {
+	like_count := models.BookReviewModelTable_LikeCount
+	return tx.Model(&models.BookReviewModel{}).Where("id = ?", reviewID).
+		UpdateColumn(like_count, gorm.Expr(like_count+" + ?", delta)).Error
+}
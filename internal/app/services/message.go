@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// processOnce 在一个事务中写入消息Key并执行fn，Key已经存在说明这条消息生效过，不再执行fn。
+// 重试和重放的消息Key不变，所以点赞数、关注数不会重复增加。key为空时直接执行fn。
+// 返回fn是否执行成功
+func processOnce(key string, fn func(tx *gorm.DB) error) (bool, error) {
+	applied := false
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		if key != "" {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProcessedMessageModel{Key: key})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// CleanupProcessedMessages 删除retention之前处理的消息Key
+func CleanupProcessedMessages(retention time.Duration) {
+	result := database.GetMysqlDB().Where(models.ProcessedMessageModelTable_CreatedAt+" < ?", time.Now().Add(-retention)).
+		Delete(&models.ProcessedMessageModel{})
+	if result.Error != nil {
+		logrus.Error("cleanup processed messages failed, err: ", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.Infof("cleaned up %d processed message keys", result.RowsAffected)
+	}
+}
+
+// RunProcessedMessageCleanup 每隔interval清理一次过期的消息Key，直到ctx结束
+func RunProcessedMessageCleanup(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			CleanupProcessedMessages(retention)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetReviewHidden 版主隐藏或恢复书评
+func SetReviewHidden(actorID uint, reviewID uint, hidden bool) error {
+	return setHidden(&models.BookReviewModel{}, reviewID, actorID, hidden, response.ErrReviewNotFound)
+}
+
+// SetCommentHidden 版主隐藏或恢复评论
+func SetCommentHidden(actorID uint, commentID uint, hidden bool) error {
+	return setHidden(&models.CommentModel{}, commentID, actorID, hidden, response.ErrCommentNotFound)
+}
+
+func setHidden(model interface{}, id uint, actorID uint, hidden bool, notFound string) error {
+	updates := map[string]interface{}{"hidden_at": nil, "hidden_by": 0}
+	if hidden {
+		updates = map[string]interface{}{"hidden_at": time.Now(), "hidden_by": actorID}
+	}
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		found, err := updateHidden(tx, model, id, "", updates)
+		if err == nil && !found {
+			return gorm.ErrRecordNotFound
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(notFound)
+		}
+		logrus.Error("update hidden state failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// updateHidden 在事务中修改书评或评论的隐藏状态，cond为额外的条件，返回是否有记录被修改。
+// 评论的可见状态变化时同步书评和评论者的评论数
+func updateHidden(tx *gorm.DB, model interface{}, id uint, cond string, updates map[string]interface{}) (bool, error) {
+	comment, isComment := model.(*models.CommentModel)
+	var wasVisible bool
+	if isComment {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(comment, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// Updates会把新的值写回comment，先记下修改前的状态
+		wasVisible = comment.HiddenAt == nil
+	}
+	query := tx.Model(model).Where("id = ?", id)
+	if cond != "" {
+		query = query.Where(cond)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if isComment {
+		visible := updates["hidden_at"] == nil
+		if wasVisible != visible {
+			delta := 1
+			if !visible {
+				delta = -1
+			}
+			return true, adjustCommentCount(tx, comment.ReviewID, comment.UserID, delta)
+		}
+	}
+	return true, nil
+}
+
+// adjustCommentCount 可见评论增加或减少时更新书评和评论者的评论数。
+// 评论数只统计未删除书评下的可见评论，书评已删除时不更新，恢复书评时重新计算
+func adjustCommentCount(tx *gorm.DB, reviewID uint, userID uint, delta int) error {
+	result := tx.Model(&models.BookReviewModel{}).Where("id = ?", reviewID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&models.UserModel{}).Where("id = ?", userID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta)).Error
+}
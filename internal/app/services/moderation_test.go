@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
+	"gorm.io/gorm"
+)
+
+func commentCounts(t *testing.T, db *gorm.DB, reviewID uint, userID uint) (uint, uint) {
+	var review models.BookReviewModel
+	require.NoError(t, db.Take(&review, reviewID).Error)
+	return review.CommentCount, reloadUser(t, db, userID).CommentCount
+}
+
+func TestCommentCountOnlyCountsVisibleComments(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	review := createTestReview(t, db, author.ID)
+	comment := models.CommentModel{ReviewID: review.ID, UserID: alice.ID, Content: "nice"}
+	require.NoError(t, db.Create(&comment).Error)
+	require.NoError(t, adjustCommentCount(db, review.ID, alice.ID, 1))
+
+	require.NoError(t, SetCommentHidden(author.ID, comment.ID, true))
+	reviewCount, userCount := commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(0), reviewCount)
+	assert.Equal(t, uint(0), userCount)
+
+	// 重复隐藏不重复计数
+	require.NoError(t, SetCommentHidden(author.ID, comment.ID, true))
+	reviewCount, _ = commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(0), reviewCount)
+
+	// 删除隐藏的评论不影响计数
+	require.NoError(t, AdminDeleteComment(comment.ID))
+	reviewCount, userCount = commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(0), reviewCount)
+	assert.Equal(t, uint(0), userCount)
+	require.NoError(t, AdminRestoreComment(comment.ID))
+
+	require.NoError(t, SetCommentHidden(author.ID, comment.ID, false))
+	reviewCount, userCount = commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(1), reviewCount)
+	assert.Equal(t, uint(1), userCount)
+
+	require.NoError(t, AdminDeleteComment(comment.ID))
+	reviewCount, userCount = commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(0), reviewCount)
+	assert.Equal(t, uint(0), userCount)
+}
+
+func TestHeldCommentCountedAfterApproval(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	review := createTestReview(t, db, author.ID)
+	now := time.Now()
+	comment := models.CommentModel{ReviewID: review.ID, UserID: alice.ID, Content: "held", HiddenAt: &now}
+	require.NoError(t, db.Create(&comment).Error)
+	HoldForReview(models.ReportTargetComment, comment.ID, alice.ID, textfilter.Result{Reasons: []string{textfilter.ReasonLinks}})
+
+	var report models.ReportModel
+	require.NoError(t, db.Where("target_id = ?", comment.ID).Take(&report).Error)
+	_, _, err := ResolveReport(author.ID, report.ID, models.ReportStatusDismissed, "")
+	require.NoError(t, err)
+
+	reviewCount, userCount := commentCounts(t, db, review.ID, alice.ID)
+	assert.Equal(t, uint(1), reviewCount)
+	assert.Equal(t, uint(1), userCount)
+}
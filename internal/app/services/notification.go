@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+)
+
+// NotifyUsers 给多个用户发送相同的站内通知，失败只记录日志
+func NotifyUsers(userIDs []uint, notificationType string, content string, refType string, refID uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+	notifications := make([]models.NotificationModel, 0, len(userIDs))
+	for _, id := range userIDs {
+		notifications = append(notifications, models.NotificationModel{
+			UserID:  id,
+			Type:    notificationType,
+			Content: content,
+			RefType: refType,
+			RefID:   refID,
+		})
+	}
+	if err := database.GetMysqlDB().Create(&notifications).Error; err != nil {
+		logrus.Error("create notifications failed, err: ", err)
+	}
+}
+
+// ListNotifications 按时间倒序分页查询用户的通知，同时返回未读数
+func ListNotifications(userID uint, unreadOnly bool, page int, pageSize int) (notifications []models.NotificationModel, total int64, unread int64, err error) {
+	db := database.GetMysqlDB()
+	mine := db.Model(&models.NotificationModel{}).Where(models.NotificationModelTable_UserID+" = ?", userID)
+	unreadQuery := db.Model(&models.NotificationModel{}).
+		Where(models.NotificationModelTable_UserID+" = ? AND "+models.NotificationModelTable_ReadAt+" IS NULL", userID)
+	if err = unreadQuery.Count(&unread).Error; err != nil {
+		logrus.Error("count notifications failed, err: ", err)
+		return nil, 0, 0, errors.New(response.ErrServerInternal)
+	}
+	query := mine
+	if unreadOnly {
+		query = unreadQuery
+		total = unread
+	} else if err = query.Count(&total).Error; err != nil {
+		logrus.Error("count notifications failed, err: ", err)
+		return nil, 0, 0, errors.New(response.ErrServerInternal)
+	}
+	err = query.Order(models.NotificationModelTable_CreatedAt + " DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&notifications).Error
+	if err != nil {
+		logrus.Error("query notifications failed, err: ", err)
+		return nil, 0, 0, errors.New(response.ErrServerInternal)
+	}
+	return notifications, total, unread, nil
+}
+
+// MarkNotificationsRead 把用户所有未读通知标记为已读
+func MarkNotificationsRead(userID uint) error {
+	err := database.GetMysqlDB().Model(&models.NotificationModel{}).
+		Where(models.NotificationModelTable_UserID+" = ? AND "+models.NotificationModelTable_ReadAt+" IS NULL", userID).
+		Update(models.NotificationModelTable_ReadAt, time.Now()).Error
+	if err != nil {
+		logrus.Error("mark notifications read failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
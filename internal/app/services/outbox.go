@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/metrics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultOutboxRelayInterval = 5 * time.Second
+	defaultOutboxBatchSize     = 100
+	defaultOutboxRetention     = 72 * time.Hour
+	// 清理已发布事件的间隔
+	outboxCleanupInterval = time.Hour
+	// 保存的发布错误的最大长度
+	maxOutboxErrorLength = 500
+	// relay领取事件后的租约，超过后事件可以被重新领取
+	outboxClaimLease = time.Minute
+	// 发布失败后的第一次重试间隔和最大重试间隔
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = 10 * time.Minute
+)
+
+// 有新事件时唤醒relay，容量为1，写入不会阻塞
+var outboxWake = make(chan struct{}, 1)
+
+// writeEvent 在tx中写入领域事件，事务提交后调用 wakeOutboxRelay
+func writeEvent(tx *gorm.DB, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEventModel{Type: eventType, Payload: string(data)}).Error
+}
+
+// wakeOutboxRelay 通知relay有新提交的事件
+func wakeOutboxRelay() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// RelayOutboxEvents 领取并发布最多batch个到期的未发布事件，返回发布成功的数量。
+// 发布在事务之外进行，某个事件失败时按退避时间重试，不影响其他事件，所以事件之间不保证顺序
+func RelayOutboxEvents(ctx context.Context, batch int, publish func(ctx context.Context, e events.Event) error) (int, error) {
+	rows, err := claimOutboxEvents(batch, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	db := database.GetMysqlDB()
+	published := 0
+	var errs []error
+	for _, row := range rows {
+		e := events.Event{ID: row.ID, Type: row.Type, Payload: json.RawMessage(row.Payload), OccurredAt: row.CreatedAt}
+		if err := publish(ctx, e); err != nil {
+			metrics.OutboxPublishFailuresTotal.Inc()
+			logrus.Errorf("publish outbox event %d failed, err: %v", row.ID, err)
+			msg := err.Error()
+			if len(msg) > maxOutboxErrorLength {
+				msg = msg[:maxOutboxErrorLength]
+			}
+			errs = append(errs, db.Model(&row).Updates(map[string]interface{}{
+				models.OutboxEventModelTable_Attempts:      gorm.Expr(models.OutboxEventModelTable_Attempts+" + ?", 1),
+				models.OutboxEventModelTable_LastError:     msg,
+				models.OutboxEventModelTable_NextAttemptAt: time.Now().Add(outboxBackoff(row.Attempts + 1)),
+				models.OutboxEventModelTable_ClaimedAt:     nil,
+			}).Error)
+			continue
+		}
+		// 发布成功但标记失败时，租约过期后事件会再次发布，订阅者用事件ID保证只生效一次
+		err := db.Model(&row).Updates(map[string]interface{}{
+			models.OutboxEventModelTable_PublishedAt: time.Now(),
+			models.OutboxEventModelTable_ClaimedAt:   nil,
+		}).Error
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		published++
+	}
+	return published, errors.Join(errs...)
+}
+
+// claimOutboxEvents 在短事务中领取最多batch个到期的未发布事件并记录领取时间。
+// 查询时锁定并跳过其他实例正在领取的事件，领取后租约内其他实例不会再领取，
+// 实例在发布过程中退出时，租约过期后由其他实例重新领取
+func claimOutboxEvents(batch int, now time.Time) ([]models.OutboxEventModel, error) {
+	var rows []models.OutboxEventModel
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		claimed_at := models.OutboxEventModelTable_ClaimedAt
+		next_attempt_at := models.OutboxEventModelTable_NextAttemptAt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(models.OutboxEventModelTable_PublishedAt+" IS NULL").
+			Where("("+claimed_at+" IS NULL OR "+claimed_at+" < ?)", now.Add(-outboxClaimLease)).
+			Where("("+next_attempt_at+" IS NULL OR "+next_attempt_at+" <= ?)", now).
+			Order(models.OutboxEventModelTable_ID).Limit(batch).Find(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		return tx.Model(&models.OutboxEventModel{}).Where(models.OutboxEventModelTable_ID+" IN ?", ids).
+			Update(claimed_at, now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// outboxBackoff 第attempts次发布失败后等待的时间，每次翻倍，最多outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
+
+// CleanupOutboxEvents 删除retention之前已经发布的事件
+func CleanupOutboxEvents(retention time.Duration) {
+	result := database.GetMysqlDB().
+		Where(models.OutboxEventModelTable_PublishedAt+" < ?", time.Now().Add(-retention)).
+		Delete(&models.OutboxEventModel{})
+	if result.Error != nil {
+		logrus.Error("cleanup outbox events failed, err: ", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.Infof("cleaned up %d published outbox events", result.RowsAffected)
+	}
+}
+
+// RunOutboxRelay 有新事件或每隔relay_interval发布一次未发布的事件，并定期清理已发布的事件，直到ctx结束
+func RunOutboxRelay(ctx context.Context, publish func(ctx context.Context, e events.Event) error) {
+	conf := config.GetOutboxConfig()
+	interval := parseOutboxDuration(conf.RelayInterval, defaultOutboxRelayInterval)
+	retention := parseOutboxDuration(conf.Retention, defaultOutboxRetention)
+	batch := conf.BatchSize
+	if batch <= 0 {
+		batch = defaultOutboxBatchSize
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(outboxCleanupInterval)
+	defer cleanup.Stop()
+	for {
+		// 一批发满说明可能还有，继续发布
+		for {
+			n, err := RelayOutboxEvents(ctx, batch, publish)
+			if err != nil {
+				logrus.Error("relay outbox events failed, err: ", err)
+			}
+			if err != nil || n < batch || ctx.Err() != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-outboxWake:
+		case <-ticker.C:
+		case <-cleanup.C:
+			CleanupOutboxEvents(retention)
+		}
+	}
+}
+
+func parseOutboxDuration(s string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return defaultValue
+	}
+	return d
+}
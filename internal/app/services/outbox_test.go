@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"gorm.io/gorm"
+)
+
+func writeTestEvents(t *testing.T, db *gorm.DB, n int) []models.OutboxEventModel {
+	for i := 0; i < n; i++ {
+		require.NoError(t, writeEvent(db, events.UserFollowed, events.FollowPayload{UserID: uint(i + 1), ToUserID: 100}))
+	}
+	return loadOutboxEvents(t, db)
+}
+
+func loadOutboxEvents(t *testing.T, db *gorm.DB) []models.OutboxEventModel {
+	var rows []models.OutboxEventModel
+	require.NoError(t, db.Order(models.OutboxEventModelTable_ID).Find(&rows).Error)
+	return rows
+}
+
+func TestRelayOutboxEvents(t *testing.T) {
+	db := dbtest.Open(t)
+	writeTestEvents(t, db, 3)
+
+	var got []events.Event
+	n, err := RelayOutboxEvents(context.Background(), 10, func(ctx context.Context, e events.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.Len(t, got, 3)
+	var payload events.FollowPayload
+	require.NoError(t, got[0].Decode(&payload))
+	assert.Equal(t, uint(1), payload.UserID)
+
+	for _, row := range loadOutboxEvents(t, db) {
+		assert.NotNil(t, row.PublishedAt)
+		assert.Nil(t, row.ClaimedAt)
+	}
+
+	// 已发布的事件不再发布
+	n, err = RelayOutboxEvents(context.Background(), 10, func(ctx context.Context, e events.Event) error {
+		t.Fatalf("event %d published twice", e.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestRelayOutboxEvents_FailedEventDoesNotBlockOthers(t *testing.T) {
+	db := dbtest.Open(t)
+	rows := writeTestEvents(t, db, 3)
+	failing := rows[0].ID
+
+	var published []uint
+	n, err := RelayOutboxEvents(context.Background(), 10, func(ctx context.Context, e events.Event) error {
+		if e.ID == failing {
+			return errors.New("broker down")
+		}
+		published = append(published, e.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []uint{rows[1].ID, rows[2].ID}, published)
+
+	rows = loadOutboxEvents(t, db)
+	assert.Nil(t, rows[0].PublishedAt)
+	assert.Nil(t, rows[0].ClaimedAt)
+	assert.Equal(t, 1, rows[0].Attempts)
+	assert.Equal(t, "broker down", rows[0].LastError)
+	require.NotNil(t, rows[0].NextAttemptAt)
+	assert.True(t, rows[0].NextAttemptAt.After(time.Now()))
+
+	// 退避时间内不重试
+	n, err = RelayOutboxEvents(context.Background(), 10, func(ctx context.Context, e events.Event) error {
+		t.Fatalf("event %d retried before backoff", e.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// 到期后重试
+	require.NoError(t, db.Model(&rows[0]).Update(models.OutboxEventModelTable_NextAttemptAt, time.Now().Add(-time.Second)).Error)
+	n, err = RelayOutboxEvents(context.Background(), 10, func(ctx context.Context, e events.Event) error {
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestClaimOutboxEvents_Lease(t *testing.T) {
+	db := dbtest.Open(t)
+	writeTestEvents(t, db, 2)
+	now := time.Now()
+
+	claimed, err := claimOutboxEvents(10, now)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+	for _, row := range loadOutboxEvents(t, db) {
+		assert.NotNil(t, row.ClaimedAt)
+	}
+
+	// 租约内其他实例领取不到
+	claimed, err = claimOutboxEvents(10, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	// 领取的实例没有发布就退出，租约过期后重新领取
+	claimed, err = claimOutboxEvents(10, now.Add(outboxClaimLease+time.Second))
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, outboxBaseBackoff, outboxBackoff(1))
+	assert.Equal(t, 2*outboxBaseBackoff, outboxBackoff(2))
+	assert.Equal(t, 4*outboxBaseBackoff, outboxBackoff(3))
+	assert.Equal(t, outboxMaxBackoff, outboxBackoff(100))
+}
+
+func TestLikeReview(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	review := createTestReview(t, db, author.ID)
+
+	require.NoError(t, LikeReview(alice.ID, review.ID))
+	err := LikeReview(alice.ID, review.ID)
+	assert.EqualError(t, err, ErrDuplicate)
+
+	var reloaded models.BookReviewModel
+	require.NoError(t, db.Take(&reloaded, review.ID).Error)
+	assert.EqualValues(t, 1, reloaded.LikeCount)
+	rows := loadOutboxEvents(t, db)
+	require.Len(t, rows, 1)
+	assert.Equal(t, events.ReviewLiked, rows[0].Type)
+
+	require.NoError(t, UnlikeReview(alice.ID, review.ID))
+	err = UnlikeReview(alice.ID, review.ID)
+	assert.EqualError(t, err, ErrDeleteNotExists)
+
+	require.NoError(t, db.Take(&reloaded, review.ID).Error)
+	assert.EqualValues(t, 0, reloaded.LikeCount)
+	rows = loadOutboxEvents(t, db)
+	require.Len(t, rows, 2)
+	assert.Equal(t, events.ReviewUnliked, rows[1].Type)
+}
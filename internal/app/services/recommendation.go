@@ -1,13 +1,19 @@
 package services
 
 import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	// "bytes"
 	// "context"
 	// "encoding/json"
 	// "fmt"
 	// "net/http"
-	// "time"
 )
 
 // RecommendationService 推荐服务
@@ -27,16 +33,36 @@ func NewRecommendationService() *RecommendationService {
 	}
 }
 
+// recommendationClient 配置启用推荐服务时使用的客户端，为nil时返回mock数据，配置热更新时替换
+var recommendationClient atomic.Pointer[RecommendationClient]
+
+// ApplyRecommendConfig 按配置创建或关闭推荐服务客户端，启动和配置热更新时调用
+func ApplyRecommendConfig(conf config.RecommendConfig) {
+	if !conf.Enabled || conf.Mock.Enabled || conf.APIURL == "" {
+		recommendationClient.Store(nil)
+		return
+	}
+	client := NewRecommendationClient(conf.APIURL)
+	if timeout, err := time.ParseDuration(conf.Timeout); err == nil && timeout > 0 {
+		client.httpClient.Timeout = timeout
+	}
+	recommendationClient.Store(client)
+}
+
 // GetPersonalizedRecommendations 获取个性化推荐
 // TODO: 未来可对接真实推荐系统
 // 当前返回mock数据
 func (s *RecommendationService) GetPersonalizedRecommendations(userID uint, topK int) ([]*models.Book, error) {
-	// 检查配置：是否启用真实推荐
-	// if config.GetRecommendConfig().Enabled {
-	// 	return s.getRemoteRecommendations(userID, topK)
-	// }
+	// 启用推荐服务时调用推荐服务，失败时返回mock数据
+	if client := recommendationClient.Load(); client != nil {
+		books, err := client.GetPersonalRecommendations(userID, topK)
+		if err == nil {
+			return bookPointers(books), nil
+		}
+		logrus.Warnf("recommendation service failed, use mock data: %v", err)
+	}
 
-	// 当前返回mock数据
+	// 未启用时返回mock数据
 	return s.getMockRecommendations(userID, topK), nil
 }
 
@@ -44,14 +70,32 @@ func (s *RecommendationService) GetPersonalizedRecommendations(userID uint, topK
 // TODO: 未来可对接RAG检索系统
 // 当前返回mock数据
 func (s *RecommendationService) SemanticSearch(query string, topK int) ([]*models.Book, error) {
-	// if config.GetRecommendConfig().Enabled {
-	// 	return s.getRemoteSearch(query, topK)
-	// }
+	if client := recommendationClient.Load(); client != nil {
+		books, err := client.SearchBooks(query, topK)
+		if err == nil {
+			return bookPointers(books), nil
+		}
+		logrus.Warnf("recommendation search failed, use mock data: %v", err)
+	}
 
-	// 当前返回mock搜索结果
+	// 未启用时返回mock搜索结果
 	return s.getMockSearchResults(query, topK), nil
 }
 
+// ChatWithBook 与图书对话，需要启用推荐服务，没有mock数据
+func (s *RecommendationService) ChatWithBook(isbn, message string, userID uint) (string, error) {
+	client := recommendationClient.Load()
+	if client == nil {
+		return "", errors.New(response.ErrRecommendationDisabled)
+	}
+	reply, err := client.ChatWithBook(isbn, message, userID)
+	if err != nil {
+		logrus.Errorf("chat with book %s failed, err: %v", isbn, err)
+		return "", errors.New(response.ErrChatWithBook)
+	}
+	return reply, nil
+}
+
 // getMockRecommendations 生成mock推荐数据
 func (s *RecommendationService) getMockRecommendations(userID uint, topK int) []*models.Book {
 	mockBooks := []*models.Book{
@@ -271,3 +315,11 @@ func (s *RecommendationService) getMockSearchResults(query string, topK int) []*
 // 	json.NewDecoder(resp.Body).Decode(&result)
 // 	return result.Books, nil
 // }
+
+func bookPointers(books []models.Book) []*models.Book {
+	result := make([]*models.Book, len(books))
+	for i := range books {
+		result[i] = &books[i]
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -206,7 +207,7 @@ func (r *RecommendationClient) ChatWithBook(isbn, message string, userID uint) (
 	}
 
 	// 发送 HTTP POST 请求
-	resp, err := r.httpClient.Post(apiURL, "application/json", nil)
+	resp, err := r.httpClient.Post(apiURL, "application/json", bytes.NewReader(reqBody))
 	if err != nil {
 		logger.Printf("Failed to call chat API: %v", err)
 		return "", fmt.Errorf("failed to call chat API: %w", err)
@@ -24,7 +24,8 @@ func CheckUsername(username string) bool {
 	return !strings.Contains(username, " ") && len(username) >= 2 && len(username) <= 32
 }
 
-func CreateUser(username string, rawPassword string) (response.RegisterResponse, error) {
+// CreateUser 创建用户，email可以为空；填写邮箱时发送验证邮件
+func CreateUser(username string, rawPassword string, email string) (response.RegisterResponse, error) {
 	var res response.RegisterResponse
 	if !CheckUsername(username) {
 		return res, errors.New(response.ErrInvalidUsername)
@@ -32,6 +33,14 @@ func CreateUser(username string, rawPassword string) (response.RegisterResponse,
 	if !CheckPassword(rawPassword) {
 		return res, errors.New(response.ErrInvalidPassword)
 	}
+	if email != "" {
+		if !CheckEmail(email) {
+			return res, errors.New(response.ErrInvalidEmail)
+		}
+		if QueryEmailVerifiedByOther(email, 0) {
+			return res, errors.New(response.ErrEmailExists)
+		}
+	}
 	if QueryUserExistByUsername(username) {
 		return res, errors.New(response.ErrUserExists)
 	}
@@ -39,12 +48,19 @@ func CreateUser(username string, rawPassword string) (response.RegisterResponse,
 	user := models.UserModel{
 		Username: username,
 		Password: pwdHash,
+		Email:    email,
 	}
 	id, err := createUser(user)
 	if err != nil {
 		logrus.Error("create user failed, err: ", err)
 		return res, errors.New(response.ErrServerInternal)
 	}
+	if email != "" {
+		// 验证邮件发送失败不影响注册，用户可以重新发送
+		if err := SendVerifyEmail(id); err != nil {
+			logrus.Error("send verify email failed, err: ", err)
+		}
+	}
 	res.CommonResponse.StatusCode = response.Success
 	res.UserID = int(id)
 	return res, nil
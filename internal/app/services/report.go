@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/audit"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 默认被多少个不同用户举报后自动隐藏
+const defaultAutoHideReports = 5
+
+// CreateReport 举报书评、评论或用户，同一用户对同一对象只能举报一次。
+// 未处理的举报数达到阈值时自动隐藏书评或评论
+func CreateReport(reporterID uint, targetType string, targetID uint, reason string, detail string) (models.ReportModel, error) {
+	var report models.ReportModel
+	if !models.IsValidReportTarget(targetType) {
+		return report, errors.New(response.ErrInvalidParams)
+	}
+	if !models.IsValidReportReason(reason) {
+		return report, errors.New(response.ErrReportReason)
+	}
+	ownerID, err := reportTargetOwner(targetType, targetID)
+	if err != nil {
+		return report, err
+	}
+	if ownerID == reporterID {
+		return report, errors.New(response.ErrReportSelf)
+	}
+	db := database.GetMysqlDB()
+	exists, err := reportExists(db, reporterID, targetType, targetID)
+	if err != nil {
+		logrus.Error("query report failed, err: ", err)
+		return report, errors.New(response.ErrServerInternal)
+	}
+	if exists {
+		return report, errors.New(response.ErrReportDuplicate)
+	}
+	report = models.ReportModel{
+		ReporterID:   reporterID,
+		TargetType:   targetType,
+		TargetID:     targetID,
+		TargetUserID: ownerID,
+		Reason:       reason,
+		Detail:       detail,
+		Status:       models.ReportStatusOpen,
+	}
+	if err := db.Create(&report).Error; err != nil {
+		// 并发重复提交时唯一索引冲突
+		if exists, _ := reportExists(db, reporterID, targetType, targetID); exists {
+			return report, errors.New(response.ErrReportDuplicate)
+		}
+		logrus.Error("create report failed, err: ", err)
+		return report, errors.New(response.ErrServerInternal)
+	}
+	autoHideReported(targetType, targetID)
+	return report, nil
+}
+
+func reportExists(db *gorm.DB, reporterID uint, targetType string, targetID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.ReportModel{}).
+		Where(models.ReportModelTable_ReporterID+" = ? AND "+models.ReportModelTable_TargetType+" = ? AND "+models.ReportModelTable_TargetID+" = ?",
+			reporterID, targetType, targetID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// reportTargetOwner 返回被举报对象的作者，举报用户时返回该用户
+func reportTargetOwner(targetType string, targetID uint) (uint, error) {
+	db := database.GetMysqlDB()
+	var ownerID uint
+	var err error
+	switch targetType {
+	case models.ReportTargetReview:
+		var review models.BookReviewModel
+		err = db.Select("id", "author_id").Take(&review, targetID).Error
+		ownerID = review.AuthorID
+	case models.ReportTargetComment:
+		var comment models.CommentModel
+		err = db.Select("id", "user_id").Take(&comment, targetID).Error
+		ownerID = comment.UserID
+	default:
+		var user models.UserModel
+		err = db.Select("id").Take(&user, targetID).Error
+		ownerID = user.ID
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New(response.ErrReportTarget)
+		}
+		logrus.Error("query report target failed, err: ", err)
+		return 0, errors.New(response.ErrServerInternal)
+	}
+	return ownerID, nil
+}
+
+// reportTargetModel 可以隐藏的举报对象对应的模型，用户返回nil
+func reportTargetModel(targetType string) interface{} {
+	switch targetType {
+	case models.ReportTargetReview:
+		return &models.BookReviewModel{}
+	case models.ReportTargetComment:
+		return &models.CommentModel{}
+	}
+	return nil
+}
+
+func autoHideThreshold() int {
+	threshold := config.GetModerationConfig().AutoHideReports
+	if threshold == 0 {
+		return defaultAutoHideReports
+	}
+	return threshold
+}
+
+// autoHideReported 未处理的举报达到阈值时隐藏书评或评论，隐藏人记为0(系统)
+func autoHideReported(targetType string, targetID uint) {
+	threshold := autoHideThreshold()
+	model := reportTargetModel(targetType)
+	if threshold < 0 || model == nil {
+		return
+	}
+	db := database.GetMysqlDB()
+	var count int64
+	err := db.Model(&models.ReportModel{}).
+		Where(models.ReportModelTable_TargetType+" = ? AND "+models.ReportModelTable_TargetID+" = ? AND "+models.ReportModelTable_Status+" = ?",
+			targetType, targetID, models.ReportStatusOpen).
+		Count(&count).Error
+	if err != nil {
+		logrus.Error("count reports failed, err: ", err)
+		return
+	}
+	if count < int64(threshold) {
+		return
+	}
+	var hidden bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		hidden, err = updateHidden(tx, model, targetID, "hidden_at IS NULL",
+			map[string]interface{}{"hidden_at": time.Now(), "hidden_by": 0})
+		return err
+	})
+	if err != nil {
+		logrus.Error("auto hide reported content failed, err: ", err)
+		return
+	}
+	if !hidden {
+		return
+	}
+	action := audit.ActionCommentHide
+	if targetType == models.ReportTargetReview {
+		action = audit.ActionReviewHide
+	}
+	audit.Record(audit.Event{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Detail:     fmt.Sprintf("auto hidden after %d reports", count),
+	})
+}
+
+// QueryReports 审核队列，按举报时间正序，status和targetType为空时不过滤
+func QueryReports(status string, targetType string, page int, pageSize int) ([]models.ReportModel, int64, error) {
+	var reports []models.ReportModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.ReportModel{})
+	if status != "" {
+		query = query.Where(models.ReportModelTable_Status+" = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where(models.ReportModelTable_TargetType+" = ?", targetType)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count reports failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Order(models.ReportModelTable_CreatedAt + " ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports).Error
+	if err != nil {
+		logrus.Error("query reports failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return reports, total, nil
+}
+
+// ResolveReport 处理举报，同一对象上所有未处理的举报一并处理并通知举报人。
+// actioned 隐藏书评或评论；dismissed 恢复被自动隐藏的内容，与举报状态在同一个事务中更新。
+// 返回被处理的举报和一并处理的举报数
+func ResolveReport(actorID uint, reportID uint, status string, note string) (models.ReportModel, int, error) {
+	var report models.ReportModel
+	if status != models.ReportStatusActioned && status != models.ReportStatusDismissed {
+		return report, 0, errors.New(response.ErrReportStatus)
+	}
+	var reporterIDs []uint
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&report, reportID).Error; err != nil {
+			return err
+		}
+		if report.Status != models.ReportStatusOpen {
+			return errors.New(response.ErrReportResolved)
+		}
+		open := tx.Model(&models.ReportModel{}).
+			Where(models.ReportModelTable_TargetType+" = ? AND "+models.ReportModelTable_TargetID+" = ? AND "+models.ReportModelTable_Status+" = ?",
+				report.TargetType, report.TargetID, models.ReportStatusOpen)
+		if err := open.Session(&gorm.Session{}).Pluck(models.ReportModelTable_ReporterID, &reporterIDs).Error; err != nil {
+			return err
+		}
+		err := open.Updates(map[string]interface{}{
+			models.ReportModelTable_Status: status,
+			"resolved_by":                  actorID,
+			"resolved_at":                  time.Now(),
+			"resolution":                   note,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return applyReportResolution(tx, report, actorID, status)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return report, 0, errors.New(response.ErrReportNotFound)
+		}
+		if err.Error() == response.ErrReportResolved {
+			return report, 0, err
+		}
+		logrus.Error("resolve report failed, err: ", err)
+		return report, 0, errors.New(response.ErrServerInternal)
+	}
+
+	// 举报人为0的是被文本过滤器拦下的内容，通知发布者审核结果
+	users := make([]uint, 0, len(reporterIDs))
+	for _, id := range reporterIDs {
+		if id == 0 {
+			NotifyUsers([]uint{report.TargetUserID}, models.NotificationReportResolved, heldResolvedMessage(report.TargetType, status),
+				report.TargetType, report.TargetID)
+			continue
+		}
+		users = append(users, id)
+	}
+	NotifyUsers(users, models.NotificationReportResolved, reportResolvedMessage(report.TargetType, status),
+		"report", report.ID)
+	report.Status = status
+	return report, len(reporterIDs), nil
+}
+
+// applyReportResolution actioned时隐藏被举报的书评或评论，dismissed时恢复被自动隐藏的内容。
+// 版主手动隐藏的内容不受dismissed影响，举报用户时不做处理
+func applyReportResolution(tx *gorm.DB, report models.ReportModel, actorID uint, status string) error {
+	model := reportTargetModel(report.TargetType)
+	if model == nil {
+		return nil
+	}
+	var err error
+	if status == models.ReportStatusActioned {
+		_, err = updateHidden(tx, model, report.TargetID, "",
+			map[string]interface{}{"hidden_at": time.Now(), "hidden_by": actorID})
+	} else {
+		_, err = updateHidden(tx, model, report.TargetID, "hidden_at IS NOT NULL AND hidden_by = 0",
+			map[string]interface{}{"hidden_at": nil, "hidden_by": 0})
+	}
+	return err
+}
+
+func reportTargetName(targetType string) string {
+	switch targetType {
+	case models.ReportTargetReview:
+		return "书评"
+	case models.ReportTargetComment:
+		return "评论"
+	}
+	return "用户"
+}
+
+func reportResolvedMessage(targetType string, status string) string {
+	name := reportTargetName(targetType)
+	if status == models.ReportStatusActioned {
+		return fmt.Sprintf("你举报的%s已处理，我们已根据社区规范采取措施，感谢你的反馈。", name)
+	}
+	return fmt.Sprintf("你举报的%s经审核未发现违反社区规范的内容，感谢你的反馈。", name)
+}
+
+func heldResolvedMessage(targetType string, status string) string {
+	name := reportTargetName(targetType)
+	if status == models.ReportStatusActioned {
+		return fmt.Sprintf("你发布的%s未通过审核，暂不公开显示。", name)
+	}
+	return fmt.Sprintf("你发布的%s已通过审核，现在所有人都可以看到了。", name)
+}
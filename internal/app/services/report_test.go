@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+)
+
+func TestResolveReport(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	review := createTestReview(t, db, author.ID)
+
+	first, err := CreateReport(alice.ID, models.ReportTargetReview, review.ID, models.ReportReasonSpam, "")
+	require.NoError(t, err)
+	_, err = CreateReport(alice.ID, models.ReportTargetReview, review.ID, models.ReportReasonSpam, "")
+	assert.EqualError(t, err, response.ErrReportDuplicate)
+	_, err = CreateReport(bob.ID, models.ReportTargetReview, review.ID, models.ReportReasonSpam, "")
+	require.NoError(t, err)
+
+	report, resolved, err := ResolveReport(author.ID+100, first.ID, models.ReportStatusActioned, "note")
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolved)
+	assert.Equal(t, models.ReportStatusActioned, report.Status)
+	var hidden models.BookReviewModel
+	require.NoError(t, db.Take(&hidden, review.ID).Error)
+	assert.NotNil(t, hidden.HiddenAt)
+
+	_, _, err = ResolveReport(author.ID, first.ID, models.ReportStatusDismissed, "")
+	assert.EqualError(t, err, response.ErrReportResolved)
+	_, _, err = ResolveReport(author.ID, 9999, models.ReportStatusDismissed, "")
+	assert.EqualError(t, err, response.ErrReportNotFound)
+}
+
+func TestResolveReportDismissKeepsModeratorHide(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	alice := createTestUser(t, db, "alice")
+	review := createTestReview(t, db, author.ID)
+	report, err := CreateReport(alice.ID, models.ReportTargetReview, review.ID, models.ReportReasonSpam, "")
+	require.NoError(t, err)
+	require.NoError(t, SetReviewHidden(author.ID, review.ID, true))
+
+	_, _, err = ResolveReport(author.ID, report.ID, models.ReportStatusDismissed, "")
+	require.NoError(t, err)
+	var r models.BookReviewModel
+	require.NoError(t, db.Take(&r, review.ID).Error)
+	assert.NotNil(t, r.HiddenAt, "dismissing a report must not unhide content hidden by a moderator")
+}
+
+func TestReportExistsPropagatesError(t *testing.T) {
+	db := dbtest.Open(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	_, err = reportExists(db, 1, models.ReportTargetReview, 1)
+	assert.Error(t, err)
+}
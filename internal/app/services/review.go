@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateReview 保存书评，并在同一个事务中写入 ReviewCreated 事件，直接发布的书评还会写入 ReviewPublished 事件
+func CreateReview(review *models.BookReviewModel) error {
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		err := writeEvent(tx, events.ReviewCreated, events.ReviewCreatedPayload{
+			ReviewID:   review.ID,
+			AuthorID:   review.AuthorID,
+			Status:     review.Status,
+			Visibility: review.Visibility,
+			Hidden:     review.HiddenAt != nil,
+		})
+		if err != nil || review.Status != models.ReviewStatusPublished {
+			return err
+		}
+		return writeReviewPublished(tx, review)
+	})
+	if err != nil {
+		return err
+	}
+	wakeOutboxRelay()
+	return nil
+}
+
+// PublishReview 按updates更新草稿或定时书评，updates中的状态为已发布时在同一个事务中写入 ReviewPublished 事件。
+// 更新后重新读取review；书评已经发布(例如同时被定时任务发布)时返回 ErrReviewNotDraft
+func PublishReview(review *models.BookReviewModel, updates map[string]interface{}) error {
+	published := false
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		var current models.BookReviewModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&current, review.ID).Error; err != nil {
+			return err
+		}
+		if current.IsPublished() {
+			return errors.New(response.ErrReviewNotDraft)
+		}
+		if err := tx.Model(review).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Take(review, review.ID).Error; err != nil {
+			return err
+		}
+		if review.Status != models.ReviewStatusPublished {
+			return nil
+		}
+		published = true
+		return writeReviewPublished(tx, review)
+	})
+	if err != nil {
+		return err
+	}
+	if published {
+		wakeOutboxRelay()
+	}
+	return nil
+}
+
+func writeReviewPublished(tx *gorm.DB, review *models.BookReviewModel) error {
+	return writeEvent(tx, events.ReviewPublished, events.ReviewPublishedPayload{
+		ReviewID:   review.ID,
+		AuthorID:   review.AuthorID,
+		Visibility: review.Visibility,
+		Hidden:     review.HiddenAt != nil,
+	})
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/markdown"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultExcerptLength = 140
+	// 补全旧书评HTML时每批处理的数量
+	reviewContentBackfillBatch = 100
+)
+
+var (
+	reviewRenderer     *markdown.Renderer
+	reviewRendererOnce sync.Once
+)
+
+func getReviewRenderer() *markdown.Renderer {
+	reviewRendererOnce.Do(func() {
+		prefixes := config.GetReviewContentConfig().ImageURLPrefixes
+		if len(prefixes) == 0 {
+			// 默认只允许站内上传目录，如 /static/
+			if prefix := strings.Trim(config.GetVedioConfig().UrlPrefix, "/"); prefix != "" {
+				prefixes = []string{"/" + prefix + "/"}
+			}
+		}
+		reviewRenderer = markdown.New(markdown.Options{ImagePrefixes: prefixes})
+	})
+	return reviewRenderer
+}
+
+// renderedContent 由书评正文生成的字段
+type renderedContent struct {
+	html       string
+	excerpt    string
+	searchText string
+	spoilers   bool
+}
+
+func renderContent(content string) renderedContent {
+	length := config.GetReviewContentConfig().ExcerptLength
+	if length <= 0 {
+		length = defaultExcerptLength
+	}
+	// 超过列长度时写入会失败，没有经过配置检查时也要限制
+	length = min(length, config.MaxExcerptLength)
+	return renderedContent{
+		html:       getReviewRenderer().HTML(content),
+		excerpt:    markdown.Excerpt(content, length),
+		searchText: markdown.PlainText(content),
+		spoilers:   markdown.HasSpoilers(content),
+	}
+}
+
+// RenderReviewContent 按Markdown渲染review.Content，填充HTML、摘要、搜索文本和剧透标记
+func RenderReviewContent(review *models.BookReviewModel) {
+	rendered := renderContent(review.Content)
+	review.ContentHTML = rendered.html
+	review.Excerpt = rendered.excerpt
+	review.SearchText = rendered.searchText
+	review.SpoilerMarked = rendered.spoilers
+}
+
+// SetReviewContent 把正文和渲染结果一起写入更新的字段
+func SetReviewContent(updates map[string]interface{}, content string) {
+	rendered := renderContent(content)
+	updates[models.BookReviewModelTable_Content] = content
+	updates[models.BookReviewModelTable_ContentHTML] = rendered.html
+	updates[models.BookReviewModelTable_Excerpt] = rendered.excerpt
+	updates[models.BookReviewModelTable_SearchText] = rendered.searchText
+	updates[models.BookReviewModelTable_SpoilerMarked] = rendered.spoilers
+}
+
+// BackfillReviewContent 给支持Markdown之前保存的书评生成HTML、摘要和搜索文本。
+// ctx结束时在当前批次之后停止，剩下的书评下次启动时继续处理
+func BackfillReviewContent(ctx context.Context) {
+	db := database.GetMysqlDB()
+	var lastID uint
+	total := 0
+	for ctx.Err() == nil {
+		var reviews []models.BookReviewModel
+		err := db.Select("id", models.BookReviewModelTable_Content).
+			Where("id > ? AND "+models.BookReviewModelTable_Content+" <> '' AND ("+
+				models.BookReviewModelTable_ContentHTML+" IS NULL OR "+models.BookReviewModelTable_ContentHTML+" = '' OR "+
+				models.BookReviewModelTable_SearchText+" IS NULL OR "+models.BookReviewModelTable_SearchText+" = '')", lastID).
+			Order("id").Limit(reviewContentBackfillBatch).Find(&reviews).Error
+		if err != nil {
+			logrus.Error("query reviews without html failed, err: ", err)
+			return
+		}
+		for _, review := range reviews {
+			updates := make(map[string]interface{})
+			SetReviewContent(updates, review.Content)
+			delete(updates, models.BookReviewModelTable_Content)
+			// 不更新 updated_at
+			if err := db.Model(&review).Session(&gorm.Session{SkipHooks: true}).UpdateColumns(updates).Error; err != nil {
+				logrus.Errorf("render review %d failed, err: %v", review.ID, err)
+			}
+			lastID = review.ID
+		}
+		total += len(reviews)
+		if len(reviews) < reviewContentBackfillBatch {
+			break
+		}
+	}
+	if total > 0 {
+		logrus.Infof("rendered html for %d reviews", total)
+	}
+}
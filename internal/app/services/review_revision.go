@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textdiff"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 内容差异中每处修改前后保留的行数
+const revisionDiffContext = 2
+
+func revisionOf(review *models.BookReviewModel) models.ReviewRevisionModel {
+	return models.ReviewRevisionModel{
+		ReviewID: review.ID,
+		Title:    review.Title,
+		Content:  review.Content,
+		Images:   review.Images,
+		Rating:   review.Rating,
+		Tags:     review.Tags,
+	}
+}
+
+// revisionDiff 两个版本之间的差异，内容按行比较，其他字段记录修改前后的值
+func revisionDiff(prev models.ReviewRevisionModel, next models.ReviewRevisionModel) string {
+	var b strings.Builder
+	if prev.Title != next.Title {
+		fmt.Fprintf(&b, "title: %q -> %q\n", prev.Title, next.Title)
+	}
+	if prev.Rating != next.Rating {
+		fmt.Fprintf(&b, "rating: %.1f -> %.1f\n", prev.Rating, next.Rating)
+	}
+	if prev.Images != next.Images {
+		fmt.Fprintf(&b, "images: %s -> %s\n", prev.Images, next.Images)
+	}
+	if prev.Tags != next.Tags {
+		fmt.Fprintf(&b, "tags: %s -> %s\n", prev.Tags, next.Tags)
+	}
+	b.WriteString(textdiff.Unified(prev.Content, next.Content, revisionDiffContext))
+	return b.String()
+}
+
+// EditReview 修改书评并在同一个事务中保存修改前后的版本。书评行加锁，并发修改同一书评时依次执行，
+// 版本号不会重复。record为false时(草稿、只改可见性等)只修改不记录版本。返回修改后的书评
+func EditReview(reviewID uint, editorID uint, updates map[string]interface{}, record bool, note string) (models.BookReviewModel, error) {
+	var review models.BookReviewModel
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&review, reviewID).Error; err != nil {
+			return err
+		}
+		before := review
+		if err := tx.Model(&review).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Take(&review, reviewID).Error; err != nil {
+			return err
+		}
+		if !record {
+			return nil
+		}
+		return recordReviewRevision(tx, &before, &review, editorID, note)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return review, errors.New(response.ErrReviewNotFound)
+	}
+	if err != nil {
+		logrus.Errorf("edit review %d failed, err: %v", reviewID, err)
+		return review, errors.New(response.ErrServerInternal)
+	}
+	return review, nil
+}
+
+// recordReviewRevision 在修改书评的事务中保存一次修改，before和after为修改前后的书评，内容没有变化时不保存。
+// 书评第一次修改时先把原始内容保存为版本1。调用方需要已经锁住书评行
+func recordReviewRevision(tx *gorm.DB, before *models.BookReviewModel, after *models.BookReviewModel, editorID uint, note string) error {
+	prev, next := revisionOf(before), revisionOf(after)
+	diff := revisionDiff(prev, next)
+	if diff == "" {
+		return nil
+	}
+	var latest models.ReviewRevisionModel
+	err := tx.Where(models.ReviewRevisionModelTable_ReviewID+" = ?", before.ID).
+		Order(models.ReviewRevisionModelTable_Version + " DESC").Take(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		prev.Version = 1
+		prev.EditorID = before.AuthorID
+		prev.CreatedAt = before.CreatedAt
+		if err := tx.Create(&prev).Error; err != nil {
+			return err
+		}
+		latest = prev
+	} else if err != nil {
+		return err
+	}
+	next.Version = latest.Version + 1
+	next.EditorID = editorID
+	next.Diff = diff
+	next.Note = note
+	return tx.Create(&next).Error
+}
+
+// ListReviewRevisions 按版本倒序分页查询书评的修改历史
+func ListReviewRevisions(reviewID uint, page int, pageSize int) ([]models.ReviewRevisionModel, int64, error) {
+	var revisions []models.ReviewRevisionModel
+	var total int64
+	query := database.GetMysqlDB().Model(&models.ReviewRevisionModel{}).
+		Where(models.ReviewRevisionModelTable_ReviewID+" = ?", reviewID)
+	if err := query.Count(&total).Error; err != nil {
+		logrus.Error("count review revisions failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	err := query.Order(models.ReviewRevisionModelTable_Version + " DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&revisions).Error
+	if err != nil {
+		logrus.Error("query review revisions failed, err: ", err)
+		return nil, 0, errors.New(response.ErrServerInternal)
+	}
+	return revisions, total, nil
+}
+
+// RollbackReview 把书评恢复到某个历史版本，作者和版主可以操作。
+// 回滚本身也会保存为一个新版本
+func RollbackReview(actorID uint, reviewID uint, revisionID uint) (models.BookReviewModel, error) {
+	db := database.GetMysqlDB()
+	var review models.BookReviewModel
+	if err := db.Take(&review, reviewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return review, errors.New(response.ErrReviewNotFound)
+		}
+		logrus.Error("query review failed, err: ", err)
+		return review, errors.New(response.ErrServerInternal)
+	}
+	if review.AuthorID != actorID && !UserHasPermission(actorID, models.PermModerateReview) {
+		return review, errors.New(response.ErrPermissionDenied)
+	}
+	var revision models.ReviewRevisionModel
+	err := db.Where("id = ? AND "+models.ReviewRevisionModelTable_ReviewID+" = ?", revisionID, reviewID).Take(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return review, errors.New(response.ErrRevisionNotFound)
+		}
+		logrus.Error("query review revision failed, err: ", err)
+		return review, errors.New(response.ErrServerInternal)
+	}
+
+	// 封面图是图片列表的第一张
+	var images []string
+	coverURL := ""
+	if json.Unmarshal([]byte(revision.Images), &images) == nil && len(images) > 0 {
+		coverURL = images[0]
+	}
+	updates := map[string]interface{}{
+		"title":                              revision.Title,
+		"images":                             revision.Images,
+		"cover_url":                          coverURL,
+		"rating":                             revision.Rating,
+		"tags":                               revision.Tags,
+		models.BookReviewModelTable_EditedAt: time.Now(),
+	}
+	SetReviewContent(updates, revision.Content)
+	return EditReview(reviewID, actorID, updates, true, fmt.Sprintf("rollback to v%d", revision.Version))
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+)
+
+func TestEditReviewRecordsRevisions(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	review := createTestReview(t, db, author.ID)
+
+	edited, err := EditReview(review.ID, author.ID, map[string]interface{}{"title": "v2"}, true, "")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", edited.Title)
+	_, err = EditReview(review.ID, author.ID, map[string]interface{}{"title": "v3"}, true, "")
+	require.NoError(t, err)
+	// 内容没有变化时不保存版本
+	_, err = EditReview(review.ID, author.ID, map[string]interface{}{"title": "v3"}, true, "")
+	require.NoError(t, err)
+	// 不记录版本的修改
+	_, err = EditReview(review.ID, author.ID, map[string]interface{}{"title": "v4"}, false, "")
+	require.NoError(t, err)
+
+	revisions, total, err := ListReviewRevisions(review.ID, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	assert.Equal(t, []int{3, 2, 1}, []int{int(revisions[0].Version), int(revisions[1].Version), int(revisions[2].Version)})
+	assert.Equal(t, "title", revisions[2].Title)
+	assert.Equal(t, "v3", revisions[0].Title)
+
+	rolledBack, err := RollbackReview(author.ID, review.ID, revisions[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "title", rolledBack.Title)
+	revisions, _, err = ListReviewRevisions(review.ID, 1, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, revisions[0].Version)
+	assert.Equal(t, "rollback to v1", revisions[0].Note)
+
+	_, err = EditReview(review.ID+100, author.ID, map[string]interface{}{"title": "x"}, true, "")
+	assert.EqualError(t, err, response.ErrReviewNotFound)
+	var count int64
+	db.Model(&models.ReviewRevisionModel{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
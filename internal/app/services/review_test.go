@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"gorm.io/gorm"
+)
+
+// outboxEventTypes outbox中按写入顺序排列的事件类型
+func outboxEventTypes(t *testing.T, db *gorm.DB) []string {
+	var types []string
+	for _, row := range loadOutboxEvents(t, db) {
+		types = append(types, row.Type)
+	}
+	return types
+}
+
+func TestCreateReview_Events(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+
+	// 草稿和定时发布的书评只有 ReviewCreated
+	draft := models.BookReviewModel{Title: "title", Content: "content", AuthorID: author.ID, Status: models.ReviewStatusDraft}
+	require.NoError(t, CreateReview(&draft))
+	publishAt := time.Now().Add(time.Hour)
+	scheduled := models.BookReviewModel{Title: "title", Content: "content", AuthorID: author.ID,
+		Status: models.ReviewStatusScheduled, ScheduledAt: &publishAt}
+	require.NoError(t, CreateReview(&scheduled))
+	assert.Equal(t, []string{events.ReviewCreated, events.ReviewCreated}, outboxEventTypes(t, db))
+
+	published := models.BookReviewModel{Title: "title", Content: "content", AuthorID: author.ID,
+		Status: models.ReviewStatusPublished, Visibility: models.ReviewVisibilityFollowers}
+	require.NoError(t, CreateReview(&published))
+	rows := loadOutboxEvents(t, db)
+	require.Len(t, rows, 4)
+	assert.Equal(t, events.ReviewCreated, rows[2].Type)
+	require.Equal(t, events.ReviewPublished, rows[3].Type)
+	var payload events.ReviewPublishedPayload
+	require.NoError(t, events.Event{Type: rows[3].Type, Payload: []byte(rows[3].Payload)}.Decode(&payload))
+	assert.Equal(t, events.ReviewPublishedPayload{ReviewID: published.ID, AuthorID: author.ID,
+		Visibility: models.ReviewVisibilityFollowers}, payload)
+}
+
+func TestPublishReview(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	draft := createReviewWith(t, db, author.ID, models.ReviewStatusDraft, models.ReviewVisibilityPublic, nil)
+
+	// 设置定时发布不是公开发布
+	publishAt := time.Now().Add(time.Hour)
+	require.NoError(t, PublishReview(&draft, map[string]interface{}{
+		models.BookReviewModelTable_Status:      models.ReviewStatusScheduled,
+		models.BookReviewModelTable_ScheduledAt: publishAt,
+	}))
+	assert.Equal(t, models.ReviewStatusScheduled, draft.Status)
+	assert.Empty(t, loadOutboxEvents(t, db))
+
+	require.NoError(t, PublishReview(&draft, map[string]interface{}{
+		models.BookReviewModelTable_Status:      models.ReviewStatusPublished,
+		models.BookReviewModelTable_ScheduledAt: nil,
+	}))
+	assert.Equal(t, models.ReviewStatusPublished, draft.Status)
+	assert.Equal(t, []string{events.ReviewPublished}, outboxEventTypes(t, db))
+
+	// 已经发布的书评不会再次发布
+	err := PublishReview(&draft, map[string]interface{}{
+		models.BookReviewModelTable_Status: models.ReviewStatusPublished,
+	})
+	assert.EqualError(t, err, response.ErrReviewNotDraft)
+	assert.Len(t, loadOutboxEvents(t, db), 1)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VisibleReviews 列表、信息流和搜索中viewer能看到的书评，用于 db.Scopes。
+// 只包含已发布的书评：作者本人的全部可见；公开的书评对所有人可见(私密账号只对粉丝)；
+// 仅粉丝可见的书评对粉丝可见。不公开列出和仅自己可见的书评不会出现在别人的列表中。
+// 和viewer之间有拉黑的用户以及viewer静音的用户的书评也不包含，见 HiddenUserIDs
+func VisibleReviews(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		base := database.GetMysqlDB()
+		following := base.Model(&models.UserFollowerModel{}).
+			Select(models.UserFollowerModelTable_FollowerID).
+			Where(models.UserFollowerModelTable_UserID+" = ?", viewerID)
+		privateUsers := base.Model(&models.UserModel{}).Select("id").
+			Where(models.UserModelTable_IsPrivate+" = ?", true)
+		visible := base.Where(models.BookReviewModelTable_AuthorID+" = ?", viewerID).
+			Or(models.BookReviewModelTable_Visibility+" = ? AND "+models.BookReviewModelTable_AuthorID+" NOT IN (?)",
+				models.ReviewVisibilityPublic, privateUsers).
+			Or(models.BookReviewModelTable_Visibility+" IN ? AND "+models.BookReviewModelTable_AuthorID+" IN (?)",
+				[]string{models.ReviewVisibilityPublic, models.ReviewVisibilityFollowers}, following)
+		return db.Where(models.BookReviewModelTable_Status+" = ?", models.ReviewStatusPublished).Where(visible).
+			Scopes(models.ExcludeUsers(models.BookReviewModelTable_AuthorID, HiddenUserIDs(viewerID)))
+	}
+}
+
+// CanViewReview 书评详情、评论、点赞和收藏前的可见性检查，viewer为0表示未登录。
+// 不公开列出的书评和公开书评一样可以通过链接查看
+func CanViewReview(viewerID uint, review *models.BookReviewModel) bool {
+	if viewerID != 0 && review.AuthorID == viewerID {
+		return true
+	}
+	if !review.IsPublished() {
+		return false
+	}
+	switch review.Visibility {
+	case models.ReviewVisibilityPublic, models.ReviewVisibilityUnlisted:
+		return CanViewUserContent(viewerID, review.AuthorID)
+	case models.ReviewVisibilityFollowers:
+		return viewerID != 0 && !IsBlocked(viewerID, review.AuthorID) && IsFollowing(viewerID, review.AuthorID)
+	}
+	return false
+}
+
+// PublishDueReviews 发布定时时间已到的书评，以定时时间作为创建时间，返回发布的数量。
+// 每篇书评的 ReviewPublished 事件和状态更新在同一个事务中写入
+func PublishDueReviews(now time.Time) (int64, error) {
+	var count int64
+	err := database.GetMysqlDB().Transaction(func(tx *gorm.DB) error {
+		var due []models.BookReviewModel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(models.BookReviewModelTable_Status+" = ? AND "+models.BookReviewModelTable_ScheduledAt+" <= ?",
+				models.ReviewStatusScheduled, now).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		ids := make([]uint, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+		}
+		result := tx.Model(&models.BookReviewModel{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				models.BookReviewModelTable_Status:      models.ReviewStatusPublished,
+				models.BookReviewModelTable_CreatedAt:   gorm.Expr(models.BookReviewModelTable_ScheduledAt),
+				models.BookReviewModelTable_ScheduledAt: nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		count = result.RowsAffected
+		for i := range due {
+			if err := writeReviewPublished(tx, &due[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		wakeOutboxRelay()
+	}
+	return count, nil
+}
+
+// RunReviewScheduler 每隔interval发布一次到期的定时书评，直到ctx结束
+func RunReviewScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := PublishDueReviews(now)
+			if err != nil {
+				logrus.Error("publish scheduled reviews failed, err: ", err)
+			} else if count > 0 {
+				logrus.Infof("published %d scheduled reviews", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"gorm.io/gorm"
+)
+
+// visibleReviewIDs 和列表、搜索、收藏列表一样用 VisibleReviews 查询viewer能看到的书评
+func visibleReviewIDs(t *testing.T, db *gorm.DB, viewerID uint, scopes ...func(*gorm.DB) *gorm.DB) []uint {
+	var ids []uint
+	scopes = append([]func(*gorm.DB) *gorm.DB{VisibleReviews(viewerID)}, scopes...)
+	require.NoError(t, db.Model(&models.BookReviewModel{}).Scopes(scopes...).
+		Order("id").Pluck("id", &ids).Error)
+	return ids
+}
+
+func createReviewWith(t *testing.T, db *gorm.DB, authorID uint, status string, visibility string, scheduledAt *time.Time) models.BookReviewModel {
+	review := models.BookReviewModel{Title: "title", Content: "content", AuthorID: authorID,
+		Status: status, Visibility: visibility, ScheduledAt: scheduledAt}
+	require.NoError(t, db.Create(&review).Error)
+	return review
+}
+
+// visibilityFixture 一个作者的各种书评，以及作者的粉丝和陌生人
+type visibilityFixture struct {
+	author, follower, stranger                  models.UserModel
+	public, unlisted, followers, private, draft models.BookReviewModel
+	scheduled                                   models.BookReviewModel
+}
+
+func newVisibilityFixture(t *testing.T, db *gorm.DB, privateAccount bool) visibilityFixture {
+	var f visibilityFixture
+	f.author = createTestUser(t, db, "author")
+	f.follower = createTestUser(t, db, "follower")
+	f.stranger = createTestUser(t, db, "stranger")
+	require.NoError(t, createFollow(db, f.follower.ID, f.author.ID))
+	if privateAccount {
+		require.NoError(t, SetAccountPrivate(f.author.ID, true))
+	}
+	published := models.ReviewStatusPublished
+	f.public = createReviewWith(t, db, f.author.ID, published, models.ReviewVisibilityPublic, nil)
+	f.unlisted = createReviewWith(t, db, f.author.ID, published, models.ReviewVisibilityUnlisted, nil)
+	f.followers = createReviewWith(t, db, f.author.ID, published, models.ReviewVisibilityFollowers, nil)
+	f.private = createReviewWith(t, db, f.author.ID, published, models.ReviewVisibilityPrivate, nil)
+	f.draft = createReviewWith(t, db, f.author.ID, models.ReviewStatusDraft, models.ReviewVisibilityPublic, nil)
+	later := time.Now().Add(time.Hour)
+	f.scheduled = createReviewWith(t, db, f.author.ID, models.ReviewStatusScheduled, models.ReviewVisibilityPublic, &later)
+	return f
+}
+
+func TestVisibleReviews(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newVisibilityFixture(t, db, false)
+
+	// 草稿和定时书评不出现在任何人的列表中，包括作者本人
+	assert.Equal(t, []uint{f.public.ID, f.unlisted.ID, f.followers.ID, f.private.ID}, visibleReviewIDs(t, db, f.author.ID))
+	assert.Equal(t, []uint{f.public.ID, f.followers.ID}, visibleReviewIDs(t, db, f.follower.ID))
+	assert.Equal(t, []uint{f.public.ID}, visibleReviewIDs(t, db, f.stranger.ID))
+	assert.Equal(t, []uint{f.public.ID}, visibleReviewIDs(t, db, 0))
+}
+
+func TestVisibleReviews_PrivateAccount(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newVisibilityFixture(t, db, true)
+
+	assert.Equal(t, []uint{f.public.ID, f.unlisted.ID, f.followers.ID, f.private.ID}, visibleReviewIDs(t, db, f.author.ID))
+	assert.Equal(t, []uint{f.public.ID, f.followers.ID}, visibleReviewIDs(t, db, f.follower.ID))
+	assert.Empty(t, visibleReviewIDs(t, db, f.stranger.ID))
+	assert.Empty(t, visibleReviewIDs(t, db, 0))
+}
+
+func TestCanViewReview(t *testing.T) {
+	for _, privateAccount := range []bool{false, true} {
+		db := dbtest.Open(t)
+		f := newVisibilityFixture(t, db, privateAccount)
+		tests := []struct {
+			review models.BookReviewModel
+			// 作者、粉丝、陌生人、未登录
+			want [4]bool
+		}{
+			{review: f.public, want: [4]bool{true, true, !privateAccount, !privateAccount}},
+			{review: f.unlisted, want: [4]bool{true, true, !privateAccount, !privateAccount}},
+			{review: f.followers, want: [4]bool{true, true, false, false}},
+			{review: f.private, want: [4]bool{true, false, false, false}},
+			{review: f.draft, want: [4]bool{true, false, false, false}},
+			{review: f.scheduled, want: [4]bool{true, false, false, false}},
+		}
+		for _, tt := range tests {
+			for i, viewerID := range []uint{f.author.ID, f.follower.ID, f.stranger.ID, 0} {
+				assert.Equal(t, tt.want[i], CanViewReview(viewerID, &tt.review),
+					"private account %v, %s %s review, viewer %d", privateAccount, tt.review.Status, tt.review.Visibility, viewerID)
+			}
+		}
+	}
+}
+
+func TestPublishDueReviews(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	now := time.Now().Truncate(time.Second)
+	dueAt := now.Add(-time.Hour)
+	notDueAt := now.Add(time.Hour)
+	due := createReviewWith(t, db, author.ID, models.ReviewStatusScheduled, models.ReviewVisibilityPublic, &dueAt)
+	notDue := createReviewWith(t, db, author.ID, models.ReviewStatusScheduled, models.ReviewVisibilityPublic, &notDueAt)
+	// 草稿即使有过去的定时时间也不发布
+	draft := createReviewWith(t, db, author.ID, models.ReviewStatusDraft, models.ReviewVisibilityPublic, &dueAt)
+
+	count, err := PublishDueReviews(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	reload := func(id uint) models.BookReviewModel {
+		var review models.BookReviewModel
+		require.NoError(t, db.Take(&review, id).Error)
+		return review
+	}
+	published := reload(due.ID)
+	assert.Equal(t, models.ReviewStatusPublished, published.Status)
+	assert.Nil(t, published.ScheduledAt)
+	// 以定时时间作为创建时间
+	assert.True(t, published.CreatedAt.Equal(dueAt), "created_at = %v, want %v", published.CreatedAt, dueAt)
+
+	assert.Equal(t, models.ReviewStatusScheduled, reload(notDue.ID).Status)
+	assert.Equal(t, models.ReviewStatusDraft, reload(draft.ID).Status)
+
+	// 发布的书评写入 ReviewPublished 事件
+	rows := loadOutboxEvents(t, db)
+	require.Len(t, rows, 1)
+	require.Equal(t, events.ReviewPublished, rows[0].Type)
+	var payload events.ReviewPublishedPayload
+	require.NoError(t, events.Event{Type: rows[0].Type, Payload: []byte(rows[0].Payload)}.Decode(&payload))
+	assert.Equal(t, due.ID, payload.ReviewID)
+	assert.Equal(t, author.ID, payload.AuthorID)
+
+	// 已经发布的不会再次发布
+	count, err = PublishDueReviews(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.Len(t, loadOutboxEvents(t, db), 1)
+}
+
+func TestVisibleReviews_ExcludesBlockedUsers(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		block func(authorID, viewerID uint) models.UserBlockModel
+	}{
+		{name: "author blocked viewer", block: func(authorID, viewerID uint) models.UserBlockModel {
+			return models.UserBlockModel{UserID: authorID, TargetID: viewerID, Kind: models.BlockKindBlock}
+		}},
+		{name: "viewer blocked author", block: func(authorID, viewerID uint) models.UserBlockModel {
+			return models.UserBlockModel{UserID: viewerID, TargetID: authorID, Kind: models.BlockKindBlock}
+		}},
+		{name: "viewer muted author", block: func(authorID, viewerID uint) models.UserBlockModel {
+			return models.UserBlockModel{UserID: viewerID, TargetID: authorID, Kind: models.BlockKindMute}
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			author := createTestUser(t, db, "author")
+			viewer := createTestUser(t, db, "viewer")
+			other := createTestUser(t, db, "other")
+			hidden := createTestReview(t, db, author.ID)
+			shown := createTestReview(t, db, other.ID)
+			block := tt.block(author.ID, viewer.ID)
+			require.NoError(t, db.Create(&block).Error)
+
+			// 书评列表
+			assert.Equal(t, []uint{shown.ID}, visibleReviewIDs(t, db, viewer.ID))
+			// 关键词搜索
+			assert.Equal(t, []uint{shown.ID}, visibleReviewIDs(t, db, viewer.ID, MatchReviewKeyword("title", viewer.ID, false)))
+			// 收藏列表
+			assert.Equal(t, []uint{shown.ID}, visibleReviewIDs(t, db, viewer.ID, func(db *gorm.DB) *gorm.DB {
+				return db.Where("id IN ?", []uint{hidden.ID, shown.ID})
+			}))
+
+			// 作者和其他人不受影响
+			assert.Equal(t, []uint{hidden.ID, shown.ID}, visibleReviewIDs(t, db, author.ID))
+			assert.Equal(t, []uint{hidden.ID, shown.ID}, visibleReviewIDs(t, db, other.ID))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+)
+
+// GetUserRole 查询用户角色，角色为空的旧数据视为普通用户
+func GetUserRole(userID uint) (string, error) {
+	user, err := GetUserById(userID)
+	if err != nil {
+		return "", err
+	}
+	if user.ID == 0 {
+		return "", errors.New(response.ErrUserNotExists)
+	}
+	if user.Role == "" {
+		return models.RoleUser, nil
+	}
+	return user.Role, nil
+}
+
+// UserHasPermission 用户是否拥有权限，查询失败时视为没有权限
+func UserHasPermission(userID uint, perm models.Permission) bool {
+	role, err := GetUserRole(userID)
+	if err != nil {
+		return false
+	}
+	return models.RoleHasPermission(role, perm)
+}
+
+// SetUserRole 修改用户角色，不能修改自己的角色，避免唯一的管理员把自己降级
+func SetUserRole(actorID uint, targetID uint, role string) error {
+	if !models.IsValidRole(role) {
+		return errors.New(response.ErrInvalidRole)
+	}
+	if actorID == targetID {
+		return errors.New(response.ErrChangeOwnRole)
+	}
+	db := database.GetMysqlDB()
+	result := db.Model(&models.UserModel{}).Where("id = ?", targetID).Update(models.UserModelTable_Role, role)
+	if result.Error != nil {
+		logrus.Error("update user role failed, err: ", result.Error)
+		return errors.New(response.ErrServerInternal)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(response.ErrUserNotExists)
+	}
+	database.GetUserInfoCacher().Delete(targetID)
+	return nil
+}
+
+// BootstrapAdmins 把配置中的用户名设为管理员，用于初始化第一个管理员
+func BootstrapAdmins(usernames []string) {
+	if len(usernames) == 0 {
+		return
+	}
+	db := database.GetMysqlDB()
+	var ids []uint
+	err := db.Model(&models.UserModel{}).
+		Where(models.UserModelTable_Username+" IN ? AND "+models.UserModelTable_Role+" <> ?", usernames, models.RoleAdmin).
+		Pluck("id", &ids).Error
+	if err != nil {
+		logrus.Error("bootstrap admins failed, err: ", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := db.Model(&models.UserModel{}).Where("id IN ?", ids).Update(models.UserModelTable_Role, models.RoleAdmin).Error; err != nil {
+		logrus.Error("bootstrap admins failed, err: ", err)
+		return
+	}
+	database.GetUserInfoCacher().DeleteMulti(ids)
+	logrus.Warnf("promoted %d user(s) to admin from config", len(ids))
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+)
+
+// refresh token明文的随机字节数
+const refreshTokenBytes = 32
+
+// 会话ID的随机字节数
+const sessionIDBytes = 16
+
+// RefreshSession 一次签发或轮换的结果
+type RefreshSession struct {
+	UserID       uint
+	SessionID    string
+	RefreshToken string
+	ExpiresAt    time.Time
+	// 数据库中refresh token的ID
+	tokenID uint
+}
+
+// CreateRefreshSession 为用户开启一个新的登录会话并签发refresh token。
+func CreateRefreshSession(userID uint, ttl time.Duration) (RefreshSession, error) {
+	var session RefreshSession
+	sessionID, err := randomHex(sessionIDBytes)
+	if err != nil {
+		return session, err
+	}
+	db := database.GetMysqlDB()
+	return issueRefreshToken(db, userID, sessionID, ttl)
+}
+
+// RotateRefreshToken 使用refresh token换取同一会话内的新refresh token。
+// 已被使用过的token再次出现时视为泄露(reuse detection),整个会话会被吊销，
+// 返回 response.ErrRefreshTokenReused 和被吊销的会话。
+func RotateRefreshToken(rawToken string, ttl time.Duration) (RefreshSession, error) {
+	var session RefreshSession
+	if rawToken == "" {
+		return session, errors.New(response.ErrRefreshTokenInvalid)
+	}
+	now := time.Now()
+	db := database.GetMysqlDB()
+	var old models.RefreshTokenModel
+	err := db.Where(models.RefreshTokenModelTable_TokenHash+" = ?", hashToken(rawToken)).Take(&old).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, errors.New(response.ErrRefreshTokenInvalid)
+		}
+		logrus.Error("query refresh token failed, err: ", err)
+		return session, errors.New(response.ErrServerInternal)
+	}
+	session.UserID = old.UserID
+	session.SessionID = old.SessionID
+	if old.ReplacedByID != 0 {
+		// 旧token被重放，吊销整个会话
+		logrus.Warnf("refresh token reuse detected, user: %d, session: %s", old.UserID, old.SessionID)
+		if err := RevokeRefreshSession(old.SessionID); err != nil {
+			return session, err
+		}
+		return session, errors.New(response.ErrRefreshTokenReused)
+	}
+	if !old.IsActive(now) {
+		return session, errors.New(response.ErrRefreshTokenInvalid)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var txErr error
+		session, txErr = issueRefreshToken(tx, old.UserID, old.SessionID, ttl)
+		if txErr != nil {
+			return txErr
+		}
+		// 条件更新，防止并发刷新时同一个token被轮换两次
+		result := tx.Model(&models.RefreshTokenModel{}).
+			Where("id = ? AND "+models.RefreshTokenModelTable_ReplacedByID+" = 0", old.ID).
+			Update(models.RefreshTokenModelTable_ReplacedByID, session.tokenID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(response.ErrRefreshTokenReused)
+		}
+		return nil
+	})
+	if err != nil {
+		if err.Error() == response.ErrRefreshTokenReused {
+			logrus.Warnf("concurrent refresh token reuse, user: %d, session: %s", old.UserID, old.SessionID)
+			RevokeRefreshSession(old.SessionID)
+			return RefreshSession{UserID: old.UserID, SessionID: old.SessionID}, err
+		}
+		logrus.Error("rotate refresh token failed, err: ", err)
+		return RefreshSession{UserID: old.UserID, SessionID: old.SessionID}, errors.New(response.ErrServerInternal)
+	}
+	return session, nil
+}
+
+// RevokeRefreshSession 吊销一个会话内所有的refresh token。
+func RevokeRefreshSession(sessionID string) error {
+	db := database.GetMysqlDB()
+	err := db.Model(&models.RefreshTokenModel{}).
+		Where(models.RefreshTokenModelTable_SessionID+" = ? AND "+models.RefreshTokenModelTable_RevokedAt+" IS NULL", sessionID).
+		Update(models.RefreshTokenModelTable_RevokedAt, time.Now()).Error
+	if err != nil {
+		logrus.Error("revoke refresh session failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// RevokeAllRefreshSessions 吊销用户所有设备上的refresh token，返回被吊销的会话ID，
+// 用于同时吊销这些会话签发的access token
+func RevokeAllRefreshSessions(userID uint) ([]string, error) {
+	var sessionIDs []string
+	db := database.GetMysqlDB()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		active := tx.Model(&models.RefreshTokenModel{}).
+			Where(models.RefreshTokenModelTable_UserID+" = ? AND "+models.RefreshTokenModelTable_RevokedAt+" IS NULL", userID)
+		if err := active.Distinct(models.RefreshTokenModelTable_SessionID).Pluck(models.RefreshTokenModelTable_SessionID, &sessionIDs).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshTokenModel{}).
+			Where(models.RefreshTokenModelTable_UserID+" = ? AND "+models.RefreshTokenModelTable_RevokedAt+" IS NULL", userID).
+			Update(models.RefreshTokenModelTable_RevokedAt, time.Now()).Error
+	})
+	if err != nil {
+		logrus.Error("revoke all refresh sessions failed, err: ", err)
+		return nil, errors.New(response.ErrServerInternal)
+	}
+	return sessionIDs, nil
+}
+
+// QueryRefreshSessionOwner 查询refresh token所属的用户和会话,不校验是否有效。
+func QueryRefreshSessionOwner(rawToken string) (userID uint, sessionID string, err error) {
+	var token models.RefreshTokenModel
+	db := database.GetMysqlDB()
+	err = db.Where(models.RefreshTokenModelTable_TokenHash+" = ?", hashToken(rawToken)).Take(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, "", errors.New(response.ErrRefreshTokenInvalid)
+		}
+		logrus.Error("query refresh token failed, err: ", err)
+		return 0, "", errors.New(response.ErrServerInternal)
+	}
+	return token.UserID, token.SessionID, nil
+}
+
+func issueRefreshToken(db *gorm.DB, userID uint, sessionID string, ttl time.Duration) (RefreshSession, error) {
+	var session RefreshSession
+	rawToken, err := randomHex(refreshTokenBytes)
+	if err != nil {
+		return session, err
+	}
+	token := models.RefreshTokenModel{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		logrus.Error("create refresh token failed, err: ", err)
+		return session, errors.New(response.ErrServerInternal)
+	}
+	session.UserID = userID
+	session.SessionID = sessionID
+	session.RefreshToken = rawToken
+	session.ExpiresAt = token.ExpiresAt
+	session.tokenID = token.ID
+	return session, nil
+}
+
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		logrus.Error("generate random bytes failed, err: ", err)
+		return "", errors.New(response.ErrServerInternal)
+	}
+	return hex.EncodeToString(b), nil
+}
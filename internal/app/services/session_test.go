@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+)
+
+func TestRotateRefreshToken(t *testing.T) {
+	dbtest.Open(t)
+	first, err := CreateRefreshSession(1, time.Hour)
+	require.NoError(t, err)
+
+	second, err := RotateRefreshToken(first.RefreshToken, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, first.SessionID, second.SessionID)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	third, err := RotateRefreshToken(second.RefreshToken, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, first.SessionID, third.SessionID)
+}
+
+func TestRotateRefreshTokenReuse(t *testing.T) {
+	dbtest.Open(t)
+	first, err := CreateRefreshSession(1, time.Hour)
+	require.NoError(t, err)
+	second, err := RotateRefreshToken(first.RefreshToken, time.Hour)
+	require.NoError(t, err)
+	other, err := CreateRefreshSession(1, time.Hour)
+	require.NoError(t, err)
+
+	// 已经轮换过的token再次出现，整个会话被吊销
+	reused, err := RotateRefreshToken(first.RefreshToken, time.Hour)
+	require.EqualError(t, err, response.ErrRefreshTokenReused)
+	assert.Equal(t, first.SessionID, reused.SessionID)
+	_, err = RotateRefreshToken(second.RefreshToken, time.Hour)
+	assert.EqualError(t, err, response.ErrRefreshTokenInvalid)
+
+	// 其他会话不受影响
+	_, err = RotateRefreshToken(other.RefreshToken, time.Hour)
+	assert.NoError(t, err)
+}
+
+func TestRotateRefreshTokenInvalid(t *testing.T) {
+	dbtest.Open(t)
+	_, err := RotateRefreshToken("", time.Hour)
+	assert.EqualError(t, err, response.ErrRefreshTokenInvalid)
+	_, err = RotateRefreshToken("unknown", time.Hour)
+	assert.EqualError(t, err, response.ErrRefreshTokenInvalid)
+
+	expired, err := CreateRefreshSession(1, -time.Minute)
+	require.NoError(t, err)
+	_, err = RotateRefreshToken(expired.RefreshToken, time.Hour)
+	assert.EqualError(t, err, response.ErrRefreshTokenInvalid)
+}
+
+func TestRevokeAllRefreshSessions(t *testing.T) {
+	dbtest.Open(t)
+	a, err := CreateRefreshSession(1, time.Hour)
+	require.NoError(t, err)
+	b, err := CreateRefreshSession(1, time.Hour)
+	require.NoError(t, err)
+	_, err = RotateRefreshToken(b.RefreshToken, time.Hour)
+	require.NoError(t, err)
+	otherUser, err := CreateRefreshSession(2, time.Hour)
+	require.NoError(t, err)
+
+	sessionIDs, err := RevokeAllRefreshSessions(1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{a.SessionID, b.SessionID}, sessionIDs)
+
+	_, err = RotateRefreshToken(a.RefreshToken, time.Hour)
+	assert.EqualError(t, err, response.ErrRefreshTokenInvalid)
+	_, err = RotateRefreshToken(otherUser.RefreshToken, time.Hour)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/markdown"
+	"gorm.io/gorm"
+)
+
+// 搜索结果片段的字数
+const reviewSnippetLength = 80
+
+// RevealSpoilers 用户是否设置了总是显示剧透，未登录时为false
+func RevealSpoilers(userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	var user models.UserModel
+	err := database.GetMysqlDB().Select("id", models.UserModelTable_RevealSpoilers).Take(&user, userID).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error("query spoiler preference failed, err: ", err)
+		}
+		return false
+	}
+	return user.RevealSpoilers
+}
+
+// SetRevealSpoilers 设置是否总是显示剧透
+func SetRevealSpoilers(userID uint, reveal bool) error {
+	err := database.GetMysqlDB().Model(&models.UserModel{}).Where("id = ?", userID).
+		Update(models.UserModelTable_RevealSpoilers, reveal).Error
+	if err != nil {
+		logrus.Error("update spoiler preference failed, err: ", err)
+		return errors.New(response.ErrServerInternal)
+	}
+	return nil
+}
+
+// MatchReviewKeyword 按关键词搜索书评的标题和正文。正文只搜索去掉剧透的文本，
+// 不显示剧透时整篇含剧透的书评只搜索标题，避免通过搜索结果推测剧情
+func MatchReviewKeyword(keyword string, viewerID uint, reveal bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		like := "%" + keyword + "%"
+		if reveal {
+			return db.Where("("+models.BookReviewModelTable_Title+" LIKE ? OR "+models.BookReviewModelTable_SearchText+" LIKE ?)", like, like)
+		}
+		return db.Where("("+models.BookReviewModelTable_Title+" LIKE ? OR ("+models.BookReviewModelTable_SearchText+" LIKE ? AND ("+
+			models.BookReviewModelTable_HasSpoilers+" = ? OR "+models.BookReviewModelTable_AuthorID+" = ?)))",
+			like, like, false, viewerID)
+	}
+}
+
+// ReviewSnippet 搜索结果中包含关键词的片段，取自去掉剧透的文本。
+// 整篇含剧透且对查看者隐藏时不返回片段
+func ReviewSnippet(review *models.BookReviewModel, keyword string, viewerID uint, reveal bool) string {
+	if review.HasSpoilers && !reveal && review.AuthorID != viewerID {
+		return ""
+	}
+	return markdown.Snippet(review.SearchText, keyword, reviewSnippetLength)
+}
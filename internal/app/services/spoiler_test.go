@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database/dbtest"
+	"gorm.io/gorm"
+)
+
+func createRenderedReview(t *testing.T, db *gorm.DB, authorID uint, content string, hasSpoilers bool) models.BookReviewModel {
+	review := models.BookReviewModel{Title: "书评", Content: content, AuthorID: authorID, HasSpoilers: hasSpoilers}
+	RenderReviewContent(&review)
+	require.NoError(t, db.Create(&review).Error)
+	return review
+}
+
+func TestSearchSpoilerReview(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	stranger := createTestUser(t, db, "stranger")
+	review := createRenderedReview(t, db, author.ID, "读到最后才知道凶手是管家", true)
+
+	// 不显示剧透时，别人搜索正文找不到整篇含剧透的书评，也看不到片段
+	assert.Empty(t, visibleReviewIDs(t, db, stranger.ID, MatchReviewKeyword("管家", stranger.ID, false)))
+	assert.Empty(t, ReviewSnippet(&review, "管家", stranger.ID, false))
+	// 标题仍然可以搜索，但不返回正文片段
+	assert.Equal(t, []uint{review.ID}, visibleReviewIDs(t, db, stranger.ID, MatchReviewKeyword("书评", stranger.ID, false)))
+	assert.Empty(t, ReviewSnippet(&review, "书评", stranger.ID, false))
+
+	// 作者总能搜到自己的书评
+	assert.Equal(t, []uint{review.ID}, visibleReviewIDs(t, db, author.ID, MatchReviewKeyword("管家", author.ID, false)))
+	assert.Contains(t, ReviewSnippet(&review, "管家", author.ID, false), "管家")
+
+	// 设置了显示剧透的用户
+	assert.Equal(t, []uint{review.ID}, visibleReviewIDs(t, db, stranger.ID, MatchReviewKeyword("管家", stranger.ID, true)))
+	assert.Contains(t, ReviewSnippet(&review, "管家", stranger.ID, true), "管家")
+}
+
+func TestSearchInlineSpoiler(t *testing.T) {
+	db := dbtest.Open(t)
+	author := createTestUser(t, db, "author")
+	stranger := createTestUser(t, db, "stranger")
+	review := createRenderedReview(t, db, author.ID, "结局很意外，最后||管家||是凶手\n\n:::spoiler 真相\n园丁是共犯\n:::\n\n推荐阅读", false)
+
+	// 剧透中的文字对任何人都不能被搜索到，包括作者和显示剧透的用户
+	for _, viewerID := range []uint{author.ID, stranger.ID, 0} {
+		for _, reveal := range []bool{false, true} {
+			assert.Empty(t, visibleReviewIDs(t, db, viewerID, MatchReviewKeyword("管家", viewerID, reveal)))
+			assert.Empty(t, visibleReviewIDs(t, db, viewerID, MatchReviewKeyword("园丁", viewerID, reveal)))
+
+			// 剧透以外的文字可以搜索，片段中不包含剧透
+			assert.Equal(t, []uint{review.ID}, visibleReviewIDs(t, db, viewerID, MatchReviewKeyword("结局", viewerID, reveal)))
+			snippet := ReviewSnippet(&review, "结局", viewerID, reveal)
+			assert.Contains(t, snippet, "结局")
+			assert.NotContains(t, snippet, "管家")
+			assert.NotContains(t, snippet, "园丁")
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/textfilter"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDuplicateLookback   = 10
+	defaultDuplicateSimilarity = 0.9
+	// 太短的内容(如"好书")重复很正常，不做重复检查
+	duplicateMinRunes = 10
+)
+
+var (
+	textFilter     *textfilter.Filter
+	textFilterOnce sync.Once
+)
+
+// GetTextFilter 按配置创建文本过滤器。词库加载失败时只记录错误，
+// 此时不过滤敏感词，链接和重复字符的检查仍然生效，词库在下一次检查时重新加载
+func GetTextFilter() *textfilter.Filter {
+	textFilterOnce.Do(func() {
+		conf := config.GetTextFilterConfig()
+		var err error
+		textFilter, err = textfilter.New(newTextFilterConfig(conf))
+		if err != nil {
+			logrus.Error("load text filter dictionaries failed, err: ", err)
+		} else {
+			logrus.Infof("text filter loaded %d words", textFilter.WordCount())
+		}
+	})
+	return textFilter
+}
+
+// RunTextFilterReload 按 text_filter.reload_interval 检查词库文件并重新加载，直到ctx结束。
+// 没有配置间隔时直接返回
+func RunTextFilterReload(ctx context.Context) {
+	interval, err := time.ParseDuration(config.GetTextFilterConfig().ReloadInterval)
+	if err != nil || interval <= 0 {
+		return
+	}
+	GetTextFilter().Watch(ctx, interval, func(err error) {
+		logrus.Warn("reload text filter dictionaries failed, keep the old ones, err: ", err)
+	})
+}
+
+func newTextFilterConfig(conf config.TextFilterConfig) textfilter.Config {
+	var filterConf textfilter.Config
+	for _, dict := range conf.Dictionaries {
+		action, err := textfilter.ParseAction(dict.Action, textfilter.ActionReject)
+		if err != nil {
+			logrus.Errorf("text filter dictionary %s: %v, use reject", dict.Path, err)
+		}
+		filterConf.Dictionaries = append(filterConf.Dictionaries, textfilter.Dictionary{Path: dict.Path, Action: action})
+	}
+	filterConf.MaxLinks = conf.MaxLinks
+	filterConf.LinkAction = parseFilterAction(conf.LinkAction, textfilter.ActionHold)
+	filterConf.MaxRepeat = conf.MaxRepeat
+	filterConf.RepeatAction = parseFilterAction(conf.RepeatAction, textfilter.ActionHold)
+	return filterConf
+}
+
+func parseFilterAction(name string, defaultAction textfilter.Action) textfilter.Action {
+	action, err := textfilter.ParseAction(name, defaultAction)
+	if err != nil {
+		logrus.Errorf("%v, use %s", err, defaultAction)
+		return defaultAction
+	}
+	return action
+}
+
+// ReloadTextFilter 立即重新加载词库，返回加载后的词数
+func ReloadTextFilter() (int, error) {
+	filter := GetTextFilter()
+	if err := filter.Reload(); err != nil {
+		logrus.Error("reload text filter dictionaries failed, err: ", err)
+		return 0, errors.New(response.ErrTextFilterReload)
+	}
+	return filter.WordCount(), nil
+}
+
+// ModerateReview 检查书评的标题和内容，返回处理后的标题、内容和合并后的结果。
+// reviewID为被更新的书评，新建时为0
+func ModerateReview(userID uint, reviewID uint, title string, content string) (string, string, textfilter.Result) {
+	filter := GetTextFilter()
+	titleRes := filter.Check(title)
+	res := filter.Check(content)
+	res.Merge(titleRes)
+	if isDuplicatePost(models.ReportTargetReview, userID, reviewID, content) {
+		res.Escalate(duplicateAction(), textfilter.ReasonDuplicate)
+	}
+	return titleRes.Text, res.Text, res
+}
+
+// ModerateComment 检查评论内容，返回处理后的内容和结果
+func ModerateComment(userID uint, content string) (string, textfilter.Result) {
+	res := GetTextFilter().Check(content)
+	if isDuplicatePost(models.ReportTargetComment, userID, 0, content) {
+		res.Escalate(duplicateAction(), textfilter.ReasonDuplicate)
+	}
+	return res.Text, res
+}
+
+func duplicateAction() textfilter.Action {
+	return parseFilterAction(config.GetTextFilterConfig().DuplicateAction, textfilter.ActionReject)
+}
+
+// isDuplicatePost 与用户最近发布的书评或评论比较，excludeID为正在更新的书评
+func isDuplicatePost(kind string, userID uint, excludeID uint, text string) bool {
+	conf := config.GetTextFilterConfig()
+	window, err := time.ParseDuration(conf.DuplicateWindow)
+	if err != nil || window <= 0 || utf8.RuneCountInString(text) < duplicateMinRunes {
+		return false
+	}
+	lookback := conf.DuplicateLookback
+	if lookback <= 0 {
+		lookback = defaultDuplicateLookback
+	}
+	threshold := conf.DuplicateSimilarity
+	if threshold <= 0 {
+		threshold = defaultDuplicateSimilarity
+	}
+
+	var query *gorm.DB
+	db := database.GetMysqlDB()
+	since := time.Now().Add(-window)
+	if kind == models.ReportTargetReview {
+		// 草稿还没有发布，和它重复不算刷屏
+		query = db.Model(&models.BookReviewModel{}).Where("author_id = ? AND created_at > ? AND id <> ? AND status <> ?",
+			userID, since, excludeID, models.ReviewStatusDraft)
+	} else {
+		query = db.Model(&models.CommentModel{}).Where("user_id = ? AND created_at > ?", userID, since)
+	}
+	var recent []string
+	if err := query.Order("created_at DESC").Limit(lookback).Pluck("content", &recent).Error; err != nil {
+		logrus.Error("query recent posts failed, err: ", err)
+		return false
+	}
+	for _, previous := range recent {
+		if textfilter.Similarity(previous, text) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldForReview 把被过滤器拦下的书评或评论放入审核队列，举报人记为0(系统)。
+// 内容需要由调用方隐藏，版主驳回后会恢复显示
+func HoldForReview(targetType string, targetID uint, ownerID uint, res textfilter.Result) {
+	reason := models.ReportReasonSpam
+	if len(res.Words) > 0 {
+		reason = models.ReportReasonOther
+	}
+	detail := "held by text filter: "
+	for i, r := range res.Reasons {
+		if i > 0 {
+			detail += ", "
+		}
+		detail += r
+	}
+	db := database.GetMysqlDB()
+	var report models.ReportModel
+	err := db.Where(models.ReportModelTable_ReporterID+" = 0 AND "+models.ReportModelTable_TargetType+" = ? AND "+models.ReportModelTable_TargetID+" = ?",
+		targetType, targetID).Take(&report).Error
+	switch {
+	case err == nil:
+		// 之前被处理过的内容再次被拦下，重新打开
+		err = db.Model(&report).Updates(map[string]interface{}{
+			models.ReportModelTable_Status: models.ReportStatusOpen,
+			"reason":                       reason,
+			"detail":                       detail,
+			"resolved_by":                  0,
+			"resolved_at":                  nil,
+			"resolution":                   "",
+		}).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = db.Create(&models.ReportModel{
+			TargetType:   targetType,
+			TargetID:     targetID,
+			TargetUserID: ownerID,
+			Reason:       reason,
+			Detail:       detail,
+			Status:       models.ReportStatusOpen,
+		}).Error
+	}
+	if err != nil {
+		logrus.Errorf("hold %s %d for review failed, err: %v", targetType, targetID, err)
+	}
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 审计事件类型
+const (
+	ActionLoginSuccess = "login_success"
+	ActionLoginFailed  = "login_failed"
+	ActionLoginLocked  = "login_locked"
+
+	ActionReviewHide     = "review_hide"
+	ActionReviewUnhide   = "review_unhide"
+	ActionReviewDelete   = "review_delete"
+	ActionReviewRestore  = "review_restore"
+	ActionCommentHide    = "comment_hide"
+	ActionCommentUnhide  = "comment_unhide"
+	ActionCommentDelete  = "comment_delete"
+	ActionCommentRestore = "comment_restore"
+	ActionUserRole       = "user_role"
+	ActionUserBan        = "user_ban"
+	ActionUserUnban      = "user_unban"
+	ActionUserForceReset = "user_force_reset"
+	ActionReportResolve  = "report_resolve"
+	ActionReviewRollback = "review_rollback"
+
+	ActionDeadLetterReplay = "dead_letter_replay"
+	ActionDeadLetterDelete = "dead_letter_delete"
+
+	ActionFeatureFlagSet    = "feature_flag_set"
+	ActionFeatureFlagDelete = "feature_flag_delete"
+)
+
+// 操作对象类型
+const (
+	TargetReview  = "review"
+	TargetComment = "comment"
+	TargetUser    = "user"
+	// 消息队列的死信
+	TargetDeadLetter = "dead_letter"
+	// 功能开关，没有数字ID。Target为开关名，只写入日志，审计表中的开关名见Detail的name=
+	TargetFeatureFlag = "feature_flag"
+)
+
+// Event 一条审计日志
+type Event struct {
+	Action string
+	// 操作者用户ID，未登录或用户不存在时为0
+	ActorID uint
+	// 操作对象，例如登录时的用户名
+	Target string
+	// 操作对象类型和ID，特权操作使用
+	TargetType string
+	TargetID   uint
+	IP         string
+	Detail     string
+}
+
+// Log 记录审计日志，统一带上audit=true字段便于日志系统过滤
+func Log(e Event) {
+	logrus.WithFields(logrus.Fields{
+		"audit":       true,
+		"action":      e.Action,
+		"actor_id":    e.ActorID,
+		"target":      e.Target,
+		"target_type": e.TargetType,
+		"target_id":   e.TargetID,
+		"ip":          e.IP,
+	}).Info(e.Detail)
+}
+
+// Store 持久化特权操作的审计日志。
+// 本包不依赖数据库，由启动时 SetStore 设置具体的实现
+type Store interface {
+	Save(e Event) error
+}
+
+var store atomic.Pointer[Store]
+
+// SetStore 设置 Record 使用的Store，nil表示只写日志
+func SetStore(s Store) {
+	if s == nil {
+		store.Store(nil)
+		return
+	}
+	store.Store(&s)
+}
+
+// Record 记录特权操作，除写日志外还通过Store持久化
+func Record(e Event) {
+	Log(e)
+	s := store.Load()
+	if s == nil {
+		return
+	}
+	if err := (*s).Save(e); err != nil {
+		logrus.Error("save audit log failed, err: ", err)
+	}
+}
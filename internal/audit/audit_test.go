@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeStore struct {
+	events []Event
+	err    error
+}
+
+func (s *fakeStore) Save(e Event) error {
+	s.events = append(s.events, e)
+	return s.err
+}
+
+func TestRecord(t *testing.T) {
+	s := &fakeStore{}
+	SetStore(s)
+	t.Cleanup(func() { SetStore(nil) })
+
+	Record(Event{Action: ActionReviewHide, ActorID: 1, TargetType: TargetReview, TargetID: 2})
+	if len(s.events) != 1 || s.events[0].Action != ActionReviewHide || s.events[0].TargetID != 2 {
+		t.Fatalf("saved events = %+v", s.events)
+	}
+
+	// 保存失败只记录日志
+	s.err = errors.New("db down")
+	Record(Event{Action: ActionUserBan})
+	if len(s.events) != 2 {
+		t.Errorf("saved %d events, want 2", len(s.events))
+	}
+
+	SetStore(nil)
+	Record(Event{Action: ActionUserUnban})
+	if len(s.events) != 2 {
+		t.Error("event saved after SetStore(nil)")
+	}
+}
@@ -94,6 +94,29 @@ func (h *HybridCache) Get(key string, dest interface{}) error {
 	return fmt.Errorf("cache miss: %s", key)
 }
 
+// Take 获取并删除，并发调用时只有一个能取到值，用于只能使用一次的值。
+// Redis可用时以Redis为准，同时删除内存中的副本
+func (h *HybridCache) Take(key string, dest interface{}) error {
+	if IsRedisEnabled() {
+		h.deleteMemory(key)
+		if err := GetDel(key, dest); err != nil {
+			return fmt.Errorf("cache miss: %s", key)
+		}
+		return nil
+	}
+
+	h.mu.Lock()
+	item, ok := h.memCache.Peek(key)
+	if ok {
+		h.memCache.Remove(key)
+	}
+	h.mu.Unlock()
+	if !ok || time.Now().After(item.ExpireTime) {
+		return fmt.Errorf("cache miss: %s", key)
+	}
+	return json.Unmarshal(item.Value, dest)
+}
+
 // Delete 删除缓存
 func (h *HybridCache) Delete(key string) error {
 	// 同时删除Redis和内存
@@ -104,6 +127,32 @@ func (h *HybridCache) Delete(key string) error {
 	return nil
 }
 
+// IncrWithTTL 原子自增计数器，键不存在时从0开始，从第一次自增开始计算过期时间。
+// 计数器不经过内存缓存，多个实例共享Redis中的计数；Redis不可用时在内存中计数
+func (h *HybridCache) IncrWithTTL(key string, expiration time.Duration) (int64, error) {
+	if IsRedisEnabled() {
+		n, err := IncrWithTTL(key, expiration)
+		if err == nil {
+			return n, nil
+		}
+		logrus.Warnf("Redis incr failed for key %s: %v, falling back to memory", key, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var n int64
+	expireTime := time.Now().Add(expiration)
+	if item, ok := h.memCache.Get(key); ok && time.Now().Before(item.ExpireTime) {
+		json.Unmarshal(item.Value, &n)
+		expireTime = item.ExpireTime
+	}
+	n++
+	data, _ := json.Marshal(n)
+	h.memCache.Add(key, cacheItem{Value: data, ExpireTime: expireTime})
+	return n, nil
+}
+
 // Exists 检查键是否存在
 func (h *HybridCache) Exists(key string) bool {
 	// 先查内存
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// newMemoryCache 只使用内存的HybridCache，测试中没有Redis
+func newMemoryCache(t *testing.T) *HybridCache {
+	memCache, err := lru.New[string, cacheItem](100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &HybridCache{memCache: memCache}
+}
+
+func TestHybridCacheTake(t *testing.T) {
+	h := newMemoryCache(t)
+	if err := h.Set("state", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var taken int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v string
+			if h.Take("state", &v) == nil && v == "value" {
+				atomic.AddInt32(&taken, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if taken != 1 {
+		t.Errorf("value taken %d times, want once", taken)
+	}
+	if h.Exists("state") {
+		t.Error("value still exists after Take")
+	}
+}
+
+func TestHybridCacheTakeExpired(t *testing.T) {
+	h := newMemoryCache(t)
+	h.Set("state", "value", -time.Second)
+	var v string
+	if err := h.Take("state", &v); err == nil {
+		t.Error("Take returned an expired value")
+	}
+}
+
+func TestHybridCacheIncrWithTTL(t *testing.T) {
+	h := newMemoryCache(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.IncrWithTTL("counter", time.Minute)
+		}()
+	}
+	wg.Wait()
+	if n, _ := h.IncrWithTTL("counter", time.Minute); n != 51 {
+		t.Errorf("counter = %d, want 51", n)
+	}
+
+	// 过期后重新计数
+	h.IncrWithTTL("expired", -time.Second)
+	if n, _ := h.IncrWithTTL("expired", time.Minute); n != 1 {
+		t.Errorf("expired counter = %d, want 1", n)
+	}
+}
@@ -103,6 +103,19 @@ func Get(key string, dest interface{}) error {
 	return json.Unmarshal(data, dest)
 }
 
+// GetDel 获取并删除，两步原子执行，用于只能使用一次的值
+func GetDel(key string, dest interface{}) error {
+	if !redisEnabled {
+		return fmt.Errorf("redis is not enabled")
+	}
+
+	data, err := rdb.GetDel(ctx, key).Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
 // Delete 删除缓存
 func Delete(keys ...string) error {
 	if !redisEnabled {
@@ -189,6 +202,24 @@ func Decr(key string) (int64, error) {
 	return rdb.Decr(ctx, key).Result()
 }
 
+// incrWithTTLScript 自增，键是新建的时候设置过期时间，两步在Redis中原子执行
+var incrWithTTLScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// IncrWithTTL 自增，从第一次自增开始计算过期时间，之后的自增不延长
+func IncrWithTTL(key string, expiration time.Duration) (int64, error) {
+	if !redisEnabled {
+		return 0, fmt.Errorf("redis is not enabled")
+	}
+
+	return incrWithTTLScript.Run(ctx, rdb, []string{key}, expiration.Milliseconds()).Int64()
+}
+
 // IncrBy 增加指定值
 func IncrBy(key string, value int64) (int64, error) {
 	if !redisEnabled {
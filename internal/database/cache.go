@@ -1,8 +1,6 @@
 package database
 
 import (
-	"sync"
-
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/cache"
 )
@@ -12,57 +10,43 @@ import (
 // 2. 返回的视频列表(feed流，喜欢列表，评论列表)中要求包含作者的动态信息(如粉丝总数)。
 //    实际应用中，用户不可能点进每个视频作者或评论用户的主页。
 
-var videoInfoCacher cache.Cacher[uint, models.VideoCacheModel]
-var videoCacheInitOnce sync.Once
-
-func InitVideoInfoCacher(cap int) {
-	videoCacheInitOnce.Do(func() {
-		videoInfoCacher = cache.NewARC[uint, models.VideoCacheModel](cap)
-	})
+// Caches 进程内的缓存，由 SetCaches 设置到全局
+type Caches struct {
+	VideoComment cache.Cacher[uint, models.CommentCacheModel]
+	UserInfo     cache.Cacher[uint, models.UserCacheModel]
+	UserFavorite cache.Cacher[uint, models.UserLikeCacheModel]
 }
 
-// GetVideoInfoCacher
-// 获取视频信息缓存器,请不要写入结构体中的指针或引用类型，这是并发不安全的。
-// 请注意Author字段可能有值，也可能没有值。
-func GetVideoInfoCacher() cache.Cacher[uint, models.VideoCacheModel] {
-	return videoInfoCacher
+// NewCaches 创建容量为cap的ARC缓存
+func NewCaches(cap int) Caches {
+	return Caches{
+		VideoComment: cache.NewARC[uint, models.CommentCacheModel](cap),
+		UserInfo:     cache.NewARC[uint, models.UserCacheModel](cap),
+		UserFavorite: cache.NewARC[uint, models.UserLikeCacheModel](cap),
+	}
 }
 
-var videoCommentCacher cache.Cacher[uint, models.CommentCacheModel]
-var videoCommentCacherInitOnce sync.Once
+var (
+	videoCommentCacher cache.Cacher[uint, models.CommentCacheModel]
+	userCacher         cache.Cacher[uint, models.UserCacheModel]
+	userFavoriteCacher cache.Cacher[uint, models.UserLikeCacheModel]
+)
 
-func InitVideoCommentCacher(cap int) {
-	videoCommentCacherInitOnce.Do(func() {
-		videoCommentCacher = cache.NewARC[uint, models.CommentCacheModel](cap)
-	})
+// SetCaches 设置全局使用的缓存
+func SetCaches(c Caches) {
+	videoCommentCacher = c.VideoComment
+	userCacher = c.UserInfo
+	userFavoriteCacher = c.UserFavorite
 }
 
 func GetVideoCommentCacher() cache.Cacher[uint, models.CommentCacheModel] {
 	return videoCommentCacher
 }
 
-var userCacher cache.Cacher[uint, models.UserCacheModel]
-var userCacheInitOnce sync.Once
-
-func InitUserCacher(cap int) {
-	userCacheInitOnce.Do(func() {
-		userCacher = cache.NewARC[uint, models.UserCacheModel](cap)
-	})
-}
-
 func GetUserInfoCacher() cache.Cacher[uint, models.UserCacheModel] {
 	return userCacher
 }
 
-var userFavoriteCacher cache.Cacher[uint, models.UserLikeCacheModel]
-var userFavoriteCacherInitOnce sync.Once
-
-func InitUserFavoriteCacher(cap int) {
-	userFavoriteCacherInitOnce.Do(func() {
-		userFavoriteCacher = cache.NewARC[uint, models.UserLikeCacheModel](cap)
-	})
-}
-
 func GetUserFavoriteCacher() cache.Cacher[uint, models.UserLikeCacheModel] {
 	return userFavoriteCacher
 }
@@ -5,15 +5,20 @@ import (
 	"time"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/config"
-	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// db 全局使用的数据库，由 SetDB 设置。导入本包不会连接数据库
 var db *gorm.DB
 
+// SetDB 设置全局使用的数据库，测试中可以设置为不连接真实数据库的实例
+func SetDB(d *gorm.DB) {
+	db = d
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return db
@@ -25,14 +30,8 @@ func GetMysqlDB() *gorm.DB {
 	return db
 }
 
-func init() {
-	connectDatabase()
-}
-
-// connectDatabase 连接数据库（支持 PostgreSQL 和 MySQL）
-func connectDatabase() {
-	dbConf := config.GetDatabaseConfig()
-
+// Open 按配置连接数据库（支持 PostgreSQL 和 MySQL），debug为true时打印所有SQL
+func Open(dbConf config.DatabaseConfig, debug bool) (*gorm.DB, error) {
 	var dialector gorm.Dialector
 
 	switch dbConf.Driver {
@@ -49,14 +48,13 @@ func connectDatabase() {
 		dialector = postgres.Open(dsn)
 	case "mysql":
 		// 保留 MySQL 支持（需要导入 gorm.io/driver/mysql）
-		panic("MySQL driver not imported. Please use PostgreSQL or add mysql driver dependency.")
+		return nil, fmt.Errorf("MySQL driver not imported. Please use PostgreSQL or add mysql driver dependency")
 	default:
-		panic(fmt.Sprintf("Unsupported database driver: %s. Supported: postgres, mysql", dbConf.Driver))
+		return nil, fmt.Errorf("unsupported database driver: %q. Supported: postgres, mysql", dbConf.Driver)
 	}
 
-	var err error
-	db, err = gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(getLogLevel()),
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(getLogLevel(debug)),
 		NowFunc: func() time.Time {
 			return time.Now().Local()
 		},
@@ -65,12 +63,12 @@ func connectDatabase() {
 	})
 
 	if err != nil {
-		panic("连接数据库失败, error:" + err.Error())
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		panic("获取数据库连接池失败, error:" + err.Error())
+		return nil, fmt.Errorf("获取数据库连接池失败: %w", err)
 	}
 
 	// 设置连接池参数
@@ -85,34 +83,9 @@ func connectDatabase() {
 		}
 	}
 
-	// 自动迁移表结构
-	migrateTable()
-}
-
-// migrateTable 自动迁移表结构
-func migrateTable() {
-	// 设置多对多关联表
-	db.SetupJoinTable(&models.UserModel{}, models.UserModelTable_FollowersSlice, &models.UserFollowerModel{})
-	db.SetupJoinTable(&models.UserModel{}, models.UserModelTable_FansSlice, &models.UserFollowerModel{})
-	db.SetupJoinTable(&models.UserModel{}, models.UserModelTable_LikesSlice, &models.UserLikeModel{})
-	db.SetupJoinTable(&models.UserModel{}, models.UserModelTable_CollectionsSlice, &models.UserCollectionModel{})
-
-	db.SetupJoinTable(&models.VideoModel{}, models.VideoModelTable_LikesSlice, &models.UserLikeModel{})
-	db.SetupJoinTable(&models.VideoModel{}, models.VideoModelTable_CollectionsSlice, &models.UserCollectionModel{})
-
-	// 自动迁移所有模型
-	err := db.AutoMigrate(
-		&models.UserModel{},
-		&models.VideoModel{},
-		&models.CommentModel{},
-		&models.UserFollowerModel{},
-		&models.UserLikeModel{},
-		&models.UserCollectionModel{},
-	)
-
-	if err != nil {
-		panic("数据库表迁移失败, error:" + err.Error())
-	}
+	// 表结构由版本化迁移维护，见 RunMigrations
+	SetupJoinTables(db)
+	return db, nil
 }
 
 // getSSLMode 获取 SSL 模式（PostgreSQL）
@@ -132,8 +105,8 @@ func getTimezone(tz string) string {
 }
 
 // getLogLevel 根据环境获取日志级别
-func getLogLevel() logger.LogLevel {
-	if config.IsDebug() {
+func getLogLevel(debug bool) logger.LogLevel {
+	if debug {
 		return logger.Info
 	}
 	return logger.Warn
@@ -0,0 +1,35 @@
+// Package dbtest 测试使用的数据库，不需要PostgreSQL。
+// 使用临时目录中的SQLite文件，按模型建表，不支持行锁和PostgreSQL专有的SQL，这部分逻辑需要在PostgreSQL上验证
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 创建一个空数据库并设置为 database 包使用的数据库，测试结束时关闭
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := database.AutoMigrateModels(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	database.SetupJoinTables(db)
+	database.SetDB(db)
+	database.SetCaches(database.NewCaches(16))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
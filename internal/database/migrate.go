@@ -1,158 +1,105 @@
 package database
 
 import (
+	"embed"
 	"fmt"
-	"log"
 
-	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/migrate"
 	"gorm.io/gorm"
 )
 
-// MigrationTask 迁移任务接口
-type MigrationTask interface {
-	Name() string
-	Up(db *gorm.DB) error
-	Down(db *gorm.DB) error
+// 版本1以后的迁移都是 migrations 目录下的SQL文件，文件名为 <版本>_<名字>.up.sql 和 .down.sql。
+// 新数据库上版本1会按当前的模型建表，所以修改表结构的迁移要写成可以重复执行的形式，
+// 例如 ADD COLUMN IF NOT EXISTS
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID 迁移时持有的PostgreSQL advisory lock
+const migrationLockID int64 = 0x626f6f6b636f6d6d // "bookcomm"
+
+// baselineMigration 版本1：按模型建表。版本化迁移之前的数据库也由它补全表结构，不能回滚
+var baselineMigration = migrate.Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up:      AutoMigrateModels,
 }
 
-// MigrationManager 迁移管理器
-type MigrationManager struct {
-	db         *gorm.DB
-	migrations []MigrationTask
-}
-
-// NewMigrationManager 创建迁移管理器
-func NewMigrationManager(db *gorm.DB) *MigrationManager {
-	return &MigrationManager{
-		db:         db,
-		migrations: []MigrationTask{},
+// NewMigrator 创建db上的Migrator
+func NewMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	migrations, err := migrate.LoadFS(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
 	}
-}
-
-// Register 注册迁移任务
-func (m *MigrationManager) Register(task MigrationTask) {
-	m.migrations = append(m.migrations, task)
-}
-
-// RunUp 执行所有向上迁移
-func (m *MigrationManager) RunUp() error {
-	for _, migration := range m.migrations {
-		log.Printf("Running migration: %s", migration.Name())
-		if err := migration.Up(m.db); err != nil {
-			return fmt.Errorf("migration %s failed: %w", migration.Name(), err)
-		}
-		log.Printf("Migration %s completed successfully", migration.Name())
+	migrations = append([]migrate.Migration{baselineMigration}, migrations...)
+	migrator, err := migrate.New(db, migrationLockID, migrations)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	migrator.Logf = logrus.Infof
+	return migrator, nil
 }
 
-// RunDown 执行所有向下迁移（回滚）
-func (m *MigrationManager) RunDown() error {
-	// 逆序执行
-	for i := len(m.migrations) - 1; i >= 0; i-- {
-		migration := m.migrations[i]
-		log.Printf("Rolling back migration: %s", migration.Name())
-		if err := migration.Down(m.db); err != nil {
-			return fmt.Errorf("rollback %s failed: %w", migration.Name(), err)
-		}
-		log.Printf("Rollback %s completed successfully", migration.Name())
+// RunMigrations 执行所有未执行的迁移，启动服务时调用
+func RunMigrations(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-// --- PostgreSQL 特定优化迁移示例 ---
-
-// AddIndexesMigration 添加索引优化
-type AddIndexesMigration struct{}
-
-func (m *AddIndexesMigration) Name() string {
-	return "add_performance_indexes"
-}
-
-func (m *AddIndexesMigration) Up(db *gorm.DB) error {
-	dbConf := config.GetDatabaseConfig()
-
-	// 仅针对 PostgreSQL 创建特定索引
-	if dbConf.Driver == "postgres" || dbConf.Driver == "postgresql" {
-		// 为用户表添加索引
-		if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_users_username ON users_models(username)").Error; err != nil {
-			return err
-		}
-
-		// 为视频表添加复合索引
-		if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_videos_author_created ON video_models(author_id, created_at DESC)").Error; err != nil {
-			return err
-		}
-
-		// 为评论表添加索引
-		if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_comments_video_created ON comment_models(video_id, created_at DESC)").Error; err != nil {
-			return err
-		}
-
-		log.Println("PostgreSQL performance indexes created")
+	count, err := migrator.Up()
+	if err != nil {
+		return err
 	}
-
-	return nil
-}
-
-func (m *AddIndexesMigration) Down(db *gorm.DB) error {
-	dbConf := config.GetDatabaseConfig()
-
-	if dbConf.Driver == "postgres" || dbConf.Driver == "postgresql" {
-		db.Exec("DROP INDEX IF EXISTS idx_users_username")
-		db.Exec("DROP INDEX IF EXISTS idx_videos_author_created")
-		db.Exec("DROP INDEX IF EXISTS idx_comments_video_created")
-		log.Println("PostgreSQL indexes dropped")
+	if count > 0 {
+		logrus.Infof("applied %d migrations", count)
 	}
-
 	return nil
 }
 
-// AddFullTextSearchMigration 添加全文搜索支持（PostgreSQL）
-type AddFullTextSearchMigration struct{}
-
-func (m *AddFullTextSearchMigration) Name() string {
-	return "add_fulltext_search"
-}
-
-func (m *AddFullTextSearchMigration) Up(db *gorm.DB) error {
-	dbConf := config.GetDatabaseConfig()
-
-	// PostgreSQL 全文搜索
-	if dbConf.Driver == "postgres" || dbConf.Driver == "postgresql" {
-		// 为视频标题添加全文搜索索引
-		if err := db.Exec(`
-			CREATE INDEX IF NOT EXISTS idx_videos_title_fulltext
-			ON video_models
-			USING gin(to_tsvector('english', title))
-		`).Error; err != nil {
-			return err
-		}
-
-		log.Println("PostgreSQL full-text search indexes created")
+// SetupJoinTables 设置多对多关联使用的模型，每次连接后都需要设置
+func SetupJoinTables(db *gorm.DB) {
+	joins := []struct {
+		model     interface{}
+		field     string
+		joinTable interface{}
+	}{
+		{&models.UserModel{}, models.UserModelTable_FollowersSlice, &models.UserFollowerModel{}},
+		{&models.UserModel{}, models.UserModelTable_FansSlice, &models.UserFollowerModel{}},
+		{&models.UserModel{}, models.UserModelTable_LikesSlice, &models.UserLikeModel{}},
+		{&models.UserModel{}, models.UserModelTable_CollectionsSlice, &models.UserCollectionModel{}},
+		{&models.BookReviewModel{}, models.BookReviewModelTable_LikesSlice, &models.UserLikeModel{}},
+		{&models.BookReviewModel{}, models.BookReviewModelTable_CollectionsSlice, &models.UserCollectionModel{}},
 	}
-
-	return nil
-}
-
-func (m *AddFullTextSearchMigration) Down(db *gorm.DB) error {
-	dbConf := config.GetDatabaseConfig()
-
-	if dbConf.Driver == "postgres" || dbConf.Driver == "postgresql" {
-		db.Exec("DROP INDEX IF EXISTS idx_videos_title_fulltext")
-		log.Println("PostgreSQL full-text search indexes dropped")
+	for _, j := range joins {
+		if err := db.SetupJoinTable(j.model, j.field, j.joinTable); err != nil {
+			logrus.Errorf("setup join table for %s failed, err: %v", j.field, err)
+		}
 	}
-
-	return nil
 }
 
-// RunMigrations 执行所有迁移（供外部调用）
-func RunMigrations() error {
-	manager := NewMigrationManager(GetDB())
-
-	// 注册迁移任务
-	manager.Register(&AddIndexesMigration{})
-	manager.Register(&AddFullTextSearchMigration{})
-
-	return manager.RunUp()
+// AutoMigrateModels 按模型创建或补全表结构，测试中也用它代替版本化迁移建表
+func AutoMigrateModels(tx *gorm.DB) error {
+	return tx.AutoMigrate(
+		&models.UserModel{},
+		&models.BookReviewModel{},
+		&models.CommentModel{},
+		&models.UserFollowerModel{},
+		&models.UserLikeModel{},
+		&models.UserCollectionModel{},
+		&models.RefreshTokenModel{},
+		&models.UserTokenModel{},
+		&models.UserIdentityModel{},
+		&models.AuditLogModel{},
+		&models.ReportModel{},
+		&models.NotificationModel{},
+		&models.UserBlockModel{},
+		&models.FollowRequestModel{},
+		&models.ReviewRevisionModel{},
+		&models.DeadLetterModel{},
+		&models.ProcessedMessageModel{},
+		&models.OutboxEventModel{},
+		&models.FeatureFlagModel{},
+	)
 }
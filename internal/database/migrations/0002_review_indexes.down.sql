@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_comment_models_review_created;
+DROP INDEX IF EXISTS idx_book_reviews_author_created;
@@ -0,0 +1,5 @@
+-- 个人主页和信息流按作者、时间查询书评
+CREATE INDEX IF NOT EXISTS idx_book_reviews_author_created ON book_reviews (author_id, created_at DESC);
+
+-- 书评的评论按时间排序
+CREATE INDEX IF NOT EXISTS idx_comment_models_review_created ON comment_models (review_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- 扩展可能被其他对象使用，不删除
+DROP INDEX IF EXISTS idx_book_reviews_search_text_trgm;
+DROP INDEX IF EXISTS idx_book_reviews_title_trgm;
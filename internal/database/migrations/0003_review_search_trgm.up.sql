@@ -0,0 +1,17 @@
+-- 书评搜索使用 LIKE '%关键词%'，trigram索引可以加速任意位置的匹配。
+-- 创建pg_trgm扩展需要相应的权限，也可以由运维预先创建；
+-- 扩展不可用时跳过这两个索引，搜索仍然可用，只是不走索引
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        BEGIN
+            CREATE EXTENSION pg_trgm;
+        EXCEPTION WHEN OTHERS THEN
+            RAISE WARNING 'pg_trgm is not available, skip trigram indexes on book_reviews: %', SQLERRM;
+            RETURN;
+        END;
+    END IF;
+    CREATE INDEX IF NOT EXISTS idx_book_reviews_title_trgm ON book_reviews USING gin (title gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_book_reviews_search_text_trgm ON book_reviews USING gin (search_text gin_trgm_ops);
+END
+$$;
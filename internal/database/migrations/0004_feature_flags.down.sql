@@ -0,0 +1 @@
+DROP TABLE IF EXISTS feature_flag_models;
@@ -0,0 +1,10 @@
+-- 管理员在后台设置的功能开关
+CREATE TABLE IF NOT EXISTS feature_flag_models (
+    name varchar(64) PRIMARY KEY,
+    enabled boolean NOT NULL,
+    percentage bigint NOT NULL,
+    users text,
+    description varchar(255),
+    updated_by bigint,
+    updated_at timestamptz
+);
@@ -0,0 +1,2 @@
+ALTER TABLE outbox_event_models DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE outbox_event_models DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,3 @@
+-- relay先在短事务中领取事件，发布后再标记；发布失败的事件按退避时间重试
+ALTER TABLE outbox_event_models ADD COLUMN IF NOT EXISTS claimed_at timestamptz;
+ALTER TABLE outbox_event_models ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
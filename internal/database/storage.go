@@ -1,18 +1,17 @@
 package database
 
 import (
-	"sync"
-
-	"github.com/sylvia-ymlin/Coconut-book-community/config"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/storage"
 )
 
-var videoSaver *storage.LocalDouyinVedioSaver
-var videoInitOnce sync.Once
+var videoSaver storage.VideoStorageService[storage.SimpleObject]
+
+// SetVideoSaver 设置视频存储，没有配置 vedio.base_path 时不设置
+func SetVideoSaver(saver storage.VideoStorageService[storage.SimpleObject]) {
+	videoSaver = saver
+}
 
+// GetVideoSaver 获取视频存储，没有配置时返回nil
 func GetVideoSaver() storage.VideoStorageService[storage.SimpleObject] {
-	videoInitOnce.Do(func() {
-		videoSaver = storage.InitLocalOSS(GetMysqlDB(), config.GetVedioConfig().BasePath)
-	})
 	return videoSaver
 }
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	ReviewCreated   = "review.created"
+	ReviewPublished = "review.published"
+	ReviewLiked     = "review.liked"
+	ReviewUnliked   = "review.unliked"
+	UserFollowed    = "user.followed"
+	UserUnfollowed  = "user.unfollowed"
+)
+
+// Types 所有事件类型
+var Types = []string{ReviewCreated, ReviewPublished, ReviewLiked, ReviewUnliked, UserFollowed, UserUnfollowed}
+
+// ReviewCreatedPayload 书评创建，包括草稿和定时发布的书评。书评对读者可见时另有 ReviewPublished 事件
+type ReviewCreatedPayload struct {
+	ReviewID   uint   `json:"review_id"`
+	AuthorID   uint   `json:"author_id"`
+	Status     string `json:"status"`
+	Visibility string `json:"visibility"`
+	// 等待人工审核
+	Hidden bool `json:"hidden"`
+}
+
+// ReviewPublishedPayload 书评公开发布，包括直接发布、草稿发布和定时发布到期
+type ReviewPublishedPayload struct {
+	ReviewID   uint   `json:"review_id"`
+	AuthorID   uint   `json:"author_id"`
+	Visibility string `json:"visibility"`
+	// 等待人工审核
+	Hidden bool `json:"hidden"`
+}
+
+// ReviewLikePayload 点赞和取消点赞
+type ReviewLikePayload struct {
+	ReviewID uint `json:"review_id"`
+	UserID   uint `json:"user_id"`
+}
+
+// FollowPayload UserID关注或取消关注ToUserID
+type FollowPayload struct {
+	UserID   uint `json:"user_id"`
+	ToUserID uint `json:"to_user_id"`
+}
+
+// Event 领域事件，和业务数据在同一个事务中写入outbox，提交后发布到消息队列
+type Event struct {
+	// outbox中的ID，重新投递时不变，订阅者可以用它保证只生效一次
+	ID         uint            `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Decode 把Payload解析到v
+func (e Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("decode %s event %d: %w", e.Type, e.ID, err)
+	}
+	return nil
+}
+
+// Handler 处理一种事件。返回错误时事件会重新投递给这种事件的所有订阅者，所以需要是幂等的
+type Handler func(Event) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+var (
+	subscribers     = make(map[string][]subscriber)
+	subscribersLock sync.RWMutex
+)
+
+// Subscribe 订阅eventType，name用于日志和错误信息
+func Subscribe(eventType string, name string, handler Handler) {
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	subscribers[eventType] = append(subscribers[eventType], subscriber{name: name, handler: handler})
+}
+
+// Dispatch 依次调用事件的所有订阅者，返回所有订阅者的错误
+func Dispatch(e Event) error {
+	subscribersLock.RLock()
+	subs := subscribers[e.Type]
+	subscribersLock.RUnlock()
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.handler(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reset 清除所有订阅者，只用于测试
+func Reset() {
+	subscribersLock.Lock()
+	subscribers = make(map[string][]subscriber)
+	subscribersLock.Unlock()
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatch(t *testing.T) {
+	Reset()
+	defer Reset()
+	var calls []string
+	Subscribe(UserFollowed, "a", func(e Event) error {
+		calls = append(calls, "a")
+		return nil
+	})
+	Subscribe(UserFollowed, "b", func(e Event) error {
+		calls = append(calls, "b")
+		return errors.New("failed")
+	})
+	Subscribe(ReviewLiked, "c", func(e Event) error {
+		calls = append(calls, "c")
+		return nil
+	})
+
+	err := Dispatch(Event{ID: 1, Type: UserFollowed})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b: failed")
+	assert.Equal(t, []string{"a", "b"}, calls)
+
+	assert.NoError(t, Dispatch(Event{ID: 2, Type: UserUnfollowed}))
+}
+
+func TestEventDecode(t *testing.T) {
+	payload, err := json.Marshal(FollowPayload{UserID: 1, ToUserID: 2})
+	require.NoError(t, err)
+	var got FollowPayload
+	require.NoError(t, Event{Type: UserFollowed, Payload: payload}.Decode(&got))
+	assert.Equal(t, FollowPayload{UserID: 1, ToUserID: 2}, got)
+
+	assert.Error(t, Event{Type: UserFollowed, Payload: json.RawMessage("{")}.Decode(&got))
+}
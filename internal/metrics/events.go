@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// 订阅者收到的领域事件数，重新投递的事件会重复计数
+	DomainEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_domain_events_total",
+			Help: "Total number of domain events delivered to subscribers",
+		},
+		[]string{"type"},
+	)
+
+	// outbox中发布失败的次数
+	OutboxPublishFailuresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_outbox_publish_failures_total",
+			Help: "Total number of failed attempts to publish outbox events",
+		},
+	)
+)
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// 入队的消息数
+	QueueEnqueuedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_queue_enqueued_total",
+			Help: "Total number of messages enqueued",
+		},
+		[]string{"queue"},
+	)
+
+	// 被worker取出的消息数
+	QueueDequeuedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_queue_dequeued_total",
+			Help: "Total number of messages dequeued by workers",
+		},
+		[]string{"queue"},
+	)
+
+	// 队列已满或已关闭时丢弃或拒绝的消息数
+	QueueDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_queue_dropped_total",
+			Help: "Total number of messages dropped or rejected because the queue was full or closed",
+		},
+		[]string{"queue"},
+	)
+
+	// 处理失败的次数
+	QueueFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bookcommunity_queue_failures_total",
+			Help: "Total number of failed message handlings",
+		},
+		[]string{"queue"},
+	)
+
+	// 消息处理耗时
+	QueueHandleDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "bookcommunity_queue_handle_duration_seconds",
+			Help:    "Message handling latency in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"queue"},
+	)
+)
+
+// QueueObserver 把一个消息队列的统计事件记录到上面的指标中，
+// 实现 messageQueue.Observer
+type QueueObserver struct {
+	enqueued prometheus.Counter
+	dequeued prometheus.Counter
+	dropped  prometheus.Counter
+	failures prometheus.Counter
+	duration prometheus.Observer
+}
+
+// NewQueueObserver 创建队列的统计
+func NewQueueObserver(queue string) *QueueObserver {
+	return &QueueObserver{
+		enqueued: QueueEnqueuedTotal.WithLabelValues(queue),
+		dequeued: QueueDequeuedTotal.WithLabelValues(queue),
+		dropped:  QueueDroppedTotal.WithLabelValues(queue),
+		failures: QueueFailuresTotal.WithLabelValues(queue),
+		duration: QueueHandleDuration.WithLabelValues(queue),
+	}
+}
+
+// RegisterQueueDepth 导出队列积压的消息数，每个队列名只能调用一次
+func RegisterQueueDepth(queue string, depth func() int) {
+	promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name:        "bookcommunity_queue_depth",
+			Help:        "Number of messages waiting in the queue",
+			ConstLabels: prometheus.Labels{"queue": queue},
+		},
+		func() float64 { return float64(depth()) },
+	)
+}
+
+func (o *QueueObserver) Enqueued() { o.enqueued.Inc() }
+
+func (o *QueueObserver) Dropped() { o.dropped.Inc() }
+
+func (o *QueueObserver) Dequeued() { o.dequeued.Inc() }
+
+func (o *QueueObserver) Handled(d time.Duration) { o.duration.Observe(d.Seconds()) }
+
+func (o *QueueObserver) Failed() { o.failures.Inc() }
@@ -0,0 +1,40 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBrokerClosed broker已经关闭
+var ErrBrokerClosed = errors.New("broker closed")
+
+// Message broker中传递的消息
+type Message struct {
+	// 消息的唯一标识，重试和重放时不变
+	Key  string
+	Body []byte
+	// 之前处理失败的次数
+	Attempts int
+}
+
+// Delivery 消费者收到的一条消息，处理后必须调用 Ack 或 Nack 其中之一
+type Delivery interface {
+	Message() Message
+	// Ack 确认消息，broker删除这条消息
+	Ack() error
+	// Nack 拒绝消息，requeue为true时重新投递，否则进入broker的死信队列
+	Nack(requeue bool) error
+}
+
+// Broker 消息代理，每个topic对应一个队列
+type Broker interface {
+	// Publish 发布消息，broker确认收到后才返回。delay大于0时延迟投递，用于重试
+	Publish(ctx context.Context, topic string, msg Message, delay time.Duration) error
+	// Consume 消费topic的消息，最多同时处理prefetch条。
+	// 连接断开后自动恢复，直到ctx结束，等正在处理的消息完成后返回
+	Consume(ctx context.Context, topic string, prefetch int, handle func(Delivery)) error
+	// Depth topic的队列中等待投递的消息数
+	Depth(topic string) (int, error)
+	Close() error
+}
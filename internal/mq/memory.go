@@ -0,0 +1,184 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBroker 进程内的 Broker 实现，消息只保存在内存中，用于测试和不需要外部broker的部署
+type MemoryBroker struct {
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+	timers map[*time.Timer]struct{}
+	closed bool
+}
+
+type memoryTopic struct {
+	ready []Message
+	// 被Nack(false)拒绝的消息
+	dead []Message
+	// 有新消息时关闭并换成新的通道，唤醒消费者
+	notify chan struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics: make(map[string]*memoryTopic),
+		timers: make(map[*time.Timer]struct{}),
+	}
+}
+
+// topic (调用需要加锁)
+func (b *MemoryBroker) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// push (调用需要加锁)
+func (b *MemoryBroker) push(topic string, msg Message) {
+	t := b.topic(topic)
+	t.ready = append(t.ready, msg)
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, msg Message, delay time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg.Body = append([]byte(nil), msg.Body...)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	if delay <= 0 {
+		b.push(topic, msg)
+		return nil
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.timers, timer)
+		if !b.closed {
+			b.push(topic, msg)
+		}
+	})
+	b.timers[timer] = struct{}{}
+	return nil
+}
+
+// next 取出一条消息，没有消息时等待，ctx结束或broker关闭时返回false
+func (b *MemoryBroker) next(ctx context.Context, topic string) (Message, bool) {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return Message{}, false
+		}
+		t := b.topic(topic)
+		if len(t.ready) > 0 {
+			msg := t.ready[0]
+			t.ready = t.ready[1:]
+			b.mu.Unlock()
+			return msg, true
+		}
+		notify := t.notify
+		b.mu.Unlock()
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return Message{}, false
+		}
+	}
+}
+
+func (b *MemoryBroker) Consume(ctx context.Context, topic string, prefetch int, handle func(Delivery)) error {
+	if prefetch <= 0 {
+		prefetch = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(prefetch)
+	for i := 0; i < prefetch; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				msg, ok := b.next(ctx, topic)
+				if !ok {
+					return
+				}
+				handle(&memoryDelivery{broker: b, topic: topic, msg: msg})
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (b *MemoryBroker) Depth(topic string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.topic(topic).ready), nil
+}
+
+// DeadLetters topic中被拒绝且不重新投递的消息
+func (b *MemoryBroker) DeadLetters(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.topic(topic).dead...)
+}
+
+// Close 停止投递，丢弃等待中的延迟消息。多次调用是安全的
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for timer := range b.timers {
+		timer.Stop()
+	}
+	for _, t := range b.topics {
+		close(t.notify)
+		t.notify = make(chan struct{})
+	}
+	return nil
+}
+
+type memoryDelivery struct {
+	broker *MemoryBroker
+	topic  string
+	msg    Message
+	once   sync.Once
+}
+
+func (d *memoryDelivery) Message() Message {
+	return d.msg
+}
+
+func (d *memoryDelivery) Ack() error {
+	d.once.Do(func() {})
+	return nil
+}
+
+func (d *memoryDelivery) Nack(requeue bool) error {
+	d.once.Do(func() {
+		b := d.broker
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if requeue && !b.closed {
+			b.push(d.topic, d.msg)
+			return
+		}
+		t := b.topic(d.topic)
+		t.dead = append(t.dead, d.msg)
+	})
+	return nil
+}
@@ -0,0 +1,262 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+)
+
+const (
+	defaultPrefetch     = 10
+	defaultMaxAttempts  = 5
+	defaultBaseBackoff  = time.Second
+	defaultMaxBackoff   = 5 * time.Minute
+	consumeRetryBackoff = time.Second
+)
+
+// QueueConfig Queue 的配置，零值的字段使用默认值
+type QueueConfig struct {
+	// 队列对应的topic，也是死信中记录的队列名
+	Topic string
+	// 同时处理的消息数，默认10
+	Prefetch int
+	// 最多处理的次数(包括第一次)，默认5
+	MaxAttempts int
+	// 第n次失败后等待 BaseBackoff*2^(n-1)，最多 MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// 发布消息时等待broker确认的时间，默认5秒
+	PublishTimeout time.Duration
+	// 保存死信，返回错误或为nil时消息交给broker的死信队列
+	DeadLetter func(messageQueue.DeadLetter) error
+	// 发布重试、保存死信等失败时调用，默认忽略
+	OnError func(error)
+	// 统计事件的接收者，默认忽略
+	Observer messageQueue.Observer
+}
+
+// Queue 基于 Broker 的消息队列，实现 messageQueue.MQ。
+// 消息以JSON发布，处理失败时带着新的失败次数延迟重新发布，超过次数后放入死信
+type Queue[T any] struct {
+	broker  Broker
+	conf    QueueConfig
+	handler func(messageQueue.Message[T]) error
+
+	mu     sync.Mutex
+	closed bool
+	cancel context.CancelFunc
+	// 消费者退出后关闭
+	done chan struct{}
+}
+
+// NewQueue 创建队列并开始消费，handler会被并发调用
+func NewQueue[T any](broker Broker, conf QueueConfig, handler func(messageQueue.Message[T]) error) *Queue[T] {
+	if conf.Prefetch <= 0 {
+		conf.Prefetch = defaultPrefetch
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = defaultMaxAttempts
+	}
+	if conf.BaseBackoff <= 0 {
+		conf.BaseBackoff = defaultBaseBackoff
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = defaultMaxBackoff
+	}
+	if conf.MaxBackoff < conf.BaseBackoff {
+		conf.MaxBackoff = conf.BaseBackoff
+	}
+	if conf.PublishTimeout <= 0 {
+		conf.PublishTimeout = defaultPublishTimeout
+	}
+	if conf.Observer == nil {
+		conf.Observer = nopObserver{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue[T]{
+		broker:  broker,
+		conf:    conf,
+		handler: handler,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go q.consume(ctx)
+	return q
+}
+
+// consume Consume 因为声明队列失败等原因返回时等待后重新开始
+func (q *Queue[T]) consume(ctx context.Context) {
+	defer close(q.done)
+	for {
+		err := q.broker.Consume(ctx, q.conf.Topic, q.conf.Prefetch, q.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			q.reportError(fmt.Errorf("queue %s: consume: %w", q.conf.Topic, err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(consumeRetryBackoff):
+		}
+	}
+}
+
+func (q *Queue[T]) reportError(err error) {
+	if q.conf.OnError != nil {
+		q.conf.OnError(err)
+	}
+}
+
+// Push 用新生成的Key发布消息，broker确认后返回
+func (q *Queue[T]) Push(msg T) error {
+	return q.PushCtx(context.Background(), msg)
+}
+
+// PushCtx 用新生成的Key发布消息，broker确认后返回
+func (q *Queue[T]) PushCtx(ctx context.Context, msg T) error {
+	return q.Enqueue(ctx, messageQueue.NewMessageKey(), msg)
+}
+
+// Enqueue 用指定的Key发布消息，最多等待 PublishTimeout
+func (q *Queue[T]) Enqueue(ctx context.Context, key string, msg T) error {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		q.conf.Observer.Dropped()
+		return messageQueue.ErrClosed
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, q.conf.PublishTimeout)
+	defer cancel()
+	if err := q.broker.Publish(ctx, q.conf.Topic, Message{Key: key, Body: body}, 0); err != nil {
+		q.conf.Observer.Dropped()
+		return err
+	}
+	q.conf.Observer.Enqueued()
+	return nil
+}
+
+// EnqueueRaw 把JSON格式的消息体入队，用于重放死信
+func (q *Queue[T]) EnqueueRaw(key string, body []byte) error {
+	var msg T
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	return q.Enqueue(context.Background(), key, msg)
+}
+
+// Len broker中等待投递的消息数，查询失败时返回0
+func (q *Queue[T]) Len() int {
+	n, err := q.broker.Depth(q.conf.Topic)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Close 停止接收新消息和消费，等待正在处理的消息完成或ctx结束。
+// 没有处理的消息留在broker中，下次启动时继续处理。不会关闭broker
+func (q *Queue[T]) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cancel()
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue[T]) handle(msg messageQueue.Message[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return q.handler(msg)
+}
+
+func (q *Queue[T]) deliver(d Delivery) {
+	q.conf.Observer.Dequeued()
+	raw := d.Message()
+	msg := messageQueue.Message[T]{Key: raw.Key, Attempts: raw.Attempts}
+	start := time.Now()
+	err := json.Unmarshal(raw.Body, &msg.Body)
+	if err != nil {
+		err = messageQueue.Permanent(fmt.Errorf("decode message: %w", err))
+	} else {
+		err = q.handle(msg)
+	}
+	q.conf.Observer.Handled(time.Since(start))
+	if err == nil {
+		q.ack(d)
+		return
+	}
+	q.conf.Observer.Failed()
+	raw.Attempts++
+	if messageQueue.IsPermanent(err) || raw.Attempts >= q.conf.MaxAttempts {
+		q.deadLetter(d, raw, err)
+		return
+	}
+	// 先发布带着新失败次数的消息再确认原消息，发布失败时让broker重新投递原消息
+	delay := messageQueue.RetryDelay(q.conf.BaseBackoff, q.conf.MaxBackoff, raw.Attempts)
+	ctx, cancel := context.WithTimeout(context.Background(), q.conf.PublishTimeout)
+	defer cancel()
+	if pubErr := q.broker.Publish(ctx, q.conf.Topic, raw, delay); pubErr != nil {
+		q.reportError(fmt.Errorf("queue %s: publish retry %s: %w", q.conf.Topic, raw.Key, pubErr))
+		q.nack(d, true)
+		return
+	}
+	q.ack(d)
+}
+
+func (q *Queue[T]) deadLetter(d Delivery, raw Message, cause error) {
+	if q.conf.DeadLetter != nil {
+		err := q.conf.DeadLetter(messageQueue.DeadLetter{
+			Queue:    q.conf.Topic,
+			Key:      raw.Key,
+			Body:     raw.Body,
+			Attempts: raw.Attempts,
+			Error:    cause.Error(),
+			FailedAt: time.Now(),
+		})
+		if err == nil {
+			q.ack(d)
+			return
+		}
+		q.reportError(fmt.Errorf("queue %s: save dead letter %s: %w", q.conf.Topic, raw.Key, err))
+	}
+	q.nack(d, false)
+}
+
+func (q *Queue[T]) ack(d Delivery) {
+	if err := d.Ack(); err != nil {
+		q.reportError(fmt.Errorf("queue %s: ack %s: %w", q.conf.Topic, d.Message().Key, err))
+	}
+}
+
+func (q *Queue[T]) nack(d Delivery, requeue bool) {
+	if err := d.Nack(requeue); err != nil {
+		q.reportError(fmt.Errorf("queue %s: nack %s: %w", q.conf.Topic, d.Message().Key, err))
+	}
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Enqueued()             {}
+func (nopObserver) Dropped()              {}
+func (nopObserver) Dequeued()             {}
+func (nopObserver) Handled(time.Duration) {}
+func (nopObserver) Failed()               {}
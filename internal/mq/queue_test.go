@@ -0,0 +1,178 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+)
+
+type testMsg struct {
+	N int `json:"n"`
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestQueue(broker Broker, conf QueueConfig, handler func(messageQueue.Message[testMsg]) error) *Queue[testMsg] {
+	if conf.Topic == "" {
+		conf.Topic = "test"
+	}
+	if conf.BaseBackoff == 0 {
+		conf.BaseBackoff = time.Millisecond
+		conf.MaxBackoff = 5 * time.Millisecond
+	}
+	return NewQueue(broker, conf, handler)
+}
+
+func TestQueue_Deliver(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	var mu sync.Mutex
+	got := make(map[int]bool)
+	q := newTestQueue(broker, QueueConfig{Prefetch: 4}, func(m messageQueue.Message[testMsg]) error {
+		mu.Lock()
+		got[m.Body.N] = true
+		mu.Unlock()
+		return nil
+	})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, q.PushCtx(context.Background(), testMsg{N: i}))
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 100
+	})
+	require.NoError(t, q.Close(context.Background()))
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueue_RetryKeepsKey(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	var mu sync.Mutex
+	var keys []string
+	var attempts []int
+	q := newTestQueue(broker, QueueConfig{}, func(m messageQueue.Message[testMsg]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, m.Key)
+		attempts = append(attempts, m.Attempts)
+		if m.Attempts < 2 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	require.NoError(t, q.Enqueue(context.Background(), "k1", testMsg{N: 1}))
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(keys) == 3
+	})
+	require.NoError(t, q.Close(context.Background()))
+	assert.Equal(t, []string{"k1", "k1", "k1"}, keys)
+	assert.Equal(t, []int{0, 1, 2}, attempts)
+}
+
+func TestQueue_DeadLetter(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	var mu sync.Mutex
+	var dead []messageQueue.DeadLetter
+	conf := QueueConfig{
+		MaxAttempts: 3,
+		DeadLetter: func(d messageQueue.DeadLetter) error {
+			mu.Lock()
+			dead = append(dead, d)
+			mu.Unlock()
+			return nil
+		},
+	}
+	q := newTestQueue(broker, conf, func(m messageQueue.Message[testMsg]) error {
+		if m.Body.N == 1 {
+			return messageQueue.Permanent(errors.New("bad message"))
+		}
+		return errors.New("always fails")
+	})
+	require.NoError(t, q.Enqueue(context.Background(), "permanent", testMsg{N: 1}))
+	require.NoError(t, q.Enqueue(context.Background(), "retried", testMsg{N: 2}))
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(dead) == 2
+	})
+	require.NoError(t, q.Close(context.Background()))
+
+	byKey := map[string]messageQueue.DeadLetter{dead[0].Key: dead[0], dead[1].Key: dead[1]}
+	assert.Equal(t, 1, byKey["permanent"].Attempts)
+	assert.Equal(t, "bad message", byKey["permanent"].Error)
+	assert.Equal(t, 3, byKey["retried"].Attempts)
+	assert.JSONEq(t, `{"n":2}`, string(byKey["retried"].Body))
+	assert.Equal(t, "test", byKey["retried"].Queue)
+	assert.Empty(t, broker.DeadLetters("test"))
+}
+
+func TestQueue_DeadLetterFallbackToBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	conf := QueueConfig{
+		DeadLetter: func(messageQueue.DeadLetter) error { return errors.New("db down") },
+	}
+	q := newTestQueue(broker, conf, func(m messageQueue.Message[testMsg]) error {
+		panic("boom")
+	})
+	require.NoError(t, q.Enqueue(context.Background(), "k", testMsg{N: 1}))
+	waitFor(t, func() bool { return len(broker.DeadLetters("test")) == 1 })
+	require.NoError(t, q.Close(context.Background()))
+	assert.Equal(t, "k", broker.DeadLetters("test")[0].Key)
+}
+
+func TestQueue_UndecodableMessage(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	called := make(chan struct{}, 1)
+	q := newTestQueue(broker, QueueConfig{}, func(m messageQueue.Message[testMsg]) error {
+		called <- struct{}{}
+		return nil
+	})
+	require.NoError(t, broker.Publish(context.Background(), "test", Message{Key: "bad", Body: []byte("{")}, 0))
+	waitFor(t, func() bool { return len(broker.DeadLetters("test")) == 1 })
+	require.NoError(t, q.Close(context.Background()))
+	assert.Empty(t, called)
+}
+
+func TestQueue_CloseWaitsForHandler(t *testing.T) {
+	broker := NewMemoryBroker()
+	defer broker.Close()
+	started := make(chan struct{})
+	block := make(chan struct{})
+	q := newTestQueue(broker, QueueConfig{Prefetch: 1}, func(m messageQueue.Message[testMsg]) error {
+		close(started)
+		<-block
+		return nil
+	})
+	require.NoError(t, q.PushCtx(context.Background(), testMsg{N: 1}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, q.PushCtx(context.Background(), testMsg{N: 2}), messageQueue.ErrClosed)
+
+	close(block)
+	require.NoError(t, q.Close(context.Background()))
+}
@@ -2,97 +2,402 @@ package mq
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	enabled bool
+const (
+	defaultExchange          = "bookcommunity"
+	defaultQueuePrefix       = "bookcommunity"
+	defaultReconnectInterval = 5 * time.Second
+	defaultPublishTimeout    = 5 * time.Second
+
+	// 消息失败次数所在的header
+	headerAttempts = "x-attempts"
 )
 
-// Config RabbitMQ配置
+// Config RabbitMQ配置，零值的字段使用默认值
 type Config struct {
-	Enabled  bool
-	URL      string
+	URL string
+	// 业务消息的direct交换机，topic作为routing key
 	Exchange string
-	Queue    string
+	// topic对应的队列名为 QueuePrefix.topic
+	QueuePrefix string
+	// 连接断开后重连的间隔
+	ReconnectInterval time.Duration
 }
 
-// InitRabbitMQ 初始化RabbitMQ连接
-func InitRabbitMQ(config Config) error {
-	if !config.Enabled {
-		logrus.Info("RabbitMQ is disabled")
-		enabled = false
-		return nil
-	}
+// RabbitBroker 基于RabbitMQ的 Broker 实现。
+//
+// 每个topic声明以下拓扑:
+//   - 队列 prefix.topic，绑定到 Exchange，被拒绝的消息进入 Exchange.dlx
+//   - 队列 prefix.topic.retry，没有消费者，消息按各自的过期时间回到 Exchange，用于延迟重试
+//   - 队列 prefix.topic.dead，绑定到 Exchange.dlx，保存被拒绝的消息
+//
+// 连接断开后在后台重连，发布和消费在新连接上重新打开channel
+type RabbitBroker struct {
+	conf Config
 
-	var err error
-	conn, err = amqp.Dial(config.URL)
+	mu   sync.Mutex
+	conn *amqp.Connection
+	// 发布用的channel，开启了publisher confirm
+	pubCh *amqp.Channel
+	// 当前连接上已经声明过拓扑的topic
+	declared map[string]bool
+	// 连接可用时关闭，断开后换成新的通道
+	ready  chan struct{}
+	closed bool
+	// Close 时关闭
+	stop chan struct{}
+}
+
+// NewRabbitBroker 连接RabbitMQ，第一次连接失败时返回错误
+func NewRabbitBroker(conf Config) (*RabbitBroker, error) {
+	if conf.Exchange == "" {
+		conf.Exchange = defaultExchange
+	}
+	if conf.QueuePrefix == "" {
+		conf.QueuePrefix = defaultQueuePrefix
+	}
+	if conf.ReconnectInterval <= 0 {
+		conf.ReconnectInterval = defaultReconnectInterval
+	}
+	conn, err := amqp.Dial(conf.URL)
 	if err != nil {
-		logrus.Warnf("Failed to connect to RabbitMQ: %v, message queue disabled", err)
-		enabled = false
+		return nil, fmt.Errorf("connect to rabbitmq: %w", err)
+	}
+	b := &RabbitBroker{
+		conf:     conf,
+		conn:     conn,
+		declared: make(map[string]bool),
+		ready:    make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	close(b.ready)
+	go b.watch(conn)
+	return b, nil
+}
+
+// watch 连接断开后不断重连，直到 Close
+func (b *RabbitBroker) watch(conn *amqp.Connection) {
+	for {
+		amqpErr := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return
+		}
+		b.conn = nil
+		b.pubCh = nil
+		b.declared = make(map[string]bool)
+		b.ready = make(chan struct{})
+		b.mu.Unlock()
+		logrus.Warnf("rabbitmq connection lost: %v, reconnecting", amqpErr)
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-time.After(b.conf.ReconnectInterval):
+			}
+			var err error
+			conn, err = amqp.Dial(b.conf.URL)
+			if err == nil {
+				break
+			}
+			logrus.Warnf("reconnect to rabbitmq failed: %v", err)
+		}
+
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			conn.Close()
+			return
+		}
+		b.conn = conn
+		close(b.ready)
+		b.mu.Unlock()
+		logrus.Info("rabbitmq reconnected")
+	}
+}
+
+// connection 返回当前连接，正在重连时等待
+func (b *RabbitBroker) connection(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return nil, ErrBrokerClosed
+		}
+		conn, ready := b.conn, b.ready
+		b.mu.Unlock()
+		if conn != nil && !conn.IsClosed() {
+			return conn, nil
+		}
+		select {
+		case <-ready:
+		case <-b.stop:
+			return nil, ErrBrokerClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if conn != nil {
+			// 连接刚断开，watch还没有换掉它
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+func (b *RabbitBroker) queueName(topic string) string {
+	return b.conf.QueuePrefix + "." + topic
+}
+
+func (b *RabbitBroker) retryQueueName(topic string) string {
+	return b.queueName(topic) + ".retry"
+}
+
+func (b *RabbitBroker) deadExchange() string {
+	return b.conf.Exchange + ".dlx"
+}
+
+// declare 声明topic的交换机和队列，重复声明是幂等的
+func (b *RabbitBroker) declare(ch *amqp.Channel, topic string) error {
+	if err := ch.ExchangeDeclare(b.conf.Exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.ExchangeDeclare(b.deadExchange(), amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
 		return err
 	}
+	queue := b.queueName(topic)
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    b.deadExchange(),
+		"x-dead-letter-routing-key": topic,
+	}); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(queue, topic, b.conf.Exchange, false, nil); err != nil {
+		return err
+	}
+	// 过期的消息回到业务交换机
+	if _, err := ch.QueueDeclare(b.retryQueueName(topic), true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    b.conf.Exchange,
+		"x-dead-letter-routing-key": topic,
+	}); err != nil {
+		return err
+	}
+	dead := queue + ".dead"
+	if _, err := ch.QueueDeclare(dead, true, false, false, false, nil); err != nil {
+		return err
+	}
+	return ch.QueueBind(dead, topic, b.deadExchange(), false, nil)
+}
 
-	channel, err = conn.Channel()
+// publishChannel 返回发布用的channel，没有时在当前连接上打开并开启publisher confirm
+func (b *RabbitBroker) publishChannel(ctx context.Context, topic string) (*amqp.Channel, error) {
+	conn, err := b.connection(ctx)
 	if err != nil {
-		logrus.Warnf("Failed to open channel: %v", err)
-		enabled = false
-		return err
+		return nil, err
 	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pubCh == nil || b.pubCh.IsClosed() {
+		ch, err := conn.Channel()
+		if err != nil {
+			return nil, err
+		}
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return nil, err
+		}
+		b.pubCh = ch
+		b.declared = make(map[string]bool)
+	}
+	if !b.declared[topic] {
+		if err := b.declare(b.pubCh, topic); err != nil {
+			return nil, fmt.Errorf("declare topology for %s: %w", topic, err)
+		}
+		b.declared[topic] = true
+	}
+	return b.pubCh, nil
+}
 
-	enabled = true
-	logrus.Infof("✅ RabbitMQ connected successfully: %s", config.URL)
+// Publish 发布持久化消息并等待broker确认。
+// 延迟消息发到重试队列，由于RabbitMQ只在队首检查过期时间，延迟可能比指定的更长
+func (b *RabbitBroker) Publish(ctx context.Context, topic string, msg Message, delay time.Duration) error {
+	ch, err := b.publishChannel(ctx, topic)
+	if err != nil {
+		return err
+	}
+	pub := amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.Key,
+		Timestamp:    time.Now(),
+		Headers:      amqp.Table{headerAttempts: int32(msg.Attempts)},
+		Body:         msg.Body,
+	}
+	exchange, routingKey := b.conf.Exchange, topic
+	if delay > 0 {
+		exchange, routingKey = "", b.retryQueueName(topic)
+		pub.Expiration = strconv.FormatInt(delay.Milliseconds(), 10)
+	}
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, pub)
+	if err != nil {
+		return err
+	}
+	ok, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("message nacked by rabbitmq")
+	}
 	return nil
 }
 
-// IsEnabled 检查RabbitMQ是否启用
-func IsEnabled() bool {
-	return enabled
+// Consume 在独立的channel上消费，channel或连接断开后等待重连并重新消费
+func (b *RabbitBroker) Consume(ctx context.Context, topic string, prefetch int, handle func(Delivery)) error {
+	if prefetch <= 0 {
+		prefetch = 1
+	}
+	for {
+		err := b.consumeOnce(ctx, topic, prefetch, handle)
+		if ctx.Err() != nil || errors.Is(err, ErrBrokerClosed) {
+			return nil
+		}
+		if err != nil {
+			logrus.Warnf("rabbitmq consumer of %s stopped: %v", topic, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.stop:
+			return nil
+		case <-time.After(b.conf.ReconnectInterval):
+		}
+	}
 }
 
-// Publish 发布消息
-func Publish(exchange, routingKey string, message interface{}) error {
-	if !enabled {
-		logrus.Warn("RabbitMQ is not enabled, message dropped")
-		return fmt.Errorf("rabbitmq is not enabled")
+func (b *RabbitBroker) consumeOnce(ctx context.Context, topic string, prefetch int, handle func(Delivery)) error {
+	conn, err := b.connection(ctx)
+	if err != nil {
+		return err
 	}
-
-	body, err := json.Marshal(message)
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := b.declare(ch, topic); err != nil {
+		return fmt.Errorf("declare topology for %s: %w", topic, err)
+	}
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		return err
+	}
+	tag := "consumer-" + topic + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	deliveries, err := ch.Consume(b.queueName(topic), tag, false, false, false, false, nil)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// ctx结束时取消消费，已经收到的消息处理完后deliveries被关闭
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			ch.Cancel(tag, false)
+		case <-b.stop:
+			ch.Cancel(tag, false)
+		case <-stopped:
+		}
+	}()
 
-	return channel.PublishWithContext(
-		ctx,
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Timestamp:   time.Now(),
-		},
-	)
+	var wg sync.WaitGroup
+	wg.Add(prefetch)
+	for i := 0; i < prefetch; i++ {
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				handle(rabbitDelivery{d})
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("delivery channel closed")
 }
 
-// Close 关闭连接
-func Close() error {
-	if channel != nil {
-		channel.Close()
+// Depth 被动声明队列，读取其中等待投递的消息数
+func (b *RabbitBroker) Depth(topic string) (int, error) {
+	b.mu.Lock()
+	conn := b.conn
+	b.mu.Unlock()
+	if conn == nil || conn.IsClosed() {
+		return 0, errors.New("rabbitmq not connected")
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, err
 	}
-	if conn != nil {
+	defer ch.Close()
+	q, err := ch.QueueDeclarePassive(b.queueName(topic), true, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
+
+// Close 关闭连接，正在运行的 Consume 退出。多次调用是安全的
+func (b *RabbitBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.stop)
+	conn := b.conn
+	b.mu.Unlock()
+	if conn != nil && !conn.IsClosed() {
 		return conn.Close()
 	}
 	return nil
 }
+
+type rabbitDelivery struct {
+	d amqp.Delivery
+}
+
+func (r rabbitDelivery) Message() Message {
+	attempts := 0
+	switch v := r.d.Headers[headerAttempts].(type) {
+	case int32:
+		attempts = int(v)
+	case int64:
+		attempts = int(v)
+	case int:
+		attempts = v
+	}
+	return Message{Key: r.d.MessageId, Body: r.d.Body, Attempts: attempts}
+}
+
+func (r rabbitDelivery) Ack() error {
+	return r.d.Ack(false)
+}
+
+func (r rabbitDelivery) Nack(requeue bool) error {
+	return r.d.Nack(false, requeue)
+}
@@ -24,7 +24,7 @@ const (
 	ActionTypeDelete  = "2"
 )
 
-var commentMQ *messageQueue.SimpleMQ[CommentMsg]
+var commentMQ messageQueue.MQ[CommentMsg]
 var commentMQInitOnce sync.Once
 
 func GetCommentMQ() messageQueue.MQ[CommentMsg] {
@@ -33,31 +33,42 @@ func GetCommentMQ() messageQueue.MQ[CommentMsg] {
 
 const commentWorkerNum int = 10
 
-func InitCommentMQ() {
+func InitCommentMQ() error {
+	var err error
 	commentMQInitOnce.Do(func() {
-		commentMQ = messageQueue.NewSimpleMQ(commentWorkerNum, CommentMsgHandler)
+		commentMQ, err = newQueue(QueueComment, commentWorkerNum, CommentMsgHandler)
 	})
+	return err
 }
 
-func CommentMsgHandler(msg CommentMsg) {
+// CommentMsgHandler 处理发表和删除评论的消息，返回错误时消息会重试。
+// key为消息Key，用于保证重试的消息不会重复发表评论
+func CommentMsgHandler(key string, msg CommentMsg) error {
 	if msg.ActionType == ActionTypeComment {
 		// 发表评论
 		//logrus.Debug("发表评论：", "video_id:", msg.VideoID, "commenter_id:", msg.CommenterID, "comment_text:", msg.CommentText)
-		err := services.CommentVideo(msg.VideoID, msg.CommenterID, msg.CommentText)
+		err := services.CommentVideo(key, msg.VideoID, msg.CommenterID, msg.CommentText)
 		if err != nil {
 			logrus.Error("发表评论失败：", err)
+			return err
 		}
 	} else if msg.ActionType == ActionTypeDelete {
 		if msg.CommentId == 0 {
-			return
+			return nil
 		}
 		// 删除评论
 		//logrus.Debug("删除评论：", "comment_id:", msg.CommentId, "commenter_id:", msg.CommenterID)
-		err := services.DeleteComment(msg.CommentId, msg.CommenterID)
-		if err != nil {
+		err := services.DeleteComment(key, msg.CommentId, msg.CommenterID)
+		if err != nil && !settled(err) {
 			logrus.Error("删除评论失败：", err)
+			if err.Error() == services.ErrDeleteNotOwner {
+				return messageQueue.Permanent(err)
+			}
+			return err
 		}
 	} else {
 		logrus.Error("不合法的参数：", msg)
+		return messageQueue.Permanent(errInvalidMsg)
 	}
+	return nil
 }
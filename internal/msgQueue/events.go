@@ -0,0 +1,43 @@
+package msgQueue
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/events"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+)
+
+const eventWorkerNum int = 4
+
+var eventMQ messageQueue.MQ[events.Event]
+var eventMQInitOnce sync.Once
+
+// InitEventMQ 创建事件队列。内存队列中的事件在重启时丢失，而放入队列后outbox就标记为已发布，
+// 所以内存后端不创建队列，由 PublishEvent 直接交给订阅者
+func InitEventMQ() error {
+	var err error
+	eventMQInitOnce.Do(func() {
+		switch strings.ToLower(config.GetMessageQueueConfig().Backend) {
+		case backendDurable, backendRabbitMQ:
+			eventMQ, err = newQueue(QueueEvents, eventWorkerNum, EventMsgHandler)
+		}
+	})
+	return err
+}
+
+// PublishEvent 把outbox中的事件放入队列，供 services.RunOutboxRelay 使用。
+// 没有事件队列时同步调用订阅者，所有订阅者成功后事件才算发布，失败时由relay重试
+func PublishEvent(ctx context.Context, e events.Event) error {
+	if eventMQ == nil {
+		return events.Dispatch(e)
+	}
+	return eventMQ.PushCtx(ctx, e)
+}
+
+// EventMsgHandler 把事件交给所有订阅者，有订阅者失败时整个事件重试
+func EventMsgHandler(key string, e events.Event) error {
+	return events.Dispatch(e)
+}
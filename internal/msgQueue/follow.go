@@ -3,6 +3,7 @@ package msgQueue
 import (
 	"sync"
 
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/response"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
 	"github.com/sirupsen/logrus"
@@ -15,7 +16,7 @@ const (
 
 const followWorkerNum int = 10
 
-var followMQ *messageQueue.SimpleMQ[FollowMsg]
+var followMQ messageQueue.MQ[FollowMsg]
 var followMQInitOnce sync.Once
 
 type FollowMsg struct {
@@ -30,26 +31,35 @@ func GetFollowMQ() messageQueue.MQ[FollowMsg] {
 	return followMQ
 }
 
-func InitFollowMQ() {
+func InitFollowMQ() error {
+	var err error
 	followMQInitOnce.Do(func() {
-		followMQ = messageQueue.NewSimpleMQ(followWorkerNum, FollowMsgHandler)
+		followMQ, err = newQueue(QueueFollow, followWorkerNum, FollowMsgHandler)
 	})
+	return err
 }
 
-func FollowMsgHandler(msg FollowMsg) {
+// FollowMsgHandler 处理关注和取消关注的消息，返回错误时消息会重试。
+// key为消息Key，用于保证重试的消息不会重复计数
+func FollowMsgHandler(key string, msg FollowMsg) error {
+	var err error
 	if msg.ActionType == ActionType_Follow {
 		// 关注
-		err := services.FollowUser(msg.UserID, msg.ToUserID)
-		if err != nil {
-			logrus.Error("关注失败：", err)
-		}
+		err = services.FollowUser(key, msg.UserID, msg.ToUserID)
 	} else if msg.ActionType == ActionType_Unfollow {
 		// 取消关注
-		err := services.UnfollowUser(msg.UserID, msg.ToUserID)
-		if err != nil {
-			logrus.Error("取消关注失败：", err)
-		}
+		err = services.UnfollowUser(key, msg.UserID, msg.ToUserID)
 	} else {
 		logrus.Error("不合法的参数：", msg)
+		return messageQueue.Permanent(errInvalidMsg)
 	}
+	if err == nil || settled(err) {
+		return nil
+	}
+	logrus.Error("关注或取消关注失败：", err)
+	// 入队后被拉黑，不再关注
+	if err.Error() == response.ErrBlocked {
+		return nil
+	}
+	return err
 }
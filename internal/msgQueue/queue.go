@@ -0,0 +1,235 @@
+package msgQueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/services"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/metrics"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/mq"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/pkg/messageQueue"
+)
+
+const defaultQueueDir = "./data/queue"
+
+// message_queue.backend 的取值，其他值使用内存队列
+const (
+	backendDurable  = "durable"
+	backendRabbitMQ = "rabbitmq"
+)
+
+// 队列名，也是死信中记录的队列
+const (
+	QueueComment = "comment"
+	QueueFollow  = "follow"
+	QueueEvents  = "events"
+)
+
+var (
+	// 按队列名重新放入消息，用于重放死信
+	replayers = make(map[string]func(key string, body []byte) error)
+	// 已经创建的队列，关闭服务时依次关闭
+	closers    []func(ctx context.Context) error
+	queuesLock sync.RWMutex
+
+	// 各队列共享的RabbitMQ连接
+	broker     *mq.RabbitBroker
+	brokerErr  error
+	brokerOnce sync.Once
+)
+
+// getBroker 第一次调用时连接RabbitMQ，连接失败时返回错误
+func getBroker() (*mq.RabbitBroker, error) {
+	brokerOnce.Do(func() {
+		conf := config.GetRabbitMQConfig()
+		broker, brokerErr = mq.NewRabbitBroker(mq.Config{
+			URL:               conf.URL,
+			Exchange:          conf.Exchange,
+			QueuePrefix:       conf.QueuePrefix,
+			ReconnectInterval: parseDuration("rabbitmq reconnect interval", conf.ReconnectInterval),
+		})
+	})
+	return broker, brokerErr
+}
+
+func brokerQueueConfig(name string, conf config.MessageQueueConfig, observer messageQueue.Observer) mq.QueueConfig {
+	rabbit := config.GetRabbitMQConfig()
+	return mq.QueueConfig{
+		Topic:          name,
+		Prefetch:       rabbit.Prefetch,
+		MaxAttempts:    conf.MaxAttempts,
+		BaseBackoff:    parseDuration("message queue backoff", conf.RetryBackoff),
+		MaxBackoff:     parseDuration("message queue backoff", conf.MaxRetryBackoff),
+		PublishTimeout: parseDuration("rabbitmq publish timeout", rabbit.PublishTimeout),
+		DeadLetter:     services.SaveDeadLetter,
+		OnError: func(err error) {
+			logrus.Error("rabbitmq queue: ", err)
+		},
+		Observer: observer,
+	}
+}
+
+// newQueue 按 message_queue.backend 创建队列: durable为写磁盘日志的持久化队列，
+// rabbitmq使用共享的broker，其他值使用内存队列。durable和rabbitmq创建失败时返回错误，
+// 不会退回到内存队列，否则重启时会悄悄丢失消息。
+// handle 返回错误时持久化队列和rabbitmq会重试，内存队列只记录日志
+func newQueue[T any](name string, workerNum int, handle func(key string, msg T) error) (messageQueue.MQ[T], error) {
+	conf := config.GetMessageQueueConfig()
+	observer := metrics.NewQueueObserver(name)
+	handler := func(m messageQueue.Message[T]) error {
+		return handle(m.Key, m.Body)
+	}
+	var queue messageQueue.MQ[T]
+	switch strings.ToLower(conf.Backend) {
+	case backendDurable:
+		durable, err := messageQueue.NewDurableMQ(durableConfig(name, workerNum, conf, observer), handler)
+		if err != nil {
+			return nil, fmt.Errorf("open durable queue %s: %w", name, err)
+		}
+		registerQueue(name, durable.EnqueueRaw, durable.Close)
+		queue = durable
+	case backendRabbitMQ:
+		broker, err := getBroker()
+		if err != nil {
+			return nil, fmt.Errorf("queue %s: %w", name, err)
+		}
+		q := mq.NewQueue(broker, brokerQueueConfig(name, conf, observer), handler)
+		registerQueue(name, q.EnqueueRaw, q.Close)
+		queue = q
+	}
+	if queue == nil {
+		memory := newMemoryQueue(workerNum, conf, observer, func(m messageQueue.Message[T]) {
+			if err := handler(m); err != nil {
+				observer.Failed()
+				logrus.Errorf("queue %s: handle message failed, err: %v", name, err)
+			}
+		})
+		registerQueue(name, memory.enqueueRaw, memory.Close)
+		queue = memory
+	}
+	metrics.RegisterQueueDepth(name, queue.Len)
+	return queue, nil
+}
+
+// memoryQueue 内存队列。新消息没有Key，处理时不检查重复；重放的死信保留原来的Key，
+// 已经生效过的消息不会重复生效
+type memoryQueue[T any] struct {
+	*messageQueue.SimpleMQ[messageQueue.Message[T]]
+}
+
+func newMemoryQueue[T any](workerNum int, conf config.MessageQueueConfig, observer messageQueue.Observer, handle func(messageQueue.Message[T])) memoryQueue[T] {
+	simple := messageQueue.NewSimpleMQ(workerNum, handle,
+		messageQueue.WithCapacity(conf.Capacity, overflowPolicy(conf.OverflowPolicy)), messageQueue.WithObserver(observer))
+	return memoryQueue[T]{SimpleMQ: simple}
+}
+
+func (q memoryQueue[T]) Push(msg T) error {
+	return q.SimpleMQ.Push(messageQueue.Message[T]{Body: msg})
+}
+
+func (q memoryQueue[T]) PushCtx(ctx context.Context, msg T) error {
+	return q.SimpleMQ.PushCtx(ctx, messageQueue.Message[T]{Body: msg})
+}
+
+// enqueueRaw 把JSON格式的消息体用原来的Key入队，用于重放死信
+func (q memoryQueue[T]) enqueueRaw(key string, body []byte) error {
+	var msg T
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	return q.SimpleMQ.PushCtx(context.Background(), messageQueue.Message[T]{Key: key, Body: msg})
+}
+
+func overflowPolicy(name string) messageQueue.OverflowPolicy {
+	policy, err := messageQueue.ParseOverflowPolicy(name, messageQueue.OverflowBlock)
+	if err != nil {
+		logrus.Errorf("%v, use block", err)
+	}
+	return policy
+}
+
+func durableConfig(name string, workerNum int, conf config.MessageQueueConfig, observer messageQueue.Observer) messageQueue.DurableConfig {
+	dir := conf.Dir
+	if dir == "" {
+		dir = defaultQueueDir
+	}
+	durable := messageQueue.DurableConfig{
+		Dir:         dir,
+		Name:        name,
+		WorkerNum:   workerNum,
+		MaxAttempts: conf.MaxAttempts,
+		DeadLetter:  services.SaveDeadLetter,
+		OnError: func(err error) {
+			logrus.Error("durable queue: ", err)
+		},
+		Observer: observer,
+	}
+	durable.BaseBackoff = parseDuration("message queue backoff", conf.RetryBackoff)
+	durable.MaxBackoff = parseDuration("message queue backoff", conf.MaxRetryBackoff)
+	return durable
+}
+
+// parseDuration 格式不正确时返回0，使用队列的默认值
+func parseDuration(what string, s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logrus.Errorf("invalid %s %q, use default", what, s)
+		return 0
+	}
+	return d
+}
+
+func registerQueue(name string, replay func(key string, body []byte) error, close func(ctx context.Context) error) {
+	queuesLock.Lock()
+	replayers[name] = replay
+	closers = append(closers, close)
+	queuesLock.Unlock()
+}
+
+// Replay 把死信重新放入原来的队列，Key不变，所以已经生效的消息不会重复生效
+func Replay(queue string, key string, body []byte) error {
+	queuesLock.RLock()
+	replay, ok := replayers[queue]
+	queuesLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("queue %s not found", queue)
+	}
+	return replay(key, body)
+}
+
+// Close 停止接收新消息，等待各队列中的消息处理完或ctx结束，最后断开RabbitMQ。
+// 持久化队列和RabbitMQ中没有处理完的消息，下次启动时继续处理
+func Close(ctx context.Context) error {
+	queuesLock.RLock()
+	defer queuesLock.RUnlock()
+	var errs []error
+	for _, close := range closers {
+		if err := close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if broker != nil {
+		if err := broker.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// settled 重复点赞、取消不存在的关注等，说明消息要达到的状态已经存在，不需要重试
+func settled(err error) bool {
+	return err.Error() == services.ErrDuplicate || err.Error() == services.ErrDeleteNotExists
+}
+
+// errInvalidMsg 消息本身不合法，重试也不会成功，直接放入死信
+var errInvalidMsg = errors.New("不合法的参数")
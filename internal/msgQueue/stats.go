@@ -0,0 +1,13 @@
+package msgQueue
+
+// QueueDepths 返回各消息队列中积压的消息数，未初始化的队列不返回
+func QueueDepths() map[string]int {
+	depths := make(map[string]int)
+	if commentMQ != nil {
+		depths["comment"] = commentMQ.Len()
+	}
+	if followMQ != nil {
+		depths["follow"] = followMQ.Len()
+	}
+	return depths
+}
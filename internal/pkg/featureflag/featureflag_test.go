@@ -0,0 +1,109 @@
+package featureflag
+
+import "testing"
+
+func TestEnabledFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   Flag
+		userID uint
+		want   bool
+	}{
+		{name: "disabled", flag: Flag{Name: "f", Percentage: 100}, userID: 1, want: false},
+		{name: "full rollout", flag: Flag{Name: "f", Enabled: true, Percentage: 100}, userID: 1, want: true},
+		{name: "full rollout anonymous", flag: Flag{Name: "f", Enabled: true, Percentage: 100}, userID: 0, want: true},
+		{name: "zero percent", flag: Flag{Name: "f", Enabled: true}, userID: 1, want: false},
+		{name: "partial anonymous", flag: Flag{Name: "f", Enabled: true, Percentage: 99}, userID: 0, want: false},
+		{name: "allowed user", flag: Flag{Name: "f", Enabled: true, Users: []uint{7}}, userID: 7, want: true},
+		{name: "allowed user disabled", flag: Flag{Name: "f", Users: []uint{7}}, userID: 7, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.EnabledFor(tt.userID); got != tt.want {
+				t.Errorf("EnabledFor(%d) = %v, want %v", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentageRollout(t *testing.T) {
+	const users = 10000
+	flag := Flag{Name: "chat_with_book", Enabled: true, Percentage: 20}
+	enabled := map[uint]bool{}
+	for id := uint(1); id <= users; id++ {
+		if flag.EnabledFor(id) {
+			enabled[id] = true
+		}
+	}
+	if n := len(enabled); n < users*17/100 || n > users*23/100 {
+		t.Errorf("20%% rollout enabled %d of %d users", n, users)
+	}
+
+	// 比例增大时已经开启的用户保持开启
+	flag.Percentage = 50
+	for id := range enabled {
+		if !flag.EnabledFor(id) {
+			t.Fatalf("user %d disabled after increasing rollout", id)
+		}
+	}
+}
+
+func TestBucketIndependentPerFlag(t *testing.T) {
+	same := 0
+	for id := uint(1); id <= 1000; id++ {
+		if Bucket("a", id) == Bucket("b", id) {
+			same++
+		}
+	}
+	if same > 50 {
+		t.Errorf("buckets of different flags match for %d of 1000 users", same)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	configured := []Flag{
+		{Name: "b", Enabled: false, Source: SourceConfig},
+		{Name: "a", Enabled: true, Source: SourceConfig},
+	}
+	overrides := []Flag{{Name: "b", Enabled: true, Source: SourceDatabase}}
+	flags := Merge(configured, overrides)
+	if len(flags) != 2 || flags[0].Name != "a" || flags[1].Name != "b" {
+		t.Fatalf("Merge() = %v", flags)
+	}
+	if !flags[1].Enabled || flags[1].Source != SourceDatabase {
+		t.Errorf("database flag should override config, got %+v", flags[1])
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	if s.Enabled("missing", 1) {
+		t.Error("undefined flag should be disabled")
+	}
+	s.Replace([]Flag{{Name: "b", Enabled: true, Percentage: 100}, {Name: "a"}})
+	if !s.Enabled("b", 1) || s.Enabled("a", 1) {
+		t.Error("Enabled() does not follow the flags")
+	}
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "a" {
+		t.Errorf("List() = %v, want sorted by name", list)
+	}
+	list[0].Enabled = true
+	if s.Enabled("a", 1) {
+		t.Error("modifying List() result changed the store")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"chat_with_book": true,
+		"v2":             true,
+		"":               false,
+		"Chat":           false,
+		"chat-with-book": false,
+	} {
+		if got := ValidName(name); got != want {
+			t.Errorf("ValidName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
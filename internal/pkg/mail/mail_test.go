@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSender_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail", "outbox.txt")
+	sender := &FileSender{Path: path, From: "noreply@example.com"}
+
+	err := sender.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "验证邮箱\r\nBcc: evil@example.com",
+		Body:    "token: abc",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "To: alice@example.com\r\n") || !strings.Contains(got, "token: abc") {
+		t.Errorf("Send() wrote %q", got)
+	}
+	if strings.Contains(got, "\r\nBcc:") {
+		t.Errorf("Send() allowed header injection: %q", got)
+	}
+
+	if err := sender.Send(context.Background(), Message{Subject: "x"}); err != ErrEmptyRecipient {
+		t.Errorf("Send() error = %v, want %v", err, ErrEmptyRecipient)
+	}
+}
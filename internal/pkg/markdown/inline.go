@@ -0,0 +1,169 @@
+package markdown
+
+import (
+	"strings"
+)
+
+type inlineKind int
+
+const (
+	inlineText inlineKind = iota
+	inlineBreak
+	inlineCode
+	inlineStrong
+	inlineEm
+	inlineDel
+	inlineSpoiler
+	inlineLink
+	inlineImage
+)
+
+type inline struct {
+	kind inlineKind
+	// 文本、行内代码，或图片的替代文字
+	text string
+	// 链接和图片的地址
+	url      string
+	children []inline
+}
+
+// 链接文字和地址的最大长度，避免大量未闭合的[在长文本中反复查找
+const maxLinkLength = 2048
+
+// inlineParser 解析一段行内文本。找不到闭合标记时记下来，
+// 后面同样的标记不用再查找，保证长文本的解析是线性的
+type inlineParser struct {
+	src     string
+	noClose map[string]bool
+	nodes   []inline
+	text    strings.Builder
+}
+
+func parseInline(src string, depth int) []inline {
+	if depth >= maxDepth {
+		return []inline{{kind: inlineText, text: src}}
+	}
+	p := &inlineParser{src: src, noClose: make(map[string]bool)}
+	p.run(depth)
+	return p.nodes
+}
+
+// find 从from开始查找delim，返回在src中的下标
+func (p *inlineParser) find(delim string, from int) int {
+	if p.noClose[delim] {
+		return -1
+	}
+	idx := strings.Index(p.src[from:], delim)
+	if idx < 0 {
+		p.noClose[delim] = true
+		return -1
+	}
+	return from + idx
+}
+
+func (p *inlineParser) flush() {
+	if p.text.Len() > 0 {
+		p.nodes = append(p.nodes, inline{kind: inlineText, text: p.text.String()})
+		p.text.Reset()
+	}
+}
+
+func (p *inlineParser) emit(node inline) {
+	p.flush()
+	p.nodes = append(p.nodes, node)
+}
+
+func isASCIIPunct(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
+
+func (p *inlineParser) run(depth int) {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			p.text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			p.emit(inline{kind: inlineBreak})
+			i++
+			continue
+		case c == '`':
+			if end := p.find("`", i+1); end > i+1 {
+				p.emit(inline{kind: inlineCode, text: s[i+1 : end]})
+				i = end + 1
+				continue
+			}
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if alt, url, end, ok := p.linkAt(i + 1); ok {
+				p.emit(inline{kind: inlineImage, text: alt, url: url})
+				i = end
+				continue
+			}
+		case c == '[':
+			if text, url, end, ok := p.linkAt(i); ok {
+				p.emit(inline{kind: inlineLink, url: url, children: parseInline(text, depth+1)})
+				i = end
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "~~"), strings.HasPrefix(s[i:], "||"):
+			delim := s[i : i+2]
+			if end := p.find(delim, i+2); end > i+2 && !isSpace(s[i+2]) {
+				kind := inlineStrong
+				switch delim {
+				case "~~":
+					kind = inlineDel
+				case "||":
+					kind = inlineSpoiler
+				}
+				p.emit(inline{kind: kind, children: parseInline(s[i+2:end], depth+1)})
+				i = end + 2
+				continue
+			}
+		case c == '*':
+			if end := p.find("*", i+1); end > i+1 && !isSpace(s[i+1]) && !isSpace(s[end-1]) {
+				p.emit(inline{kind: inlineEm, children: parseInline(s[i+1:end], depth+1)})
+				i = end + 1
+				continue
+			}
+		}
+		p.text.WriteByte(c)
+		i++
+	}
+	p.flush()
+}
+
+// linkAt 解析从s[i]='['开始的 [文字](地址)，地址后面可以带标题，标题会被忽略。
+// 返回文字、地址和结束位置
+func (p *inlineParser) linkAt(i int) (string, string, int, bool) {
+	s := p.src
+	limit := len(s)
+	if limit > i+maxLinkLength {
+		limit = i + maxLinkLength
+	}
+	mid := strings.Index(s[i:limit], "](")
+	if mid < 0 {
+		return "", "", 0, false
+	}
+	mid += i
+	end := strings.IndexByte(s[mid:limit], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+	end += mid
+	text := s[i+1 : mid]
+	target := strings.TrimSpace(s[mid+2 : end])
+	if strings.ContainsAny(text, "[]") {
+		return "", "", 0, false
+	}
+	if fields := strings.Fields(target); len(fields) > 0 {
+		target = fields[0]
+	}
+	return text, target, end + 1, true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
@@ -0,0 +1,211 @@
+// Package markdown 书评使用的Markdown子集：标题、引用、列表、代码、分隔线、
+// 剧透块，以及加粗、斜体、删除线、行内代码、剧透、链接和图片。
+//
+// 渲染时所有文本都会转义，不支持内嵌HTML，链接只允许http(s)、mailto和站内地址，
+// 图片只允许配置的前缀，所以输出可以直接作为HTML使用
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 引用、剧透块和行内标记的最大嵌套层数，超过后按普通文本处理
+const maxDepth = 8
+
+// SpoilerSummary 剧透块没有写标题时显示的标题
+const SpoilerSummary = "剧透"
+
+// SpoilerPlaceholder 纯文本和摘要中代替剧透内容的文字
+const SpoilerPlaceholder = "[剧透内容已隐藏]"
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockQuote
+	blockList
+	blockCode
+	blockRule
+	blockSpoiler
+)
+
+type block struct {
+	kind blockKind
+	// 标题级别
+	level int
+	// 段落、标题、代码块的文本
+	text string
+	// 有序列表
+	ordered bool
+	start   int
+	items   []string
+	// 引用和剧透块的内容
+	children []*block
+	// 剧透块的标题
+	title string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	bulletPattern  = regexp.MustCompile(`^ {0,3}[-*+][ \t]+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+)
+
+// parse 把源文本解析为块
+func parse(src string) []*block {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	return parseBlocks(strings.Split(src, "\n"), 0)
+}
+
+func isFence(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "```")
+}
+
+// spoilerTitle 判断是否为剧透块的开始行 ":::spoiler [标题]"
+func spoilerTitle(trimmed string) (string, bool) {
+	rest, ok := strings.CutPrefix(trimmed, ":::")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSpace(rest)
+	title, ok := strings.CutPrefix(rest, "spoiler")
+	if !ok || (title != "" && title[0] != ' ' && title[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(title), true
+}
+
+// startsBlock 判断一行是否会打断段落
+func startsBlock(line string, depth int) bool {
+	trimmed := strings.TrimSpace(line)
+	if isFence(trimmed) || rulePattern.MatchString(line) || headingPattern.MatchString(line) ||
+		bulletPattern.MatchString(line) || orderedPattern.MatchString(line) {
+		return true
+	}
+	if depth >= maxDepth {
+		return false
+	}
+	_, spoiler := spoilerTitle(trimmed)
+	return spoiler || strings.HasPrefix(trimmed, ">")
+}
+
+func parseBlocks(lines []string, depth int) []*block {
+	var blocks []*block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			i++
+			continue
+		}
+
+		if isFence(trimmed) {
+			var code []string
+			i++
+			for i < len(lines) && !isFence(strings.TrimSpace(lines[i])) {
+				code = append(code, lines[i])
+				i++
+			}
+			// 跳过结束的```，没有结束时到文末为止
+			i++
+			blocks = append(blocks, &block{kind: blockCode, text: strings.Join(code, "\n")})
+			continue
+		}
+
+		if title, ok := spoilerTitle(trimmed); ok && depth < maxDepth {
+			var inner []string
+			nested := 0
+			i++
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if _, open := spoilerTitle(t); open {
+					nested++
+				} else if t == ":::" {
+					if nested == 0 {
+						break
+					}
+					nested--
+				}
+				inner = append(inner, lines[i])
+			}
+			i++
+			blocks = append(blocks, &block{kind: blockSpoiler, title: title, children: parseBlocks(inner, depth+1)})
+			continue
+		}
+
+		if rulePattern.MatchString(line) {
+			blocks = append(blocks, &block{kind: blockRule})
+			i++
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			blocks = append(blocks, &block{kind: blockHeading, level: len(m[1]), text: m[2]})
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") && depth < maxDepth {
+			var inner []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+				t = strings.TrimPrefix(t, ">")
+				inner = append(inner, strings.TrimPrefix(t, " "))
+			}
+			blocks = append(blocks, &block{kind: blockQuote, children: parseBlocks(inner, depth+1)})
+			continue
+		}
+
+		if bulletPattern.MatchString(line) || orderedPattern.MatchString(line) {
+			var b *block
+			b, i = parseList(lines, i)
+			blocks = append(blocks, b)
+			continue
+		}
+
+		para := []string{trimmed}
+		for i++; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" || startsBlock(lines[i], depth) {
+				break
+			}
+			para = append(para, strings.TrimSpace(lines[i]))
+		}
+		blocks = append(blocks, &block{kind: blockParagraph, text: strings.Join(para, "\n")})
+	}
+	return blocks
+}
+
+// parseList 解析从lines[i]开始的列表，不支持嵌套列表，缩进的行并入上一项。
+// 返回列表和下一行的下标
+func parseList(lines []string, i int) (*block, int) {
+	b := &block{kind: blockList}
+	if m := orderedPattern.FindStringSubmatch(lines[i]); m != nil {
+		b.ordered = true
+		b.start, _ = strconv.Atoi(m[1])
+	}
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if b.ordered {
+			if m := orderedPattern.FindStringSubmatch(line); m != nil {
+				b.items = append(b.items, strings.TrimSpace(m[2]))
+				continue
+			}
+		} else if m := bulletPattern.FindStringSubmatch(line); m != nil && !rulePattern.MatchString(line) {
+			b.items = append(b.items, strings.TrimSpace(m[1]))
+			continue
+		}
+		if strings.TrimSpace(line) == "" || (line[0] != ' ' && line[0] != '\t') {
+			break
+		}
+		b.items[len(b.items)-1] += "\n" + strings.TrimSpace(line)
+	}
+	return b, i
+}
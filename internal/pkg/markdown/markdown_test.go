@@ -0,0 +1,105 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderer_HTML(t *testing.T) {
+	r := New(Options{ImagePrefixes: []string{"/static/"}})
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "plain text keeps line breaks", src: "第一行\n第二行\n\n第二段", want: "<p>第一行<br>第二行</p>\n<p>第二段</p>"},
+		{name: "heading", src: "## 读后感 ##", want: "<h2>读后感</h2>"},
+		{name: "quote", src: "> 人生而自由\n> 却无往不在枷锁之中", want: "<blockquote>\n<p>人生而自由<br>却无往不在枷锁之中</p>\n</blockquote>"},
+		{name: "lists", src: "- 一\n- 二\n  续\n\n3. 三\n4. 四", want: "<ul>\n<li>一</li>\n<li>二<br>续</li>\n</ul>\n<ol start=\"3\">\n<li>三</li>\n<li>四</li>\n</ol>"},
+		{name: "inline", src: "**粗** *斜* ~~删~~ `a<b`", want: "<p><strong>粗</strong> <em>斜</em> <del>删</del> <code>a&lt;b</code></p>"},
+		{name: "spoiler", src: ":::spoiler 结局\n凶手是**管家**\n:::", want: "<details class=\"spoiler\"><summary>结局</summary>\n<p>凶手是<strong>管家</strong></p>\n</details>"},
+		{name: "code block", src: "```\n<script>\n```", want: "<pre><code>&lt;script&gt;</code></pre>"},
+		{name: "rule", src: "---", want: "<hr>"},
+		{name: "inline spoiler", src: "最后||**他**死了||", want: "<p>最后<span class=\"spoiler\"><strong>他</strong>死了</span></p>"},
+		{name: "html escaped", src: "<img src=x onerror=alert(1)>", want: "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{name: "link", src: "[豆瓣](https://book.douban.com \"title\")", want: "<p><a href=\"https://book.douban.com\" rel=\"nofollow noopener ugc\" target=\"_blank\">豆瓣</a></p>"},
+		{name: "javascript link dropped", src: "[点我](javascript:alert(1))", want: "<p>点我)</p>"},
+		{name: "uploaded image", src: "![封面](/static/2024/01/a.jpg)", want: "<p><img src=\"/static/2024/01/a.jpg\" alt=\"封面\" loading=\"lazy\"></p>"},
+		{name: "external image dropped", src: "![外链](https://evil.com/a.jpg)", want: "<p>外链</p>"},
+		{name: "image path traversal dropped", src: "![x](/static/../secret.png)", want: "<p>x</p>"},
+		{name: "encoded image path traversal dropped", src: "![x](/static/%2e%2e/secret.png)", want: "<p>x</p>"},
+		{name: "encoded slash traversal dropped", src: "![x](/static%2f%2E%2E%2fsecret.png)", want: "<p>x</p>"},
+		{name: "escaped marker", src: `\*不是斜体\*`, want: "<p>*不是斜体*</p>"},
+		{name: "unclosed markers", src: "**a *b [c](d", want: "<p>**a *b [c](d</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.HTML(tt.src); got != tt.want {
+				t.Errorf("HTML(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderer_HTMLDeepNesting(t *testing.T) {
+	r := New(Options{})
+	src := strings.Repeat(">", 10000) + " x\n" + strings.Repeat("[", 10000) + strings.Repeat("**", 10000)
+	out := r.HTML(src)
+	if strings.Count(out, "<blockquote>") > maxDepth {
+		t.Errorf("quote nesting not limited: %d", strings.Count(out, "<blockquote>"))
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	src := "# 标题\n\n> 引用的**原文**\n\n:::spoiler\n凶手是管家\n:::\n\n- [链接](https://a.com)\n- ![图](/static/a.jpg)"
+	if got, want := PlainText(src), "标题 引用的原文 "+SpoilerPlaceholder+" 链接 图"; got != want {
+		t.Errorf("PlainText() = %q, want %q", got, want)
+	}
+	if got, want := Excerpt(src, 5), "标题 引用…"; got != want {
+		t.Errorf("Excerpt() = %q, want %q", got, want)
+	}
+	if got := Excerpt("短", 5); got != "短" {
+		t.Errorf("Excerpt() = %q, want %q", got, "短")
+	}
+}
+
+func TestHasSpoilers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{src: "没有剧透", want: false},
+		{src: "> - 凶手是||管家||", want: true},
+		{src: ":::spoiler\n结局\n:::", want: true},
+		{src: "`||不是剧透||`", want: false},
+		{src: "a || b", want: false},
+	}
+	for _, tt := range tests {
+		if got := HasSpoilers(tt.src); got != tt.want {
+			t.Errorf("HasSpoilers(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := PlainText("开头的一些文字，凶手是||管家||。中间提到了 Hercule Poirot 的推理，最后是结尾")
+	if strings.Contains(text, "管家") {
+		t.Fatalf("PlainText() leaks spoiler: %q", text)
+	}
+	tests := []struct {
+		keyword string
+		max     int
+		want    string
+	}{
+		{keyword: "hercule", max: 11, want: "…了 Hercule P…"},
+		{keyword: "开头", max: 6, want: "开头的一些文…"},
+		{keyword: "结尾", max: 6, want: "…，最后是结尾"},
+		{keyword: "找不到", max: 4, want: "开头的一…"},
+		{keyword: "开头", max: 1000, want: text},
+	}
+	for _, tt := range tests {
+		if got := Snippet(text, tt.keyword, tt.max); got != tt.want {
+			t.Errorf("Snippet(%q, %d) = %q, want %q", tt.keyword, tt.max, got, tt.want)
+		}
+	}
+}
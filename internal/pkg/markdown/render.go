@@ -0,0 +1,300 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options 渲染选项
+type Options struct {
+	// 允许显示的图片地址前缀(如上传目录 "/static/")，其他图片只显示替代文字。
+	// 为空时不显示任何图片
+	ImagePrefixes []string
+}
+
+// Renderer 把Markdown渲染为安全的HTML，可以并发使用
+type Renderer struct {
+	opts Options
+}
+
+// New 创建渲染器
+func New(opts Options) *Renderer {
+	return &Renderer{opts: opts}
+}
+
+// HTML 渲染为HTML
+func (r *Renderer) HTML(src string) string {
+	var sb strings.Builder
+	r.writeBlocks(&sb, parse(src))
+	return sb.String()
+}
+
+func (r *Renderer) writeBlocks(sb *strings.Builder, blocks []*block) {
+	for i, b := range blocks {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		switch b.kind {
+		case blockParagraph:
+			sb.WriteString("<p>")
+			r.writeInline(sb, parseInline(b.text, 0))
+			sb.WriteString("</p>")
+		case blockHeading:
+			level := strconv.Itoa(b.level)
+			sb.WriteString("<h" + level + ">")
+			r.writeInline(sb, parseInline(b.text, 0))
+			sb.WriteString("</h" + level + ">")
+		case blockQuote:
+			sb.WriteString("<blockquote>\n")
+			r.writeBlocks(sb, b.children)
+			sb.WriteString("\n</blockquote>")
+		case blockList:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			sb.WriteString("<" + tag)
+			if b.ordered && b.start != 1 {
+				sb.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+			}
+			sb.WriteString(">\n")
+			for _, item := range b.items {
+				sb.WriteString("<li>")
+				r.writeInline(sb, parseInline(item, 0))
+				sb.WriteString("</li>\n")
+			}
+			sb.WriteString("</" + tag + ">")
+		case blockCode:
+			sb.WriteString("<pre><code>")
+			sb.WriteString(html.EscapeString(b.text))
+			sb.WriteString("</code></pre>")
+		case blockRule:
+			sb.WriteString("<hr>")
+		case blockSpoiler:
+			title := b.title
+			if title == "" {
+				title = SpoilerSummary
+			}
+			sb.WriteString(`<details class="spoiler"><summary>`)
+			r.writeInline(sb, parseInline(title, 0))
+			sb.WriteString("</summary>\n")
+			r.writeBlocks(sb, b.children)
+			sb.WriteString("\n</details>")
+		}
+	}
+}
+
+func (r *Renderer) writeInline(sb *strings.Builder, nodes []inline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case inlineText:
+			sb.WriteString(html.EscapeString(n.text))
+		case inlineBreak:
+			sb.WriteString("<br>")
+		case inlineCode:
+			sb.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case inlineSpoiler:
+			sb.WriteString(`<span class="spoiler">`)
+			r.writeInline(sb, n.children)
+			sb.WriteString("</span>")
+		case inlineStrong, inlineEm, inlineDel:
+			tag := "strong"
+			if n.kind == inlineEm {
+				tag = "em"
+			} else if n.kind == inlineDel {
+				tag = "del"
+			}
+			sb.WriteString("<" + tag + ">")
+			r.writeInline(sb, n.children)
+			sb.WriteString("</" + tag + ">")
+		case inlineLink:
+			if !safeLinkURL(n.url) {
+				r.writeInline(sb, n.children)
+				continue
+			}
+			sb.WriteString(`<a href="` + html.EscapeString(n.url) + `" rel="nofollow noopener ugc" target="_blank">`)
+			r.writeInline(sb, n.children)
+			sb.WriteString("</a>")
+		case inlineImage:
+			if !r.imageAllowed(n.url) {
+				sb.WriteString(html.EscapeString(n.text))
+				continue
+			}
+			sb.WriteString(`<img src="` + html.EscapeString(n.url) + `" alt="` + html.EscapeString(n.text) + `" loading="lazy">`)
+		}
+	}
+}
+
+// safeLinkURL 只允许http(s)、mailto和以/开头的站内地址
+func safeLinkURL(raw string) bool {
+	if raw == "" || strings.ContainsAny(raw, "\"'<>\\") {
+		return false
+	}
+	if strings.HasPrefix(raw, "/") {
+		return !strings.HasPrefix(raw, "//")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
+
+// imageAllowed 图片必须来自配置的前缀，且不能用..跳出上传目录。
+// 路径先解码再规范化后比较前缀，%2e%2e 这样编码过的..也会被拒绝
+func (r *Renderer) imageAllowed(raw string) bool {
+	if !safeLinkURL(raw) {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || strings.Contains(u.Path, "..") || strings.Contains(u.Path, "\\") {
+		return false
+	}
+	u.Path = path.Clean(u.Path)
+	u.RawPath = ""
+	normalized := u.String()
+	for _, prefix := range r.opts.ImagePrefixes {
+		if prefix != "" && strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlainText 去掉所有标记，返回纯文本，剧透块和行内剧透替换为 SpoilerPlaceholder。
+// 连续的空白合并为一个空格
+func PlainText(src string) string {
+	var sb strings.Builder
+	writePlainBlocks(&sb, parse(src))
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// Excerpt 取纯文本的前maxRunes个字符作为摘要，被截断时以…结尾
+func Excerpt(src string, maxRunes int) string {
+	text := PlainText(src)
+	if maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxRunes])) + "…"
+}
+
+func writePlainBlocks(sb *strings.Builder, blocks []*block) {
+	for _, b := range blocks {
+		switch b.kind {
+		case blockParagraph, blockHeading:
+			writePlainInline(sb, parseInline(b.text, 0))
+		case blockQuote:
+			writePlainBlocks(sb, b.children)
+		case blockList:
+			for _, item := range b.items {
+				writePlainInline(sb, parseInline(item, 0))
+				sb.WriteByte(' ')
+			}
+		case blockCode:
+			sb.WriteString(b.text)
+		case blockSpoiler:
+			sb.WriteString(SpoilerPlaceholder)
+		}
+		sb.WriteByte(' ')
+	}
+}
+
+func writePlainInline(sb *strings.Builder, nodes []inline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case inlineText, inlineCode, inlineImage:
+			sb.WriteString(n.text)
+		case inlineBreak:
+			sb.WriteByte(' ')
+		case inlineSpoiler:
+			sb.WriteString(SpoilerPlaceholder)
+		default:
+			writePlainInline(sb, n.children)
+		}
+	}
+}
+
+// HasSpoilers 正文中是否有剧透块或行内剧透
+func HasSpoilers(src string) bool {
+	return blocksHaveSpoiler(parse(src))
+}
+
+func blocksHaveSpoiler(blocks []*block) bool {
+	for _, b := range blocks {
+		switch b.kind {
+		case blockSpoiler:
+			return true
+		case blockParagraph, blockHeading:
+			if inlineHasSpoiler(parseInline(b.text, 0)) {
+				return true
+			}
+		case blockList:
+			for _, item := range b.items {
+				if inlineHasSpoiler(parseInline(item, 0)) {
+					return true
+				}
+			}
+		case blockQuote:
+			if blocksHaveSpoiler(b.children) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inlineHasSpoiler(nodes []inline) bool {
+	for _, n := range nodes {
+		if n.kind == inlineSpoiler || inlineHasSpoiler(n.children) {
+			return true
+		}
+	}
+	return false
+}
+
+// Snippet 从纯文本中截取包含keyword(不区分大小写)的片段，最多maxRunes个字符，
+// 前后被截断时加上…。找不到keyword时返回开头的片段。
+// 应该传入 PlainText 的结果，这样片段中不会出现剧透
+func Snippet(text string, keyword string, maxRunes int) string {
+	runes := []rune(text)
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return text
+	}
+	kw := []rune(keyword)
+	start := 0
+	if len(kw) > 0 {
+		for i := 0; i+len(kw) <= len(runes); i++ {
+			if strings.EqualFold(string(runes[i:i+len(kw)]), keyword) {
+				// 关键词放在片段中间
+				start = i - (maxRunes-len(kw))/2
+				break
+			}
+		}
+	}
+	if start > len(runes)-maxRunes {
+		start = len(runes) - maxRunes
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxRunes
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
@@ -0,0 +1,613 @@
+package messageQueue
+
+import (
+	"bufio"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	walOpPush  = "push"
+	walOpRetry = "retry"
+	walOpAck   = "ack"
+
+	defaultMaxAttempts      = 5
+	defaultBaseBackoff      = time.Second
+	defaultMaxBackoff       = 5 * time.Minute
+	defaultCompactThreshold = 1000
+)
+
+// ErrClosed 队列已经关闭
+var ErrClosed = errors.New("message queue closed")
+
+// Message 投递给处理函数的消息
+type Message[T any] struct {
+	// 消息的唯一标识，重试和重放时不变，可以作为幂等键
+	Key string `json:"key"`
+	// 之前失败的次数
+	Attempts int `json:"attempts"`
+	Body     T   `json:"body"`
+}
+
+// DeadLetter 多次处理失败后放弃的消息
+type DeadLetter struct {
+	Queue string
+	Key   string
+	// 消息体的JSON
+	Body     []byte
+	Attempts int
+	Error    string
+	FailedAt time.Time
+}
+
+// DurableConfig DurableMQ 的配置，零值的字段使用默认值
+type DurableConfig struct {
+	// 日志文件所在目录，文件名为 Name.wal
+	Dir  string
+	Name string
+	// 处理消息的协程数，默认1
+	WorkerNum int
+	// 最多处理的次数(包括第一次)，默认5
+	MaxAttempts int
+	// 第n次失败后等待 BaseBackoff*2^(n-1)，最多 MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// 保存死信，返回错误时消息留在队列中，等待 MaxBackoff 后再次处理
+	DeadLetter func(DeadLetter) error
+	// 日志中已确认的记录超过这个数量时重写日志，默认1000
+	CompactThreshold int
+	// 写入后不调用fsync，只用于测试
+	NoSync bool
+	// 写日志、保存死信等失败时调用，默认忽略
+	OnError func(error)
+	// 统计事件的接收者，默认忽略
+	Observer Observer
+}
+
+// permanentError 不需要重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装处理函数返回的错误，消息不再重试，直接进入死信
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent 错误是否由 Permanent 包装
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// walRecord 日志中的一行
+type walRecord struct {
+	Op       string          `json:"op"`
+	Key      string          `json:"key"`
+	Attempts int             `json:"attempts,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+type durableEntry[T any] struct {
+	msg Message[T]
+	// 入队顺序，重写日志时保持原来的顺序
+	seq uint64
+}
+
+// DurableMQ 把消息写入磁盘上的追加日志(WAL)后再投递，进程崩溃或重启后未确认的消息会重新投递。
+//
+// 投递语义为至少一次：处理函数返回nil才算确认，返回错误时按指数退避重试，
+// 超过最大次数或返回 Permanent 错误的消息交给死信处理函数。
+// 同一条消息的每次投递 Key 都相同，处理函数应当用 Key 保证重复投递不会重复生效。
+type DurableMQ[T any] struct {
+	conf    DurableConfig
+	handler func(Message[T]) error
+
+	// fileMu 保护日志文件的写入和重写，fsync只在持有fileMu时进行，不阻塞取消息和确认。
+	// 需要同时持有时先加fileMu再加mu
+	fileMu sync.Mutex
+	file   *os.File
+	// 上次重写日志后确认的消息数(fileMu)
+	acked int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[string]*durableEntry[T]
+	// 等待处理的消息，重试的消息在退避结束后放回
+	ready  []string
+	timers map[string]*time.Timer
+	seq    uint64
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewDurableMQ 打开(或创建)日志文件，恢复其中未确认的消息并启动处理协程。
+// handler 会被并发调用
+func NewDurableMQ[T any](conf DurableConfig, handler func(Message[T]) error) (*DurableMQ[T], error) {
+	if conf.Name == "" {
+		return nil, errors.New("durable queue name is empty")
+	}
+	if conf.WorkerNum <= 0 {
+		conf.WorkerNum = 1
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = defaultMaxAttempts
+	}
+	if conf.BaseBackoff <= 0 {
+		conf.BaseBackoff = defaultBaseBackoff
+	}
+	if conf.MaxBackoff < conf.BaseBackoff {
+		conf.MaxBackoff = defaultMaxBackoff
+		if conf.MaxBackoff < conf.BaseBackoff {
+			conf.MaxBackoff = conf.BaseBackoff
+		}
+	}
+	if conf.CompactThreshold <= 0 {
+		conf.CompactThreshold = defaultCompactThreshold
+	}
+	if conf.Observer == nil {
+		conf.Observer = nopObserver{}
+	}
+	if err := os.MkdirAll(conf.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	mq := &DurableMQ[T]{
+		conf:    conf,
+		handler: handler,
+		pending: make(map[string]*durableEntry[T]),
+		timers:  make(map[string]*time.Timer),
+	}
+	mq.cond = sync.NewCond(&mq.mu)
+	if err := mq.recover(); err != nil {
+		return nil, err
+	}
+	// 恢复的消息已经等待过一次重启，直接重新处理
+	entries := mq.sortedEntries()
+	for _, e := range entries {
+		mq.ready = append(mq.ready, e.msg.Key)
+	}
+	for i := 0; i < conf.WorkerNum; i++ {
+		mq.wg.Add(1)
+		go mq.worker()
+	}
+	return mq, nil
+}
+
+func (mq *DurableMQ[T]) path() string {
+	return filepath.Join(mq.conf.Dir, mq.conf.Name+".wal")
+}
+
+// recover 重放日志得到未确认的消息，然后重写日志
+func (mq *DurableMQ[T]) recover() error {
+	f, err := os.Open(mq.path())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var rec walRecord
+			// 崩溃时最后一行可能没有写完，跳过无法解析的行
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				mq.reportError(fmt.Errorf("queue %s: skip broken wal record: %w", mq.conf.Name, err))
+				continue
+			}
+			mq.apply(rec)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	mq.fileMu.Lock()
+	defer mq.fileMu.Unlock()
+	return mq.compact()
+}
+
+func (mq *DurableMQ[T]) apply(rec walRecord) {
+	switch rec.Op {
+	case walOpPush:
+		var body T
+		if err := json.Unmarshal(rec.Body, &body); err != nil {
+			mq.reportError(fmt.Errorf("queue %s: decode message %s: %w", mq.conf.Name, rec.Key, err))
+			return
+		}
+		if _, ok := mq.pending[rec.Key]; !ok {
+			mq.seq++
+			mq.pending[rec.Key] = &durableEntry[T]{msg: Message[T]{Key: rec.Key, Attempts: rec.Attempts, Body: body}, seq: mq.seq}
+		}
+	case walOpRetry:
+		if e, ok := mq.pending[rec.Key]; ok {
+			e.msg.Attempts = rec.Attempts
+		}
+	case walOpAck:
+		delete(mq.pending, rec.Key)
+	}
+}
+
+func (mq *DurableMQ[T]) sortedEntries() []*durableEntry[T] {
+	entries := make([]*durableEntry[T], 0, len(mq.pending))
+	for _, e := range mq.pending {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	return entries
+}
+
+// compact 只保留未确认的消息，写入临时文件后替换原日志(调用需要持有fileMu，不能持有mu)。
+// 只在复制未确认的消息时短暂加mu，写文件和fsync时不阻塞取消息和确认。
+// 入队时先加入pending再写日志，所以复制时已经在pending中的消息不会丢失
+func (mq *DurableMQ[T]) compact() error {
+	mq.mu.Lock()
+	entries := mq.sortedEntries()
+	lines := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		line, err := encodeRecord(walOpPush, e.msg)
+		if err != nil {
+			mq.mu.Unlock()
+			return err
+		}
+		lines = append(lines, line)
+	}
+	mq.mu.Unlock()
+
+	tmpPath := mq.path() + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, line := range lines {
+		if _, err := w.Write(line); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if mq.file != nil {
+		mq.file.Close()
+		mq.file = nil
+	}
+	if err := os.Rename(tmpPath, mq.path()); err != nil {
+		return err
+	}
+	mq.file, err = os.OpenFile(mq.path(), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	mq.acked = 0
+	return nil
+}
+
+func encodeRecord[T any](op string, msg Message[T]) ([]byte, error) {
+	rec := walRecord{Op: op, Key: msg.Key, Attempts: msg.Attempts}
+	if op == walOpPush {
+		body, err := json.Marshal(msg.Body)
+		if err != nil {
+			return nil, err
+		}
+		rec.Body = body
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// appendRecord 追加一条记录(调用需要持有fileMu)。只有入队需要fsync，
+// 丢失确认或重试次数只会导致多投递一次
+func (mq *DurableMQ[T]) appendRecord(op string, msg Message[T]) error {
+	if mq.file == nil {
+		return ErrClosed
+	}
+	line, err := encodeRecord(op, msg)
+	if err != nil {
+		return err
+	}
+	if _, err := mq.file.Write(line); err != nil {
+		return err
+	}
+	if op == walOpPush && !mq.conf.NoSync {
+		return mq.file.Sync()
+	}
+	return nil
+}
+
+func (mq *DurableMQ[T]) reportError(err error) {
+	if mq.conf.OnError != nil {
+		mq.conf.OnError(err)
+	}
+}
+
+// NewMessageKey 生成随机的消息Key
+func NewMessageKey() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		// 几乎不会发生，退回到时间戳
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Push 用新生成的Key入队，写日志失败时返回错误，消息不会入队
+func (mq *DurableMQ[T]) Push(msg T) error {
+	return mq.Enqueue(NewMessageKey(), msg)
+}
+
+// PushCtx 用新生成的Key入队，写日志失败时返回错误，由调用方决定如何处理
+func (mq *DurableMQ[T]) PushCtx(ctx context.Context, msg T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return mq.Enqueue(NewMessageKey(), msg)
+}
+
+// Enqueue 写入日志后入队，写日志失败时返回错误。key已经在队列中时忽略
+func (mq *DurableMQ[T]) Enqueue(key string, msg T) error {
+	mq.mu.Lock()
+	if mq.closed {
+		mq.mu.Unlock()
+		mq.conf.Observer.Dropped()
+		return ErrClosed
+	}
+	if _, ok := mq.pending[key]; ok {
+		mq.mu.Unlock()
+		return nil
+	}
+	// 先加入pending再写日志，同时进行的重写日志会保留这条消息
+	m := Message[T]{Key: key, Body: msg}
+	mq.seq++
+	mq.pending[key] = &durableEntry[T]{msg: m, seq: mq.seq}
+	mq.mu.Unlock()
+
+	mq.fileMu.Lock()
+	err := mq.appendRecord(walOpPush, m)
+	mq.fileMu.Unlock()
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if err != nil {
+		delete(mq.pending, key)
+		return err
+	}
+	mq.ready = append(mq.ready, key)
+	mq.cond.Signal()
+	mq.conf.Observer.Enqueued()
+	return nil
+}
+
+// EnqueueRaw 把JSON格式的消息体入队，用于重放死信
+func (mq *DurableMQ[T]) EnqueueRaw(key string, body []byte) error {
+	var msg T
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+	return mq.Enqueue(key, msg)
+}
+
+// Len 未确认的消息数，包括正在处理和等待重试的消息
+func (mq *DurableMQ[T]) Len() int {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	return len(mq.pending)
+}
+
+func (mq *DurableMQ[T]) worker() {
+	defer mq.wg.Done()
+	for {
+		mq.mu.Lock()
+		for len(mq.ready) == 0 && !mq.closed {
+			mq.cond.Wait()
+		}
+		if mq.closed {
+			mq.mu.Unlock()
+			return
+		}
+		key := mq.ready[0]
+		mq.ready = mq.ready[1:]
+		e, ok := mq.pending[key]
+		var msg Message[T]
+		if ok {
+			msg = e.msg
+		}
+		mq.mu.Unlock()
+		if ok {
+			mq.conf.Observer.Dequeued()
+			mq.deliver(msg)
+		}
+	}
+}
+
+func (mq *DurableMQ[T]) handle(msg Message[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return mq.handler(msg)
+}
+
+func (mq *DurableMQ[T]) deliver(msg Message[T]) {
+	start := time.Now()
+	err := mq.handle(msg)
+	mq.conf.Observer.Handled(time.Since(start))
+	if err != nil {
+		mq.conf.Observer.Failed()
+	}
+	if err == nil {
+		mq.ack(msg)
+		return
+	}
+	msg.Attempts++
+	if IsPermanent(err) || msg.Attempts >= mq.conf.MaxAttempts {
+		if mq.conf.DeadLetter != nil {
+			body, _ := json.Marshal(msg.Body)
+			dlErr := mq.conf.DeadLetter(DeadLetter{
+				Queue:    mq.conf.Name,
+				Key:      msg.Key,
+				Body:     body,
+				Attempts: msg.Attempts,
+				Error:    err.Error(),
+				FailedAt: time.Now(),
+			})
+			if dlErr != nil {
+				mq.reportError(fmt.Errorf("queue %s: save dead letter %s: %w", mq.conf.Name, msg.Key, dlErr))
+				mq.retry(msg, mq.conf.MaxBackoff)
+				return
+			}
+		}
+		mq.ack(msg)
+		return
+	}
+	mq.retry(msg, RetryDelay(mq.conf.BaseBackoff, mq.conf.MaxBackoff, msg.Attempts))
+}
+
+func (mq *DurableMQ[T]) ack(msg Message[T]) {
+	mq.mu.Lock()
+	delete(mq.pending, msg.Key)
+	mq.mu.Unlock()
+
+	mq.fileMu.Lock()
+	defer mq.fileMu.Unlock()
+	if err := mq.appendRecord(walOpAck, msg); err != nil {
+		mq.reportError(fmt.Errorf("queue %s: ack %s: %w", mq.conf.Name, msg.Key, err))
+		return
+	}
+	mq.acked++
+	if mq.acked >= mq.conf.CompactThreshold {
+		if err := mq.compact(); err != nil {
+			mq.reportError(fmt.Errorf("queue %s: compact wal: %w", mq.conf.Name, err))
+		}
+	}
+}
+
+// retry 记录失败次数，delay之后重新放回队列
+func (mq *DurableMQ[T]) retry(msg Message[T], delay time.Duration) {
+	mq.mu.Lock()
+	e, ok := mq.pending[msg.Key]
+	if !ok {
+		mq.mu.Unlock()
+		return
+	}
+	e.msg.Attempts = msg.Attempts
+	mq.mu.Unlock()
+
+	mq.fileMu.Lock()
+	if err := mq.appendRecord(walOpRetry, msg); err != nil {
+		mq.reportError(fmt.Errorf("queue %s: record retry %s: %w", mq.conf.Name, msg.Key, err))
+	}
+	mq.fileMu.Unlock()
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return
+	}
+	key := msg.Key
+	mq.timers[key] = time.AfterFunc(delay, func() {
+		mq.mu.Lock()
+		defer mq.mu.Unlock()
+		delete(mq.timers, key)
+		if mq.closed {
+			return
+		}
+		if _, ok := mq.pending[key]; ok {
+			mq.ready = append(mq.ready, key)
+			mq.cond.Signal()
+		}
+	})
+}
+
+// RetryDelay 第attempts次失败后的等待时间(base*2^(attempts-1)，最多max)，加上最多20%的随机抖动，避免同时重试
+func RetryDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if jitter := int64(delay / 5); jitter > 0 {
+		delay += time.Duration(rand.Int64N(jitter))
+	}
+	return delay
+}
+
+// Close 停止取新消息，等待正在处理的消息完成或ctx结束后关闭日志。
+// ctx先结束时返回ctx的错误，日志在正在处理的消息完成、写入确认后再关闭，不会丢失确认。
+// 未处理完的消息留在日志中，下次启动时重新投递
+func (mq *DurableMQ[T]) Close(ctx context.Context) error {
+	mq.mu.Lock()
+	if mq.closed {
+		mq.mu.Unlock()
+		return nil
+	}
+	mq.closed = true
+	for key, t := range mq.timers {
+		t.Stop()
+		delete(mq.timers, key)
+	}
+	mq.cond.Broadcast()
+	mq.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		mq.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return mq.closeFile()
+	case <-ctx.Done():
+		go func() {
+			<-done
+			if err := mq.closeFile(); err != nil {
+				mq.reportError(fmt.Errorf("queue %s: close wal: %w", mq.conf.Name, err))
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// closeFile 所有worker退出后同步并关闭日志
+func (mq *DurableMQ[T]) closeFile() error {
+	mq.fileMu.Lock()
+	defer mq.fileMu.Unlock()
+	if mq.file == nil {
+		return nil
+	}
+	err := mq.file.Sync()
+	mq.file.Close()
+	mq.file = nil
+	return err
+}
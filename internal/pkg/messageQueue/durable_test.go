@@ -0,0 +1,291 @@
+package messageQueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMsg struct {
+	N int `json:"n"`
+}
+
+func newTestDurableMQ(t *testing.T, dir string, conf DurableConfig, handler func(Message[testMsg]) error) *DurableMQ[testMsg] {
+	t.Helper()
+	conf.Dir = dir
+	conf.Name = "test"
+	conf.NoSync = true
+	if conf.BaseBackoff == 0 {
+		conf.BaseBackoff = time.Millisecond
+		conf.MaxBackoff = 5 * time.Millisecond
+	}
+	mq, err := NewDurableMQ(conf, handler)
+	require.NoError(t, err)
+	return mq
+}
+
+// waitClosed 等待Close超时后仍在处理的消息完成、日志关闭
+func waitClosed[T any](t *testing.T, mq *DurableMQ[T]) {
+	t.Helper()
+	waitFor(t, func() bool {
+		mq.fileMu.Lock()
+		defer mq.fileMu.Unlock()
+		return mq.file == nil
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDurableMQ_Deliver(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[int]bool)
+	mq := newTestDurableMQ(t, t.TempDir(), DurableConfig{WorkerNum: 4}, func(m Message[testMsg]) error {
+		mu.Lock()
+		got[m.Body.N] = true
+		mu.Unlock()
+		return nil
+	})
+	for i := 0; i < 100; i++ {
+		mq.Push(testMsg{N: i})
+	}
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Len(t, got, 100)
+}
+
+func TestDurableMQ_RetryKeepsKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	mq := newTestDurableMQ(t, t.TempDir(), DurableConfig{MaxAttempts: 5}, func(m Message[testMsg]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, m.Key)
+		if m.Attempts < 2 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	require.NoError(t, mq.Enqueue("k1", testMsg{N: 1}))
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Equal(t, []string{"k1", "k1", "k1"}, keys)
+}
+
+func TestDurableMQ_DeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	var dead []DeadLetter
+	conf := DurableConfig{
+		MaxAttempts: 3,
+		DeadLetter: func(d DeadLetter) error {
+			mu.Lock()
+			dead = append(dead, d)
+			mu.Unlock()
+			return nil
+		},
+	}
+	mq := newTestDurableMQ(t, t.TempDir(), conf, func(m Message[testMsg]) error {
+		if m.Body.N == 1 {
+			return Permanent(errors.New("bad message"))
+		}
+		return errors.New("always fails")
+	})
+	require.NoError(t, mq.Enqueue("permanent", testMsg{N: 1}))
+	require.NoError(t, mq.Enqueue("retried", testMsg{N: 2}))
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+
+	require.Len(t, dead, 2)
+	byKey := map[string]DeadLetter{dead[0].Key: dead[0], dead[1].Key: dead[1]}
+	assert.Equal(t, 1, byKey["permanent"].Attempts)
+	assert.Equal(t, "bad message", byKey["permanent"].Error)
+	assert.Equal(t, 3, byKey["retried"].Attempts)
+	assert.JSONEq(t, `{"n":2}`, string(byKey["retried"].Body))
+	assert.Equal(t, "test", byKey["retried"].Queue)
+}
+
+func TestDurableMQ_RecoverAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	block := make(chan struct{})
+	started := make(chan struct{}, 2)
+	mq := newTestDurableMQ(t, dir, DurableConfig{}, func(m Message[testMsg]) error {
+		started <- struct{}{}
+		<-block
+		return errors.New("interrupted")
+	})
+	require.NoError(t, mq.Enqueue("a", testMsg{N: 1}))
+	require.NoError(t, mq.Enqueue("b", testMsg{N: 2}))
+	<-started
+	// 处理中的消息没有确认，关闭后留在日志中
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, mq.Close(ctx), context.DeadlineExceeded)
+	close(block)
+	waitClosed(t, mq)
+
+	var mu sync.Mutex
+	var got []string
+	mq = newTestDurableMQ(t, dir, DurableConfig{}, func(m Message[testMsg]) error {
+		mu.Lock()
+		got = append(got, m.Key)
+		mu.Unlock()
+		return nil
+	})
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Equal(t, []string{"a", "b"}, got)
+
+	// 全部确认后重启，日志中没有消息
+	mq = newTestDurableMQ(t, dir, DurableConfig{}, func(m Message[testMsg]) error { return nil })
+	assert.Equal(t, 0, mq.Len())
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestDurableMQ_CloseTimeoutKeepsAck(t *testing.T) {
+	dir := t.TempDir()
+	block := make(chan struct{})
+	started := make(chan struct{})
+	mq := newTestDurableMQ(t, dir, DurableConfig{}, func(m Message[testMsg]) error {
+		close(started)
+		<-block
+		return nil
+	})
+	require.NoError(t, mq.Enqueue("a", testMsg{N: 1}))
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, mq.Close(ctx), context.DeadlineExceeded)
+	// 超时后处理完成的消息仍然写入确认，重启后不再投递
+	close(block)
+	waitClosed(t, mq)
+
+	mq = newTestDurableMQ(t, dir, DurableConfig{}, func(m Message[testMsg]) error {
+		t.Errorf("message %s delivered again", m.Key)
+		return nil
+	})
+	assert.Equal(t, 0, mq.Len())
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestDurableMQ_PushReturnsWalError(t *testing.T) {
+	mq := newTestDurableMQ(t, t.TempDir(), DurableConfig{}, func(m Message[testMsg]) error { return nil })
+	mq.fileMu.Lock()
+	mq.file.Close()
+	mq.fileMu.Unlock()
+
+	// 写日志失败时返回错误，消息不会只留在内存中
+	assert.ErrorIs(t, mq.Push(testMsg{N: 1}), os.ErrClosed)
+	assert.Equal(t, 0, mq.Len())
+	mq.Close(context.Background())
+}
+
+func TestDurableMQ_CompactWhileEnqueue(t *testing.T) {
+	dir := t.TempDir()
+	mq := newTestDurableMQ(t, dir, DurableConfig{WorkerNum: 4, CompactThreshold: 3, MaxAttempts: 1000}, func(m Message[testMsg]) error {
+		// 只有偶数消息被确认，奇数消息一直重试
+		if m.Body.N%2 == 1 {
+			return errors.New("retry")
+		}
+		return nil
+	})
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, mq.Push(testMsg{N: p*50 + i}))
+			}
+		}(p)
+	}
+	wg.Wait()
+	waitFor(t, func() bool { return mq.Len() == 100 })
+	require.NoError(t, mq.Close(context.Background()))
+
+	// 重写日志时入队的消息没有丢失
+	mq = newTestDurableMQ(t, dir, DurableConfig{MaxAttempts: 100}, func(m Message[testMsg]) error { return errors.New("stop") })
+	assert.Equal(t, 100, mq.Len())
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestDurableMQ_SkipBrokenRecord(t *testing.T) {
+	dir := t.TempDir()
+	wal := `{"op":"push","key":"a","body":{"n":1}}
+{"op":"push","key":"b","body":{"n":2}}
+{"op":"ack","key":"a"}
+{"op":"push","key":"c","bo`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.wal"), []byte(wal), 0o644))
+
+	var mu sync.Mutex
+	var got []int
+	var errs int
+	mq := newTestDurableMQ(t, dir, DurableConfig{OnError: func(error) { errs++ }}, func(m Message[testMsg]) error {
+		mu.Lock()
+		got = append(got, m.Body.N)
+		mu.Unlock()
+		return nil
+	})
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Equal(t, []int{2}, got)
+	assert.Equal(t, 1, errs)
+}
+
+func TestDurableMQ_Compact(t *testing.T) {
+	dir := t.TempDir()
+	mq := newTestDurableMQ(t, dir, DurableConfig{CompactThreshold: 10}, func(m Message[testMsg]) error { return nil })
+	for i := 0; i < 25; i++ {
+		mq.Push(testMsg{N: i})
+	}
+	waitFor(t, func() bool { return mq.Len() == 0 })
+	require.NoError(t, mq.Close(context.Background()))
+	data, err := os.ReadFile(filepath.Join(dir, "test.wal"))
+	require.NoError(t, err)
+	// 最后一次重写后最多剩下不到10条消息的记录
+	assert.Less(t, len(data), 10*2*80)
+}
+
+func TestDurableMQ_EnqueueAfterClose(t *testing.T) {
+	mq := newTestDurableMQ(t, t.TempDir(), DurableConfig{}, func(m Message[testMsg]) error { return nil })
+	require.NoError(t, mq.Close(context.Background()))
+	assert.ErrorIs(t, mq.Enqueue("a", testMsg{}), ErrClosed)
+}
+
+func TestRetryDelay(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+	for attempts, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		d := RetryDelay(base, max, attempts)
+		assert.GreaterOrEqual(t, d, want, "attempts %d", attempts)
+		assert.Less(t, d, want+want/5+1, "attempts %d", attempts)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("x")
+	assert.Nil(t, Permanent(nil))
+	assert.True(t, IsPermanent(Permanent(base)))
+	assert.ErrorIs(t, Permanent(base), base)
+	assert.False(t, IsPermanent(base))
+}
@@ -1,9 +1,16 @@
 package messageQueue
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Doraemonkeys/arrayQueue"
+	"github.com/sirupsen/logrus"
 )
 
 // This file contains the implementation of a SimpleMQ data structure in Go.
@@ -13,6 +20,63 @@ import (
 // including Push and Len, which allow messages to be added to the queue
 // and the current length of the queue to be retrieved.
 
+// ErrQueueFull 队列已满，OverflowError 策略下 PushCtx 返回该错误
+var ErrQueueFull = errors.New("message queue is full")
+
+// OverflowPolicy 队列达到容量后如何处理新消息
+type OverflowPolicy int
+
+const (
+	// 等待队列有空位，PushCtx 在ctx结束时返回
+	OverflowBlock OverflowPolicy = iota
+	// 丢弃新消息
+	OverflowDrop
+	// 拒绝新消息，PushCtx 返回 ErrQueueFull，Push 丢弃消息
+	OverflowError
+)
+
+// ParseOverflowPolicy 解析配置中的策略名 block, drop, error，为空时返回defaultPolicy
+func ParseOverflowPolicy(name string, defaultPolicy OverflowPolicy) (OverflowPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return defaultPolicy, nil
+	case "block":
+		return OverflowBlock, nil
+	case "drop":
+		return OverflowDrop, nil
+	case "error":
+		return OverflowError, nil
+	}
+	return defaultPolicy, fmt.Errorf("unknown overflow policy %q", name)
+}
+
+// SimpleOption SimpleMQ 的可选配置
+type SimpleOption func(*simpleOptions)
+
+type simpleOptions struct {
+	capacity int
+	policy   OverflowPolicy
+	observer Observer
+}
+
+// WithCapacity 限制队列中等待的消息数(不包括已经交给worker的缓冲)，
+// 为0时不限制
+func WithCapacity(capacity int, policy OverflowPolicy) SimpleOption {
+	return func(o *simpleOptions) {
+		o.capacity = capacity
+		o.policy = policy
+	}
+}
+
+// WithObserver 设置统计事件的接收者
+func WithObserver(observer Observer) SimpleOption {
+	return func(o *simpleOptions) {
+		if observer != nil {
+			o.observer = observer
+		}
+	}
+}
+
 type SimpleMQ[T any] struct {
 	que     *arrayQueue.Queue[T]
 	queLock sync.Mutex
@@ -26,13 +90,26 @@ type SimpleMQ[T any] struct {
 	//由于只有一个goroutine读取队列中的消息，可能发生读饥饿的情况，
 	// buf是用于存储消息的缓冲区,保证在低概率抢到锁的情况下，也能读取到足够的队列中的消息
 	buf []T
+
+	capacity int
+	policy   OverflowPolicy
+	observer Observer
+	// 队列有空位时关闭并换成新的通道，唤醒阻塞的Push
+	spaceChan chan struct{}
+	closed    bool
+	// worker全部退出后关闭
+	done chan struct{}
 }
 
 // NewSimpleMQ function creates a new SimpleMQ instance and starts the worker goroutines.
 // The worker function processes messages from the message channel
 // and calls the provided message handler function.
 // The message handler must be a safe function that can be called concurrently.
-func NewSimpleMQ[T any](workerNum int, msgHandler func(T)) *SimpleMQ[T] {
+func NewSimpleMQ[T any](workerNum int, msgHandler func(T), opts ...SimpleOption) *SimpleMQ[T] {
+	options := simpleOptions{observer: nopObserver{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
 	var buf []T = make([]T, workerNum*2)
 	var Msg chan T = make(chan T, len(buf))
 	var Wait chan struct{} = make(chan struct{}, 1)
@@ -43,30 +120,59 @@ func NewSimpleMQ[T any](workerNum int, msgHandler func(T)) *SimpleMQ[T] {
 		msgChan:   Msg,
 		waitChan:  Wait,
 		buf:       buf,
+		capacity:  options.capacity,
+		policy:    options.policy,
+		observer:  options.observer,
+		spaceChan: make(chan struct{}),
+		done:      make(chan struct{}),
 	}
 	ret.queMinCap = 200
+	var workers sync.WaitGroup
+	workers.Add(workerNum)
 	for i := 0; i < workerNum; i++ {
-		go ret.worker(msgHandler)
+		go func() {
+			defer workers.Done()
+			ret.worker(msgHandler)
+		}()
 	}
+	go func() {
+		workers.Wait()
+		close(ret.done)
+	}()
 
 	go sendMsg(ret, msgHandler)
 	return ret
 }
 
+// worker 处理消息直到msgChan被关闭
 func (mq *SimpleMQ[T]) worker(msgHandler func(T)) {
-	for {
-		msg := <-mq.msgChan
-		msgHandler(msg)
+	for msg := range mq.msgChan {
+		mq.observer.Dequeued()
+		mq.handle(msgHandler, msg)
 	}
 }
 
+func (mq *SimpleMQ[T]) handle(msgHandler func(T), msg T) {
+	start := time.Now()
+	defer func() {
+		// 处理函数panic时只丢弃这条消息，worker继续工作
+		if r := recover(); r != nil {
+			mq.observer.Failed()
+			logrus.Errorf("message queue: handler panic, message dropped: %v\n%s", r, debug.Stack())
+		}
+		mq.observer.Handled(time.Since(start))
+	}()
+	msgHandler(msg)
+}
+
 // sendMsg function reads messages from the queue and sends them to the message channel.
 // The implementation also includes a wait channel to notify the sendMsg function
 // when the queue is not empty.
-// 单线程读取队列中的消息，发送到消息通道中
+// 单线程读取队列中的消息，发送到消息通道中，队列关闭且消息取完后关闭消息通道
 func sendMsg[T any](mq *SimpleMQ[T], msgHandler func(T)) {
 	for {
 		var empty bool = false
+		var closed bool = false
 		var msgNum int = 0
 		mq.queLock.Lock()
 		// 读取队列中的消息，放到buf中，保证在低概率抢到锁的情况下，也能读取到足够的队列中的消息
@@ -78,14 +184,22 @@ func sendMsg[T any](mq *SimpleMQ[T], msgHandler func(T)) {
 		if mq.que.Empty() {
 			empty = true
 		}
+		closed = mq.closed
+		if msgNum > 0 {
+			mq.notifySpace()
+		}
 		mq.queLock.Unlock()
 		//log.Printf("msgNum: %v, empty: %v,msgChan len: %v\n", msgNum, empty, len(mq.msgChan))
 		// 发送消息到消息通道中
 		for i := 0; i < msgNum; i++ {
 			mq.msgChan <- mq.buf[i]
 		}
+		if empty && closed {
+			close(mq.msgChan)
+			return
+		}
 		// 读取完队列中的消息后，若队列为空，
-		// 其他goroutine再次调用Push时，必然会给waitChan发送消息
+		// 其他goroutine再次调用Push或Close时，必然会给waitChan发送消息
 		if empty {
 			// 等待队列中有消息
 			<-mq.waitChan
@@ -102,16 +216,89 @@ func (mq *SimpleMQ[T]) tryShrink() {
 	}
 }
 
-func (mq *SimpleMQ[T]) Push(msg T) {
+// notifySpace 唤醒等待空位的Push(调用需要加锁)
+func (mq *SimpleMQ[T]) notifySpace() {
+	close(mq.spaceChan)
+	mq.spaceChan = make(chan struct{})
+}
+
+// Push 入队，队列已满时按策略等待、丢弃或返回 ErrQueueFull，队列关闭后返回 ErrClosed
+func (mq *SimpleMQ[T]) Push(msg T) error {
+	return mq.PushCtx(context.Background(), msg)
+}
+
+// PushCtx 入队，队列已满时按策略等待、丢弃或返回 ErrQueueFull，队列关闭后返回 ErrClosed
+func (mq *SimpleMQ[T]) PushCtx(ctx context.Context, msg T) error {
 	mq.queLock.Lock()
+	for {
+		if mq.closed {
+			mq.queLock.Unlock()
+			mq.observer.Dropped()
+			return ErrClosed
+		}
+		if mq.capacity <= 0 || mq.que.Len() < mq.capacity {
+			break
+		}
+		switch mq.policy {
+		case OverflowDrop:
+			mq.queLock.Unlock()
+			mq.observer.Dropped()
+			return nil
+		case OverflowError:
+			mq.queLock.Unlock()
+			mq.observer.Dropped()
+			return ErrQueueFull
+		}
+		space := mq.spaceChan
+		mq.queLock.Unlock()
+		select {
+		case <-space:
+		case <-ctx.Done():
+			mq.observer.Dropped()
+			return ctx.Err()
+		}
+		mq.queLock.Lock()
+	}
 	mq.que.Push(msg)
-	if mq.Len() == 1 {
+	if mq.que.Len() == 1 {
 		// 通知发送消息的协程,队列中有消息了
-		mq.waitChan <- struct{}{}
+		mq.wakeSender()
 	}
 	mq.queLock.Unlock()
+	mq.observer.Enqueued()
+	return nil
+}
+
+// wakeSender waitChan已经有通知时不需要再发送
+func (mq *SimpleMQ[T]) wakeSender() {
+	select {
+	case mq.waitChan <- struct{}{}:
+	default:
+	}
 }
 
+// Len 队列中等待的消息数，不包括已经交给worker的消息
 func (mq *SimpleMQ[T]) Len() int {
+	mq.queLock.Lock()
+	defer mq.queLock.Unlock()
 	return mq.que.Len()
 }
+
+// Close 停止接收新消息，等待队列中的消息全部处理完，或ctx结束时返回ctx.Err()。
+// 多次调用是安全的
+func (mq *SimpleMQ[T]) Close(ctx context.Context) error {
+	mq.queLock.Lock()
+	if !mq.closed {
+		mq.closed = true
+		// 唤醒等待空位的Push，让它们返回 ErrClosed
+		mq.notifySpace()
+		mq.wakeSender()
+	}
+	mq.queLock.Unlock()
+	select {
+	case <-mq.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,10 +1,15 @@
 package messageQueue
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // benchmarkSimpleMQ_Push
@@ -98,3 +103,147 @@ func BenchmarkSimpleMQ_Push2(b *testing.B) {
 	}
 	<-done
 }
+
+type countObserver struct {
+	enqueued, dropped, dequeued, handled, failed atomic.Int64
+}
+
+func (o *countObserver) Enqueued()             { o.enqueued.Add(1) }
+func (o *countObserver) Dropped()              { o.dropped.Add(1) }
+func (o *countObserver) Dequeued()             { o.dequeued.Add(1) }
+func (o *countObserver) Handled(time.Duration) { o.handled.Add(1) }
+func (o *countObserver) Failed()               { o.failed.Add(1) }
+
+func TestSimpleMQ_CloseDrains(t *testing.T) {
+	var handled atomic.Int64
+	obs := &countObserver{}
+	mq := NewSimpleMQ(4, func(n int) {
+		time.Sleep(time.Millisecond)
+		handled.Add(1)
+	}, WithObserver(obs))
+	for i := 0; i < 200; i++ {
+		mq.Push(i)
+	}
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Equal(t, int64(200), handled.Load())
+	assert.Equal(t, int64(200), obs.enqueued.Load())
+	assert.Equal(t, int64(200), obs.dequeued.Load())
+	assert.Equal(t, int64(200), obs.handled.Load())
+
+	// 关闭后不再接收消息
+	assert.ErrorIs(t, mq.PushCtx(context.Background(), 1), ErrClosed)
+	mq.Push(1)
+	assert.Equal(t, int64(2), obs.dropped.Load())
+	assert.NoError(t, mq.Close(context.Background()))
+}
+
+func TestSimpleMQ_CloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	mq := NewSimpleMQ(1, func(n int) { <-release })
+	mq.Push(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, mq.Close(ctx), context.DeadlineExceeded)
+	close(release)
+	assert.NoError(t, mq.Close(context.Background()))
+}
+
+// fullSimpleMQ 返回一个worker被阻塞、队列中已有capacity条消息的队列
+func fullSimpleMQ(t *testing.T, capacity int, policy OverflowPolicy, obs Observer) (*SimpleMQ[int], chan struct{}) {
+	t.Helper()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	mq := NewSimpleMQ(1, func(n int) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}, WithCapacity(capacity, policy), WithObserver(obs))
+	// 先占满worker和msgChan，再让sendMsg取出一条阻塞在发送上，之后的消息都留在队列中
+	mq.Push(0)
+	<-started
+	for i := 0; i < cap(mq.msgChan)+1; i++ {
+		mq.Push(0)
+		waitFor(t, func() bool { return mq.Len() == 0 })
+	}
+	for i := 0; i < capacity; i++ {
+		require.NoError(t, mq.PushCtx(context.Background(), i))
+	}
+	return mq, release
+}
+
+func TestSimpleMQ_OverflowError(t *testing.T) {
+	obs := &countObserver{}
+	mq, release := fullSimpleMQ(t, 3, OverflowError, obs)
+	assert.ErrorIs(t, mq.PushCtx(context.Background(), 9), ErrQueueFull)
+	assert.Equal(t, 3, mq.Len())
+	assert.Equal(t, int64(1), obs.dropped.Load())
+	close(release)
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestSimpleMQ_OverflowDrop(t *testing.T) {
+	obs := &countObserver{}
+	mq, release := fullSimpleMQ(t, 3, OverflowDrop, obs)
+	assert.NoError(t, mq.PushCtx(context.Background(), 9))
+	mq.Push(9)
+	assert.Equal(t, 3, mq.Len())
+	assert.Equal(t, int64(2), obs.dropped.Load())
+	close(release)
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestSimpleMQ_OverflowBlock(t *testing.T) {
+	obs := &countObserver{}
+	mq, release := fullSimpleMQ(t, 3, OverflowBlock, obs)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, mq.PushCtx(ctx, 9), context.DeadlineExceeded)
+
+	// 有空位后阻塞的Push继续执行
+	pushed := make(chan error)
+	go func() { pushed <- mq.PushCtx(context.Background(), 9) }()
+	close(release)
+	assert.NoError(t, <-pushed)
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestSimpleMQ_CloseWakesBlockedPush(t *testing.T) {
+	mq, release := fullSimpleMQ(t, 1, OverflowBlock, nopObserver{})
+	pushed := make(chan error)
+	go func() { pushed <- mq.PushCtx(context.Background(), 9) }()
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mq.Close(ctx)
+	assert.ErrorIs(t, <-pushed, ErrClosed)
+	close(release)
+	require.NoError(t, mq.Close(context.Background()))
+}
+
+func TestSimpleMQ_RecoverPanic(t *testing.T) {
+	obs := &countObserver{}
+	var handled atomic.Int64
+	mq := NewSimpleMQ(1, func(n int) {
+		if n == 1 {
+			panic("boom")
+		}
+		handled.Add(1)
+	}, WithObserver(obs))
+	mq.Push(1)
+	mq.Push(2)
+	require.NoError(t, mq.Close(context.Background()))
+	assert.Equal(t, int64(1), handled.Load())
+	assert.Equal(t, int64(1), obs.failed.Load())
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for name, want := range map[string]OverflowPolicy{"": OverflowError, "block": OverflowBlock, "Drop": OverflowDrop, "error": OverflowError} {
+		got, err := ParseOverflowPolicy(name, OverflowError)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+	_, err := ParseOverflowPolicy("wait", OverflowBlock)
+	assert.Error(t, err)
+}
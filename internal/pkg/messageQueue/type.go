@@ -1,8 +1,16 @@
 package messageQueue
 
+import (
+	"context"
+	"time"
+)
+
 type MQ[T any] interface {
-	// Push push a message to queue
-	Push(T)
+	// Push push a message to queue, returns the error if the message is not queued
+	Push(T) error
+	// PushCtx push a message to queue, when the queue is full it blocks until ctx is done,
+	// drops the message or returns ErrQueueFull according to the queue's OverflowPolicy
+	PushCtx(ctx context.Context, msg T) error
 	// Pop pop a message from queue
 	//Pop()
 	// PopWithTimeout pop a message from queue with timeout
@@ -10,4 +18,28 @@ type MQ[T any] interface {
 
 	// Len get the length of queue
 	Len() int
+	// Close stop accepting messages and wait for the queued ones to be handled or ctx to be done
+	Close(ctx context.Context) error
+}
+
+// Observer 接收队列的统计事件，用于导出监控指标，方法会被并发调用
+type Observer interface {
+	// 消息入队
+	Enqueued()
+	// 队列已满或已关闭，消息被丢弃或拒绝
+	Dropped()
+	// 消息被worker取出
+	Dequeued()
+	// 处理完一条消息，d为处理耗时
+	Handled(d time.Duration)
+	// 处理失败
+	Failed()
 }
+
+type nopObserver struct{}
+
+func (nopObserver) Enqueued()             {}
+func (nopObserver) Dropped()              {}
+func (nopObserver) Dequeued()             {}
+func (nopObserver) Handled(time.Duration) {}
+func (nopObserver) Failed()               {}
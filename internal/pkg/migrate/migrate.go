@@ -0,0 +1,242 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrIrreversible 迁移没有Down，不能回滚
+var ErrIrreversible = errors.New("migration is irreversible")
+
+// Migration 一个版本的迁移，版本号递增，已经执行的版本记录在 schema_migrations 中
+type Migration struct {
+	Version int64
+	Name    string
+	// Up内容的sha256，已经执行的迁移内容变化时拒绝继续迁移。为空时不检查
+	Checksum string
+	Up       func(tx *gorm.DB) error
+	// 为nil时不能回滚
+	Down func(tx *gorm.DB) error
+}
+
+// Checksum 计算迁移内容的校验和
+func Checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// SQL 由SQL语句创建迁移，down为空时不能回滚
+func SQL(version int64, name string, up string, down string) Migration {
+	m := Migration{
+		Version:  version,
+		Name:     name,
+		Checksum: Checksum(up),
+		Up:       execSQL(up),
+	}
+	if strings.TrimSpace(down) != "" {
+		m.Down = execSQL(down)
+	}
+	return m
+}
+
+func execSQL(sql string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(sql).Error
+	}
+}
+
+// LoadFS 读取dir下的 <版本>_<名字>.up.sql 和 <版本>_<名字>.down.sql，
+// 例如 0002_review_indexes.up.sql，其他文件忽略
+func LoadFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	type files struct {
+		name     string
+		up, down string
+		hasUp    bool
+	}
+	byVersion := make(map[int64]*files)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		version, name, direction, ok := parseFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		f, exist := byVersion[version]
+		if !exist {
+			f = &files{name: name}
+			byVersion[version] = f
+		} else if f.name != name {
+			return nil, fmt.Errorf("migration version %d used by both %s and %s", version, f.name, name)
+		}
+		if direction == "up" {
+			f.up, f.hasUp = string(data), true
+		} else {
+			f.down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, f := range byVersion {
+		if !f.hasUp {
+			return nil, fmt.Errorf("migration %d_%s has no up file", version, f.name)
+		}
+		migrations = append(migrations, SQL(version, f.name, f.up, f.down))
+	}
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+// parseFileName 解析 0002_review_indexes.up.sql
+func parseFileName(fileName string) (version int64, name string, direction string, ok bool) {
+	base, found := strings.CutSuffix(fileName, ".sql")
+	if !found {
+		return 0, "", "", false
+	}
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", false
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+	versionStr, name, found := strings.Cut(base, "_")
+	if !found || name == "" {
+		return 0, "", "", false
+	}
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", false
+	}
+	return version, name, direction, true
+}
+
+func sortMigrations(migrations []Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// Record schema_migrations 中的一行
+type Record struct {
+	Version   int64     `gorm:"primarykey;autoIncrement:false"`
+	Name      string    `gorm:"size:200;not null"`
+	Checksum  string    `gorm:"size:64"`
+	AppliedAt time.Time `gorm:"not null"`
+	// 执行耗时，毫秒
+	ExecutionMS int64 `gorm:"not null;default:0"`
+}
+
+func (r *Record) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 一个版本的迁移状态
+type Status struct {
+	Version int64
+	Name    string
+	Applied bool
+	// 未执行时为nil
+	AppliedAt *time.Time
+	// 已执行，但代码中的内容和执行时不同
+	ChecksumMismatch bool
+	// 已执行，但代码中没有这个版本，通常是数据库被新版本迁移过
+	Missing bool
+}
+
+// step 一次要执行的迁移
+type step struct {
+	migration Migration
+	up        bool
+}
+
+// plan 计算从已执行的版本迁移到target需要执行的步骤。
+// 升级按版本从小到大执行所有不超过target且未执行的迁移；rollback为true时，按版本从大到小回滚所有超过target的迁移，
+// 为false时忽略它们，滚动升级中旧版本的实例重启时不会回滚新版本的迁移
+func plan(migrations []Migration, applied map[int64]Record, target int64, rollback bool) ([]step, error) {
+	known := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+		if r, ok := applied[m.Version]; ok && m.Checksum != "" && r.Checksum != "" && r.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %d_%s has been modified after it was applied (checksum mismatch)", m.Version, m.Name)
+		}
+	}
+	if _, ok := known[target]; !ok && target != 0 {
+		return nil, fmt.Errorf("unknown migration version %d", target)
+	}
+
+	var steps []step
+	// 回滚
+	var appliedVersions []int64
+	for version := range applied {
+		if rollback && version > target {
+			appliedVersions = append(appliedVersions, version)
+		}
+	}
+	sort.Slice(appliedVersions, func(i, j int) bool { return appliedVersions[i] > appliedVersions[j] })
+	for _, version := range appliedVersions {
+		m, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("cannot roll back migration %d: not found in this binary", version)
+		}
+		if m.Down == nil {
+			return nil, fmt.Errorf("cannot roll back migration %d_%s: %w", m.Version, m.Name, ErrIrreversible)
+		}
+		steps = append(steps, step{migration: m, up: false})
+	}
+	// 升级
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			steps = append(steps, step{migration: m, up: true})
+		}
+	}
+	return steps, nil
+}
+
+// statuses 合并代码中的迁移和已执行的记录，按版本排序
+func statuses(migrations []Migration, applied map[int64]Record) []Status {
+	result := make([]Status, 0, len(migrations))
+	known := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		s := Status{Version: m.Version, Name: m.Name}
+		if r, ok := applied[m.Version]; ok {
+			appliedAt := r.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+			s.ChecksumMismatch = m.Checksum != "" && r.Checksum != "" && r.Checksum != m.Checksum
+		}
+		result = append(result, s)
+	}
+	for version, r := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := r.AppliedAt
+		result = append(result, Status{Version: version, Name: r.Name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result
+}
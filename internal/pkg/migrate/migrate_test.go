@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func noop(*gorm.DB) error { return nil }
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "baseline", Up: noop},
+		SQL(2, "indexes", "CREATE INDEX a ON t (x);", "DROP INDEX a;"),
+		SQL(3, "search", "CREATE INDEX b ON t (y);", "DROP INDEX b;"),
+	}
+}
+
+func versions(steps []step) []int64 {
+	var result []int64
+	for _, s := range steps {
+		v := s.migration.Version
+		if !s.up {
+			v = -v
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func appliedRecords(migrations []Migration, upTo int64) map[int64]Record {
+	applied := make(map[int64]Record)
+	for _, m := range migrations {
+		if m.Version <= upTo {
+			applied[m.Version] = Record{Version: m.Version, Name: m.Name, Checksum: m.Checksum, AppliedAt: time.Now()}
+		}
+	}
+	return applied
+}
+
+func TestParseFileName(t *testing.T) {
+	version, name, direction, ok := parseFileName("0002_review_indexes.up.sql")
+	require.True(t, ok)
+	assert.Equal(t, int64(2), version)
+	assert.Equal(t, "review_indexes", name)
+	assert.Equal(t, "up", direction)
+
+	_, _, direction, ok = parseFileName("0002_review_indexes.down.sql")
+	require.True(t, ok)
+	assert.Equal(t, "down", direction)
+
+	for _, bad := range []string{"README.md", "0002.up.sql", "x_name.up.sql", "0002_name.sql", "0_name.up.sql"} {
+		_, _, _, ok := parseFileName(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0003_search.up.sql":    {Data: []byte("CREATE INDEX b;")},
+		"migrations/0002_indexes.up.sql":   {Data: []byte("CREATE INDEX a;")},
+		"migrations/0002_indexes.down.sql": {Data: []byte("DROP INDEX a;")},
+		"migrations/README.md":             {Data: []byte("docs")},
+	}
+	migrations, err := LoadFS(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, "indexes", migrations[0].Name)
+	assert.Equal(t, Checksum("CREATE INDEX a;"), migrations[0].Checksum)
+	assert.NotNil(t, migrations[0].Down)
+	assert.Nil(t, migrations[1].Down)
+
+	_, err = LoadFS(fstest.MapFS{"m/0002_a.down.sql": {Data: []byte("x")}}, "m")
+	assert.Error(t, err)
+	_, err = LoadFS(fstest.MapFS{
+		"m/0002_a.up.sql": {Data: []byte("x")},
+		"m/0002_b.up.sql": {Data: []byte("y")},
+	}, "m")
+	assert.Error(t, err)
+}
+
+func TestPlanUp(t *testing.T) {
+	migrations := testMigrations()
+	steps, err := plan(migrations, appliedRecords(migrations, 1), 3, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, versions(steps))
+
+	steps, err = plan(migrations, appliedRecords(migrations, 3), 3, false)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	// 数据库被更新的版本迁移过，升级时保持不变
+	applied := appliedRecords(migrations, 3)
+	applied[4] = Record{Version: 4, Name: "future"}
+	steps, err = plan(migrations, applied, 3, false)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+}
+
+func TestPlanDown(t *testing.T) {
+	migrations := testMigrations()
+	steps, err := plan(migrations, appliedRecords(migrations, 3), 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{-3, -2}, versions(steps))
+
+	// baseline 没有Down
+	_, err = plan(migrations, appliedRecords(migrations, 3), 0, true)
+	assert.ErrorIs(t, err, ErrIrreversible)
+}
+
+func TestPlanErrors(t *testing.T) {
+	migrations := testMigrations()
+	_, err := plan(migrations, nil, 4, true)
+	assert.Error(t, err)
+
+	applied := appliedRecords(migrations, 2)
+	r := applied[2]
+	r.Checksum = Checksum("something else")
+	applied[2] = r
+	_, err = plan(migrations, applied, 3, false)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	// 不能回滚代码中没有的版本
+	applied = appliedRecords(migrations, 3)
+	applied[4] = Record{Version: 4, Name: "future"}
+	_, err = plan(migrations, applied, 3, true)
+	assert.Error(t, err)
+}
+
+func TestStatuses(t *testing.T) {
+	migrations := testMigrations()
+	applied := appliedRecords(migrations, 2)
+	applied[9] = Record{Version: 9, Name: "future"}
+	result := statuses(migrations, applied)
+	require.Len(t, result, 4)
+	assert.True(t, result[0].Applied)
+	assert.True(t, result[1].Applied)
+	assert.False(t, result[2].Applied)
+	assert.Nil(t, result[2].AppliedAt)
+	assert.True(t, result[3].Missing)
+}
+
+func TestNewDuplicateVersion(t *testing.T) {
+	_, err := New(nil, 1, []Migration{{Version: 1, Name: "a"}, {Version: 1, Name: "b"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrator 在数据库上执行迁移。
+// PostgreSQL上整个迁移过程持有advisory lock，多个实例同时启动时只有一个在迁移，其他等待后发现已经是最新版本
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	// advisory lock的key，使用同一个数据库的程序需要不同的key
+	lockID int64
+	// 打印执行的迁移，默认不打印
+	Logf func(format string, args ...interface{})
+}
+
+// New 创建Migrator，版本号重复时返回错误
+func New(db *gorm.DB, lockID int64, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sortMigrations(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", sorted[i].Version, sorted[i-1].Name, sorted[i].Name)
+		}
+	}
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		lockID:     lockID,
+		Logf:       func(string, ...interface{}) {},
+	}, nil
+}
+
+// Latest 最新的版本，没有迁移时返回0
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up 执行所有未执行的迁移，返回执行的数量。数据库中比代码更新的版本保持不变
+func (m *Migrator) Up() (int, error) {
+	count := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := loadApplied(conn)
+		if err != nil {
+			return err
+		}
+		count, err = m.migrate(conn, applied, m.Latest(), false)
+		return err
+	})
+	return count, err
+}
+
+// Down 回滚最后执行的一个迁移，返回回滚的数量
+func (m *Migrator) Down() (int, error) {
+	count := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := loadApplied(conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		if len(versions) == 0 {
+			return nil
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		var target int64
+		if len(versions) > 1 {
+			target = versions[len(versions)-2]
+		}
+		count, err = m.migrate(conn, applied, target, true)
+		return err
+	})
+	return count, err
+}
+
+// To 升级或回滚到version，version为0时回滚所有迁移。返回执行的数量
+func (m *Migrator) To(version int64) (int, error) {
+	count := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := loadApplied(conn)
+		if err != nil {
+			return err
+		}
+		count, err = m.migrate(conn, applied, version, true)
+		return err
+	})
+	return count, err
+}
+
+// Status 所有迁移的状态，包括数据库中有记录但代码中没有的版本
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.db.AutoMigrate(&Record{}); err != nil {
+		return nil, err
+	}
+	applied, err := loadApplied(m.db)
+	if err != nil {
+		return nil, err
+	}
+	return statuses(m.migrations, applied), nil
+}
+
+func (m *Migrator) migrate(conn *gorm.DB, applied map[int64]Record, target int64, rollback bool) (int, error) {
+	steps, err := plan(m.migrations, applied, target, rollback)
+	if err != nil {
+		return 0, err
+	}
+	for i, s := range steps {
+		if err := m.run(conn, s); err != nil {
+			return i, err
+		}
+	}
+	return len(steps), nil
+}
+
+// run 在一个事务中执行迁移并更新记录
+func (m *Migrator) run(conn *gorm.DB, s step) error {
+	mig := s.migration
+	start := time.Now()
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if !s.up {
+			if err := mig.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&Record{Version: mig.Version}).Error
+		}
+		if err := mig.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&Record{
+			Version:     mig.Version,
+			Name:        mig.Name,
+			Checksum:    mig.Checksum,
+			AppliedAt:   time.Now(),
+			ExecutionMS: time.Since(start).Milliseconds(),
+		}).Error
+	})
+	direction := "up"
+	if !s.up {
+		direction = "down"
+	}
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+	m.Logf("migration %d_%s %s done in %v", mig.Version, mig.Name, direction, time.Since(start))
+	return nil
+}
+
+// withLock 在同一个连接上加锁、建表并执行fn。session级的advisory lock必须在加锁的连接上释放
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == "postgres" {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", m.lockID).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", m.lockID)
+		}
+		if err := conn.AutoMigrate(&Record{}); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func loadApplied(db *gorm.DB) (map[int64]Record, error) {
+	var records []Record
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
@@ -0,0 +1,149 @@
+// Package textdiff 按行比较两段文本，用于记录书评的修改历史
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 超过这个规模时不再计算最长公共子序列，直接视为整段替换
+const maxCells = 4 << 20
+
+// Op 一行的编辑操作，Kind为' '(不变)、'-'(删除)或'+'(新增)
+type Op struct {
+	Kind byte
+	Text string
+}
+
+// LineOps 把a修改为b的逐行操作
+func LineOps(a, b string) []Op {
+	al, bl := splitLines(a), splitLines(b)
+	// 去掉相同的前缀和后缀，减少计算量
+	prefix := 0
+	for prefix < len(al) && prefix < len(bl) && al[prefix] == bl[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(al)-prefix && suffix < len(bl)-prefix && al[len(al)-1-suffix] == bl[len(bl)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(al)+len(bl))
+	for _, line := range al[:prefix] {
+		ops = append(ops, Op{Kind: ' ', Text: line})
+	}
+	ops = append(ops, diffLines(al[prefix:len(al)-suffix], bl[prefix:len(bl)-suffix])...)
+	for _, line := range al[len(al)-suffix:] {
+		ops = append(ops, Op{Kind: ' ', Text: line})
+	}
+	return ops
+}
+
+// diffLines 用最长公共子序列计算a到b的操作
+func diffLines(a, b []string) []Op {
+	n, m := len(a), len(b)
+	ops := make([]Op, 0, n+m)
+	if n*m > maxCells {
+		for _, line := range a {
+			ops = append(ops, Op{Kind: '-', Text: line})
+		}
+		for _, line := range b {
+			ops = append(ops, Op{Kind: '+', Text: line})
+		}
+		return ops
+	}
+	// lcs[i*(m+1)+j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Op{Kind: ' ', Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, Op{Kind: '-', Text: a[i]})
+			i++
+		default:
+			ops = append(ops, Op{Kind: '+', Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Op{Kind: '-', Text: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Op{Kind: '+', Text: b[j]})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// Unified 生成unified格式的差异，每处修改前后保留context行不变的内容。
+// 两段文本相同时返回空字符串
+func Unified(a, b string, context int) string {
+	ops := LineOps(a, b)
+	// lines[k] 为第k个操作之前a和b各有多少行
+	lines := make([][2]int, len(ops)+1)
+	for k, op := range ops {
+		lines[k+1] = lines[k]
+		if op.Kind != '+' {
+			lines[k+1][0]++
+		}
+		if op.Kind != '-' {
+			lines[k+1][1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			// 两处修改离得近时合并成一段
+			if run < len(ops) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			end = min(end+context, len(ops))
+			break
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n",
+			lines[start][0]+1, lines[end][0]-lines[start][0],
+			lines[start][1]+1, lines[end][1]-lines[start][1])
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.Kind)
+			out.WriteString(op.Text)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
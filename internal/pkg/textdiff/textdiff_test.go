@@ -0,0 +1,41 @@
+package textdiff
+
+import "testing"
+
+func TestLineOps(t *testing.T) {
+	ops := LineOps("a\nb\nc\nd", "a\nc\nx\nd")
+	want := []Op{{' ', "a"}, {'-', "b"}, {' ', "c"}, {'+', "x"}, {' ', "d"}}
+	if len(ops) != len(want) {
+		t.Fatalf("LineOps() = %v, want %v", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("ops[%d] = %v, want %v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{name: "same", a: "a\nb", b: "a\nb", context: 1, want: ""},
+		{name: "from empty", a: "", b: "x\ny", context: 1, want: "@@ -1,0 +1,2 @@\n+x\n+y\n"},
+		{name: "replace middle", a: "1\n2\n3\n4\n5", b: "1\n2\nthree\n4\n5", context: 1,
+			want: "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n"},
+		{name: "two hunks", a: "1\n2\n3\n4\n5\n6\n7\n8", b: "one\n2\n3\n4\n5\n6\n7\neight", context: 1,
+			want: "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+eight\n"},
+		{name: "merged hunks", a: "1\n2\n3\n4", b: "one\n2\n3\nfour", context: 1,
+			want: "@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n-4\n+four\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package textfilter
+
+import (
+	"unicode"
+)
+
+// Match 一次命中，Start和End是原文中的rune下标，区间为[Start, End)
+type Match struct {
+	Start int
+	End   int
+	// 命中的词(词库中的原词)
+	Word string
+}
+
+type acNode struct {
+	next map[rune]int32
+	fail int32
+	// 以该节点结尾的词(包括fail链上的)在words中的下标
+	out []int32
+}
+
+// Matcher Aho–Corasick多模式匹配，构建后只读，可以并发使用。
+// 匹配前忽略大小写、全角半角差异，并跳过空白和标点，
+// 所以"傻 逼"、"ＡＢＣ"、"a.b.c"都能命中对应的词。
+// 只由拉丁字母和数字组成的词只命中完整的单词: 前后不能紧接字母或数字，
+// 中间只能在词本身有空格的地方跨过空白，否则"an also"会命中"anal"，"classic"会命中"ass"
+type Matcher struct {
+	nodes []acNode
+	words []string
+	// 每个词归一化后的rune数
+	lengths []int
+	// 每个词是否只由拉丁字母和数字组成
+	latin []bool
+	// 拉丁字母的词中，spaces[w][k]表示原词第k个有效字符前有空白
+	spaces [][]bool
+}
+
+// NewMatcher 用词表构建匹配器，空词和归一化后为空的词会被忽略
+func NewMatcher(words []string) *Matcher {
+	m := &Matcher{nodes: []acNode{{}}}
+	for _, w := range words {
+		runes := normalize(w)
+		if len(runes) == 0 {
+			continue
+		}
+		cur := int32(0)
+		for _, r := range runes {
+			if m.nodes[cur].next == nil {
+				m.nodes[cur].next = make(map[rune]int32)
+			}
+			nxt, ok := m.nodes[cur].next[r]
+			if !ok {
+				m.nodes = append(m.nodes, acNode{})
+				nxt = int32(len(m.nodes) - 1)
+				m.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].out = append(m.nodes[cur].out, int32(len(m.words)))
+		m.words = append(m.words, w)
+		m.lengths = append(m.lengths, len(runes))
+		m.latin = append(m.latin, isLatinWord(runes))
+		m.spaces = append(m.spaces, spaceBefore(w))
+	}
+	m.buildFail()
+	return m
+}
+
+// buildFail 按BFS顺序计算fail指针，并把fail链上的输出合并到当前节点
+func (m *Matcher) buildFail() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		m.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					m.nodes[child].fail = 0
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if out := m.nodes[m.nodes[child].fail].out; len(out) > 0 {
+				m.nodes[child].out = append(m.nodes[child].out, out...)
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 词的数量
+func (m *Matcher) Len() int {
+	return len(m.words)
+}
+
+// FindAll 返回text中所有命中，按结束位置排序，可能重叠
+func (m *Matcher) FindAll(text string) []Match {
+	if m == nil || len(m.words) == 0 {
+		return nil
+	}
+	var matches []Match
+	runes := []rune(text)
+	// positions[i] 为第i个有效字符在原文中的rune下标
+	var positions []int
+	cur := int32(0)
+	for pos, r := range runes {
+		r, ok := foldRune(r)
+		if !ok {
+			continue
+		}
+		positions = append(positions, pos)
+		for {
+			if nxt, found := m.nodes[cur].next[r]; found {
+				cur = nxt
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		for _, w := range m.nodes[cur].out {
+			start := positions[len(positions)-m.lengths[w]]
+			if m.latin[w] && !wholeWord(runes, positions[len(positions)-m.lengths[w]:], m.spaces[w]) {
+				continue
+			}
+			matches = append(matches, Match{Start: start, End: pos + 1, Word: m.words[w]})
+		}
+	}
+	return matches
+}
+
+// wholeWord 原文中依次位于positions的有效字符是否组成完整的单词: 前后不是拉丁字母或数字，
+// 字符之间只在原词有空白的地方有空白
+func wholeWord(runes []rune, positions []int, spaces []bool) bool {
+	start, end := positions[0], positions[len(positions)-1]+1
+	if start > 0 && isLatinRune(runes[start-1]) {
+		return false
+	}
+	if end < len(runes) && isLatinRune(runes[end]) {
+		return false
+	}
+	for k := 1; k < len(positions); k++ {
+		if spaces[k] {
+			continue
+		}
+		for _, r := range runes[positions[k-1]+1 : positions[k]] {
+			if unicode.IsSpace(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// spaceBefore 词中每个有效字符前是否有空白
+func spaceBefore(word string) []bool {
+	var spaces []bool
+	space := false
+	for _, r := range word {
+		if _, ok := foldRune(r); !ok {
+			space = space || unicode.IsSpace(r)
+			continue
+		}
+		spaces = append(spaces, space && len(spaces) > 0)
+		space = false
+	}
+	return spaces
+}
+
+// isLatinWord 归一化后的词是否只由拉丁字母和数字组成
+func isLatinWord(runes []rune) bool {
+	for _, r := range runes {
+		if !isLatinRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLatinRune r是否是拉丁字母或数字，包括全角的
+func isLatinRune(r rune) bool {
+	r, ok := foldRune(r)
+	return ok && (unicode.Is(unicode.Latin, r) || unicode.IsDigit(r))
+}
+
+// normalize 归一化文本，只保留有效字符
+func normalize(s string) []rune {
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r, ok := foldRune(r); ok {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// foldRune 全角转半角并转小写，空白、标点等无效字符返回false
+func foldRune(r rune) (rune, bool) {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+		return r, false
+	}
+	return unicode.ToLower(r), true
+}
@@ -0,0 +1,30 @@
+package textfilter
+
+// Similarity 两段文本的相似度(0~1)，使用字符二元组的Jaccard系数，
+// 比较前忽略大小写、全角半角、空白和标点
+func Similarity(a, b string) float64 {
+	ra, rb := normalize(a), normalize(b)
+	if len(ra) < 2 || len(rb) < 2 {
+		if string(ra) == string(rb) {
+			return 1
+		}
+		return 0
+	}
+	setA, setB := bigrams(ra), bigrams(rb)
+	inter := 0
+	for g := range setA {
+		if setB[g] {
+			inter++
+		}
+	}
+	union := len(setA) + len(setB) - inter
+	return float64(inter) / float64(union)
+}
+
+func bigrams(runes []rune) map[[2]rune]bool {
+	set := make(map[[2]rune]bool, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		set[[2]rune{runes[i], runes[i+1]}] = true
+	}
+	return set
+}
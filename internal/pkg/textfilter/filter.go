@@ -0,0 +1,288 @@
+package textfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Action 命中后的处理方式，数值越大越严格
+type Action int
+
+const (
+	ActionAllow Action = iota
+	// 把命中的词替换为*
+	ActionMask
+	// 保存但不公开，等待人工审核
+	ActionHold
+	// 拒绝发布
+	ActionReject
+)
+
+var actionNames = map[Action]string{
+	ActionAllow:  "allow",
+	ActionMask:   "mask",
+	ActionHold:   "hold",
+	ActionReject: "reject",
+}
+
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction 解析配置中的动作名，空字符串解析为defaultAction
+func ParseAction(name string, defaultAction Action) (Action, error) {
+	if name == "" {
+		return defaultAction, nil
+	}
+	for a, n := range actionNames {
+		if strings.EqualFold(n, name) {
+			return a, nil
+		}
+	}
+	return ActionAllow, fmt.Errorf("textfilter: unknown action %q", name)
+}
+
+// 命中原因
+const (
+	ReasonWord      = "sensitive_word"
+	ReasonLinks     = "too_many_links"
+	ReasonRepeat    = "repeated_chars"
+	ReasonDuplicate = "duplicate"
+)
+
+// Dictionary 一个词库文件，每行一个词，#开头的行为注释
+type Dictionary struct {
+	Path   string
+	Action Action
+}
+
+// Config 过滤器配置，零值的规则不生效
+type Config struct {
+	Dictionaries []Dictionary
+	// 链接数超过MaxLinks时按LinkAction处理
+	MaxLinks   int
+	LinkAction Action
+	// 同一字符连续出现超过MaxRepeat次时按RepeatAction处理
+	MaxRepeat    int
+	RepeatAction Action
+}
+
+// Result 检查结果
+type Result struct {
+	Action Action
+	// 处理后的文本，动作为mask的词已替换为*
+	Text    string
+	Reasons []string
+	// 命中的敏感词
+	Words []string
+}
+
+// Escalate 动作取更严格的一个，并记录原因
+func (r *Result) Escalate(action Action, reason string) {
+	if action == ActionAllow {
+		return
+	}
+	if action > r.Action {
+		r.Action = action
+	}
+	for _, existing := range r.Reasons {
+		if existing == reason {
+			return
+		}
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// Merge 合并另一段文本的检查结果，Text保持不变
+func (r *Result) Merge(other Result) {
+	for _, reason := range other.Reasons {
+		r.Escalate(other.Action, reason)
+	}
+	r.Words = append(r.Words, other.Words...)
+}
+
+type dictMatcher struct {
+	action  Action
+	matcher *Matcher
+}
+
+type filterState struct {
+	matchers []dictMatcher
+	// 加载时词库文件的修改时间
+	modTimes []time.Time
+}
+
+// Filter 文本过滤器，可以并发使用。词库可以在运行时重新加载，
+// 加载失败时继续使用旧的词库
+type Filter struct {
+	conf     Config
+	state    atomic.Pointer[filterState]
+	reloadMu sync.Mutex
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)[^\s]+`)
+
+// New 创建过滤器并加载词库。加载失败时仍然返回可用的过滤器和错误，
+// 此时没有敏感词，其他规则正常生效，之后可以用 Reload 或 Watch 重试加载
+func New(conf Config) (*Filter, error) {
+	f := &Filter{conf: conf}
+	f.state.Store(&filterState{})
+	return f, f.Reload()
+}
+
+// Reload 重新加载所有词库
+func (f *Filter) Reload() error {
+	f.reloadMu.Lock()
+	defer f.reloadMu.Unlock()
+	state := &filterState{}
+	for _, dict := range f.conf.Dictionaries {
+		info, err := os.Stat(dict.Path)
+		if err != nil {
+			return fmt.Errorf("textfilter: %w", err)
+		}
+		words, err := LoadWords(dict.Path)
+		if err != nil {
+			return err
+		}
+		state.matchers = append(state.matchers, dictMatcher{action: dict.Action, matcher: NewMatcher(words)})
+		state.modTimes = append(state.modTimes, info.ModTime())
+	}
+	f.state.Store(state)
+	return nil
+}
+
+// ReloadIfChanged 词库文件修改时间变化时重新加载，返回是否重新加载
+func (f *Filter) ReloadIfChanged() (bool, error) {
+	state := f.state.Load()
+	if len(state.modTimes) != len(f.conf.Dictionaries) {
+		// 之前没有加载成功
+		return true, f.Reload()
+	}
+	for i, dict := range f.conf.Dictionaries {
+		info, err := os.Stat(dict.Path)
+		if err != nil {
+			return false, fmt.Errorf("textfilter: %w", err)
+		}
+		if !info.ModTime().Equal(state.modTimes[i]) {
+			return true, f.Reload()
+		}
+	}
+	return false, nil
+}
+
+// Watch 每隔interval检查一次词库文件，直到ctx结束。onError为nil时忽略错误
+func (f *Filter) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := f.ReloadIfChanged(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// WordCount 当前词库中词的总数
+func (f *Filter) WordCount() int {
+	n := 0
+	for _, m := range f.state.Load().matchers {
+		n += m.matcher.Len()
+	}
+	return n
+}
+
+// Check 检查一段文本
+func (f *Filter) Check(text string) Result {
+	res := Result{Action: ActionAllow, Text: text}
+	var masked []Match
+	for _, m := range f.state.Load().matchers {
+		matches := m.matcher.FindAll(text)
+		if len(matches) == 0 {
+			continue
+		}
+		for _, match := range matches {
+			res.Words = append(res.Words, match.Word)
+		}
+		res.Escalate(m.action, ReasonWord)
+		if m.action == ActionMask {
+			masked = append(masked, matches...)
+		}
+	}
+	if len(masked) > 0 {
+		res.Text = mask(text, masked)
+	}
+	if f.conf.MaxLinks > 0 && len(linkPattern.FindAllStringIndex(text, -1)) > f.conf.MaxLinks {
+		res.Escalate(f.conf.LinkAction, ReasonLinks)
+	}
+	if f.conf.MaxRepeat > 0 && longestRun(text) > f.conf.MaxRepeat {
+		res.Escalate(f.conf.RepeatAction, ReasonRepeat)
+	}
+	return res
+}
+
+// mask 把命中区间内的有效字符替换为*，保留被跳过的空白和标点
+func mask(text string, matches []Match) string {
+	runes := []rune(text)
+	for _, m := range matches {
+		for i := m.Start; i < m.End; i++ {
+			if _, ok := foldRune(runes[i]); ok {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes)
+}
+
+// longestRun 同一个非空白字符连续出现的最大次数
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var prev rune = -1
+	for _, r := range text {
+		if r == prev && r != ' ' && r != '\n' {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// LoadWords 读取词库文件，忽略空行和#开头的注释
+func LoadWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("textfilter: %w", err)
+	}
+	defer file.Close()
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("textfilter: read %s: %w", path, err)
+	}
+	return words, nil
+}
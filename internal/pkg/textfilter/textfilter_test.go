@@ -0,0 +1,210 @@
+package textfilter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatcher_FindAll(t *testing.T) {
+	m := NewMatcher([]string{"中国", "国人", "中国人民", "his", "赌博", "", "  "})
+	if m.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5", m.Len())
+	}
+	tests := []struct {
+		name string
+		text string
+		want []Match
+	}{
+		{name: "overlapping", text: "我们中国人民", want: []Match{
+			{Start: 2, End: 4, Word: "中国"},
+			{Start: 3, End: 5, Word: "国人"},
+			{Start: 2, End: 6, Word: "中国人民"},
+		}},
+		{name: "chinese", text: "网络赌博平台", want: []Match{{Start: 2, End: 4, Word: "赌博"}}},
+		{name: "separators and full-width", text: "赌 博 ＨＩＳ", want: []Match{
+			{Start: 0, End: 3, Word: "赌博"},
+			{Start: 4, End: 7, Word: "his"},
+		}},
+		{name: "prefix of longer word", text: "中国的书", want: []Match{{Start: 0, End: 2, Word: "中国"}}},
+		{name: "empty", text: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.FindAll(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindAll(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+			for _, w := range tt.want {
+				found := false
+				for _, g := range got {
+					found = found || g == w
+				}
+				if !found {
+					t.Errorf("FindAll(%q) = %+v, missing %+v", tt.text, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcher_LatinWholeWords(t *testing.T) {
+	m := NewMatcher([]string{"anal", "ass", "idiot", "online casino"})
+	tests := []struct {
+		name string
+		text string
+		want []Match
+	}{
+		{name: "across words", text: "an also", want: nil},
+		{name: "across words at end", text: "an al", want: nil},
+		{name: "inside word", text: "analysis", want: nil},
+		{name: "inside word middle", text: "a classic book", want: nil},
+		{name: "followed by digit", text: "idiot123", want: nil},
+		{name: "whole word", text: "you ass!", want: []Match{{Start: 4, End: 7, Word: "ass"}}},
+		{name: "punctuation inside", text: "a.n.a.l", want: []Match{{Start: 0, End: 7, Word: "anal"}}},
+		{name: "next to chinese", text: "笨蛋IDIOT", want: []Match{{Start: 2, End: 7, Word: "idiot"}}},
+		{name: "phrase", text: "best Online  Casino", want: []Match{{Start: 5, End: 19, Word: "online casino"}}},
+		{name: "phrase split elsewhere", text: "onl ine casino", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.FindAll(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func writeDict(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilter_Check(t *testing.T) {
+	dir := t.TempDir()
+	maskDict := filepath.Join(dir, "mask.txt")
+	rejectDict := filepath.Join(dir, "reject.txt")
+	writeDict(t, maskDict, "# 打码\n笨蛋\nidiot\n")
+	writeDict(t, rejectDict, "代开发票\n")
+	f, err := New(Config{
+		Dictionaries: []Dictionary{{Path: maskDict, Action: ActionMask}, {Path: rejectDict, Action: ActionReject}},
+		MaxLinks:     2,
+		LinkAction:   ActionHold,
+		MaxRepeat:    5,
+		RepeatAction: ActionHold,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		text        string
+		wantAction  Action
+		wantText    string
+		wantReasons []string
+	}{
+		{name: "clean", text: "这本书很好看", wantAction: ActionAllow, wantText: "这本书很好看"},
+		{name: "mask", text: "你这个笨 蛋, IDIOT!", wantAction: ActionMask, wantText: "你这个* *, *****!", wantReasons: []string{ReasonWord}},
+		{name: "reject wins", text: "笨蛋，代开发票", wantAction: ActionReject, wantText: "**，代开发票", wantReasons: []string{ReasonWord}},
+		{name: "links", text: "http://a.com www.b.com https://c.com", wantAction: ActionHold, wantText: "http://a.com www.b.com https://c.com", wantReasons: []string{ReasonLinks}},
+		{name: "repeat", text: "好好好好好好看", wantAction: ActionHold, wantText: "好好好好好好看", wantReasons: []string{ReasonRepeat}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Check(tt.text)
+			if got.Action != tt.wantAction || got.Text != tt.wantText || !reflect.DeepEqual(got.Reasons, tt.wantReasons) {
+				t.Errorf("Check(%q) = %v %q %v, want %v %q %v", tt.text, got.Action, got.Text, got.Reasons, tt.wantAction, tt.wantText, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestFilter_ReloadIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	writeDict(t, path, "foo\n")
+	f, err := New(Config{Dictionaries: []Dictionary{{Path: path, Action: ActionReject}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := f.ReloadIfChanged(); reloaded || err != nil {
+		t.Fatalf("ReloadIfChanged() = %v, %v, want false, nil", reloaded, err)
+	}
+
+	writeDict(t, path, "foo\nbar\n")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := f.ReloadIfChanged(); !reloaded || err != nil {
+		t.Fatalf("ReloadIfChanged() = %v, %v, want true, nil", reloaded, err)
+	}
+	if f.WordCount() != 2 || f.Check("bar").Action != ActionReject {
+		t.Errorf("dictionary not reloaded, WordCount() = %d", f.WordCount())
+	}
+
+	// 加载失败时保留旧词库
+	os.Remove(path)
+	if _, err := f.ReloadIfChanged(); err == nil {
+		t.Error("ReloadIfChanged() error = nil for missing file")
+	}
+	if f.Check("foo").Action != ActionReject {
+		t.Error("old dictionary dropped after failed reload")
+	}
+}
+
+func TestNew_MissingDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	f, err := New(Config{Dictionaries: []Dictionary{{Path: path, Action: ActionReject}}, MaxRepeat: 3, RepeatAction: ActionHold})
+	if err == nil {
+		t.Fatal("New() error = nil for missing dictionary")
+	}
+	if f == nil || f.Check("aaaa").Action != ActionHold {
+		t.Fatal("filter without dictionaries should still apply other rules")
+	}
+
+	// 词库出现后重新加载
+	writeDict(t, path, "foo\n")
+	if reloaded, err := f.ReloadIfChanged(); !reloaded || err != nil {
+		t.Fatalf("ReloadIfChanged() = %v, %v, want true, nil", reloaded, err)
+	}
+	if f.Check("foo").Action != ActionReject {
+		t.Error("dictionary not loaded after it appeared")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{a: "这本书真的很好看", b: "这本书真的很好看！", min: 1, max: 1},
+		{a: "Great Book", b: "great  book", min: 1, max: 1},
+		{a: "这本书真的很好看", b: "这本书真的很难看", min: 0.5, max: 0.9},
+		{a: "完全不同的内容", b: "something else", min: 0, max: 0},
+		{a: "好", b: "好", min: 1, max: 1},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %v, want [%v, %v]", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	if a, err := ParseAction("HOLD", ActionAllow); a != ActionHold || err != nil {
+		t.Errorf("ParseAction(HOLD) = %v, %v", a, err)
+	}
+	if a, err := ParseAction("", ActionReject); a != ActionReject || err != nil {
+		t.Errorf("ParseAction(\"\") = %v, %v", a, err)
+	}
+	if _, err := ParseAction("delete", ActionAllow); err == nil {
+		t.Error("ParseAction(delete) error = nil")
+	}
+}
@@ -1,22 +1,36 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"github.com/sylvia-ymlin/Coconut-book-community/config"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/admin"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/block"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/collect"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/comment"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/follow"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/like"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/moderation"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/notification"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/oauth"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/recommendation"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/report"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/review"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/handlers/user"
 	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/middleware"
+	"github.com/sylvia-ymlin/Coconut-book-community/internal/app/models"
 	"github.com/sylvia-ymlin/Coconut-book-community/utils"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -26,19 +40,118 @@ type DouyinServer struct {
 	PanicHandler gin.HandlerFunc
 	// Service
 	// ...
+	httpServer *http.Server
+	// 单独监听的Prometheus指标，没有配置 metrics_addr 时为nil
+	metricsServer *http.Server
 }
 
-func NewDouyinServer() *DouyinServer {
-	router := initDouyinRouter()
-	return &DouyinServer{Router: router}
+// Handlers 需要数据库、缓存或消息队列的处理器，由 initiate.Container 创建并注入依赖
+type Handlers struct {
+	Review  *review.Handler
+	Like    *like.Handler
+	Collect *collect.Handler
+	Comment *comment.Handler
+	Follow  *follow.Handler
 }
 
-func (s *DouyinServer) Run(addr string) error {
-	return s.Router.Run(addr)
+// NewDouyinServer 按conf创建路由，h以外的处理器和服务使用的依赖需要先设置好
+func NewDouyinServer(conf config.Config, h Handlers) *DouyinServer {
+	router := initDouyinRouter(conf, h)
+	s := &DouyinServer{
+		Router:     router,
+		httpServer: newHTTPServer(":"+conf.ServerPort, router, conf.HTTPServer),
+	}
+	if conf.HTTPServer.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		s.metricsServer = newHTTPServer(conf.HTTPServer.MetricsAddr, mux, conf.HTTPServer)
+	}
+	return s
+}
+
+// newHTTPServer 按配置设置超时，没有配置或格式不正确的超时为0，即不限制
+func newHTTPServer(addr string, handler http.Handler, conf config.HTTPServerConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: parseTimeout(conf.ReadHeaderTimeout),
+		ReadTimeout:       parseTimeout(conf.ReadTimeout),
+		WriteTimeout:      parseTimeout(conf.WriteTimeout),
+		IdleTimeout:       parseTimeout(conf.IdleTimeout),
+	}
+}
+
+func parseTimeout(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
+
+// originSet 允许跨域请求的前端地址
+type originSet struct {
+	origins atomic.Pointer[map[string]bool]
+}
+
+func newOriginSet(origins []string) *originSet {
+	s := &originSet{}
+	s.set(origins)
+	return s
+}
+
+func (s *originSet) set(origins []string) {
+	m := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		m[o] = true
+	}
+	s.origins.Store(&m)
+}
+
+func (s *originSet) allowed(origin string) bool {
+	return (*s.origins.Load())[origin]
+}
+
+// Run 监听 server_port 并处理请求，配置了 metrics_addr 时同时监听指标，直到Shutdown。
+// 任一个监听失败时返回错误，Shutdown导致的退出返回nil
+func (s *DouyinServer) Run() error {
+	if s.metricsServer == nil {
+		return ignoreClosed(s.httpServer.ListenAndServe())
+	}
+	errCh := make(chan error, 2)
+	go func() { errCh <- ignoreClosed(s.metricsServer.ListenAndServe()) }()
+	go func() { errCh <- ignoreClosed(s.httpServer.ListenAndServe()) }()
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve 在l上处理请求，直到Shutdown，用于测试或由外部创建监听
+func (s *DouyinServer) Serve(l net.Listener) error {
+	return ignoreClosed(s.httpServer.Serve(l))
+}
+
+// Shutdown 停止接收新连接，等待进行中的请求处理完，ctx结束时返回ctx的错误，未处理完的连接被关闭
+func (s *DouyinServer) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		s.httpServer.Close()
+	}
+	if s.metricsServer != nil {
+		s.metricsServer.Close()
+	}
+	return err
+}
+
+func ignoreClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func initPanicLogWriter() io.Writer {
-	panicLogPath := filepath.Join(config.GetLogConfig().Path, config.GetLogConfig().PanicLogName)
+func initPanicLogWriter(logConf config.LogConfig) io.Writer {
+	panicLogPath := filepath.Join(logConf.Path, logConf.PanicLogName)
 	return utils.GetNewLazyFileWriter(panicLogPath)
 }
 
@@ -47,23 +160,26 @@ func initPanicLogWriter() io.Writer {
 // middleware -> handler -> service -> message queue -> database
 //
 // middleware -> handler -> service -> cache -> database
-func initDouyinRouter() *gin.Engine {
+func initDouyinRouter(conf config.Config, h Handlers) *gin.Engine {
 	router := gin.New()
-	writer := io.MultiWriter(initPanicLogWriter(), os.Stdout)
-	if config.IsDebug() {
+	// 只信任配置的反向代理，否则客户端可以伪造X-Forwarded-For绕过按IP的登录限制
+	if err := router.SetTrustedProxies(conf.HTTPServer.TrustedProxies); err != nil {
+		panic("设置可信代理失败, error:" + err.Error())
+	}
+	writer := io.MultiWriter(initPanicLogWriter(conf.Log), os.Stdout)
+	if conf.IsDebug() {
 		router.Use(gin.Logger(), gin.RecoveryWithWriter(writer))
 	} else {
 		router.Use(gin.RecoveryWithWriter(writer))
 	}
 
-	// CORS配置 - 支持前后端联调
+	// CORS配置 - 允许的前端地址见 cors.allow_origins，修改后热更新
+	origins := newOriginSet(conf.CORS.AllowOrigins)
+	config.OnChange("cors", func(_, new config.Config) {
+		origins.set(new.CORS.AllowOrigins)
+	})
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{
-		"http://localhost:3000",
-		"http://localhost:5173",
-		"http://127.0.0.1:3000",
-		"http://127.0.0.1:5173",
-	}
+	corsConfig.AllowOriginFunc = origins.allowed
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
 	corsConfig.ExposeHeaders = []string{"Content-Length"}
@@ -84,17 +200,49 @@ func initDouyinRouter() *gin.Engine {
 		// 注册登录（不需要认证）
 		apiGroup.POST("/register", user.UserRegisterHandler)
 		apiGroup.POST("/login", middleware.UserLoginHandler)
+		apiGroup.POST("/token/refresh", middleware.RefreshTokenHandler)
+		apiGroup.POST("/logout", middleware.JWTMiddleWare(), middleware.LogoutHandler)
+		apiGroup.POST("/logout/all", middleware.JWTMiddleWare(), middleware.LogoutAllHandler)
+
+		// 邮箱验证与找回密码
+		apiGroup.POST("/email/verify", user.VerifyEmailHandler)
+		apiGroup.POST("/email/verify/resend", middleware.JWTMiddleWare(), user.ResendVerifyEmailHandler)
+		apiGroup.POST("/password/forgot", user.ForgotPasswordHandler)
+		apiGroup.POST("/password/reset", user.ResetPasswordHandler)
+
+		// OIDC第三方登录
+		apiGroup.GET("/auth/oidc/:provider/login", oauth.OIDCLoginHandler)
+		apiGroup.POST("/auth/oidc/:provider/callback", middleware.JWTMiddleWare("/api/auth/oidc/:provider/callback"), oauth.OIDCCallbackHandler)
+		apiGroup.POST("/auth/oidc/:provider/link", middleware.JWTMiddleWare(), oauth.OIDCLinkHandler)
 
 		// 用户信息（需要认证）
 		userGroup.GET("/:id", user.GetUserInfoHandler)
 
-		// 用户的收藏列表
-		userGroup.GET("/:user_id/collections", collect.GetUserCollectionsHandler)
+		// 用户的收藏列表（私密账号需要登录）
+		userGroup.GET("/:id/collections", middleware.JWTMiddleWare("/api/users/:id/collections"), h.Collect.GetUserCollectionsHandler)
 
 		// 关注相关
-		userGroup.POST("/:id/follow", middleware.JWTMiddleWare(), follow.PostFollowActionHandler)
-		userGroup.GET("/:id/followers", follow.QueryFanListHandler)     // 粉丝列表
-		userGroup.GET("/:id/following", follow.QueryFollowListHandler)  // 关注列表
+		userGroup.POST("/:id/follow", middleware.JWTMiddleWare(), h.Follow.PostFollowActionHandler)
+		userGroup.GET("/:id/followers", middleware.JWTMiddleWare("/api/users/:id/followers"), h.Follow.QueryFanListHandler)    // 粉丝列表
+		userGroup.GET("/:id/following", middleware.JWTMiddleWare("/api/users/:id/following"), h.Follow.QueryFollowListHandler) // 关注列表
+
+		// 私密账号与关注请求
+		userGroup.PUT("/me/privacy", middleware.JWTMiddleWare(), h.Follow.UpdatePrivacyHandler)                   // 设置私密账号
+		userGroup.PUT("/me/spoiler-preference", middleware.JWTMiddleWare(), user.UpdateSpoilerPreferenceHandler) // 设置剧透显示
+		followRequestGroup := userGroup.Group("/me/follow-requests", middleware.JWTMiddleWare())
+		{
+			followRequestGroup.GET("", h.Follow.ListFollowRequestsHandler)                 // 待处理的关注请求
+			followRequestGroup.POST("/:id/approve", h.Follow.ApproveFollowRequestHandler) // 同意
+			followRequestGroup.POST("/:id/reject", h.Follow.RejectFollowRequestHandler)   // 拒绝
+		}
+
+		// 拉黑与静音
+		blockGroup := userGroup.Group("/me/blocks", middleware.JWTMiddleWare())
+		{
+			blockGroup.GET("", block.ListBlocksHandler)          // 屏蔽列表
+			blockGroup.POST("", block.BlockUserHandler)          // 拉黑或静音
+			blockGroup.DELETE("/:id", block.UnblockUserHandler) // 解除屏蔽
+		}
 	}
 
 	// ====================
@@ -103,24 +251,33 @@ func initDouyinRouter() *gin.Engine {
 	reviewGroup := apiGroup.Group("/reviews")
 	{
 		// 书评 CRUD
-		reviewGroup.POST("", middleware.JWTMiddleWare(), review.CreateReviewHandler)      // 创建书评
-		reviewGroup.GET("", review.GetReviewListHandler)                                   // 查询列表
-		reviewGroup.GET("/:id", review.GetReviewDetailHandler)                            // 查询详情
-		reviewGroup.PUT("/:id", middleware.JWTMiddleWare(), review.UpdateReviewHandler)   // 更新书评
-		reviewGroup.DELETE("/:id", middleware.JWTMiddleWare(), review.DeleteReviewHandler) // 删除书评
+		reviewGroup.POST("", middleware.JWTMiddleWare(), h.Review.CreateReviewHandler)      // 创建书评
+		reviewGroup.GET("", middleware.JWTMiddleWare("/api/reviews"), h.Review.GetReviewListHandler) // 查询列表（登录可选）
+		reviewGroup.GET("/:id", middleware.JWTMiddleWare("/api/reviews/:id"), h.Review.GetReviewDetailHandler) // 查询详情（登录可选）
+		reviewGroup.PUT("/:id", middleware.JWTMiddleWare(), h.Review.UpdateReviewHandler)   // 更新书评
+		reviewGroup.DELETE("/:id", middleware.JWTMiddleWare(), h.Review.DeleteReviewHandler) // 删除书评
+
+		// 草稿与定时发布
+		reviewGroup.GET("/drafts", middleware.JWTMiddleWare(), h.Review.GetDraftListHandler)         // 我的草稿
+		reviewGroup.PUT("/:id/draft", middleware.JWTMiddleWare(), h.Review.AutosaveDraftHandler)     // 自动保存草稿
+		reviewGroup.POST("/:id/publish", middleware.JWTMiddleWare(), h.Review.PublishReviewHandler) // 发布草稿
+
+		// 修改历史
+		reviewGroup.GET("/:id/revisions", middleware.JWTMiddleWare("/api/reviews/:id/revisions"), h.Review.GetReviewRevisionsHandler) // 修改历史（登录可选）
+		reviewGroup.POST("/:id/rollback", middleware.JWTMiddleWare(), h.Review.RollbackReviewHandler)                                  // 恢复到历史版本
 
 		// 点赞相关
-		reviewGroup.POST("/:id/like", middleware.JWTMiddleWare(), like.LikeReviewHandler)   // 点赞
-		reviewGroup.DELETE("/:id/like", middleware.JWTMiddleWare(), like.UnlikeReviewHandler) // 取消点赞
-		reviewGroup.GET("/:id/likes", like.GetReviewLikesHandler)                          // 点赞列表
+		reviewGroup.POST("/:id/like", middleware.JWTMiddleWare(), h.Like.LikeReviewHandler)   // 点赞
+		reviewGroup.DELETE("/:id/like", middleware.JWTMiddleWare(), h.Like.UnlikeReviewHandler) // 取消点赞
+		reviewGroup.GET("/:id/likes", middleware.JWTMiddleWare("/api/reviews/:id/likes"), h.Like.GetReviewLikesHandler) // 点赞列表（登录可选）
 
 		// 评论相关
-		reviewGroup.POST("/:id/comments", middleware.JWTMiddleWare(), comment.CreateCommentHandler)  // 发布评论
-		reviewGroup.GET("/:id/comments", comment.GetCommentListHandler)                              // 评论列表
+		reviewGroup.POST("/:id/comments", middleware.JWTMiddleWare(), h.Comment.CreateCommentHandler)  // 发布评论
+		reviewGroup.GET("/:id/comments", middleware.JWTMiddleWare("/api/reviews/:id/comments"), h.Comment.GetCommentListHandler) // 评论列表（登录可选）
 
 		// 收藏相关
-		reviewGroup.POST("/:id/collect", middleware.JWTMiddleWare(), collect.CollectReviewHandler)   // 收藏
-		reviewGroup.DELETE("/:id/collect", middleware.JWTMiddleWare(), collect.UncollectReviewHandler) // 取消收藏
+		reviewGroup.POST("/:id/collect", middleware.JWTMiddleWare(), h.Collect.CollectReviewHandler)   // 收藏
+		reviewGroup.DELETE("/:id/collect", middleware.JWTMiddleWare(), h.Collect.UncollectReviewHandler) // 取消收藏
 	}
 
 	// ====================
@@ -128,7 +285,7 @@ func initDouyinRouter() *gin.Engine {
 	// ====================
 	commentGroup := apiGroup.Group("/comments")
 	{
-		commentGroup.DELETE("/:id", middleware.JWTMiddleWare(), comment.DeleteCommentHandler) // 删除评论
+		commentGroup.DELETE("/:id", middleware.JWTMiddleWare(), h.Comment.DeleteCommentHandler) // 删除评论
 	}
 
 	// ====================
@@ -136,8 +293,58 @@ func initDouyinRouter() *gin.Engine {
 	// ====================
 	feedGroup := apiGroup.Group("/feed")
 	{
-		feedGroup.GET("", review.GetDiscoveryFeedHandler)                                    // 发现页
-		feedGroup.GET("/following", middleware.JWTMiddleWare(), review.GetFollowingFeedHandler) // 关注页
+		feedGroup.GET("", middleware.JWTMiddleWare("/api/feed"), h.Review.GetDiscoveryFeedHandler) // 发现页（登录可选）
+		feedGroup.GET("/following", middleware.JWTMiddleWare(), h.Review.GetFollowingFeedHandler) // 关注页
+	}
+
+	// ====================
+	// 内容审核（版主）
+	// ====================
+	moderationGroup := apiGroup.Group("/moderation", middleware.JWTMiddleWare())
+	{
+		moderationGroup.POST("/reviews/:id/hide", middleware.RequirePermission(models.PermModerateReview), moderation.HideReviewHandler)       // 隐藏书评
+		moderationGroup.POST("/reviews/:id/unhide", middleware.RequirePermission(models.PermModerateReview), moderation.UnhideReviewHandler)   // 恢复书评
+		moderationGroup.POST("/comments/:id/hide", middleware.RequirePermission(models.PermModerateComment), moderation.HideCommentHandler)     // 隐藏评论
+		moderationGroup.POST("/comments/:id/unhide", middleware.RequirePermission(models.PermModerateComment), moderation.UnhideCommentHandler) // 恢复评论
+		moderationGroup.GET("/reports", middleware.RequirePermission(models.PermHandleReport), moderation.ReportQueueHandler)                  // 审核队列
+		moderationGroup.POST("/reports/:id/resolve", middleware.RequirePermission(models.PermHandleReport), moderation.ResolveReportHandler)   // 处理举报
+	}
+
+	// ====================
+	// 举报与通知
+	// ====================
+	apiGroup.POST("/reports", middleware.JWTMiddleWare(), report.CreateReportHandler) // 举报
+	notificationGroup := apiGroup.Group("/notifications", middleware.JWTMiddleWare())
+	{
+		notificationGroup.GET("", notification.ListNotificationsHandler)           // 通知列表
+		notificationGroup.POST("/read", notification.MarkNotificationsReadHandler) // 全部已读
+	}
+
+	// ====================
+	// 管理后台（管理员）
+	// ====================
+	adminGroup := apiGroup.Group("/admin", middleware.JWTMiddleWare(), middleware.RequireRole(models.RoleAdmin))
+	{
+		adminGroup.GET("/users", admin.SearchUsersHandler)                                   // 搜索用户
+		adminGroup.PUT("/users/:id/role", admin.SetUserRoleHandler)                          // 设置用户角色
+		adminGroup.POST("/users/:id/ban", admin.BanUserHandler)                              // 封禁用户
+		adminGroup.POST("/users/:id/unban", admin.UnbanUserHandler)                          // 解封用户
+		adminGroup.POST("/users/:id/force-password-reset", admin.ForcePasswordResetHandler) // 强制重置密码
+		adminGroup.DELETE("/reviews/:id", admin.DeleteReviewHandler)                         // 删除书评
+		adminGroup.POST("/reviews/:id/restore", admin.RestoreReviewHandler)                  // 恢复书评
+		adminGroup.DELETE("/comments/:id", admin.DeleteCommentHandler)                       // 删除评论
+		adminGroup.POST("/comments/:id/restore", admin.RestoreCommentHandler)                // 恢复评论
+		adminGroup.GET("/audit-logs", admin.AuditLogListHandler)                             // 审计日志
+		adminGroup.GET("/stats", admin.StatsHandler)                                         // 系统统计
+		adminGroup.GET("/metrics", gin.WrapH(promhttp.Handler()))                            // Prometheus指标
+		adminGroup.POST("/text-filter/reload", admin.ReloadTextFilterHandler)                // 重新加载敏感词库
+		adminGroup.GET("/config", admin.ConfigHandler)                                       // 当前生效的配置
+		adminGroup.GET("/queues/dead-letters", admin.DeadLetterListHandler)                  // 死信列表
+		adminGroup.POST("/queues/dead-letters/:id/replay", admin.ReplayDeadLetterHandler)    // 重放死信
+		adminGroup.DELETE("/queues/dead-letters/:id", admin.DeleteDeadLetterHandler)         // 删除死信
+		adminGroup.GET("/feature-flags", admin.FeatureFlagListHandler)                       // 功能开关
+		adminGroup.PUT("/feature-flags/:name", admin.SetFeatureFlagHandler)                  // 设置功能开关
+		adminGroup.DELETE("/feature-flags/:name", admin.DeleteFeatureFlagHandler)            // 恢复为配置文件中的开关
 	}
 
 	// ====================
@@ -148,8 +355,7 @@ func initDouyinRouter() *gin.Engine {
 		bookGroup.GET("/search", recommendation.SearchBooksHandler)           // 搜索图书
 		bookGroup.GET("/recommendations", recommendation.GetRecommendationsHandler) // 个性化推荐
 		bookGroup.GET("/:isbn", recommendation.GetBookDetailHandler)          // 图书详情
-		// TODO: Chat with Book
-		// bookGroup.POST("/:isbn/chat", recommendation.ChatWithBookHandler)
+		bookGroup.POST("/:isbn/chat", middleware.JWTMiddleWare(), middleware.RequireFeature(models.FeatureChatWithBook), recommendation.ChatWithBookHandler) // 与图书对话
 	}
 
 	// ====================
@@ -159,7 +365,7 @@ func initDouyinRouter() *gin.Engine {
 	legacyGroup := router.Group("/douyin")
 	{
 		legacyGroup.POST("/user/register/", user.UserRegisterHandler)
-		legacyGroup.POST("/user/login/", middleware.UserLoginHandler)
+		legacyGroup.POST("/user/login/", middleware.LegacyUserLoginHandler)
 		legacyGroup.GET("/user/", middleware.JWTMiddleWare(), user.GetUserInfoHandler)
 	}
 
@@ -171,6 +377,7 @@ func initDouyinRouter() *gin.Engine {
 		})
 	})
 
+
 	// Swagger API documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/sylvia-ymlin/Coconut-book-community/config"
 	_ "github.com/sylvia-ymlin/Coconut-book-community/docs"
 	"github.com/sylvia-ymlin/Coconut-book-community/initiate"
+	"github.com/sylvia-ymlin/Coconut-book-community/pkg/log"
 )
 
 // @title BookCommunity API
@@ -40,6 +42,8 @@ var (
 	BuildTime = "unknown"
 
 	showVersion = flag.Bool("version", false, "Show version information")
+	configFile  = flag.String("config", config.DefaultFile, "Config file; empty to use only environment variables and defaults")
+	logLevel    = flag.String("loglevel", "", "Log level (panic,fatal,error,warn,info,debug,trace); overrides log.level and is kept on config reload")
 )
 
 func main() {
@@ -51,6 +55,16 @@ func main() {
 		fmt.Printf("Built: %s\n", BuildTime)
 		os.Exit(0)
 	}
+	log.SetLevelOverride(*logLevel)
 
-	initiate.Run()
+	switch flag.Arg(0) {
+	case "migrate":
+		// bookcommunity migrate up|down|status|to <version>
+		os.Exit(initiate.Migrate(*configFile, flag.Args()[1:]))
+	case "config":
+		// bookcommunity config check
+		os.Exit(initiate.Config(*configFile, flag.Args()[1:]))
+	}
+
+	initiate.Run(*configFile)
 }
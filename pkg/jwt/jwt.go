@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -11,26 +13,67 @@ type Cryptoer interface {
 
 // CryptJWT signing Key
 type CryptJWT struct {
-	signingKey []byte
-	cryptoer   Cryptoer
+	keyring  *Keyring
+	cryptoer Cryptoer
+	// token的有效期,为0表示不过期
+	tokenTTL time.Duration
 }
 
 type CustomClaims struct {
 	Username string `json:"username"`
+	// 登录会话ID,用于退出登录时吊销同一会话的token
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // NewJWT creates a new JWT instance.
 // The signing key is used to sign the token.
 // The cryptoer is used to encrypt and decrypt the token.It can be nil.
-func NewJWT(SigningKey []byte, cryptoer Cryptoer) *CryptJWT {
-	return &CryptJWT{signingKey: SigningKey, cryptoer: cryptoer}
+func NewJWT(signingKey []byte, cryptoer Cryptoer) *CryptJWT {
+	return &CryptJWT{keyring: &Keyring{keys: []SigningKey{{Key: signingKey}}}, cryptoer: cryptoer}
+}
+
+// NewJWTWithKeyring creates a new JWT instance that signs with the active key of the keyring
+// and writes its kid into the token header.
+func NewJWTWithKeyring(keyring *Keyring, cryptoer Cryptoer) *CryptJWT {
+	return &CryptJWT{keyring: keyring, cryptoer: cryptoer}
+}
+
+// Keyring returns the keyring used to sign and verify tokens.
+func (j *CryptJWT) Keyring() *Keyring {
+	return j.keyring
+}
+
+// SetTokenTTL sets the lifetime of tokens created by CreateToken.
+// Tokens never expire if ttl is 0.
+func (j *CryptJWT) SetTokenTTL(ttl time.Duration) {
+	j.tokenTTL = ttl
+}
+
+// TokenTTL returns the lifetime of tokens created by CreateToken.
+func (j *CryptJWT) TokenTTL() time.Duration {
+	return j.tokenTTL
 }
 
-// CreateToken creates a new token
+// CreateToken creates a new token.
+// IssuedAt and ExpiresAt are filled in if the claims do not set them.
 func (j *CryptJWT) CreateToken(claims CustomClaims) (string, error) {
+	now := time.Now()
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.ExpiresAt == nil && j.tokenTTL > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(j.tokenTTL))
+	}
+	key, err := j.keyring.SigningKey(now)
+	if err != nil {
+		return "", err
+	}
 	jwTtoken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := jwTtoken.SignedString(j.signingKey)
+	if key.KID != "" {
+		jwTtoken.Header["kid"] = key.KID
+	}
+	token, err := jwTtoken.SignedString(key.Key)
 	if err != nil {
 		return "", err
 	}
@@ -54,8 +97,10 @@ func (j *CryptJWT) ParseToken(tokenString string) (*CustomClaims, error) {
 
 	// 解析token
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (i interface{}, err error) {
-		return j.signingKey, nil
-	})
+		// 没有kid的旧token使用kid为空的密钥验签
+		kid, _ := token.Header["kid"].(string)
+		return j.keyring.VerificationKey(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +108,10 @@ func (j *CryptJWT) ParseToken(tokenString string) (*CustomClaims, error) {
 	claims, ok := token.Claims.(*CustomClaims)
 
 	if ok && token.Valid { // 校验token
+		// 设置了有效期时，拒绝没有过期时间的旧token
+		if j.tokenTTL > 0 && claims.ExpiresAt == nil {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
 		return claims, nil
 	}
 	return nil, jwt.ErrInvalidType
@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCryptJWT_TokenTTL(t *testing.T) {
+	j := NewJWT([]byte("0123456789abcdef"), nil)
+	j.SetTokenTTL(time.Minute)
+
+	claims := CustomClaims{Username: "alice", SessionID: "s1"}
+	claims.ID = "1"
+	token, err := j.CreateToken(claims)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	got, err := j.ParseToken(token)
+	if err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	if got.ExpiresAt == nil || got.IssuedAt == nil {
+		t.Fatalf("ParseToken() exp = %v, iat = %v, want both set", got.ExpiresAt, got.IssuedAt)
+	}
+	if got.SessionID != "s1" || got.Username != "alice" || got.ID != "1" {
+		t.Errorf("ParseToken() = %+v", got)
+	}
+
+	expired := CustomClaims{Username: "alice"}
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	token, err = j.CreateToken(expired)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if _, err := j.ParseToken(token); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("ParseToken() error = %v, want %v", err, jwt.ErrTokenExpired)
+	}
+}
+
+func TestCryptJWT_RejectTokenWithoutExpiry(t *testing.T) {
+	legacy := NewJWT([]byte("0123456789abcdef"), nil)
+	token, err := legacy.CreateToken(CustomClaims{Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+
+	j := NewJWT([]byte("0123456789abcdef"), nil)
+	j.SetTokenTTL(time.Minute)
+	if _, err := j.ParseToken(token); !errors.Is(err, jwt.ErrTokenInvalidClaims) {
+		t.Errorf("ParseToken() error = %v, want %v", err, jwt.ErrTokenInvalidClaims)
+	}
+}
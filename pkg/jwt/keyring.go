@@ -0,0 +1,111 @@
+package jwt
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrKeyNotFound     = errors.New("jwt: signing key not found")
+	ErrNoActiveKey     = errors.New("jwt: no active signing key")
+	ErrDuplicateKeyID  = errors.New("jwt: duplicate key id")
+	ErrEmptySigningKey = errors.New("jwt: empty signing key")
+)
+
+// SigningKey 一把签名密钥
+type SigningKey struct {
+	// KID 写入token header的key id,为空表示旧版不带kid的token使用的密钥
+	KID string
+	Key []byte
+	// ActivateAt 开始用于签名的时间,之前只用于验签。零值表示立即生效
+	ActivateAt time.Time
+}
+
+// Keyring 签名密钥环。
+// 所有密钥都可以用于验签,签名使用已生效(ActivateAt<=now)且ActivateAt最晚的密钥。
+// 轮换时先加入带未来ActivateAt的新密钥,到时间后自动切换签名密钥,
+// 旧密钥签发的token在过期前仍然可以通过验签。
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+// NewKeyring creates a keyring with the given keys.
+func NewKeyring(keys ...SigningKey) (*Keyring, error) {
+	kr := &Keyring{}
+	for _, k := range keys {
+		if err := kr.AddKey(k); err != nil {
+			return nil, err
+		}
+	}
+	return kr, nil
+}
+
+// AddKey adds a verification key. It becomes the signing key once ActivateAt is reached.
+func (kr *Keyring) AddKey(key SigningKey) error {
+	if len(key.Key) == 0 {
+		return ErrEmptySigningKey
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	for _, k := range kr.keys {
+		if k.KID == key.KID {
+			return ErrDuplicateKeyID
+		}
+	}
+	kr.keys = append(kr.keys, key)
+	// 按生效时间排序,签名时取最后一个已生效的
+	sort.SliceStable(kr.keys, func(i, j int) bool {
+		return kr.keys[i].ActivateAt.Before(kr.keys[j].ActivateAt)
+	})
+	return nil
+}
+
+// RemoveKey removes a key. Tokens signed with it can no longer be verified.
+func (kr *Keyring) RemoveKey(kid string) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	for i, k := range kr.keys {
+		if k.KID == kid {
+			kr.keys = append(kr.keys[:i], kr.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// SigningKey returns the key used to sign tokens at now.
+func (kr *Keyring) SigningKey(now time.Time) (SigningKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	for i := len(kr.keys) - 1; i >= 0; i-- {
+		if !kr.keys[i].ActivateAt.After(now) {
+			return kr.keys[i], nil
+		}
+	}
+	return SigningKey{}, ErrNoActiveKey
+}
+
+// VerificationKey returns the key with the given kid.
+func (kr *Keyring) VerificationKey(kid string) ([]byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	for _, k := range kr.keys {
+		if k.KID == kid {
+			return k.Key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// KeyIDs returns the ids of all keys ordered by activation time.
+func (kr *Keyring) KeyIDs() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	ids := make([]string, 0, len(kr.keys))
+	for _, k := range kr.keys {
+		ids = append(ids, k.KID)
+	}
+	return ids
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyring_Rotation(t *testing.T) {
+	now := time.Now()
+	kr, err := NewKeyring(
+		SigningKey{KID: "k1", Key: []byte("key-1"), ActivateAt: now.Add(-time.Hour)},
+		SigningKey{KID: "k2", Key: []byte("key-2"), ActivateAt: now.Add(time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := NewJWTWithKeyring(kr, nil)
+	j.SetTokenTTL(time.Minute)
+
+	oldToken, err := j.CreateToken(CustomClaims{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新密钥生效后改用k2签名，k1签发的token仍然有效
+	kr.RemoveKey("k2")
+	if err := kr.AddKey(SigningKey{KID: "k2", Key: []byte("key-2"), ActivateAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := kr.SigningKey(time.Now()); key.KID != "k2" {
+		t.Fatalf("SigningKey() = %q, want k2", key.KID)
+	}
+	newToken, err := j.CreateToken(CustomClaims{Username: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := j.ParseToken(token); err != nil {
+			t.Errorf("ParseToken() error = %v", err)
+		}
+	}
+
+	// 移除旧密钥后旧token失效
+	kr.RemoveKey("k1")
+	if _, err := j.ParseToken(oldToken); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("ParseToken() error = %v, want %v", err, ErrKeyNotFound)
+	}
+	if err := kr.AddKey(SigningKey{KID: "k2", Key: []byte("x")}); !errors.Is(err, ErrDuplicateKeyID) {
+		t.Errorf("AddKey() error = %v, want %v", err, ErrDuplicateKeyID)
+	}
+}
+
+func TestKeyring_LegacyTokenWithoutKid(t *testing.T) {
+	legacy := NewJWT([]byte("legacy-key"), nil)
+	token, err := legacy.CreateToken(CustomClaims{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr, _ := NewKeyring(
+		SigningKey{Key: []byte("legacy-key")},
+		SigningKey{KID: "k1", Key: []byte("key-1"), ActivateAt: time.Now().Add(-time.Second)},
+	)
+	j := NewJWTWithKeyring(kr, nil)
+	if _, err := j.ParseToken(token); err != nil {
+		t.Errorf("ParseToken() error = %v", err)
+	}
+	if key, _ := kr.SigningKey(time.Now()); key.KID != "k1" {
+		t.Errorf("SigningKey() = %q, want k1", key.KID)
+	}
+}
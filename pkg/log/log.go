@@ -2,7 +2,6 @@ package log
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -333,6 +332,22 @@ func (hook *logHook) openLogFile(tempFileName string) error {
 	return nil
 }
 
+// levelOverride 命令行 --loglevel 指定的日志级别，优先于配置文件，配置热更新时不修改
+var levelOverride string
+
+// SetLevelOverride 设置命令行指定的日志级别，在创建logger之前调用，为空时使用配置文件的级别
+func SetLevelOverride(level string) {
+	levelOverride = level
+}
+
+// SetGlobalLevel 修改全局logger的日志级别，用于配置热更新
+func SetGlobalLevel(level string) {
+	if levelOverride != "" {
+		return
+	}
+	logrus.SetLevel(PraseLevel(level))
+}
+
 // 默认 --loglevel=info
 func InitGlobalLogger(config LogConfig) error {
 	return initlLog(logrus.StandardLogger(), config)
@@ -349,13 +364,12 @@ func NewLogger(config LogConfig) (*logrus.Logger, error) {
 }
 
 func initlLog(logger *logrus.Logger, config LogConfig) error {
-	var levelStr = flag.String("loglevel", "", "log level(panic,fatal,error,warn,info,debug,trace)")
-	flag.Parse()
-	if *levelStr == "" {
-		*levelStr = config.LogLevel
+	levelStr := config.LogLevel
+	if levelOverride != "" {
+		levelStr = levelOverride
 	}
 
-	var level logrus.Level = PraseLevel(*levelStr)
+	var level logrus.Level = PraseLevel(levelStr)
 	//fmt.Println("level:", level)
 
 	logger.SetReportCaller(true) //开启调用者信息
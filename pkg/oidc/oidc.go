@@ -0,0 +1,369 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingIDToken = errors.New("oidc: token response has no id_token")
+	ErrNonceMismatch  = errors.New("oidc: nonce mismatch")
+	ErrIssuerMismatch = errors.New("oidc: issuer mismatch")
+	ErrMissingExpiry  = errors.New("oidc: id_token has no exp")
+	ErrMissingSubject = errors.New("oidc: id_token has no sub")
+)
+
+// 默认申请的scope
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// 同一个Provider两次刷新JWKS的最小间隔，防止伪造kid的token触发大量请求
+const jwksRefreshInterval = time.Minute
+
+// 验证id_token时允许的时钟偏差
+const clockLeeway = time.Minute
+
+// Config 一个OIDC身份提供方的客户端配置
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// 为空时使用 openid email profile
+	Scopes []string
+	// 以下为空时通过 {Issuer}/.well-known/openid-configuration 获取
+	AuthURL  string
+	TokenURL string
+	JWKSURL  string
+	// 为空时使用 http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Token token endpoint的响应
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Claims 从id_token中取出的用户信息
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Provider OIDC客户端，并发安全。mu只保护下面的字段，访问提供方的网络请求不持有mu
+type Provider struct {
+	config Config
+
+	mu        sync.Mutex
+	endpoints endpoints
+	// kid -> public key
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// endpoints 提供方的地址，配置中没有的通过discovery获取
+type endpoints struct {
+	authURL  string
+	tokenURL string
+	jwksURL  string
+}
+
+func (e endpoints) complete() bool {
+	return e.authURL != "" && e.tokenURL != "" && e.jwksURL != ""
+}
+
+// NewProvider creates a provider. Endpoints missing from config are discovered lazily.
+func NewProvider(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &Provider{
+		config: config,
+		endpoints: endpoints{
+			authURL:  config.AuthURL,
+			tokenURL: config.TokenURL,
+			jwksURL:  config.JWKSURL,
+		},
+	}
+}
+
+// AuthCodeURL returns the URL of the provider's consent page.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.config.ClientID)
+	v.Set("redirect_uri", p.config.RedirectURL)
+	v.Set("scope", strings.Join(p.config.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", codeChallenge)
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(ep.authURL, "?") {
+		sep = "&"
+	}
+	return ep.authURL + sep + v.Encode(), nil
+}
+
+// Exchange exchanges the authorization code for tokens.
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (Token, error) {
+	var token Token
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return token, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client_secret_basic, RFC 6749 2.3.1 要求先做表单编码
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return token, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return token, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(body, &oauthErr)
+		return token, fmt.Errorf("oidc: token endpoint returned %d: %s %s", resp.StatusCode, oauthErr.Error, oauthErr.Description)
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return token, fmt.Errorf("oidc: decode token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return token, ErrMissingIDToken
+	}
+	return token, nil
+}
+
+// idTokenClaims id_token中关心的字段
+type idTokenClaims struct {
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// VerifyIDToken verifies the signature, issuer, audience, expiry and nonce of an id_token.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (Claims, error) {
+	var claims Claims
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return claims, err
+	}
+	parsed := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, parsed, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.verificationKey(ctx, ep.jwksURL, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithLeeway(clockLeeway),
+	)
+	if err != nil {
+		return claims, err
+	}
+	if parsed.ExpiresAt == nil {
+		return claims, ErrMissingExpiry
+	}
+	if parsed.Subject == "" {
+		return claims, ErrMissingSubject
+	}
+	if parsed.Nonce != nonce {
+		return claims, ErrNonceMismatch
+	}
+	claims.Subject = parsed.Subject
+	claims.Email = parsed.Email
+	claims.EmailVerified = bool(parsed.EmailVerified)
+	claims.Name = parsed.Name
+	claims.PreferredUsername = parsed.PreferredUsername
+	return claims, nil
+}
+
+// discoveryDocument openid-configuration中关心的字段
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover 返回提供方的地址，缺少的通过discovery获取。
+// 并发的请求可能各自获取一次，结果相同，不影响正确性
+func (p *Provider) discover(ctx context.Context) (endpoints, error) {
+	p.mu.Lock()
+	ep := p.endpoints
+	p.mu.Unlock()
+	if ep.complete() {
+		return ep, nil
+	}
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, wellKnown, &doc); err != nil {
+		return ep, err
+	}
+	if doc.Issuer != p.config.Issuer {
+		return ep, fmt.Errorf("%w: want %q, got %q", ErrIssuerMismatch, p.config.Issuer, doc.Issuer)
+	}
+	if ep.authURL == "" {
+		ep.authURL = doc.AuthorizationEndpoint
+	}
+	if ep.tokenURL == "" {
+		ep.tokenURL = doc.TokenEndpoint
+	}
+	if ep.jwksURL == "" {
+		ep.jwksURL = doc.JWKSURI
+	}
+	if !ep.complete() {
+		return ep, errors.New("oidc: discovery document is missing endpoints")
+	}
+	p.mu.Lock()
+	p.endpoints = ep
+	p.mu.Unlock()
+	return ep, nil
+}
+
+// verificationKey 按kid查找公钥，找不到时刷新一次JWKS以支持提供方轮换密钥
+func (p *Provider) verificationKey(ctx context.Context, jwksURL string, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.lookupKey(kid)
+	// 在请求前占用刷新间隔，并发的未知kid不会同时请求JWKS
+	lastFetch := p.keysFetchedAt
+	refresh := !ok && (lastFetch.IsZero() || time.Since(lastFetch) >= jwksRefreshInterval)
+	if refresh {
+		p.keysFetchedAt = time.Now()
+	}
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+	keys, err := p.fetchKeys(ctx, jwksURL)
+	if err != nil {
+		// 请求失败时恢复，下一次验证可以重试
+		p.mu.Lock()
+		p.keysFetchedAt = lastFetch
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURL string) (map[string]interface{}, error) {
+	var set jsonWebKeySet
+	if err := p.getJSON(ctx, jwksURL, &set); err != nil {
+		return nil, err
+	}
+	return set.publicKeys()
+}
+
+// lookupKey 调用方需要持有mu
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// RandomString returns a random URL-safe string, used for state, nonce and PKCE verifiers.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// S256Challenge returns the PKCE code challenge of the verifier.
+func S256Challenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// flexBool 兼容把email_verified写成字符串"true"的提供方
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
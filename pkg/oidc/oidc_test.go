@@ -0,0 +1,215 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider 本地模拟的OIDC提供方，签发RS256的id_token
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// code -> 授权请求
+	codes map[string]url.Values
+	// 覆盖id_token中的claims
+	override func(claims jwt.MapClaims)
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{t: t, key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.handleToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize 模拟用户在提供方同意授权，返回code
+func (m *mockProvider) authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	code := "code-" + u.Query().Get("state")
+	m.codes[code] = u.Query()
+	return code
+}
+
+func (m *mockProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	clientID, secret, _ := r.BasicAuth()
+	if !ok || clientID != "client" || secret != "secret" ||
+		S256Challenge(r.PostForm.Get("code_verifier")) != auth.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(m.codes, r.PostForm.Get("code"))
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            auth.Get("client_id"),
+		"sub":            "user-123",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          auth.Get("nonce"),
+		"email":          "alice@example.com",
+		"email_verified": "true",
+	}
+	if m.override != nil {
+		m.override(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock-key"
+	idToken, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"expires_in":   3600,
+	})
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       m.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+}
+
+func (m *mockProvider) login(t *testing.T, p *Provider, authNonce string) (Claims, error) {
+	ctx := context.Background()
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state1", authNonce, S256Challenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	token, err := p.Exchange(ctx, m.authorize(authURL), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	return p.VerifyIDToken(ctx, token.IDToken, "nonce1")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	claims, err := m.login(t, m.provider(), "nonce1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	want := Claims{Subject: "user-123", Email: "alice@example.com", EmailVerified: true}
+	if claims != want {
+		t.Errorf("VerifyIDToken() = %+v, want %+v", claims, want)
+	}
+}
+
+func TestProvider_VerifyIDTokenRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string
+		override func(jwt.MapClaims)
+		wantErr  error
+	}{
+		{name: "nonce mismatch", nonce: "other", wantErr: ErrNonceMismatch},
+		{name: "wrong audience", nonce: "nonce1", override: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, wantErr: jwt.ErrTokenInvalidAudience},
+		{name: "wrong issuer", nonce: "nonce1", override: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: jwt.ErrTokenInvalidIssuer},
+		{name: "expired", nonce: "nonce1", override: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: jwt.ErrTokenExpired},
+		{name: "missing exp", nonce: "nonce1", override: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: ErrMissingExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.override = tt.override
+			if _, err := m.login(t, m.provider(), tt.nonce); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyIDToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvider_ExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+	ctx := context.Background()
+	verifier, _ := RandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state1", "nonce1", S256Challenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(ctx, m.authorize(authURL), "wrong-verifier"); err == nil {
+		t.Error("Exchange() error = nil, want invalid_grant")
+	}
+}
+
+func TestProvider_FetchDoesNotBlockOtherRequests(t *testing.T) {
+	m := newMockProvider(t)
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	p := m.provider()
+	go p.verificationKey(context.Background(), slow.URL, "unknown")
+	<-arrived
+
+	// JWKS请求未返回时，其他请求不需要等待
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("AuthCodeURL() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AuthCodeURL() blocked by a pending JWKS fetch")
+	}
+}
@@ -1,10 +1,19 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/wumansgy/goEncrypt/aes"
+	goaes "github.com/wumansgy/goEncrypt/aes"
 )
 
 type CbcAESCrypt struct {
@@ -24,17 +33,152 @@ func (a *CbcAESCrypt) Encrypt(plainText string) (string, error) {
 	if plainText == "" {
 		return "", errors.New("plainText is empty")
 	}
-	return aes.AesCbcEncryptHex([]byte(plainText), a.secretKey, nil)
+	return goaes.AesCbcEncryptHex([]byte(plainText), a.secretKey, nil)
 }
 
 func (a *CbcAESCrypt) Decrypt(cipherTextHex string) (string, error) {
 	if cipherTextHex == "" {
 		return "", errors.New("cipherTextHex is empty")
 	}
-	plaintext, err := aes.AesCbcDecryptByHex(cipherTextHex, a.secretKey, nil)
+	plaintext, err := goaes.AesCbcDecryptByHex(cipherTextHex, a.secretKey, nil)
 	return string(plaintext), err
 }
 
+var (
+	ErrCipherKeyNotFound = errors.New("cipher key version not found")
+	ErrNoActiveCipherKey = errors.New("no active cipher key")
+	ErrInvalidCipherText = errors.New("invalid cipher text")
+)
+
+// GCM密文格式: v<密钥版本>.<base64url(nonce+密文)>
+// 版本前缀同时作为附加数据参与认证，篡改版本号会导致解密失败
+const gcmCipherTextPrefix = "v"
+
+type gcmKey struct {
+	version    uint32
+	aead       cipher.AEAD
+	activateAt time.Time
+}
+
+// GcmAESCrypt AES-GCM加密器，密文带密钥版本号，支持多把密钥轮换。
+// 加密使用已生效且生效时间最晚的密钥，解密按密文中的版本号选择密钥。
+type GcmAESCrypt struct {
+	mu   sync.RWMutex
+	keys []gcmKey
+	// 解密不带版本号的旧CBC密文，可以为nil
+	legacy *CbcAESCrypt
+}
+
+// NewGCMAESCrypt 创建AES-GCM加密器, HexSecretKey为16进制字符串，版本号为1
+func NewGCMAESCrypt(HexSecretKey string) (*GcmAESCrypt, error) {
+	c := &GcmAESCrypt{}
+	return c, c.AddKey(1, HexSecretKey, time.Time{})
+}
+
+// AddKey 添加一把密钥，activateAt之后用于加密，之前只用于解密
+func (g *GcmAESCrypt) AddKey(version uint32, HexSecretKey string, activateAt time.Time) error {
+	secretKey, err := HexStrToBytes(HexSecretKey)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(secretKey)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, k := range g.keys {
+		if k.version == version {
+			return fmt.Errorf("duplicate cipher key version %d", version)
+		}
+	}
+	g.keys = append(g.keys, gcmKey{version: version, aead: aead, activateAt: activateAt})
+	sort.SliceStable(g.keys, func(i, j int) bool {
+		return g.keys[i].activateAt.Before(g.keys[j].activateAt)
+	})
+	return nil
+}
+
+// SetLegacy 设置旧的CBC加密器，用于解密轮换前签发的密文
+func (g *GcmAESCrypt) SetLegacy(legacy *CbcAESCrypt) {
+	g.legacy = legacy
+}
+
+// Encrypt 加密为带版本号的密文
+func (g *GcmAESCrypt) Encrypt(plainText string) (string, error) {
+	if plainText == "" {
+		return "", errors.New("plainText is empty")
+	}
+	key, err := g.activeKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+	prefix := gcmCipherTextPrefix + strconv.FormatUint(uint64(key.version), 10)
+	nonce := make([]byte, key.aead.NonceSize(), key.aead.NonceSize()+len(plainText)+key.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plainText), []byte(prefix))
+	return prefix + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (g *GcmAESCrypt) Decrypt(cipherText string) (string, error) {
+	if cipherText == "" {
+		return "", errors.New("cipherText is empty")
+	}
+	prefix, payload, found := strings.Cut(cipherText, ".")
+	if !found || !strings.HasPrefix(prefix, gcmCipherTextPrefix) {
+		if g.legacy != nil {
+			return g.legacy.Decrypt(cipherText)
+		}
+		return "", ErrInvalidCipherText
+	}
+	version, err := strconv.ParseUint(strings.TrimPrefix(prefix, gcmCipherTextPrefix), 10, 32)
+	if err != nil {
+		return "", ErrInvalidCipherText
+	}
+	key, err := g.key(uint32(version))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return "", ErrInvalidCipherText
+	}
+	nonce, sealed := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, sealed, []byte(prefix))
+	if err != nil {
+		return "", ErrInvalidCipherText
+	}
+	return string(plaintext), nil
+}
+
+func (g *GcmAESCrypt) activeKey(now time.Time) (gcmKey, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for i := len(g.keys) - 1; i >= 0; i-- {
+		if !g.keys[i].activateAt.After(now) {
+			return g.keys[i], nil
+		}
+	}
+	return gcmKey{}, ErrNoActiveCipherKey
+}
+
+func (g *GcmAESCrypt) key(version uint32) (gcmKey, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, k := range g.keys {
+		if k.version == version {
+			return k, nil
+		}
+	}
+	return gcmKey{}, ErrCipherKeyNotFound
+}
+
 // HexStrToBytes convert hex string to bytes
 func HexStrToBytes(hexStr string) ([]byte, error) {
 	if len(hexStr)%2 != 0 {
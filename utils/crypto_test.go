@@ -2,7 +2,9 @@ package utils
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHexStrToBytes(t *testing.T) {
@@ -42,3 +44,65 @@ func TestHexStrToBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestGcmAESCrypt_Rotation(t *testing.T) {
+	legacy, err := NewAESCrypt("fedcba9876543210fedcba9876543210")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyText, err := legacy.Encrypt("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewGCMAESCrypt("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetLegacy(legacy)
+	v1Text, err := g.Encrypt("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(v1Text, "v1.") {
+		t.Fatalf("Encrypt() = %q, want prefix v1.", v1Text)
+	}
+
+	// 未生效的新密钥只用于解密
+	if err := g.AddKey(2, "00112233445566778899aabbccddeeff", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := g.Encrypt("token"); !strings.HasPrefix(got, "v1.") {
+		t.Errorf("Encrypt() = %q, want key v1 before activation", got)
+	}
+	g2, _ := NewGCMAESCrypt("0123456789abcdef0123456789abcdef")
+	g2.AddKey(2, "00112233445566778899aabbccddeeff", time.Now().Add(-time.Second))
+	v2Text, err := g2.Encrypt("token")
+	if err != nil || !strings.HasPrefix(v2Text, "v2.") {
+		t.Fatalf("Encrypt() = %q, %v, want prefix v2.", v2Text, err)
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "legacy cbc", in: legacyText},
+		{name: "v1", in: v1Text},
+		{name: "v2", in: v2Text},
+		{name: "tampered version", in: "v2" + strings.TrimPrefix(v1Text, "v1"), wantErr: true},
+		{name: "unknown version", in: "v9" + strings.TrimPrefix(v1Text, "v1"), wantErr: true},
+		{name: "tampered payload", in: v1Text[:len(v1Text)-2] + "AA", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.Decrypt(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != "token" {
+				t.Errorf("Decrypt() = %q, want %q", got, "token")
+			}
+		})
+	}
+}